@@ -3,11 +3,14 @@ package api
 import (
 	"database/sql"
 	"errors"
+	"log"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	db "github.com/go_backend_misc/db/sqlc"
 	"github.com/go_backend_misc/token"
+	"github.com/go_backend_misc/util"
 	"github.com/lib/pq"
 )
 
@@ -23,6 +26,10 @@ func (server *Server) createAccount(ginCtx *gin.Context) {
 	}
 
 	authPayload := ginCtx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if !server.requireVerifiedEmail(ginCtx, server.config.RequireVerifiedEmailForAccounts, authPayload.Username) {
+		return
+	}
+
 	arg := db.CreateAccountParams{
 		Owner:    authPayload.Username,
 		Currency: req.Currency,
@@ -48,8 +55,32 @@ func (server *Server) createAccount(ginCtx *gin.Context) {
 		return
 	}
 
-	ginCtx.JSON(http.StatusOK, account)
+	ginCtx.JSON(http.StatusOK, createAccountResponse(&account))
+
+}
 
+type accountResponse struct {
+	ID               int64     `json:"id"`
+	Owner            string    `json:"owner"`
+	Balance          string    `json:"balance"`
+	HeldBalance      string    `json:"held_balance"`
+	AvailableBalance string    `json:"available_balance"`
+	Currency         string    `json:"currency"`
+	Kind             string    `json:"kind"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+func createAccountResponse(account *db.Account) accountResponse {
+	return accountResponse{
+		ID:               account.ID,
+		Owner:            account.Owner,
+		Balance:          util.NewMoney(account.Balance, account.Currency).Decimal(),
+		HeldBalance:      util.NewMoney(account.HeldBalance, account.Currency).Decimal(),
+		AvailableBalance: util.NewMoney(account.AvailableBalance, account.Currency).Decimal(),
+		Currency:         account.Currency,
+		Kind:             account.Kind,
+		CreatedAt:        account.CreatedAt,
+	}
 }
 
 type getAccountParams struct {
@@ -79,7 +110,7 @@ func (server *Server) getAccount(ctx *gin.Context) {
 		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
 		return
 	}
-	ctx.JSON(http.StatusOK, account)
+	ctx.JSON(http.StatusOK, createAccountResponse(&account))
 
 }
 
@@ -111,6 +142,154 @@ func (server *Server) listAccounts(ctx *gin.Context) {
 		return
 	}
 
-	ctx.JSON(http.StatusOK, accounts)
+	response := make([]accountResponse, len(accounts))
+	for i := range accounts {
+		response[i] = createAccountResponse(&accounts[i])
+	}
+	ctx.JSON(http.StatusOK, response)
+
+}
+
+type accountBalanceResponse struct {
+	AccountID          int64      `json:"account_id"`
+	At                 time.Time  `json:"at"`
+	Balance            string     `json:"balance"`
+	Currency           string     `json:"currency"`
+	SnapshotTakenUntil *time.Time `json:"snapshot_taken_until,omitempty"`
+}
+
+type getAccountBalanceQueryParams struct {
+	// At defaults to now
+	At time.Time `form:"at" time_format:"2006-01-02T15:04:05Z07:00"`
+}
+
+// getAccountBalance computes the balance of the account at a point in time from its entries,
+// auditors get it as admins
+func (server *Server) getAccountBalance(ctx *gin.Context) {
+	var uri getAccountParams
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	var req getAccountBalanceQueryParams
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	now := time.Now()
+	if req.At.IsZero() {
+		req.At = now
+	}
+	if req.At.After(now) {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errors.New("at can't be in the future")))
+		return
+	}
+
+	account, ok := server.readableAccount(ctx, uri.Id, "balance")
+	if !ok {
+		return
+	}
+
+	balance, err := server.store.AccountBalanceAt(ctx, account.ID, req.At)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, accountBalanceResponse{
+		AccountID:          balance.AccountID,
+		At:                 balance.At,
+		Balance:            util.NewMoney(balance.Balance, account.Currency).Decimal(),
+		Currency:           account.Currency,
+		SnapshotTakenUntil: balance.SnapshotTakenUntil,
+	})
+}
+
+// readableAccount gets an account the authenticated user can read, their own or any as an admin,
+// what is the part of the account read by admins for the security log
+func (server *Server) readableAccount(ctx *gin.Context, accountID int64, what string) (db.Account, bool) {
+	account, err := server.store.GetAccount(ctx, accountID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+		} else {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return account, false
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if account.Owner != authPayload.Username {
+		isAdmin, err := server.isAdmin(ctx, authPayload.Username)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return account, false
+		}
+		if !isAdmin {
+			err := errors.New("account doesn't belong to the authenticated user")
+			ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+			return account, false
+		}
+		log.Printf("security: admin %v reading the %s of account %d of %v", authPayload.Username, what, account.ID, account.Owner)
+	}
+	return account, true
+}
+
+// limitUsageResponse leaves out the limit and what remains of it when there is no limit
+type limitUsageResponse struct {
+	Limit     string    `json:"limit,omitempty"`
+	Used      string    `json:"used"`
+	Remaining string    `json:"remaining,omitempty"`
+	Since     time.Time `json:"since"`
+}
+
+func createLimitUsageResponse(usage *db.LimitUsage, currency string) limitUsageResponse {
+	response := limitUsageResponse{
+		Used:  util.NewMoney(usage.Used, currency).Decimal(),
+		Since: usage.Since,
+	}
+	if usage.Limit > 0 {
+		response.Limit = util.NewMoney(usage.Limit, currency).Decimal()
+		response.Remaining = util.NewMoney(usage.Remaining, currency).Decimal()
+	}
+	return response
+}
+
+type accountLimitsResponse struct {
+	AccountID      int64              `json:"account_id"`
+	Currency       string             `json:"currency"`
+	KYCTier        int32              `json:"kyc_tier"`
+	PerTransaction string             `json:"per_transaction,omitempty"`
+	Daily          limitUsageResponse `json:"daily"`
+	Monthly        limitUsageResponse `json:"monthly"`
+}
+
+// getAccountLimits returns the transfer limits of the account and what it can still send within them
+func (server *Server) getAccountLimits(ctx *gin.Context) {
+	var uri getAccountParams
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	account, ok := server.readableAccount(ctx, uri.Id, "limits")
+	if !ok {
+		return
+	}
 
+	allowance, err := server.store.TransferAllowance(ctx, account.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	response := accountLimitsResponse{
+		AccountID: allowance.AccountID,
+		Currency:  account.Currency,
+		KYCTier:   allowance.KYCTier,
+		Daily:     createLimitUsageResponse(&allowance.Daily, account.Currency),
+		Monthly:   createLimitUsageResponse(&allowance.Monthly, account.Currency),
+	}
+	if allowance.PerTransaction > 0 {
+		response.PerTransaction = util.NewMoney(allowance.PerTransaction, account.Currency).Decimal()
+	}
+	ctx.JSON(http.StatusOK, response)
 }
@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	mockdb "github.com/go_backend_misc/db/mock"
 	db "github.com/go_backend_misc/db/sqlc"
@@ -103,6 +104,7 @@ func TestGetAccountAPI(t *testing.T) {
 
 			store := mockdb.NewMockStore(ctrl)
 			testCase.buildStubs(store)
+			stubAuthUser(store)
 
 			// start server
 			server := newTestServer(t, store)
@@ -118,6 +120,271 @@ func TestGetAccountAPI(t *testing.T) {
 	}
 }
 
+func TestGetAccountBalanceAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+	at := time.Date(2026, 3, 31, 23, 59, 0, 0, time.UTC)
+	takenUntil := time.Date(2026, 3, 31, 0, 0, 0, 0, time.UTC)
+	balance := db.AccountBalance{AccountID: account.ID, At: at, Balance: 1500, SnapshotTakenUntil: &takenUntil}
+
+	testCases := []struct {
+		name          string
+		query         string
+		username      string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "OK",
+			query:    "?at=2026-03-31T23:59:00Z",
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), account.ID).Times(1).Return(account, nil)
+				store.EXPECT().AccountBalanceAt(gomock.Any(), account.ID, at).Times(1).Return(balance, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var response accountBalanceResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+				require.Equal(t, "15.00", response.Balance)
+				require.Equal(t, account.Currency, response.Currency)
+				require.True(t, takenUntil.Equal(*response.SnapshotTakenUntil))
+			},
+		},
+		{
+			name:     "Now by default",
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), account.ID).Times(1).Return(account, nil)
+				store.EXPECT().
+					AccountBalanceAt(gomock.Any(), account.ID, gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, accountID int64, at time.Time) (db.AccountBalance, error) {
+						require.WithinDuration(t, time.Now(), at, time.Minute)
+						return db.AccountBalance{AccountID: accountID, At: at, Balance: account.Balance}, nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:     "Admin",
+			query:    "?at=2026-03-31T23:59:00Z",
+			username: "an_admin",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUserByUsername(gomock.Any(), "an_admin").
+					AnyTimes().
+					Return(db.User{Username: "an_admin", Role: util.RoleAdmin}, nil)
+				store.EXPECT().GetAccount(gomock.Any(), account.ID).Times(1).Return(account, nil)
+				store.EXPECT().AccountBalanceAt(gomock.Any(), account.ID, at).Times(1).Return(balance, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:     "Not the owner",
+			query:    "?at=2026-03-31T23:59:00Z",
+			username: "someone_else",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), account.ID).Times(1).Return(account, nil)
+				store.EXPECT().AccountBalanceAt(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:     "Invalid time",
+			query:    "?at=2026-03-31",
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:     "Future",
+			query:    "?at=" + time.Now().Add(time.Hour).UTC().Format(time.RFC3339),
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:     "Not found",
+			query:    "?at=2026-03-31T23:59:00Z",
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), account.ID).Times(1).Return(db.Account{}, sql.ErrNoRows)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:     "Internal error",
+			query:    "?at=2026-03-31T23:59:00Z",
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), account.ID).Times(1).Return(account, nil)
+				store.EXPECT().
+					AccountBalanceAt(gomock.Any(), account.ID, at).
+					Times(1).
+					Return(db.AccountBalance{}, sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			stubAuthUser(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+			url := fmt.Sprintf("/accounts/%d/balance%s", account.ID, tc.query)
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, tc.username)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestGetAccountLimitsAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+	account.Currency = util.USD
+	allowance := db.TransferAllowance{
+		AccountID:      account.ID,
+		Currency:       account.Currency,
+		KYCTier:        1,
+		PerTransaction: 50000,
+		Daily:          db.LimitUsage{Limit: 100000, Used: 25050, Remaining: 74950},
+		Monthly:        db.LimitUsage{Used: 25050},
+	}
+
+	testCases := []struct {
+		name          string
+		username      string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "OK",
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), account.ID).Times(1).Return(account, nil)
+				store.EXPECT().TransferAllowance(gomock.Any(), account.ID).Times(1).Return(allowance, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var response accountLimitsResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+				require.Equal(t, int32(1), response.KYCTier)
+				require.Equal(t, "500.00", response.PerTransaction)
+				require.Equal(t, "1000.00", response.Daily.Limit)
+				require.Equal(t, "250.50", response.Daily.Used)
+				require.Equal(t, "749.50", response.Daily.Remaining)
+				// no monthly limit
+				require.Empty(t, response.Monthly.Limit)
+				require.Empty(t, response.Monthly.Remaining)
+				require.Equal(t, "250.50", response.Monthly.Used)
+			},
+		},
+		{
+			name:     "Admin",
+			username: "an_admin",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUserByUsername(gomock.Any(), "an_admin").
+					AnyTimes().
+					Return(db.User{Username: "an_admin", Role: util.RoleAdmin}, nil)
+				store.EXPECT().GetAccount(gomock.Any(), account.ID).Times(1).Return(account, nil)
+				store.EXPECT().TransferAllowance(gomock.Any(), account.ID).Times(1).Return(allowance, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:     "Not the owner",
+			username: "someone_else",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), account.ID).Times(1).Return(account, nil)
+				store.EXPECT().TransferAllowance(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:     "Not found",
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), account.ID).Times(1).Return(db.Account{}, sql.ErrNoRows)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:     "Internal error",
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), account.ID).Times(1).Return(account, nil)
+				store.EXPECT().
+					TransferAllowance(gomock.Any(), account.ID).
+					Times(1).
+					Return(db.TransferAllowance{}, sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			stubAuthUser(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+			url := fmt.Sprintf("/accounts/%d/limits", account.ID)
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, tc.username)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
 func randomAccount(owner string) db.Account {
 	return db.Account{
 		ID:       util.RandomInt(1, 1000),
@@ -131,10 +398,10 @@ func requireBodyMatchAccount(t *testing.T, body *bytes.Buffer, account *db.Accou
 	data, err := io.ReadAll(body)
 	require.NoError(t, err)
 
-	var receivedAccount db.Account
+	var receivedAccount accountResponse
 	err = json.Unmarshal(data, &receivedAccount)
 	require.NoError(t, err)
 	require.Equal(t, receivedAccount.ID, account.ID)
 	require.Equal(t, receivedAccount.Currency, account.Currency)
-	require.Equal(t, receivedAccount.Balance, account.Balance)
+	require.Equal(t, receivedAccount.Balance, util.NewMoney(account.Balance, account.Currency).Decimal())
 }
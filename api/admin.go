@@ -0,0 +1,34 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go_backend_misc/token"
+	"github.com/go_backend_misc/util"
+)
+
+// isAdmin looks the role up on every request, so revoking it takes effect right away
+func (server *Server) isAdmin(ctx *gin.Context, username string) (bool, error) {
+	user, err := server.store.GetUserByUsername(ctx, username)
+	if err != nil {
+		return false, err
+	}
+	return user.Role == util.RoleAdmin, nil
+}
+
+// requireAdmin only lets admins through, it runs after authMiddleware
+func (server *Server) requireAdmin(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	isAdmin, err := server.isAdmin(ctx, authPayload.Username)
+	if err != nil {
+		ctx.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if !isAdmin {
+		ctx.AbortWithStatusJSON(http.StatusForbidden, errorResponse(errors.New("admin role required")))
+		return
+	}
+	ctx.Next()
+}
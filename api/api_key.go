@@ -0,0 +1,198 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/go_backend_misc/db/sqlc"
+	"github.com/go_backend_misc/token"
+	"github.com/go_backend_misc/util"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	apiKeyTag          = "sbk"
+	apiKeyPrefixBytes  = 4
+	apiKeySecretBytes  = 24
+	scopeAccountsRead  = "accounts:read"
+	scopeAccountsWrite = "accounts:write"
+	scopeTransfers     = "transfers:write"
+	// scopeSession is never granted to API keys, routes requiring it are only reachable with a user session
+	scopeSession = "session"
+)
+
+var grantableScopes = []string{scopeAccountsRead, scopeAccountsWrite, scopeTransfers}
+
+var errInvalidAPIKey = errors.New("invalid api key")
+
+type createAPIKeyRequest struct {
+	Name      string     `json:"name" binding:"required"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1,dive,scope"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type apiKeyResponse struct {
+	ID        uuid.UUID  `json:"id"`
+	Name      string     `json:"name"`
+	Prefix    string     `json:"prefix"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type createAPIKeyResponse struct {
+	// Key is only returned once, only its hash is stored
+	Key    string         `json:"key"`
+	APIKey apiKeyResponse `json:"api_key"`
+}
+
+func createAPIKeyResponseFromAPIKey(apiKey *db.ApiKey) apiKeyResponse {
+	response := apiKeyResponse{
+		ID:        apiKey.ID,
+		Name:      apiKey.Name,
+		Prefix:    apiKey.Prefix,
+		Scopes:    apiKey.Scopes,
+		CreatedAt: apiKey.CreatedAt,
+	}
+	if apiKey.ExpiresAt.Valid {
+		response.ExpiresAt = &apiKey.ExpiresAt.Time
+	}
+	if apiKey.RevokedAt.Valid {
+		response.RevokedAt = &apiKey.RevokedAt.Time
+	}
+	return response
+}
+
+// newAPIKey generates a key of the form sbk_<prefix>_<secret>
+// The prefix is stored in plain text so the key can be looked up and shown to the user
+func newAPIKey() (key string, prefix string, err error) {
+	prefix, err = util.RandomSecret(apiKeyPrefixBytes)
+	if err != nil {
+		return "", "", err
+	}
+	secret, err := util.RandomSecret(apiKeySecretBytes)
+	if err != nil {
+		return "", "", err
+	}
+	return fmt.Sprintf("%s_%s_%s", apiKeyTag, prefix, secret), prefix, nil
+}
+
+func parseAPIKeyPrefix(key string) (string, error) {
+	parts := strings.Split(key, "_")
+	if len(parts) != 3 || parts[0] != apiKeyTag {
+		return "", errInvalidAPIKey
+	}
+	return parts[1], nil
+}
+
+func (server *Server) createAPIKey(ctx *gin.Context) {
+	var req createAPIKeyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if req.ExpiresAt != nil && req.ExpiresAt.Before(time.Now()) {
+		ctx.JSON(http.StatusBadRequest, errorMessageResponse("expires_at must be in the future"))
+		return
+	}
+
+	key, prefix, err := newAPIKey()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	arg := db.CreateAPIKeyParams{
+		ID:        uuid.New(),
+		Owner:     authPayload.Username,
+		Name:      req.Name,
+		Prefix:    prefix,
+		HashedKey: util.HashSecret(key),
+		Scopes:    req.Scopes,
+	}
+	if req.ExpiresAt != nil {
+		arg.ExpiresAt = sql.NullTime{Time: *req.ExpiresAt, Valid: true}
+	}
+
+	apiKey, err := server.store.CreateAPIKey(ctx, arg)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code.Name() == "foreign_key_violation" {
+			ctx.JSON(http.StatusConflict, errorMessageResponse("Owner does not exist"))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	response := createAPIKeyResponse{
+		Key:    key,
+		APIKey: createAPIKeyResponseFromAPIKey(&apiKey),
+	}
+	ctx.JSON(http.StatusCreated, response)
+}
+
+type listAPIKeysQueryParams struct {
+	Offset   int32 `form:"offset" binding:"min=0"`
+	PageSize int32 `form:"page_size" binding:"required,min=1,max=20"`
+}
+
+func (server *Server) listAPIKeys(ctx *gin.Context) {
+	var req listAPIKeysQueryParams
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	apiKeys, err := server.store.ListAPIKeysByOwner(ctx, db.ListAPIKeysByOwnerParams{
+		Owner:  authPayload.Username,
+		Limit:  req.PageSize,
+		Offset: req.Offset,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	response := make([]apiKeyResponse, 0, len(apiKeys))
+	for i := range apiKeys {
+		response = append(response, createAPIKeyResponseFromAPIKey(&apiKeys[i]))
+	}
+	ctx.JSON(http.StatusOK, response)
+}
+
+type revokeAPIKeyParams struct {
+	ID string `uri:"id" binding:"required,uuid"`
+}
+
+func (server *Server) revokeAPIKey(ctx *gin.Context) {
+	var req revokeAPIKeyParams
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	apiKey, err := server.store.RevokeAPIKey(ctx, db.RevokeAPIKeyParams{
+		ID:    uuid.MustParse(req.ID),
+		Owner: authPayload.Username,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorMessageResponse("api key not found or already revoked"))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, createAPIKeyResponseFromAPIKey(&apiKey))
+}
@@ -0,0 +1,42 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go_backend_misc/util"
+)
+
+type currencyResponse struct {
+	Code     string `json:"code"`
+	Exponent int32  `json:"exponent"`
+	Symbol   string `json:"symbol"`
+	// MinTransferAmount and MaxTransferAmount are decimal strings, omitted when there is no limit
+	MinTransferAmount string `json:"min_transfer_amount,omitempty"`
+	MaxTransferAmount string `json:"max_transfer_amount,omitempty"`
+}
+
+func createCurrencyResponse(currency *util.Currency) currencyResponse {
+	response := currencyResponse{
+		Code:     currency.Code,
+		Exponent: currency.Exponent,
+		Symbol:   currency.Symbol,
+	}
+	if currency.MinTransferAmount > 0 {
+		response.MinTransferAmount = util.NewMoney(currency.MinTransferAmount, currency.Code).Decimal()
+	}
+	if currency.MaxTransferAmount > 0 {
+		response.MaxTransferAmount = util.NewMoney(currency.MaxTransferAmount, currency.Code).Decimal()
+	}
+	return response
+}
+
+// listCurrencies lists the enabled currencies of the registry, the ones accounts and transfers can use
+func (server *Server) listCurrencies(ctx *gin.Context) {
+	currencies := util.Currencies()
+	response := make([]currencyResponse, len(currencies))
+	for i := range currencies {
+		response[i] = createCurrencyResponse(&currencies[i])
+	}
+	ctx.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	mockdb "github.com/go_backend_misc/db/mock"
+	"github.com/go_backend_misc/util"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+// setTestCurrencies replaces the registry for the test, like a reload from the currencies table would
+func setTestCurrencies(t *testing.T, currencies []util.Currency) {
+	previous := util.Currencies()
+	util.SetCurrencies(currencies)
+	t.Cleanup(func() { util.SetCurrencies(previous) })
+}
+
+func testCurrencies() []util.Currency {
+	return []util.Currency{
+		{Code: util.USD, Exponent: 2, Symbol: "$", Enabled: true, MinTransferAmount: 50, MaxTransferAmount: 500},
+		{Code: util.EUR, Exponent: 2, Symbol: "€", Enabled: false},
+		{Code: "GBP", Exponent: 2, Symbol: "£", Enabled: true},
+	}
+}
+
+func TestListCurrenciesAPI(t *testing.T) {
+	setTestCurrencies(t, testCurrencies())
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	server := newTestServer(t, mockdb.NewMockStore(ctrl))
+	request, err := http.NewRequest(http.MethodGet, "/currencies", nil)
+	require.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var response []currencyResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	expected := []currencyResponse{
+		{Code: "GBP", Exponent: 2, Symbol: "£"},
+		{Code: util.USD, Exponent: 2, Symbol: "$", MinTransferAmount: "0.50", MaxTransferAmount: "5.00"},
+	}
+	require.Equal(t, expected, response)
+}
+
+func TestCreateTransferCurrencyRegistry(t *testing.T) {
+	setTestCurrencies(t, testCurrencies())
+
+	testCases := []struct {
+		name          string
+		amount        string
+		currency      string
+		expectedError string
+	}{
+		{name: "Added currency", amount: "1.00", currency: "GBP"},
+		{name: "Within the limits", amount: "5.00", currency: util.USD},
+		{name: "Disabled currency", amount: "1.00", currency: util.EUR, expectedError: "'currency' tag"},
+		{name: "Unknown currency", amount: "1.00", currency: "CHF", expectedError: "'currency' tag"},
+		{name: "Below the minimum", amount: "0.49", currency: util.USD, expectedError: "below the minimum"},
+		{name: "Above the maximum", amount: "5.01", currency: util.USD, expectedError: "above the maximum"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			fromAccount, toAccount := getAccounts()
+			fromAccount.Currency, toAccount.Currency = tc.currency, tc.currency
+			store := mockdb.NewMockStore(ctrl)
+			if tc.expectedError == "" {
+				store.EXPECT().GetAccount(gomock.Any(), fromAccount.ID).Times(1).Return(fromAccount, nil)
+				store.EXPECT().GetAccount(gomock.Any(), toAccount.ID).Times(1).Return(toAccount, nil)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(*getOkTransferResult(fromAccount, toAccount), nil)
+			} else {
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			}
+			stubAuthUser(store)
+
+			server := newTestServer(t, store)
+			data, err := json.Marshal(gin.H{
+				"from_account_id": fromAccount.ID,
+				"to_account_id":   toAccount.ID,
+				"amount":          tc.amount,
+				"currency":        tc.currency,
+			})
+			require.NoError(t, err)
+			request, err := http.NewRequest(http.MethodPost, "/transfer", bytes.NewReader(data))
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, fromAccount.Owner)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			if tc.expectedError == "" {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				return
+			}
+			require.Equal(t, http.StatusBadRequest, recorder.Code)
+			require.Contains(t, recorder.Body.String(), tc.expectedError)
+		})
+	}
+}
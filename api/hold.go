@@ -0,0 +1,293 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/go_backend_misc/db/sqlc"
+	"github.com/go_backend_misc/risk"
+	"github.com/go_backend_misc/token"
+	"github.com/go_backend_misc/util"
+)
+
+const (
+	defaultHoldExpiry    = 7 * 24 * time.Hour
+	defaultHoldMaxExpiry = 30 * 24 * time.Hour
+)
+
+type createHoldRequest struct {
+	FromAccountID int64  `json:"from_account_id" binding:"required,min=1"`
+	ToAccountID   int64  `json:"to_account_id" binding:"required,min=1"`
+	Amount        string `json:"amount" binding:"required,amount"`
+	Currency      string `json:"currency" binding:"required,currency"`
+	// ExpiresAt defaults to the configured hold expiry
+	ExpiresAt *time.Time `json:"expires_at"`
+	// TOTPCode is required for amounts above the configured step-up amount
+	TOTPCode string `json:"totp_code" binding:"omitempty,alphanum"`
+}
+
+type holdResponse struct {
+	ID             int64      `json:"id"`
+	FromAccountID  int64      `json:"from_account_id"`
+	ToAccountID    int64      `json:"to_account_id"`
+	Amount         string     `json:"amount"`
+	Fee            string     `json:"fee"`
+	Currency       string     `json:"currency"`
+	Status         string     `json:"status"`
+	CapturedAmount *string    `json:"captured_amount,omitempty"`
+	TransferID     *int64     `json:"transfer_id,omitempty"`
+	ExpiresAt      time.Time  `json:"expires_at"`
+	SettledAt      *time.Time `json:"settled_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+func createHoldResponse(hold *db.Hold) holdResponse {
+	response := holdResponse{
+		ID:             hold.ID,
+		FromAccountID:  hold.AccountID,
+		ToAccountID:    hold.ToAccountID,
+		Amount:         util.NewMoney(hold.Amount, hold.Currency).Decimal(),
+		Fee:            util.NewMoney(hold.Fee, hold.Currency).Decimal(),
+		Currency:       hold.Currency,
+		Status:         hold.Status,
+		CapturedAmount: optionalDecimal(hold.CapturedAmount, hold.Currency),
+		ExpiresAt:      hold.ExpiresAt,
+		CreatedAt:      hold.CreatedAt,
+	}
+	if hold.TransferID.Valid {
+		response.TransferID = &hold.TransferID.Int64
+	}
+	if hold.SettledAt.Valid {
+		response.SettledAt = &hold.SettledAt.Time
+	}
+	return response
+}
+
+// createHold authorizes a transfer: the amount stays in the account but is no longer available
+// until the receiving account captures or voids the hold, or the hold expires
+// It runs the same checks as createTransfer
+func (server *Server) createHold(ctx *gin.Context) {
+	var req createHoldRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	amount, ok := parseTransferAmount(ctx, req.Amount, req.Currency)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(server.holdDefaultExpiry())
+	if req.ExpiresAt != nil {
+		expiresAt = *req.ExpiresAt
+	}
+	if !expiresAt.After(now) {
+		ctx.JSON(http.StatusBadRequest, errorMessageResponse("expires_at must be in the future"))
+		return
+	}
+	if expiresAt.After(now.Add(server.holdMaxExpiry())) {
+		ctx.JSON(http.StatusBadRequest, errorMessageResponse("expires_at is too far ahead"))
+		return
+	}
+	if req.FromAccountID == req.ToAccountID {
+		ctx.JSON(http.StatusBadRequest, errorMessageResponse("cannot hold funds for the same account"))
+		return
+	}
+
+	fromAccount, isValid := server.validAccount(ctx, req.FromAccountID, req.Currency)
+	if !isValid {
+		return
+	}
+	if _, isValid := server.validDestination(ctx, req.ToAccountID, req.Currency); !isValid {
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if fromAccount.Owner != authPayload.Username {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errors.New("unauthorized user")))
+		return
+	}
+	if !server.requireVerifiedEmail(ctx, server.config.RequireVerifiedEmailForTransfers, authPayload.Username) {
+		return
+	}
+	if !server.requireMFAForAmount(ctx, authPayload.Username, amount, req.TOTPCode) {
+		return
+	}
+	// the fee is held with the amount and charged at capture, as quoted now
+	fee := server.feeSchedule.Calculate(req.Currency, amount).Total
+
+	if !server.screenTransferWithoutReview(ctx, &risk.Transfer{
+		Owner:         authPayload.Username,
+		FromAccountID: req.FromAccountID,
+		ToAccountID:   req.ToAccountID,
+		Amount:        amount,
+		Currency:      req.Currency,
+	}, fee) {
+		return
+	}
+
+	hold, err := server.store.CreateHoldTx(ctx, db.CreateHoldParams{
+		AccountID:   req.FromAccountID,
+		ToAccountID: req.ToAccountID,
+		Amount:      amount,
+		Currency:    req.Currency,
+		ExpiresAt:   expiresAt,
+		Fee:         fee,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrInsufficientFunds) {
+			ctx.JSON(http.StatusConflict, errorResponse(err))
+			return
+		}
+		if errors.Is(err, db.ErrInvalidDestination) {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, createHoldResponse(&hold))
+}
+
+func (server *Server) holdDefaultExpiry() time.Duration {
+	if server.config.HoldDefaultExpiry > 0 {
+		return server.config.HoldDefaultExpiry
+	}
+	return defaultHoldExpiry
+}
+
+func (server *Server) holdMaxExpiry() time.Duration {
+	if server.config.HoldMaxExpiry > 0 {
+		return server.config.HoldMaxExpiry
+	}
+	return defaultHoldMaxExpiry
+}
+
+type holdURIParams struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// loadHold loads the hold in the uri and checks the authenticated user owns one of its accounts,
+// with receiverOnly the user must own the receiving account
+func (server *Server) loadHold(ctx *gin.Context, receiverOnly bool) (db.Hold, bool) {
+	var req holdURIParams
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return db.Hold{}, false
+	}
+
+	hold, err := server.store.GetHold(ctx, req.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return hold, false
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return hold, false
+	}
+
+	accountIDs := []int64{hold.ToAccountID}
+	if !receiverOnly {
+		accountIDs = append(accountIDs, hold.AccountID)
+	}
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	for _, accountID := range accountIDs {
+		account, err := server.store.GetAccount(ctx, accountID)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return hold, false
+		}
+		if account.Owner == authPayload.Username {
+			return hold, true
+		}
+	}
+
+	err = errors.New("hold doesn't belong to the authenticated user")
+	ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+	return hold, false
+}
+
+func (server *Server) getHold(ctx *gin.Context) {
+	hold, ok := server.loadHold(ctx, false)
+	if !ok {
+		return
+	}
+	ctx.JSON(http.StatusOK, createHoldResponse(&hold))
+}
+
+type captureHoldRequest struct {
+	// Amount is the amount captured, a decimal string in the currency of the hold, the whole hold when omitted
+	Amount string `json:"amount" binding:"omitempty,amount"`
+}
+
+type captureHoldResponse struct {
+	Hold holdResponse `json:"hold"`
+	transferTxResponse
+}
+
+// captureHold is for the receiving account, like a merchant settling a card payment
+func (server *Server) captureHold(ctx *gin.Context) {
+	// the body is optional, an empty one captures the whole hold
+	var req captureHoldRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	hold, ok := server.loadHold(ctx, true)
+	if !ok {
+		return
+	}
+	amount, ok := parseOptionalAmount(ctx, req.Amount, hold.Currency)
+	if !ok {
+		return
+	}
+
+	result, err := server.store.CaptureHoldTx(ctx, db.CaptureHoldTxParams{
+		HoldID: hold.ID,
+		Amount: amount,
+	})
+	if err != nil {
+		server.holdErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, captureHoldResponse{
+		Hold:               createHoldResponse(&result.Hold),
+		transferTxResponse: createTransferTxResponse(&result.TransferTxResult),
+	})
+}
+
+// voidHold is for the receiving account too, the sender waits for the hold to expire
+func (server *Server) voidHold(ctx *gin.Context) {
+	hold, ok := server.loadHold(ctx, true)
+	if !ok {
+		return
+	}
+
+	hold, err := server.store.VoidHoldTx(ctx, hold.ID)
+	if err != nil {
+		server.holdErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, createHoldResponse(&hold))
+}
+
+func (server *Server) holdErrorResponse(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, db.ErrHoldNotActive), errors.Is(err, db.ErrInsufficientFunds),
+		errors.Is(err, db.ErrTransferLimitExceeded), errors.Is(err, db.ErrInvalidDestination):
+		ctx.JSON(http.StatusConflict, errorResponse(err))
+	case errors.Is(err, db.ErrHoldCaptureExceeds):
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+	default:
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+	}
+}
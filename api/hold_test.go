@@ -0,0 +1,384 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	mockdb "github.com/go_backend_misc/db/mock"
+	db "github.com/go_backend_misc/db/sqlc"
+	"github.com/go_backend_misc/fee"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestCreateHoldAPI(t *testing.T) {
+	fromAccount, toAccount := getAccounts()
+	toAccount.Owner = "merchant"
+	expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+
+	validBody := func() gin.H {
+		return gin.H{
+			"from_account_id": fromAccount.ID,
+			"to_account_id":   toAccount.ID,
+			"amount":          "1.00",
+			"currency":        fromAccount.Currency,
+			"expires_at":      expiresAt,
+		}
+	}
+	stubAccounts := func(store *mockdb.MockStore) {
+		store.EXPECT().GetAccount(gomock.Any(), fromAccount.ID).Times(1).Return(fromAccount, nil)
+		store.EXPECT().GetAccount(gomock.Any(), toAccount.ID).Times(1).Return(toAccount, nil)
+	}
+
+	testCases := []struct {
+		name          string
+		body          func() gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: validBody,
+			buildStubs: func(store *mockdb.MockStore) {
+				stubAccounts(store)
+				arg := db.CreateHoldParams{
+					AccountID:   fromAccount.ID,
+					ToAccountID: toAccount.ID,
+					Amount:      100,
+					Currency:    fromAccount.Currency,
+					ExpiresAt:   expiresAt,
+				}
+				store.EXPECT().
+					CreateHoldTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(db.Hold{
+						ID:          1,
+						AccountID:   arg.AccountID,
+						ToAccountID: arg.ToAccountID,
+						Amount:      arg.Amount,
+						Currency:    arg.Currency,
+						Status:      db.HoldActive,
+						ExpiresAt:   arg.ExpiresAt,
+					}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+
+				var response holdResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+				require.Equal(t, db.HoldActive, response.Status)
+				require.Equal(t, fromAccount.ID, response.FromAccountID)
+				require.Nil(t, response.TransferID)
+			},
+		},
+		{
+			name: "Default expiry",
+			body: func() gin.H {
+				body := validBody()
+				delete(body, "expires_at")
+				return body
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				stubAccounts(store)
+				store.EXPECT().
+					CreateHoldTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.CreateHoldParams) (db.Hold, error) {
+						require.WithinDuration(t, time.Now().Add(defaultHoldExpiry), arg.ExpiresAt, time.Minute)
+						return db.Hold{ID: 1, Status: db.HoldActive}, nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+			},
+		},
+		{
+			name: "Insufficient funds",
+			body: validBody,
+			buildStubs: func(store *mockdb.MockStore) {
+				stubAccounts(store)
+				store.EXPECT().
+					CreateHoldTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Hold{}, fmt.Errorf("%w: account 123", db.ErrInsufficientFunds))
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name: "Expires too far ahead",
+			body: func() gin.H {
+				body := validBody()
+				body["expires_at"] = time.Now().Add(2 * defaultHoldMaxExpiry)
+				return body
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateHoldTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			stubAuthUser(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body())
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/holds", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, fromAccount.Owner)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestCreateHoldFee(t *testing.T) {
+	fromAccount, toAccount := getAccounts()
+	toAccount.Owner = "merchant"
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetAccount(gomock.Any(), fromAccount.ID).Times(1).Return(fromAccount, nil)
+	store.EXPECT().GetAccount(gomock.Any(), toAccount.ID).Times(1).Return(toAccount, nil)
+	// the fee quoted now is held with the amount
+	store.EXPECT().
+		CreateHoldTx(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ any, arg db.CreateHoldParams) (db.Hold, error) {
+			require.Equal(t, int64(100), arg.Amount)
+			require.Equal(t, int64(12), arg.Fee)
+			return db.Hold{ID: 1, Amount: arg.Amount, Fee: arg.Fee, Currency: arg.Currency, Status: db.HoldActive}, nil
+		})
+	stubAuthUser(store)
+
+	server := newTestServer(t, store)
+	schedule, err := fee.ParseSchedule([]byte("currencies:\n  USD:\n    flat: 12\n"))
+	require.NoError(t, err)
+	server.feeSchedule = schedule
+
+	data, err := json.Marshal(gin.H{
+		"from_account_id": fromAccount.ID,
+		"to_account_id":   toAccount.ID,
+		"amount":          "1.00",
+		"currency":        "USD",
+	})
+	require.NoError(t, err)
+	request, err := http.NewRequest(http.MethodPost, "/holds", bytes.NewReader(data))
+	require.NoError(t, err)
+	addAuthorization(t, request, server.tokenMaker, fromAccount.Owner)
+
+	recorder := httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusCreated, recorder.Code)
+
+	var response holdResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	require.Equal(t, "0.12", response.Fee)
+}
+
+func TestSettleHoldAPI(t *testing.T) {
+	fromAccount, toAccount := getAccounts()
+	toAccount.Owner = "merchant"
+	hold := db.Hold{
+		ID:          1,
+		AccountID:   fromAccount.ID,
+		ToAccountID: toAccount.ID,
+		Amount:      100,
+		Currency:    fromAccount.Currency,
+		Status:      db.HoldActive,
+		ExpiresAt:   time.Now().Add(time.Hour),
+	}
+	stubHold := func(store *mockdb.MockStore) {
+		store.EXPECT().GetHold(gomock.Any(), hold.ID).Times(1).Return(hold, nil)
+		store.EXPECT().GetAccount(gomock.Any(), toAccount.ID).AnyTimes().Return(toAccount, nil)
+		store.EXPECT().GetAccount(gomock.Any(), fromAccount.ID).AnyTimes().Return(fromAccount, nil)
+	}
+
+	testCases := []struct {
+		name          string
+		url           string
+		username      string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "Partial capture",
+			url:      "/holds/1/capture",
+			username: toAccount.Owner,
+			body:     gin.H{"amount": "0.60"},
+			buildStubs: func(store *mockdb.MockStore) {
+				stubHold(store)
+				captured := hold
+				captured.Status = db.HoldCaptured
+				captured.CapturedAmount = db.Int64ToSqlInt64(60)
+				captured.TransferID = db.Int64ToSqlInt64(9)
+
+				arg := db.CaptureHoldTxParams{HoldID: hold.ID, Amount: 60}
+				store.EXPECT().
+					CaptureHoldTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(db.CaptureHoldTxResult{
+						Hold:             captured,
+						TransferTxResult: db.TransferTxResult{Transfer: db.Transfer{ID: 9, Amount: 60}},
+					}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var response captureHoldResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+				require.Equal(t, db.HoldCaptured, response.Hold.Status)
+				require.Equal(t, "0.60", *response.Hold.CapturedAmount)
+				require.Equal(t, int64(9), response.Transfer.ID)
+			},
+		},
+		{
+			name:     "Sender can't capture",
+			url:      "/holds/1/capture",
+			username: fromAccount.Owner,
+			buildStubs: func(store *mockdb.MockStore) {
+				stubHold(store)
+				store.EXPECT().CaptureHoldTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:     "Capture more than held",
+			url:      "/holds/1/capture",
+			username: toAccount.Owner,
+			body:     gin.H{"amount": "2.00"},
+			buildStubs: func(store *mockdb.MockStore) {
+				stubHold(store)
+				store.EXPECT().
+					CaptureHoldTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.CaptureHoldTxResult{}, db.ErrHoldCaptureExceeds)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:     "Capture an expired hold",
+			url:      "/holds/1/capture",
+			username: toAccount.Owner,
+			buildStubs: func(store *mockdb.MockStore) {
+				stubHold(store)
+				store.EXPECT().
+					CaptureHoldTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.CaptureHoldTxResult{}, fmt.Errorf("%w: hold 1", db.ErrHoldNotActive))
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name:     "Void",
+			url:      "/holds/1/void",
+			username: toAccount.Owner,
+			buildStubs: func(store *mockdb.MockStore) {
+				stubHold(store)
+				voided := hold
+				voided.Status = db.HoldVoided
+				store.EXPECT().VoidHoldTx(gomock.Any(), hold.ID).Times(1).Return(voided, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var response holdResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+				require.Equal(t, db.HoldVoided, response.Status)
+			},
+		},
+		{
+			name:     "Sender can't void",
+			url:      "/holds/1/void",
+			username: fromAccount.Owner,
+			buildStubs: func(store *mockdb.MockStore) {
+				stubHold(store)
+				store.EXPECT().VoidHoldTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			stubAuthUser(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			var body bytes.Buffer
+			if tc.body != nil {
+				require.NoError(t, json.NewEncoder(&body).Encode(tc.body))
+			}
+			request, err := http.NewRequest(http.MethodPost, tc.url, &body)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, tc.username)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestGetHoldAPI(t *testing.T) {
+	fromAccount, toAccount := getAccounts()
+	toAccount.Owner = "merchant"
+	hold := db.Hold{ID: 1, AccountID: fromAccount.ID, ToAccountID: toAccount.ID, Amount: 100, Status: db.HoldActive}
+
+	for _, username := range []string{fromAccount.Owner, toAccount.Owner} {
+		ctrl := gomock.NewController(t)
+		store := mockdb.NewMockStore(ctrl)
+		store.EXPECT().GetHold(gomock.Any(), hold.ID).Times(1).Return(hold, nil)
+		store.EXPECT().GetAccount(gomock.Any(), toAccount.ID).Times(1).Return(toAccount, nil)
+		store.EXPECT().GetAccount(gomock.Any(), fromAccount.ID).MaxTimes(1).Return(fromAccount, nil)
+		stubAuthUser(store)
+
+		server := newTestServer(t, store)
+		recorder := httptest.NewRecorder()
+
+		request, err := http.NewRequest(http.MethodGet, "/holds/1", nil)
+		require.NoError(t, err)
+
+		addAuthorization(t, request, server.tokenMaker, username)
+		server.router.ServeHTTP(recorder, request)
+		require.Equal(t, http.StatusOK, recorder.Code)
+		ctrl.Finish()
+	}
+}
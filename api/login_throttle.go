@@ -0,0 +1,106 @@
+package api
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/go_backend_misc/db/sqlc"
+)
+
+// LoginThrottleUsernameKey and LoginThrottleIPKey build the keys failed logins are tracked under
+func LoginThrottleUsernameKey(username string) string {
+	return "username:" + username
+}
+
+func LoginThrottleIPKey(ip string) string {
+	return "ip:" + ip
+}
+
+// MFAThrottleKey is the key failed MFA codes are tracked under, the codes of a user get the same backoff
+// and lockout as logins, across login challenges and step-up transfers
+func MFAThrottleKey(username string) string {
+	return "mfa:" + username
+}
+
+// loginRetryAfter returns how long a key must wait before its next login attempt
+// Every failure doubles the delay, and reaching the maximum number of failures locks the key out
+func (server *Server) loginRetryAfter(throttle db.LoginThrottle, now time.Time) time.Duration {
+	if now.Sub(throttle.LastFailedAt) > server.config.LoginAttemptWindow {
+		return 0
+	}
+
+	var delay time.Duration
+	if throttle.FailedCount >= server.config.LoginMaxFailedAttempts {
+		delay = server.config.LoginLockoutDuration
+	} else {
+		backoff := float64(server.config.LoginBackoffBase) * math.Pow(2, float64(throttle.FailedCount-1))
+		delay = time.Duration(math.Min(backoff, float64(server.config.LoginLockoutDuration)))
+	}
+
+	retryAfter := throttle.LastFailedAt.Add(delay).Sub(now)
+	if retryAfter < 0 {
+		return 0
+	}
+	return retryAfter
+}
+
+// checkLoginThrottle writes a 429 response and returns false when any of the keys is throttled
+func (server *Server) checkLoginThrottle(ctx *gin.Context, keys ...string) bool {
+	now := time.Now()
+	var retryAfter time.Duration
+	for _, key := range keys {
+		throttle, err := server.store.GetLoginThrottle(ctx, key)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				continue
+			}
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return false
+		}
+
+		if keyRetryAfter := server.loginRetryAfter(throttle, now); keyRetryAfter > retryAfter {
+			retryAfter = keyRetryAfter
+		}
+	}
+
+	if retryAfter > 0 {
+		seconds := int64(math.Ceil(retryAfter.Seconds()))
+		log.Printf("security: throttled login attempt keys=%v retry_after=%ds", keys, seconds)
+		ctx.Header("Retry-After", fmt.Sprint(seconds))
+		ctx.JSON(http.StatusTooManyRequests, errorMessageResponse("too many failed login attempts, try again later"))
+		return false
+	}
+	return true
+}
+
+// recordLoginFailure counts a failed login against every key
+// Errors are only logged: the client gets the "invalid credentials" response regardless
+func (server *Server) recordLoginFailure(ctx *gin.Context, keys ...string) {
+	resetBefore := time.Now().Add(-server.config.LoginAttemptWindow)
+	for _, key := range keys {
+		throttle, err := server.store.RecordLoginFailure(ctx, db.RecordLoginFailureParams{
+			Key:         key,
+			ResetBefore: resetBefore,
+		})
+		if err != nil {
+			log.Printf("security: cannot record failed login key=%v: %v", key, err)
+			continue
+		}
+
+		log.Printf("security: failed login key=%v failed_count=%d", key, throttle.FailedCount)
+		if throttle.FailedCount == server.config.LoginMaxFailedAttempts {
+			log.Printf("security: locked out key=%v for %v", key, server.config.LoginLockoutDuration)
+		}
+	}
+}
+
+func (server *Server) resetLoginThrottle(ctx *gin.Context, key string) {
+	if _, err := server.store.DeleteLoginThrottle(ctx, key); err != nil {
+		log.Printf("security: cannot reset login throttle key=%v: %v", key, err)
+	}
+}
@@ -0,0 +1,37 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	db "github.com/go_backend_misc/db/sqlc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoginRetryAfter(t *testing.T) {
+	server := newTestServer(t, nil)
+	server.config.LoginMaxFailedAttempts = 4
+	server.config.LoginBackoffBase = time.Second
+	server.config.LoginLockoutDuration = time.Minute
+	server.config.LoginAttemptWindow = time.Hour
+
+	now := time.Now()
+	testCases := []struct {
+		failedCount  int32
+		lastFailedAt time.Time
+		expected     time.Duration
+	}{
+		{failedCount: 1, lastFailedAt: now, expected: time.Second},
+		{failedCount: 2, lastFailedAt: now, expected: 2 * time.Second},
+		{failedCount: 3, lastFailedAt: now, expected: 4 * time.Second},
+		{failedCount: 3, lastFailedAt: now.Add(-time.Second), expected: 3 * time.Second},
+		{failedCount: 4, lastFailedAt: now, expected: time.Minute},
+		{failedCount: 4, lastFailedAt: now.Add(-2 * time.Minute), expected: 0},
+		{failedCount: 10, lastFailedAt: now.Add(-2 * time.Hour), expected: 0},
+	}
+
+	for _, tc := range testCases {
+		throttle := db.LoginThrottle{FailedCount: tc.failedCount, LastFailedAt: tc.lastFailedAt}
+		require.Equal(t, tc.expected, server.loginRetryAfter(throttle, now))
+	}
+}
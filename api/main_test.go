@@ -7,17 +7,30 @@ import (
 
 	"github.com/gin-gonic/gin"
 	db "github.com/go_backend_misc/db/sqlc"
+	"github.com/go_backend_misc/mail"
 	"github.com/go_backend_misc/util"
 	"github.com/stretchr/testify/require"
 )
 
 func newTestServer(t *testing.T, store db.Store) *Server {
+	emailSender, err := mail.NewFileSender("test@simplebank.local", t.TempDir())
+	require.NoError(t, err)
+
 	config := util.Config{
-		TokenSymmetricKey:   util.RandomString(32),
-		AccessTokenDuration: time.Minute,
+		TokenSymmetricKey:          util.RandomString(32),
+		AccessTokenDuration:        time.Minute,
+		MFAEncryptionKey:           util.RandomString(32),
+		MFAChallengeDuration:       time.Minute,
+		MFAStepUpAmount:            1000,
+		LoginMaxFailedAttempts:     5,
+		LoginBackoffBase:           time.Second,
+		LoginLockoutDuration:       time.Minute,
+		LoginAttemptWindow:         time.Hour,
+		PasswordResetTokenDuration: time.Minute,
+		VerifyEmailTokenDuration:   time.Minute,
 	}
 
-	server, err := NewServer(config, store)
+	server, err := NewServer(config, store, emailSender)
 	require.NoError(t, err)
 
 	return server
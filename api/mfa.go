@@ -0,0 +1,315 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/go_backend_misc/db/sqlc"
+	"github.com/go_backend_misc/token"
+	"github.com/go_backend_misc/util"
+	"github.com/google/uuid"
+)
+
+const (
+	mfaIssuer               = "simple_bank"
+	mfaRecoveryCodeCount    = 10
+	mfaRecoveryCodeBytes    = 5
+	mfaMaxChallengeAttempts = 5
+)
+
+var (
+	errInvalidMFACode = errors.New("invalid two-factor code")
+	errMFANotEnabled  = errors.New("two-factor authentication is not enabled")
+	errMFARequired    = errors.New("a totp_code is required for this transfer")
+)
+
+type enrollMFAResponse struct {
+	Secret        string   `json:"secret"`
+	OtpauthURI    string   `json:"otpauth_uri"`
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// enrollMFA generates a new TOTP secret and recovery codes
+// MFA is only enforced once the user confirms a code through activateMFA
+func (server *Server) enrollMFA(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	userMFA, err := server.store.GetUserMFA(ctx, authPayload.Username)
+	if err != nil && err != sql.ErrNoRows {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if err == nil && userMFA.Enabled {
+		ctx.JSON(http.StatusConflict, errorMessageResponse("two-factor authentication is already enabled"))
+		return
+	}
+
+	secret, err := util.GenerateTOTPSecret()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	encryptedSecret, err := util.Encrypt([]byte(server.config.MFAEncryptionKey), []byte(secret))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	recoveryCodes := make([]string, 0, mfaRecoveryCodeCount)
+	hashedRecoveryCodes := make([]string, 0, mfaRecoveryCodeCount)
+	for i := 0; i < mfaRecoveryCodeCount; i++ {
+		code, err := util.RandomSecret(mfaRecoveryCodeBytes)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		recoveryCodes = append(recoveryCodes, code)
+		hashedRecoveryCodes = append(hashedRecoveryCodes, util.HashSecret(code))
+	}
+
+	_, err = server.store.EnrollMFATx(ctx, db.EnrollMFATxParams{
+		Username:            authPayload.Username,
+		EncryptedSecret:     encryptedSecret,
+		HashedRecoveryCodes: hashedRecoveryCodes,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	response := enrollMFAResponse{
+		Secret:        secret,
+		OtpauthURI:    util.TOTPURI(mfaIssuer, authPayload.Username, secret),
+		RecoveryCodes: recoveryCodes,
+	}
+	ctx.JSON(http.StatusCreated, response)
+}
+
+type activateMFARequest struct {
+	Code string `json:"code" binding:"required,numeric,len=6"`
+}
+
+func (server *Server) activateMFA(ctx *gin.Context) {
+	var req activateMFARequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	userMFA, err := server.store.GetUserMFA(ctx, authPayload.Username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorMessageResponse("two-factor authentication is not enrolled"))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if userMFA.Enabled {
+		ctx.JSON(http.StatusConflict, errorMessageResponse("two-factor authentication is already enabled"))
+		return
+	}
+
+	if err := server.checkTOTP(ctx, userMFA, req.Code); err != nil {
+		server.mfaErrorResponse(ctx, err)
+		return
+	}
+
+	if _, err := server.store.EnableUserMFA(ctx, authPayload.Username); err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"enabled": true})
+}
+
+type mfaChallengeResponse struct {
+	MFARequired bool      `json:"mfa_required"`
+	MFAToken    uuid.UUID `json:"mfa_token"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+func (server *Server) createMFAChallenge(ctx *gin.Context, username string) {
+	challenge, err := server.store.CreateMFAChallenge(ctx, db.CreateMFAChallengeParams{
+		ID:        uuid.New(),
+		Username:  username,
+		ExpiresAt: time.Now().Add(server.config.MFAChallengeDuration),
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	response := mfaChallengeResponse{
+		MFARequired: true,
+		MFAToken:    challenge.ID,
+		ExpiresAt:   challenge.ExpiresAt,
+	}
+	ctx.JSON(http.StatusOK, response)
+}
+
+type loginMFARequest struct {
+	MFAToken string `json:"mfa_token" binding:"required,uuid"`
+	// Code is either a TOTP code or one of the recovery codes
+	Code string `json:"code" binding:"required,alphanum"`
+}
+
+// loginUserMFA completes the second step of the login for users with MFA enabled
+func (server *Server) loginUserMFA(ctx *gin.Context) {
+	var req loginMFARequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	challengeID := uuid.MustParse(req.MFAToken)
+	challenge, err := server.store.GetMFAChallenge(ctx, challengeID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusUnauthorized, errorMessageResponse("invalid mfa token"))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if challenge.ConsumedAt.Valid || time.Now().After(challenge.ExpiresAt) ||
+		challenge.FailedAttempts >= mfaMaxChallengeAttempts {
+		ctx.JSON(http.StatusUnauthorized, errorMessageResponse("mfa token has expired"))
+		return
+	}
+
+	if !server.checkLoginThrottle(ctx, MFAThrottleKey(challenge.Username)) {
+		return
+	}
+	if err := server.verifyMFACode(ctx, challenge.Username, req.Code); err != nil {
+		if errors.Is(err, errInvalidMFACode) {
+			if _, err := server.store.IncrementMFAChallengeAttempts(ctx, challengeID); err != nil {
+				ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+				return
+			}
+		}
+		server.mfaErrorResponse(ctx, err)
+		return
+	}
+
+	// consuming is conditional, so a token can't be redeemed twice concurrently
+	if _, err := server.store.ConsumeMFAChallenge(ctx, challengeID); err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusUnauthorized, errorMessageResponse("mfa token has expired"))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	user, err := server.store.GetUserByUsername(ctx, challenge.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	server.issueAccessToken(ctx, &user)
+}
+
+// requireMFAForAmount enforces the step-up requirement on high-value transfers
+// It writes the error response and returns false when the transfer must be refused
+func (server *Server) requireMFAForAmount(ctx *gin.Context, username string, amount int64, code string) bool {
+	if server.config.MFAStepUpAmount <= 0 || amount <= server.config.MFAStepUpAmount {
+		return true
+	}
+	if code == "" {
+		ctx.JSON(http.StatusForbidden, errorResponse(errMFARequired))
+		return false
+	}
+	if !server.checkLoginThrottle(ctx, MFAThrottleKey(username)) {
+		return false
+	}
+	if err := server.verifyMFACode(ctx, username, code); err != nil {
+		server.mfaErrorResponse(ctx, err)
+		return false
+	}
+	return true
+}
+
+// verifyMFACode accepts a TOTP code or an unused recovery code
+// Invalid codes count against MFAThrottleKey, callers check the throttle first
+func (server *Server) verifyMFACode(ctx *gin.Context, username string, code string) error {
+	err := server.checkMFACode(ctx, username, code)
+	switch {
+	case errors.Is(err, errInvalidMFACode):
+		server.recordLoginFailure(ctx, MFAThrottleKey(username))
+	case err == nil:
+		server.resetLoginThrottle(ctx, MFAThrottleKey(username))
+	}
+	return err
+}
+
+func (server *Server) checkMFACode(ctx *gin.Context, username string, code string) error {
+	userMFA, err := server.store.GetUserMFA(ctx, username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return errMFANotEnabled
+		}
+		return err
+	}
+	if !userMFA.Enabled {
+		return errMFANotEnabled
+	}
+
+	err = server.checkTOTP(ctx, userMFA, code)
+	if !errors.Is(err, errInvalidMFACode) {
+		return err
+	}
+
+	_, err = server.store.UseMFARecoveryCode(ctx, db.UseMFARecoveryCodeParams{
+		Username:   username,
+		HashedCode: util.HashSecret(code),
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return errInvalidMFACode
+		}
+		return err
+	}
+	return nil
+}
+
+// checkTOTP validates a TOTP code and records its time step so it can't be replayed
+func (server *Server) checkTOTP(ctx *gin.Context, userMFA db.UserMfa, code string) error {
+	secret, err := util.Decrypt([]byte(server.config.MFAEncryptionKey), userMFA.EncryptedSecret)
+	if err != nil {
+		return fmt.Errorf("cannot decrypt totp secret: %w", err)
+	}
+
+	step, ok := util.ValidateTOTP(string(secret), code, time.Now())
+	if !ok {
+		return errInvalidMFACode
+	}
+
+	rows, err := server.store.UpdateMFALastUsedStep(ctx, db.UpdateMFALastUsedStepParams{
+		Username: userMFA.Username,
+		Step:     step,
+	})
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		// the code (or a later one) was already used
+		return errInvalidMFACode
+	}
+	return nil
+}
+
+func (server *Server) mfaErrorResponse(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, errInvalidMFACode):
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+	case errors.Is(err, errMFANotEnabled):
+		ctx.JSON(http.StatusForbidden, errorResponse(err))
+	default:
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+	}
+}
@@ -0,0 +1,295 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	mockdb "github.com/go_backend_misc/db/mock"
+	db "github.com/go_backend_misc/db/sqlc"
+	"github.com/go_backend_misc/util"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func randomUserMFA(t *testing.T, server *Server, username string) (userMFA db.UserMfa, secret string) {
+	secret, err := util.GenerateTOTPSecret()
+	require.NoError(t, err)
+	encryptedSecret, err := util.Encrypt([]byte(server.config.MFAEncryptionKey), []byte(secret))
+	require.NoError(t, err)
+
+	userMFA = db.UserMfa{
+		Username:        username,
+		EncryptedSecret: encryptedSecret,
+		Enabled:         true,
+	}
+	return
+}
+
+func stubNoMFAThrottle(store *mockdb.MockStore, username string) {
+	store.EXPECT().
+		GetLoginThrottle(gomock.Any(), MFAThrottleKey(username)).
+		Times(1).
+		Return(db.LoginThrottle{}, sql.ErrNoRows)
+}
+
+func TestLoginUserMFAAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	challenge := db.MfaChallenge{
+		ID:        uuid.New(),
+		Username:  user.Username,
+		ExpiresAt: time.Now().Add(time.Minute),
+	}
+
+	testCases := []struct {
+		name          string
+		buildStubs    func(store *mockdb.MockStore, server *Server) (code string)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			buildStubs: func(store *mockdb.MockStore, server *Server) string {
+				userMFA, secret := randomUserMFA(t, server, user.Username)
+				code, err := util.TOTPCode(secret, time.Now())
+				require.NoError(t, err)
+
+				store.EXPECT().GetMFAChallenge(gomock.Any(), gomock.Eq(challenge.ID)).Times(1).Return(challenge, nil)
+				stubNoMFAThrottle(store, user.Username)
+				store.EXPECT().GetUserMFA(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(userMFA, nil)
+				store.EXPECT().DeleteLoginThrottle(gomock.Any(), MFAThrottleKey(user.Username)).Times(1).Return(int64(1), nil)
+				store.EXPECT().UpdateMFALastUsedStep(gomock.Any(), gomock.Any()).Times(1).Return(int64(1), nil)
+				store.EXPECT().ConsumeMFAChallenge(gomock.Any(), gomock.Eq(challenge.ID)).Times(1).Return(challenge, nil)
+				store.EXPECT().GetUserByUsername(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				return code
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var response loginUserResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+				require.NotEmpty(t, response.AccessToken)
+			},
+		},
+		{
+			name: "Recovery code",
+			buildStubs: func(store *mockdb.MockStore, server *Server) string {
+				userMFA, _ := randomUserMFA(t, server, user.Username)
+				recoveryCode := "abcdef1234"
+
+				store.EXPECT().GetMFAChallenge(gomock.Any(), gomock.Eq(challenge.ID)).Times(1).Return(challenge, nil)
+				stubNoMFAThrottle(store, user.Username)
+				store.EXPECT().GetUserMFA(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(userMFA, nil)
+				store.EXPECT().DeleteLoginThrottle(gomock.Any(), MFAThrottleKey(user.Username)).Times(1).Return(int64(1), nil)
+				store.EXPECT().
+					UseMFARecoveryCode(gomock.Any(), gomock.Eq(db.UseMFARecoveryCodeParams{
+						Username:   user.Username,
+						HashedCode: util.HashSecret(recoveryCode),
+					})).
+					Times(1).
+					Return(db.MfaRecoveryCode{}, nil)
+				store.EXPECT().ConsumeMFAChallenge(gomock.Any(), gomock.Eq(challenge.ID)).Times(1).Return(challenge, nil)
+				store.EXPECT().GetUserByUsername(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				return recoveryCode
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "Invalid code",
+			buildStubs: func(store *mockdb.MockStore, server *Server) string {
+				userMFA, _ := randomUserMFA(t, server, user.Username)
+
+				store.EXPECT().GetMFAChallenge(gomock.Any(), gomock.Eq(challenge.ID)).Times(1).Return(challenge, nil)
+				stubNoMFAThrottle(store, user.Username)
+				store.EXPECT().GetUserMFA(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(userMFA, nil)
+				store.EXPECT().UseMFARecoveryCode(gomock.Any(), gomock.Any()).Times(1).Return(db.MfaRecoveryCode{}, sql.ErrNoRows)
+				store.EXPECT().
+					RecordLoginFailure(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.RecordLoginFailureParams) (db.LoginThrottle, error) {
+						require.Equal(t, MFAThrottleKey(user.Username), arg.Key)
+						return db.LoginThrottle{Key: arg.Key, FailedCount: 1}, nil
+					})
+				store.EXPECT().IncrementMFAChallengeAttempts(gomock.Any(), gomock.Eq(challenge.ID)).Times(1).Return(challenge, nil)
+				store.EXPECT().ConsumeMFAChallenge(gomock.Any(), gomock.Any()).Times(0)
+				return "000000"
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "Too many attempts",
+			buildStubs: func(store *mockdb.MockStore, server *Server) string {
+				lockedChallenge := challenge
+				lockedChallenge.FailedAttempts = mfaMaxChallengeAttempts
+
+				store.EXPECT().GetMFAChallenge(gomock.Any(), gomock.Eq(challenge.ID)).Times(1).Return(lockedChallenge, nil)
+				store.EXPECT().GetUserMFA(gomock.Any(), gomock.Any()).Times(0)
+				return "000000"
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			// a new challenge comes with every password login, the failed codes of the user are counted across them
+			name: "Locked out",
+			buildStubs: func(store *mockdb.MockStore, server *Server) string {
+				store.EXPECT().GetMFAChallenge(gomock.Any(), gomock.Eq(challenge.ID)).Times(1).Return(challenge, nil)
+				store.EXPECT().
+					GetLoginThrottle(gomock.Any(), MFAThrottleKey(user.Username)).
+					Times(1).
+					Return(db.LoginThrottle{
+						FailedCount:  server.config.LoginMaxFailedAttempts,
+						LastFailedAt: time.Now(),
+					}, nil)
+				store.EXPECT().GetUserMFA(gomock.Any(), gomock.Any()).Times(0)
+				return "000000"
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			server := newTestServer(t, store)
+			code := tc.buildStubs(store, server)
+
+			recorder := httptest.NewRecorder()
+			data, err := json.Marshal(gin.H{"mfa_token": challenge.ID.String(), "code": code})
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/user/login/mfa", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestTransferStepUpMFA(t *testing.T) {
+	fromAccount, toAccount := getAccounts()
+	fromAccount.Balance = 5000
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	server := newTestServer(t, store)
+	stubAuthUser(store)
+
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(toAccount.ID)).Times(1).Return(toAccount, nil)
+	store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+
+	recorder := httptest.NewRecorder()
+	data, err := json.Marshal(gin.H{
+		"from_account_id": fromAccount.ID,
+		"to_account_id":   toAccount.ID,
+		"amount":          util.NewMoney(server.config.MFAStepUpAmount+1, fromAccount.Currency).Decimal(),
+		"currency":        fromAccount.Currency,
+	})
+	require.NoError(t, err)
+
+	request, err := http.NewRequest(http.MethodPost, "/transfer", bytes.NewReader(data))
+	require.NoError(t, err)
+	addAuthorization(t, request, server.tokenMaker, fromAccount.Owner)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusForbidden, recorder.Code)
+	require.Contains(t, recorder.Body.String(), errMFARequired.Error())
+}
+
+func TestTransferStepUpMFAThrottle(t *testing.T) {
+	fromAccount, toAccount := getAccounts()
+	fromAccount.Balance = 5000
+
+	testCases := []struct {
+		name          string
+		buildStubs    func(store *mockdb.MockStore, server *Server)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "Invalid code is counted",
+			buildStubs: func(store *mockdb.MockStore, server *Server) {
+				userMFA, _ := randomUserMFA(t, server, fromAccount.Owner)
+				stubNoMFAThrottle(store, fromAccount.Owner)
+				store.EXPECT().GetUserMFA(gomock.Any(), fromAccount.Owner).Times(1).Return(userMFA, nil)
+				store.EXPECT().UseMFARecoveryCode(gomock.Any(), gomock.Any()).Times(1).Return(db.MfaRecoveryCode{}, sql.ErrNoRows)
+				store.EXPECT().
+					RecordLoginFailure(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.RecordLoginFailureParams) (db.LoginThrottle, error) {
+						require.Equal(t, MFAThrottleKey(fromAccount.Owner), arg.Key)
+						return db.LoginThrottle{Key: arg.Key, FailedCount: 1}, nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "Locked out",
+			buildStubs: func(store *mockdb.MockStore, server *Server) {
+				store.EXPECT().
+					GetLoginThrottle(gomock.Any(), MFAThrottleKey(fromAccount.Owner)).
+					Times(1).
+					Return(db.LoginThrottle{
+						FailedCount:  server.config.LoginMaxFailedAttempts,
+						LastFailedAt: time.Now(),
+					}, nil)
+				store.EXPECT().GetUserMFA(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			server := newTestServer(t, store)
+			store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+			store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(toAccount.ID)).Times(1).Return(toAccount, nil)
+			store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			tc.buildStubs(store, server)
+			stubAuthUser(store)
+
+			recorder := httptest.NewRecorder()
+			data, err := json.Marshal(gin.H{
+				"from_account_id": fromAccount.ID,
+				"to_account_id":   toAccount.ID,
+				"amount":          util.NewMoney(server.config.MFAStepUpAmount+1, fromAccount.Currency).Decimal(),
+				"currency":        fromAccount.Currency,
+				"totp_code":       "000000",
+			})
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/transfer", bytes.NewReader(data))
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, fromAccount.Owner)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
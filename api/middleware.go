@@ -1,22 +1,27 @@
 package api
 
 import (
+	"database/sql"
 	"errors"
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	db "github.com/go_backend_misc/db/sqlc"
 	"github.com/go_backend_misc/token"
+	"github.com/go_backend_misc/util"
 )
 
 const (
 	authorizationHeaderKey  = "authorization"
 	authorizationTypeBearer = "bearer"
+	authorizationTypeAPIKey = "apikey"
 	authorizationPayloadKey = "authorization_payload"
 )
 
-func authMiddleware(tokenMaker token.TokenMaker) gin.HandlerFunc {
+func authMiddleware(tokenMaker token.TokenMaker, store db.Store) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		authorizationHeaderKey := ctx.GetHeader(authorizationHeaderKey)
 		if len(authorizationHeaderKey) == 0 {
@@ -24,21 +29,23 @@ func authMiddleware(tokenMaker token.TokenMaker) gin.HandlerFunc {
 			return
 		}
 		fields := strings.Fields(authorizationHeaderKey)
-		if len(fields) > 2 {
+		if len(fields) != 2 {
 			err := errors.New("invalid authorization header")
 			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
 			return
 		}
 
+		var payload *token.Payload
+		var err error
 		authorizationType := strings.ToLower(fields[0])
-		if authorizationType != authorizationTypeBearer {
-			err := fmt.Errorf("unsupported authorization type %v", authorizationType)
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
-			return
+		switch authorizationType {
+		case authorizationTypeBearer:
+			payload, err = verifyAccessToken(ctx, tokenMaker, store, fields[1])
+		case authorizationTypeAPIKey:
+			payload, err = verifyAPIKey(ctx, store, fields[1])
+		default:
+			err = fmt.Errorf("unsupported authorization type %v", authorizationType)
 		}
-
-		accessToken := fields[1]
-		payload, err := tokenMaker.VerifyToken(accessToken)
 		if err != nil {
 			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
 			return
@@ -49,3 +56,77 @@ func authMiddleware(tokenMaker token.TokenMaker) gin.HandlerFunc {
 		ctx.Next()
 	}
 }
+
+var errTokenBeforePasswordChange = errors.New("token was issued before the last password change")
+
+// verifyAccessToken also rejects tokens issued before the user's last password change,
+// so changing the password logs out every other session
+func verifyAccessToken(ctx *gin.Context, tokenMaker token.TokenMaker, store db.Store, accessToken string) (*token.Payload, error) {
+	payload, err := tokenMaker.VerifyToken(accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := store.GetUserByUsername(ctx, payload.Username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, token.ErrInvalidToken
+		}
+		return nil, err
+	}
+	if payload.IssuedAt.Before(user.PasswordChangedAt) {
+		return nil, errTokenBeforePasswordChange
+	}
+
+	return payload, nil
+}
+
+// verifyAPIKey checks the key against its stored hash and builds a payload
+// restricted to the key's scopes
+func verifyAPIKey(ctx *gin.Context, store db.Store, key string) (*token.Payload, error) {
+	prefix, err := parseAPIKeyPrefix(key)
+	if err != nil {
+		return nil, err
+	}
+
+	apiKey, err := store.GetAPIKeyByPrefix(ctx, prefix)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errInvalidAPIKey
+		}
+		return nil, err
+	}
+
+	if !util.CheckSecret(key, apiKey.HashedKey) {
+		return nil, errInvalidAPIKey
+	}
+	if apiKey.RevokedAt.Valid {
+		return nil, errors.New("api key has been revoked")
+	}
+	if apiKey.ExpiresAt.Valid && time.Now().After(apiKey.ExpiresAt.Time) {
+		return nil, errors.New("api key has expired")
+	}
+
+	payload := &token.Payload{
+		ID:        apiKey.ID,
+		Username:  apiKey.Owner,
+		IssuedAt:  apiKey.CreatedAt,
+		ExpiredAt: apiKey.ExpiresAt.Time,
+		Scopes:    apiKey.Scopes,
+	}
+	return payload, nil
+}
+
+// requireScope rejects requests whose payload doesn't grant the scope
+// It must run after authMiddleware
+func requireScope(scope string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+		if !authPayload.HasScope(scope) {
+			err := fmt.Errorf("missing required scope %v", scope)
+			ctx.AbortWithStatusJSON(http.StatusForbidden, errorResponse(err))
+			return
+		}
+		ctx.Next()
+	}
+}
@@ -8,8 +8,13 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	mockdb "github.com/go_backend_misc/db/mock"
+	db "github.com/go_backend_misc/db/sqlc"
 	"github.com/go_backend_misc/token"
+	"github.com/go_backend_misc/util"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
 )
 
 func addAuthorization(t *testing.T, request *http.Request, tokenMaker token.TokenMaker, username string) {
@@ -18,10 +23,36 @@ func addAuthorization(t *testing.T, request *http.Request, tokenMaker token.Toke
 	request.Header.Set("Authorization", "bearer "+token)
 }
 
+// stubAuthUser lets authMiddleware load the user of any access token
+func stubAuthUser(store *mockdb.MockStore) {
+	store.EXPECT().
+		GetUserByUsername(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(_ any, username string) (db.User, error) {
+			return db.User{Username: username}, nil
+		})
+}
+
+func randomAPIKey(t *testing.T, owner string, scopes []string) (apiKey db.ApiKey, key string) {
+	key, prefix, err := newAPIKey()
+	require.NoError(t, err)
+	apiKey = db.ApiKey{
+		ID:        uuid.New(),
+		Owner:     owner,
+		Name:      util.RandomString(8),
+		Prefix:    prefix,
+		HashedKey: util.HashSecret(key),
+		Scopes:    scopes,
+		CreatedAt: time.Now(),
+	}
+	return
+}
+
 func TestAuthMiddleware(t *testing.T) {
 	type authTestCase struct {
 		name          string
 		setupAuth     func(request *http.Request, tokenMaken token.TokenMaker)
+		buildStubs    func(store *mockdb.MockStore)
 		checkResponse func(recorder *httptest.ResponseRecorder)
 	}
 	okTestCase := authTestCase{
@@ -29,6 +60,7 @@ func TestAuthMiddleware(t *testing.T) {
 		setupAuth: func(request *http.Request, tokenMaker token.TokenMaker) {
 			addAuthorization(t, request, tokenMaker, "test")
 		},
+		buildStubs: stubAuthUser,
 		checkResponse: func(recorder *httptest.ResponseRecorder) {
 			require.Equal(t, http.StatusOK, recorder.Code)
 		},
@@ -98,17 +130,117 @@ func TestAuthMiddleware(t *testing.T) {
 		},
 	}
 
+	passwordChangedTestCase := authTestCase{
+		name: "Token issued before password change",
+		setupAuth: func(request *http.Request, tokenMaker token.TokenMaker) {
+			addAuthorization(t, request, tokenMaker, "test")
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetUserByUsername(gomock.Any(), gomock.Eq("test")).
+				Times(1).
+				Return(db.User{Username: "test", PasswordChangedAt: time.Now().Add(time.Second)}, nil)
+		},
+		checkResponse: func(recorder *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusUnauthorized, recorder.Code)
+
+			var content map[string]string
+			json.Unmarshal(recorder.Body.Bytes(), &content)
+			require.Equal(t, errTokenBeforePasswordChange.Error(), content["error"])
+		},
+	}
+
+	apiKey, key := randomAPIKey(t, "test", []string{scopeAccountsRead})
+	apiKeyTestCase := authTestCase{
+		name: "API key OK",
+		setupAuth: func(request *http.Request, tokenMaker token.TokenMaker) {
+			request.Header.Set("Authorization", "ApiKey "+key)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetAPIKeyByPrefix(gomock.Any(), gomock.Eq(apiKey.Prefix)).
+				Times(1).
+				Return(apiKey, nil)
+		},
+		checkResponse: func(recorder *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusOK, recorder.Code)
+		},
+	}
+
+	wrongAPIKeyTestCase := authTestCase{
+		name: "API key with wrong secret",
+		setupAuth: func(request *http.Request, tokenMaker token.TokenMaker) {
+			request.Header.Set("Authorization", "ApiKey "+key+"0")
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetAPIKeyByPrefix(gomock.Any(), gomock.Eq(apiKey.Prefix)).
+				Times(1).
+				Return(apiKey, nil)
+		},
+		checkResponse: func(recorder *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusUnauthorized, recorder.Code)
+
+			var content map[string]string
+			json.Unmarshal(recorder.Body.Bytes(), &content)
+			require.Equal(t, errInvalidAPIKey.Error(), content["error"])
+		},
+	}
+
+	revokedAPIKey := apiKey
+	revokedAPIKey.RevokedAt.Valid = true
+	revokedAPIKeyTestCase := authTestCase{
+		name: "Revoked API key",
+		setupAuth: func(request *http.Request, tokenMaker token.TokenMaker) {
+			request.Header.Set("Authorization", "ApiKey "+key)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetAPIKeyByPrefix(gomock.Any(), gomock.Eq(apiKey.Prefix)).
+				Times(1).
+				Return(revokedAPIKey, nil)
+		},
+		checkResponse: func(recorder *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusUnauthorized, recorder.Code)
+		},
+	}
+
+	malformedAPIKeyTestCase := authTestCase{
+		name: "Malformed API key",
+		setupAuth: func(request *http.Request, tokenMaker token.TokenMaker) {
+			request.Header.Set("Authorization", "ApiKey not-a-key")
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetAPIKeyByPrefix(gomock.Any(), gomock.Any()).
+				Times(0)
+		},
+		checkResponse: func(recorder *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusUnauthorized, recorder.Code)
+		},
+	}
+
 	testCases := []authTestCase{
 		okTestCase, noHeaderTestCase, invalidResponseTestCase,
-		unsupportedAuthTestCase, verifyTokenErrorTestCase,
+		unsupportedAuthTestCase, verifyTokenErrorTestCase, passwordChangedTestCase,
+		apiKeyTestCase, wrongAPIKeyTestCase, revokedAPIKeyTestCase,
+		malformedAPIKeyTestCase,
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			server := newTestServer(t, nil)
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			if tc.buildStubs != nil {
+				tc.buildStubs(store)
+			}
+
+			server := newTestServer(t, store)
 			server.router.GET(
 				"/auth",
-				authMiddleware(server.tokenMaker),
+				authMiddleware(server.tokenMaker, server.store),
 				func(ctx *gin.Context) { ctx.JSON(http.StatusOK, gin.H{}) },
 			)
 			recorder := httptest.NewRecorder()
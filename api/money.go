@@ -0,0 +1,53 @@
+package api
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go_backend_misc/util"
+)
+
+// parseAmount reads a decimal amount of the currency into minor units, the amount validator already
+// checked it's a positive decimal
+func parseAmount(ctx *gin.Context, amount string, currency string) (int64, bool) {
+	money, err := util.ParseMoney(amount, currency)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return 0, false
+	}
+	return money.Amount, true
+}
+
+// parseTransferAmount is parseAmount for the amount of a new transfer, it must be within the transfer limits
+// of the currency
+func parseTransferAmount(ctx *gin.Context, amount string, currencyCode string) (int64, bool) {
+	minorUnits, ok := parseAmount(ctx, amount, currencyCode)
+	if !ok {
+		return 0, false
+	}
+	currency, _ := util.LookupCurrency(currencyCode)
+	if err := currency.CheckTransferAmount(minorUnits); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return 0, false
+	}
+	return minorUnits, true
+}
+
+// parseOptionalAmount is parseAmount for amounts that can be omitted, it returns 0 for an empty amount
+// Captures and refunds use it, they are bounded by what they settle rather than by the transfer limits
+func parseOptionalAmount(ctx *gin.Context, amount string, currency string) (int64, bool) {
+	if amount == "" {
+		return 0, true
+	}
+	return parseAmount(ctx, amount, currency)
+}
+
+// optionalDecimal is for nullable amounts
+func optionalDecimal(amount sql.NullInt64, currency string) *string {
+	if !amount.Valid {
+		return nil
+	}
+	decimal := util.NewMoney(amount.Int64, currency).Decimal()
+	return &decimal
+}
@@ -0,0 +1,176 @@
+package api
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/go_backend_misc/db/sqlc"
+	"github.com/go_backend_misc/token"
+	"github.com/go_backend_misc/util"
+)
+
+const passwordResetTokenBytes = 32
+
+type changePasswordRequest struct {
+	OldPassword string `json:"old_password" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+// changePassword invalidates every token issued so far, so a fresh token is returned
+func (server *Server) changePassword(ctx *gin.Context) {
+	var req changePasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	user, err := server.store.GetUserByUsername(ctx, authPayload.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if err := util.CheckPassword(req.OldPassword, user.HashedPassword); err != nil {
+		ctx.JSON(http.StatusUnauthorized, errorMessageResponse("invalid credentials"))
+		return
+	}
+
+	if !server.checkPasswordPolicy(ctx, req.NewPassword, user.Username, user.Email) {
+		return
+	}
+
+	hashedPassword, err := server.passwordHasher.HashPassword(req.NewPassword)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorMessageResponse("Error while processing password"))
+		return
+	}
+
+	user, err = server.store.UpdateUserPassword(ctx, db.UpdateUserPasswordParams{
+		Username:          user.Username,
+		HashedPassword:    hashedPassword,
+		PasswordChangedAt: passwordChangedAt(),
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	log.Printf("security: password changed username=%v", user.Username)
+	server.issueAccessToken(ctx, &user)
+}
+
+type requestPasswordResetRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// requestPasswordReset emails a one-time reset token
+// The response is the same whether the email exists or not, to avoid leaking accounts
+func (server *Server) requestPasswordReset(ctx *gin.Context) {
+	var req requestPasswordResetRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	accepted := gin.H{"message": "if the email belongs to an account, a reset link has been sent"}
+	user, err := server.store.GetUserByEmail(ctx, req.Email)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusAccepted, accepted)
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	resetToken, err := util.RandomSecret(passwordResetTokenBytes)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	_, err = server.store.CreatePasswordResetToken(ctx, db.CreatePasswordResetTokenParams{
+		HashedToken: util.HashSecret(resetToken),
+		Username:    user.Username,
+		ExpiresAt:   time.Now().Add(server.config.PasswordResetTokenDuration),
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	subject := "Reset your Simple Bank password"
+	content := fmt.Sprintf(
+		"Hello %s,\n\nUse this token to reset your password, it expires in %v:\n\n%s\n\n"+
+			"If you didn't ask for a password reset, you can ignore this email.\n",
+		user.FullName, server.config.PasswordResetTokenDuration, resetToken,
+	)
+	if err := server.emailSender.SendEmail(subject, content, []string{user.Email}); err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorMessageResponse("cannot send password reset email"))
+		return
+	}
+
+	log.Printf("security: password reset requested username=%v", user.Username)
+	ctx.JSON(http.StatusAccepted, accepted)
+}
+
+type confirmPasswordResetRequest struct {
+	Token       string `json:"token" binding:"required,hexadecimal"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+func (server *Server) confirmPasswordReset(ctx *gin.Context) {
+	var req confirmPasswordResetRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	hashedToken := util.HashSecret(req.Token)
+	user, err := server.store.GetUserByPasswordResetToken(ctx, hashedToken)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusBadRequest, errorMessageResponse("invalid or expired reset token"))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if !server.checkPasswordPolicy(ctx, req.NewPassword, user.Username, user.Email) {
+		return
+	}
+
+	hashedPassword, err := server.passwordHasher.HashPassword(req.NewPassword)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorMessageResponse("Error while processing password"))
+		return
+	}
+
+	user, err = server.store.ResetPasswordTx(ctx, db.ResetPasswordTxParams{
+		HashedToken:       hashedToken,
+		HashedPassword:    hashedPassword,
+		PasswordChangedAt: passwordChangedAt(),
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusBadRequest, errorMessageResponse("invalid or expired reset token"))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	log.Printf("security: password reset username=%v", user.Username)
+	ctx.JSON(http.StatusOK, createUserResponseFromUser(&user))
+}
+
+// passwordChangedAt is stored in a column without time zone, so it's always written in UTC
+func passwordChangedAt() time.Time {
+	return time.Now().UTC()
+}
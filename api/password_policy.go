@@ -0,0 +1,21 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// checkPasswordPolicy writes a 400 with every violation and returns false when the password is rejected
+func (server *Server) checkPasswordPolicy(ctx *gin.Context, password string, username string, email string) bool {
+	violations := server.passwordPolicy.Validate(password, username, email)
+	if len(violations) == 0 {
+		return true
+	}
+
+	ctx.JSON(http.StatusBadRequest, gin.H{
+		"error":      "password does not meet the policy",
+		"violations": violations,
+	})
+	return false
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/go_backend_misc/db/sqlc"
+	"github.com/go_backend_misc/ratelimit"
+	"github.com/go_backend_misc/token"
+	"github.com/go_backend_misc/util"
+)
+
+const (
+	rateLimitStoreMemory   = "memory"
+	rateLimitStorePostgres = "postgres"
+)
+
+// rateLimits are applied per route group in setupRouter
+type rateLimits struct {
+	public        ratelimit.Limit
+	login         ratelimit.Limit
+	authenticated ratelimit.Limit
+	transfers     ratelimit.Limit
+}
+
+func newRateLimits(config util.Config) (limits rateLimits, err error) {
+	for _, setting := range []struct {
+		limit *ratelimit.Limit
+		value string
+	}{
+		{&limits.public, config.RateLimitPublic},
+		{&limits.login, config.RateLimitLogin},
+		{&limits.authenticated, config.RateLimitAuthenticated},
+		{&limits.transfers, config.RateLimitTransfers},
+	} {
+		*setting.limit, err = ratelimit.ParseLimit(setting.value)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+func newRateLimitStore(config util.Config, store db.Store) (ratelimit.Store, error) {
+	switch config.RateLimitStore {
+	case "", rateLimitStoreMemory:
+		return ratelimit.NewMemoryStore(), nil
+	case rateLimitStorePostgres:
+		return ratelimit.NewPostgresStore(store), nil
+	default:
+		return nil, fmt.Errorf("unknown rate limit store %q", config.RateLimitStore)
+	}
+}
+
+// rateLimitKeyFunc identifies who a request is counted against
+type rateLimitKeyFunc func(ctx *gin.Context) string
+
+func ipRateLimitKey(ctx *gin.Context) string {
+	return "ip:" + ctx.ClientIP()
+}
+
+// usernameRateLimitKey must run after authMiddleware
+func usernameRateLimitKey(ctx *gin.Context) string {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	return "user:" + authPayload.Username
+}
+
+// rateLimitMiddleware takes a token from the bucket of the client for the route group
+// and sets the RateLimit-* headers of the IETF draft. A failing store lets requests through
+func rateLimitMiddleware(store ratelimit.Store, group string, limit ratelimit.Limit, keyFunc rateLimitKeyFunc) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !limit.Enabled() {
+			ctx.Next()
+			return
+		}
+
+		key := group + ":" + keyFunc(ctx)
+		result, err := store.Take(ctx, key, limit)
+		if err != nil {
+			log.Printf("cannot check rate limit key=%v: %v", key, err)
+			ctx.Next()
+			return
+		}
+
+		ctx.Header("RateLimit-Policy", fmt.Sprintf("%d;w=%d", limit.Requests, int(math.Ceil(limit.Period.Seconds()))))
+		ctx.Header("RateLimit-Limit", strconv.Itoa(limit.Requests))
+		ctx.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining()))
+		ctx.Header("RateLimit-Reset", strconv.Itoa(int(math.Ceil(result.ResetAfter(limit).Seconds()))))
+
+		if !result.Allowed {
+			ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(result.RetryAfter(limit).Seconds()))))
+			ctx.AbortWithStatusJSON(http.StatusTooManyRequests, errorMessageResponse("too many requests, try again later"))
+			return
+		}
+		ctx.Next()
+	}
+}
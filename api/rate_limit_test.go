@@ -0,0 +1,113 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	mockdb "github.com/go_backend_misc/db/mock"
+	"github.com/go_backend_misc/ratelimit"
+	"github.com/go_backend_misc/util"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+type failingRateLimitStore struct{}
+
+func (failingRateLimitStore) Take(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("store is down")
+}
+
+func newRateLimitTestRouter(store ratelimit.Store, limit ratelimit.Limit) *gin.Engine {
+	router := gin.New()
+	router.GET("/limited", rateLimitMiddleware(store, "test", limit, ipRateLimitKey), func(ctx *gin.Context) {
+		ctx.Status(http.StatusOK)
+	})
+	return router
+}
+
+func sendLimitedRequest(t *testing.T, router *gin.Engine, clientIP string) *httptest.ResponseRecorder {
+	request, err := http.NewRequest(http.MethodGet, "/limited", nil)
+	require.NoError(t, err)
+	request.RemoteAddr = clientIP + ":1234"
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	limit := ratelimit.Limit{Requests: 2, Period: time.Minute}
+	router := newRateLimitTestRouter(ratelimit.NewMemoryStore(), limit)
+
+	recorder := sendLimitedRequest(t, router, "10.0.0.1")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, "2", recorder.Header().Get("RateLimit-Limit"))
+	require.Equal(t, "1", recorder.Header().Get("RateLimit-Remaining"))
+	require.Equal(t, "30", recorder.Header().Get("RateLimit-Reset"))
+	require.Equal(t, "2;w=60", recorder.Header().Get("RateLimit-Policy"))
+
+	recorder = sendLimitedRequest(t, router, "10.0.0.1")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, "0", recorder.Header().Get("RateLimit-Remaining"))
+
+	recorder = sendLimitedRequest(t, router, "10.0.0.1")
+	require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	require.Equal(t, "0", recorder.Header().Get("RateLimit-Remaining"))
+	require.Equal(t, "30", recorder.Header().Get("Retry-After"))
+
+	// each client IP has its own bucket
+	recorder = sendLimitedRequest(t, router, "10.0.0.2")
+	require.Equal(t, http.StatusOK, recorder.Code)
+}
+
+func TestRateLimitMiddlewareDisabled(t *testing.T) {
+	router := newRateLimitTestRouter(ratelimit.NewMemoryStore(), ratelimit.Limit{})
+
+	for i := 0; i < 5; i++ {
+		recorder := sendLimitedRequest(t, router, "10.0.0.1")
+		require.Equal(t, http.StatusOK, recorder.Code)
+		require.Empty(t, recorder.Header().Get("RateLimit-Limit"))
+	}
+}
+
+func TestRateLimitMiddlewareStoreError(t *testing.T) {
+	router := newRateLimitTestRouter(failingRateLimitStore{}, ratelimit.Limit{Requests: 1, Period: time.Minute})
+
+	recorder := sendLimitedRequest(t, router, "10.0.0.1")
+	require.Equal(t, http.StatusOK, recorder.Code)
+}
+
+func TestRateLimitPerUsername(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	stubAuthUser(store)
+	store.EXPECT().
+		ListAccountsByUsername(gomock.Any(), gomock.Any()).
+		AnyTimes()
+
+	server := newTestServer(t, store)
+	server.rateLimits.authenticated = ratelimit.Limit{Requests: 1, Period: time.Minute}
+	server.setupRouter()
+
+	listAccounts := func(username string) *httptest.ResponseRecorder {
+		request, err := http.NewRequest(http.MethodGet, "/accounts/?offset=1&page_size=5", nil)
+		require.NoError(t, err)
+		addAuthorization(t, request, server.tokenMaker, username)
+
+		recorder := httptest.NewRecorder()
+		server.router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	username := util.RandomOwner()
+	require.Equal(t, http.StatusOK, listAccounts(username).Code)
+	require.Equal(t, http.StatusTooManyRequests, listAccounts(username).Code)
+	require.Equal(t, http.StatusOK, listAccounts(username+"x").Code)
+}
@@ -0,0 +1,31 @@
+package api
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go_backend_misc/token"
+)
+
+// reconcileLedger reports the balance mismatches, orphan entries and unbalanced transfers without changing anything
+func (server *Server) reconcileLedger(ctx *gin.Context) {
+	report, err := server.store.ReconcileLedger(ctx, false)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, report)
+}
+
+// correctLedger runs the same scan and writes a correcting journal for each balance mismatch
+func (server *Server) correctLedger(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	report, err := server.store.ReconcileLedger(ctx, true)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	log.Printf("security: admin %v wrote %d ledger corrections", authPayload.Username, len(report.Corrections))
+	ctx.JSON(http.StatusOK, report)
+}
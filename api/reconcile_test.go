@@ -0,0 +1,116 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	mockdb "github.com/go_backend_misc/db/mock"
+	db "github.com/go_backend_misc/db/sqlc"
+	"github.com/go_backend_misc/util"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestReconcileLedgerAPI(t *testing.T) {
+	report := db.LedgerReport{
+		BalanceMismatches: []db.ListBalanceMismatchesRow{
+			{ID: 123, Owner: "test_owner", Currency: util.USD, Balance: 300, EntriesTotal: 250},
+		},
+		OrphanEntries:       []db.Entry{},
+		UnbalancedTransfers: []db.ListUnbalancedTransfersRow{},
+	}
+	correctedReport := report
+	correctedReport.Corrections = []db.Journal{{ID: 9, Kind: db.JournalCorrection}}
+
+	testCases := []struct {
+		name          string
+		method        string
+		username      string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "Report",
+			method:   http.MethodGet,
+			username: "an_admin",
+			buildStubs: func(store *mockdb.MockStore) {
+				stubAdmin(store)
+				store.EXPECT().ReconcileLedger(gomock.Any(), false).Times(1).Return(report, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var response db.LedgerReport
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+				require.Equal(t, report.BalanceMismatches, response.BalanceMismatches)
+				require.Empty(t, response.Corrections)
+			},
+		},
+		{
+			name:     "Correct",
+			method:   http.MethodPost,
+			username: "an_admin",
+			buildStubs: func(store *mockdb.MockStore) {
+				stubAdmin(store)
+				store.EXPECT().ReconcileLedger(gomock.Any(), true).Times(1).Return(correctedReport, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var response db.LedgerReport
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+				require.Len(t, response.Corrections, 1)
+			},
+		},
+		{
+			name:     "Not an admin",
+			method:   http.MethodGet,
+			username: "test_owner",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ReconcileLedger(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:     "Internal error",
+			method:   http.MethodGet,
+			username: "an_admin",
+			buildStubs: func(store *mockdb.MockStore) {
+				stubAdmin(store)
+				store.EXPECT().
+					ReconcileLedger(gomock.Any(), false).
+					Times(1).
+					Return(db.LedgerReport{}, errors.New("connection refused"))
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			stubAuthUser(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(tc.method, "/admin/reconcile", nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, tc.username)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
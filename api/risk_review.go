@@ -0,0 +1,209 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/go_backend_misc/db/sqlc"
+	"github.com/go_backend_misc/risk"
+	"github.com/go_backend_misc/token"
+	"github.com/go_backend_misc/util"
+)
+
+var errTransferDenied = errors.New("transfer denied")
+
+type riskReviewResponse struct {
+	ID            int64  `json:"id"`
+	Owner         string `json:"owner"`
+	FromAccountID int64  `json:"from_account_id"`
+	ToAccountID   int64  `json:"to_account_id"`
+	Amount        string `json:"amount"`
+	Fee           string `json:"fee"`
+	Currency      string `json:"currency"`
+	Decision      string `json:"decision"`
+	// Reasons are only shown to admins, they would tell how to get around the rules
+	Reasons    []string   `json:"reasons,omitempty"`
+	Status     string     `json:"status"`
+	TransferID *int64     `json:"transfer_id,omitempty"`
+	ReviewedBy string     `json:"reviewed_by,omitempty"`
+	ReviewedAt *time.Time `json:"reviewed_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func createRiskReviewResponse(review *db.RiskReview) riskReviewResponse {
+	response := riskReviewResponse{
+		ID:            review.ID,
+		Owner:         review.Owner,
+		FromAccountID: review.FromAccountID,
+		ToAccountID:   review.ToAccountID,
+		Amount:        util.NewMoney(review.Amount, review.Currency).Decimal(),
+		Fee:           util.NewMoney(review.Fee, review.Currency).Decimal(),
+		Currency:      review.Currency,
+		Decision:      review.Decision,
+		Reasons:       review.Reasons,
+		Status:        review.Status,
+		ReviewedBy:    review.ReviewedBy.String,
+		CreatedAt:     review.CreatedAt,
+	}
+	if review.TransferID.Valid {
+		response.TransferID = &review.TransferID.Int64
+	}
+	if review.ReviewedAt.Valid {
+		response.ReviewedAt = &review.ReviewedAt.Time
+	}
+	return response
+}
+
+// screenTransfer runs the risk rules on a transfer about to be made, it tells whether to go on with it
+// Held transfers are recorded for review and get a 202 with the review, denied ones are recorded and get a 403
+func (server *Server) screenTransfer(ctx *gin.Context, transfer *risk.Transfer, fee int64) bool {
+	decision, review, err := server.riskEngine.Review(ctx, server.store, transfer, fee, true)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return false
+	}
+	switch decision {
+	case risk.Allow:
+		return true
+	case risk.Deny:
+		ctx.JSON(http.StatusForbidden, errorResponse(errTransferDenied))
+		return false
+	}
+	response := createRiskReviewResponse(&review)
+	response.Reasons = nil
+	ctx.JSON(http.StatusAccepted, response)
+	return false
+}
+
+// screenTransferWithoutReview runs the risk rules on a transfer made later, a hold or a scheduled or standing
+// transfer, which can't wait for a review: held and denied transfers are recorded as denied and get a 403
+func (server *Server) screenTransferWithoutReview(ctx *gin.Context, transfer *risk.Transfer, fee int64) bool {
+	decision, _, err := server.riskEngine.Review(ctx, server.store, transfer, fee, false)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return false
+	}
+	if decision != risk.Allow {
+		ctx.JSON(http.StatusForbidden, errorResponse(errTransferDenied))
+		return false
+	}
+	return true
+}
+
+type listRiskReviewsQueryParams struct {
+	// Status is pending by default, the review queue
+	Status   string `form:"status" binding:"omitempty,oneof=pending approved rejected denied"`
+	Offset   int32  `form:"offset" binding:"min=0"`
+	PageSize int32  `form:"page_size" binding:"required,min=1,max=20"`
+}
+
+// listRiskReviews lists the reviews of a status, oldest first
+func (server *Server) listRiskReviews(ctx *gin.Context) {
+	var req listRiskReviewsQueryParams
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if req.Status == "" {
+		req.Status = db.RiskReviewPending
+	}
+
+	reviews, err := server.store.ListRiskReviews(ctx, db.ListRiskReviewsParams{
+		Status: req.Status,
+		Limit:  req.PageSize,
+		Offset: req.Offset,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	response := make([]riskReviewResponse, 0, len(reviews))
+	for i := range reviews {
+		response = append(response, createRiskReviewResponse(&reviews[i]))
+	}
+	ctx.JSON(http.StatusOK, response)
+}
+
+type riskReviewURIParams struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+type approveRiskReviewResponse struct {
+	Review riskReviewResponse `json:"review"`
+	transferTxResponse
+}
+
+// approveRiskReview makes the held transfer, it can still fail on the balance or the limits of the sender
+func (server *Server) approveRiskReview(ctx *gin.Context) {
+	var uri riskReviewURIParams
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	result, err := server.store.ApproveRiskReviewTx(ctx, db.ApproveRiskReviewTxParams{
+		ID:         uri.ID,
+		ReviewedBy: authPayload.Username,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+		case errors.Is(err, db.ErrRiskReviewNotPending), errors.Is(err, db.ErrInsufficientFunds),
+			errors.Is(err, db.ErrTransferLimitExceeded), errors.Is(err, db.ErrInvalidDestination):
+			ctx.JSON(http.StatusConflict, errorResponse(err))
+		default:
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	log.Printf("security: admin %v approved risk review %d as transfer %d", authPayload.Username, result.Review.ID, result.Transfer.ID)
+	ctx.JSON(http.StatusOK, approveRiskReviewResponse{
+		Review:             createRiskReviewResponse(&result.Review),
+		transferTxResponse: createTransferTxResponse(&result.TransferTxResult),
+	})
+}
+
+// rejectRiskReview drops the held transfer, nothing was moved for it
+func (server *Server) rejectRiskReview(ctx *gin.Context) {
+	var uri riskReviewURIParams
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if _, err := server.store.GetRiskReview(ctx, uri.ID); err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	review, err := server.store.SettleRiskReview(ctx, db.SettleRiskReviewParams{
+		ID:         uri.ID,
+		Status:     db.RiskReviewRejected,
+		ReviewedBy: sql.NullString{String: authPayload.Username, Valid: true},
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			// it was found above, so it's settled
+			ctx.JSON(http.StatusConflict, errorResponse(db.ErrRiskReviewNotPending))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	log.Printf("security: admin %v rejected risk review %d", authPayload.Username, review.ID)
+	ctx.JSON(http.StatusOK, createRiskReviewResponse(&review))
+}
@@ -0,0 +1,622 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	mockdb "github.com/go_backend_misc/db/mock"
+	db "github.com/go_backend_misc/db/sqlc"
+	"github.com/go_backend_misc/risk"
+	"github.com/go_backend_misc/util"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func stubAdmin(store *mockdb.MockStore) {
+	store.EXPECT().
+		GetUserByUsername(gomock.Any(), "an_admin").
+		AnyTimes().
+		Return(db.User{Username: "an_admin", Role: util.RoleAdmin}, nil)
+}
+
+func randomRiskReview(fromAccount db.Account, toAccount db.Account) db.RiskReview {
+	return db.RiskReview{
+		ID:            util.RandomInt(1, 1000),
+		Owner:         fromAccount.Owner,
+		FromAccountID: fromAccount.ID,
+		ToAccountID:   toAccount.ID,
+		Amount:        500,
+		Currency:      fromAccount.Currency,
+		Decision:      string(risk.Hold),
+		Reasons:       []string{"large_amount: 5.00 USD is at least 1.00 USD"},
+		Status:        db.RiskReviewPending,
+	}
+}
+
+func TestCreateTransferRiskRules(t *testing.T) {
+	fromAccount, toAccount := getAccounts()
+
+	testCases := []struct {
+		name          string
+		action        risk.Decision
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "Allow",
+			action: risk.Allow,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateRiskReview(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(*getOkTransferResult(fromAccount, toAccount), nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "Hold",
+			action: risk.Hold,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateRiskReview(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.CreateRiskReviewParams) (db.RiskReview, error) {
+						require.Equal(t, fromAccount.Owner, arg.Owner)
+						require.Equal(t, int64(500), arg.Amount)
+						require.Equal(t, string(risk.Hold), arg.Decision)
+						require.Equal(t, db.RiskReviewPending, arg.Status)
+						require.Len(t, arg.Reasons, 1)
+						return randomRiskReview(fromAccount, toAccount), nil
+					})
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusAccepted, recorder.Code)
+
+				var response riskReviewResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+				require.Equal(t, db.RiskReviewPending, response.Status)
+				require.Equal(t, "5.00", response.Amount)
+				// the owner doesn't learn which rules held the transfer
+				require.Empty(t, response.Reasons)
+			},
+		},
+		{
+			name:   "Deny",
+			action: risk.Deny,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateRiskReview(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.CreateRiskReviewParams) (db.RiskReview, error) {
+						require.Equal(t, db.RiskReviewDenied, arg.Status)
+						review := randomRiskReview(fromAccount, toAccount)
+						review.Decision, review.Status = arg.Decision, arg.Status
+						return review, nil
+					})
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				require.NotContains(t, recorder.Body.String(), "large_amount")
+			},
+		},
+		{
+			name:   "Internal error",
+			action: risk.Hold,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateRiskReview(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.RiskReview{}, sql.ErrConnDone)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().GetAccount(gomock.Any(), fromAccount.ID).Times(1).Return(fromAccount, nil)
+			store.EXPECT().GetAccount(gomock.Any(), toAccount.ID).Times(1).Return(toAccount, nil)
+			tc.buildStubs(store)
+			stubAuthUser(store)
+
+			server := newTestServer(t, store)
+			server.riskEngine = risk.NewEngine()
+			server.riskEngine.Add(&risk.LargeAmount{Amounts: map[string]int64{util.USD: 100}}, tc.action)
+
+			data, err := json.Marshal(gin.H{
+				"from_account_id": fromAccount.ID,
+				"to_account_id":   toAccount.ID,
+				"amount":          "5.00",
+				"currency":        util.USD,
+			})
+			require.NoError(t, err)
+			request, err := http.NewRequest(http.MethodPost, "/transfer", bytes.NewReader(data))
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, fromAccount.Owner)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestDeferredTransferRiskRules(t *testing.T) {
+	fromAccount, toAccount := getAccounts()
+	later := time.Now().Add(time.Hour).UTC()
+
+	testCases := []struct {
+		name       string
+		url        string
+		body       gin.H
+		buildStubs func(store *mockdb.MockStore)
+	}{
+		{
+			name: "Hold",
+			url:  "/holds",
+			body: gin.H{"expires_at": later},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateHoldTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name: "Scheduled transfer",
+			url:  "/scheduled-transfers",
+			body: gin.H{"execute_at": later},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name: "Standing order",
+			url:  "/standing-orders",
+			body: gin.H{"rrule": "freq=weekly;count=4", "starts_at": later},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateStandingOrder(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().GetAccount(gomock.Any(), fromAccount.ID).Times(1).Return(fromAccount, nil)
+			store.EXPECT().GetAccount(gomock.Any(), toAccount.ID).Times(1).Return(toAccount, nil)
+			// nobody is around to approve a transfer made later, the held one is refused and recorded as denied
+			store.EXPECT().
+				CreateRiskReview(gomock.Any(), gomock.Any()).
+				Times(1).
+				DoAndReturn(func(_ any, arg db.CreateRiskReviewParams) (db.RiskReview, error) {
+					require.Equal(t, string(risk.Hold), arg.Decision)
+					require.Equal(t, db.RiskReviewDenied, arg.Status)
+					require.Equal(t, int64(500), arg.Amount)
+					return randomRiskReview(fromAccount, toAccount), nil
+				})
+			tc.buildStubs(store)
+			stubAuthUser(store)
+
+			server := newTestServer(t, store)
+			server.riskEngine = risk.NewEngine()
+			server.riskEngine.Add(&risk.LargeAmount{Amounts: map[string]int64{util.USD: 100}}, risk.Hold)
+
+			body := gin.H{
+				"from_account_id": fromAccount.ID,
+				"to_account_id":   toAccount.ID,
+				"amount":          "5.00",
+				"currency":        util.USD,
+			}
+			for key, value := range tc.body {
+				body[key] = value
+			}
+			data, err := json.Marshal(body)
+			require.NoError(t, err)
+			request, err := http.NewRequest(http.MethodPost, tc.url, bytes.NewReader(data))
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, fromAccount.Owner)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			require.Equal(t, http.StatusForbidden, recorder.Code)
+		})
+	}
+}
+
+func TestBatchTransferRiskRules(t *testing.T) {
+	fromAccount, toAccount := getAccounts()
+	// the velocity rule holds the third transfer, 789 doesn't exist and isn't screened
+	items := []gin.H{
+		{"to_account_id": toAccount.ID, "amount": "1.00"},
+		{"to_account_id": 789, "amount": "1.00"},
+		{"to_account_id": toAccount.ID, "amount": "1.00"},
+		{"to_account_id": toAccount.ID, "amount": "1.00"},
+	}
+	review := randomRiskReview(fromAccount, toAccount)
+
+	stubReview := func(store *mockdb.MockStore, status string) {
+		store.EXPECT().
+			CreateRiskReview(gomock.Any(), gomock.Any()).
+			Times(1).
+			DoAndReturn(func(_ any, arg db.CreateRiskReviewParams) (db.RiskReview, error) {
+				require.Equal(t, toAccount.ID, arg.ToAccountID)
+				require.Equal(t, int64(100), arg.Amount)
+				require.Equal(t, status, arg.Status)
+				review.Status = status
+				return review, nil
+			})
+	}
+
+	testCases := []struct {
+		name          string
+		mode          string
+		action        risk.Decision
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "Best effort leaves the held item out",
+			mode:   batchModeBestEffort,
+			action: risk.Hold,
+			buildStubs: func(store *mockdb.MockStore) {
+				stubReview(store, db.RiskReviewPending)
+				store.EXPECT().
+					BatchTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.BatchTransferTxParams) (db.BatchTransferTxResult, error) {
+						require.Len(t, arg.Items, 3)
+						require.False(t, arg.AllOrNothing)
+						return db.BatchTransferTxResult{
+							FromAccount: fromAccount,
+							Items: []db.BatchTransferItemResult{
+								{Index: 0, Transfer: &db.Transfer{ID: 1, Amount: 100}},
+								{Index: 1, Error: "invalid batch item: account 789 not found"},
+								{Index: 2, Transfer: &db.Transfer{ID: 2, Amount: 100}},
+							},
+							Succeeded: 2,
+							Failed:    1,
+						}, nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				var response batchTransferResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+				require.Len(t, response.Items, 4)
+				require.Equal(t, 2, response.Succeeded)
+				require.Equal(t, 2, response.Failed)
+				require.NotNil(t, response.Items[2].Transfer)
+				require.Equal(t, 3, response.Items[3].Index)
+				require.Equal(t, fmt.Sprintf("transfer held for risk review %d", review.ID), response.Items[3].Error)
+			},
+		},
+		{
+			name:   "Best effort leaves the denied item out",
+			mode:   batchModeBestEffort,
+			action: risk.Deny,
+			buildStubs: func(store *mockdb.MockStore) {
+				stubReview(store, db.RiskReviewDenied)
+				store.EXPECT().
+					BatchTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.BatchTransferTxResult{FromAccount: fromAccount, Items: make([]db.BatchTransferItemResult, 3)}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Contains(t, recorder.Body.String(), errTransferDenied.Error())
+			},
+		},
+		{
+			name:   "All or nothing refuses the batch",
+			mode:   batchModeAllOrNothing,
+			action: risk.Hold,
+			buildStubs: func(store *mockdb.MockStore) {
+				stubReview(store, db.RiskReviewDenied)
+				store.EXPECT().BatchTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				require.Contains(t, recorder.Body.String(), "item 3")
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().GetAccount(gomock.Any(), fromAccount.ID).Times(1).Return(fromAccount, nil)
+			store.EXPECT().GetAccount(gomock.Any(), toAccount.ID).Times(1).Return(toAccount, nil)
+			store.EXPECT().GetAccount(gomock.Any(), int64(789)).Times(1).Return(db.Account{}, sql.ErrNoRows)
+			store.EXPECT().CountTransfersSince(gomock.Any(), gomock.Any()).Times(3).Return(int64(0), nil)
+			tc.buildStubs(store)
+			stubAuthUser(store)
+
+			server := newTestServer(t, store)
+			server.riskEngine = risk.NewEngine()
+			server.riskEngine.Add(&risk.Velocity{Count: 2, Window: time.Hour}, tc.action)
+
+			data, err := json.Marshal(gin.H{
+				"from_account_id": fromAccount.ID,
+				"currency":        util.USD,
+				"mode":            tc.mode,
+				"items":           items,
+			})
+			require.NoError(t, err)
+			request, err := http.NewRequest(http.MethodPost, "/transfers/batch", bytes.NewReader(data))
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, fromAccount.Owner)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestListRiskReviewsAPI(t *testing.T) {
+	fromAccount, toAccount := getAccounts()
+	review := randomRiskReview(fromAccount, toAccount)
+
+	testCases := []struct {
+		name          string
+		query         string
+		username      string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "Pending by default",
+			query:    "?page_size=5",
+			username: "an_admin",
+			buildStubs: func(store *mockdb.MockStore) {
+				stubAdmin(store)
+				store.EXPECT().
+					ListRiskReviews(gomock.Any(), db.ListRiskReviewsParams{Status: db.RiskReviewPending, Limit: 5}).
+					Times(1).
+					Return([]db.RiskReview{review}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var response []riskReviewResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+				require.Len(t, response, 1)
+				require.Equal(t, review.Reasons, response[0].Reasons)
+			},
+		},
+		{
+			name:     "Invalid status",
+			query:    "?page_size=5&status=unknown",
+			username: "an_admin",
+			buildStubs: func(store *mockdb.MockStore) {
+				stubAdmin(store)
+				store.EXPECT().ListRiskReviews(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:     "Not an admin",
+			query:    "?page_size=5",
+			username: fromAccount.Owner,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListRiskReviews(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			stubAuthUser(store)
+
+			server := newTestServer(t, store)
+			request, err := http.NewRequest(http.MethodGet, "/admin/risk-reviews"+tc.query, nil)
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, tc.username)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestSettleRiskReviewAPI(t *testing.T) {
+	fromAccount, toAccount := getAccounts()
+	review := randomRiskReview(fromAccount, toAccount)
+	approved := review
+	approved.Status = db.RiskReviewApproved
+	rejected := review
+	rejected.Status = db.RiskReviewRejected
+
+	testCases := []struct {
+		name          string
+		action        string
+		username      string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "Approve",
+			action:   "approve",
+			username: "an_admin",
+			buildStubs: func(store *mockdb.MockStore) {
+				stubAdmin(store)
+				store.EXPECT().
+					ApproveRiskReviewTx(gomock.Any(), db.ApproveRiskReviewTxParams{ID: review.ID, ReviewedBy: "an_admin"}).
+					Times(1).
+					Return(db.ApproveRiskReviewTxResult{
+						Review:           approved,
+						TransferTxResult: *getOkTransferResult(fromAccount, toAccount),
+					}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var response approveRiskReviewResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+				require.Equal(t, db.RiskReviewApproved, response.Review.Status)
+				require.Equal(t, fromAccount.ID, response.Transfer.FromAccountID)
+			},
+		},
+		{
+			name:     "Approve not pending",
+			action:   "approve",
+			username: "an_admin",
+			buildStubs: func(store *mockdb.MockStore) {
+				stubAdmin(store)
+				store.EXPECT().
+					ApproveRiskReviewTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ApproveRiskReviewTxResult{}, db.ErrRiskReviewNotPending)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name:     "Approve insufficient funds",
+			action:   "approve",
+			username: "an_admin",
+			buildStubs: func(store *mockdb.MockStore) {
+				stubAdmin(store)
+				store.EXPECT().
+					ApproveRiskReviewTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ApproveRiskReviewTxResult{}, fmt.Errorf("%w: account 123", db.ErrInsufficientFunds))
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name:     "Approve not found",
+			action:   "approve",
+			username: "an_admin",
+			buildStubs: func(store *mockdb.MockStore) {
+				stubAdmin(store)
+				store.EXPECT().
+					ApproveRiskReviewTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ApproveRiskReviewTxResult{}, sql.ErrNoRows)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:     "Approve not an admin",
+			action:   "approve",
+			username: fromAccount.Owner,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ApproveRiskReviewTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:     "Reject",
+			action:   "reject",
+			username: "an_admin",
+			buildStubs: func(store *mockdb.MockStore) {
+				stubAdmin(store)
+				store.EXPECT().GetRiskReview(gomock.Any(), review.ID).Times(1).Return(review, nil)
+				store.EXPECT().
+					SettleRiskReview(gomock.Any(), db.SettleRiskReviewParams{
+						ID:         review.ID,
+						Status:     db.RiskReviewRejected,
+						ReviewedBy: sql.NullString{String: "an_admin", Valid: true},
+					}).
+					Times(1).
+					Return(rejected, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var response riskReviewResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+				require.Equal(t, db.RiskReviewRejected, response.Status)
+			},
+		},
+		{
+			name:     "Reject not pending",
+			action:   "reject",
+			username: "an_admin",
+			buildStubs: func(store *mockdb.MockStore) {
+				stubAdmin(store)
+				store.EXPECT().GetRiskReview(gomock.Any(), review.ID).Times(1).Return(approved, nil)
+				store.EXPECT().SettleRiskReview(gomock.Any(), gomock.Any()).Times(1).Return(db.RiskReview{}, sql.ErrNoRows)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name:     "Reject not found",
+			action:   "reject",
+			username: "an_admin",
+			buildStubs: func(store *mockdb.MockStore) {
+				stubAdmin(store)
+				store.EXPECT().GetRiskReview(gomock.Any(), review.ID).Times(1).Return(db.RiskReview{}, sql.ErrNoRows)
+				store.EXPECT().SettleRiskReview(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			stubAuthUser(store)
+
+			server := newTestServer(t, store)
+			url := fmt.Sprintf("/admin/risk-reviews/%d/%s", review.ID, tc.action)
+			request, err := http.NewRequest(http.MethodPost, url, nil)
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, tc.username)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
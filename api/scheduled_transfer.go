@@ -0,0 +1,196 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/go_backend_misc/db/sqlc"
+	"github.com/go_backend_misc/risk"
+	"github.com/go_backend_misc/token"
+	"github.com/go_backend_misc/util"
+)
+
+// transfers can't be scheduled further ahead than this
+const maxScheduleAhead = 366 * 24 * time.Hour
+
+type createScheduledTransferRequest struct {
+	FromAccountID int64     `json:"from_account_id" binding:"required,min=1"`
+	ToAccountID   int64     `json:"to_account_id" binding:"required,min=1"`
+	Amount        string    `json:"amount" binding:"required,amount"`
+	Currency      string    `json:"currency" binding:"required,currency"`
+	ExecuteAt     time.Time `json:"execute_at" binding:"required"`
+	// TOTPCode is required for amounts above the configured step-up amount
+	TOTPCode string `json:"totp_code" binding:"omitempty,alphanum"`
+}
+
+type scheduledTransferResponse struct {
+	ID            int64      `json:"id"`
+	FromAccountID int64      `json:"from_account_id"`
+	ToAccountID   int64      `json:"to_account_id"`
+	Amount        string     `json:"amount"`
+	Currency      string     `json:"currency"`
+	ExecuteAt     time.Time  `json:"execute_at"`
+	Status        string     `json:"status"`
+	Attempts      int32      `json:"attempts"`
+	LastError     string     `json:"last_error,omitempty"`
+	TransferID    *int64     `json:"transfer_id,omitempty"`
+	ExecutedAt    *time.Time `json:"executed_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+func createScheduledTransferResponse(scheduled *db.ScheduledTransfer) scheduledTransferResponse {
+	response := scheduledTransferResponse{
+		ID:            scheduled.ID,
+		FromAccountID: scheduled.FromAccountID,
+		ToAccountID:   scheduled.ToAccountID,
+		Amount:        util.NewMoney(scheduled.Amount, scheduled.Currency).Decimal(),
+		Currency:      scheduled.Currency,
+		ExecuteAt:     scheduled.ExecuteAt,
+		Status:        scheduled.Status,
+		Attempts:      scheduled.Attempts,
+		LastError:     scheduled.LastError.String,
+		CreatedAt:     scheduled.CreatedAt,
+	}
+	if scheduled.TransferID.Valid {
+		response.TransferID = &scheduled.TransferID.Int64
+	}
+	if scheduled.ExecutedAt.Valid {
+		response.ExecutedAt = &scheduled.ExecutedAt.Time
+	}
+	return response
+}
+
+// createScheduledTransfer runs the same checks as createTransfer now,
+// the executor checks the accounts again when the transfer is due
+func (server *Server) createScheduledTransfer(ctx *gin.Context) {
+	var req createScheduledTransferRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	amount, ok := parseTransferAmount(ctx, req.Amount, req.Currency)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	if !req.ExecuteAt.After(now) {
+		ctx.JSON(http.StatusBadRequest, errorMessageResponse("execute_at must be in the future"))
+		return
+	}
+	if req.ExecuteAt.After(now.Add(maxScheduleAhead)) {
+		ctx.JSON(http.StatusBadRequest, errorMessageResponse("execute_at must be within a year"))
+		return
+	}
+	if req.FromAccountID == req.ToAccountID {
+		ctx.JSON(http.StatusBadRequest, errorMessageResponse("cannot transfer to the same account"))
+		return
+	}
+
+	fromAccount, isValid := server.validAccount(ctx, req.FromAccountID, req.Currency)
+	if !isValid {
+		return
+	}
+	if _, isValid := server.validDestination(ctx, req.ToAccountID, req.Currency); !isValid {
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if fromAccount.Owner != authPayload.Username {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errors.New("unauthorized user")))
+		return
+	}
+	if !server.requireVerifiedEmail(ctx, server.config.RequireVerifiedEmailForTransfers, authPayload.Username) {
+		return
+	}
+	if !server.requireMFAForAmount(ctx, authPayload.Username, amount, req.TOTPCode) {
+		return
+	}
+	if !server.screenTransferWithoutReview(ctx, &risk.Transfer{
+		Owner:         authPayload.Username,
+		FromAccountID: req.FromAccountID,
+		ToAccountID:   req.ToAccountID,
+		Amount:        amount,
+		Currency:      req.Currency,
+	}, server.feeSchedule.Calculate(req.Currency, amount).Total) {
+		return
+	}
+
+	scheduled, err := server.store.CreateScheduledTransfer(ctx, db.CreateScheduledTransferParams{
+		Owner:         authPayload.Username,
+		FromAccountID: req.FromAccountID,
+		ToAccountID:   req.ToAccountID,
+		Amount:        amount,
+		Currency:      req.Currency,
+		ExecuteAt:     req.ExecuteAt,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, createScheduledTransferResponse(&scheduled))
+}
+
+type listScheduledTransfersQueryParams struct {
+	Offset   int32 `form:"offset" binding:"min=0"`
+	PageSize int32 `form:"page_size" binding:"required,min=1,max=20"`
+}
+
+func (server *Server) listScheduledTransfers(ctx *gin.Context) {
+	var req listScheduledTransfersQueryParams
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	scheduledTransfers, err := server.store.ListScheduledTransfers(ctx, db.ListScheduledTransfersParams{
+		Owner:  authPayload.Username,
+		Limit:  req.PageSize,
+		Offset: req.Offset,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	response := make([]scheduledTransferResponse, 0, len(scheduledTransfers))
+	for i := range scheduledTransfers {
+		response = append(response, createScheduledTransferResponse(&scheduledTransfers[i]))
+	}
+	ctx.JSON(http.StatusOK, response)
+}
+
+type scheduledTransferURIParams struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// cancelScheduledTransfer only cancels pending transfers, a transfer being executed right now
+// holds its row lock, so the cancel waits and then finds it completed
+func (server *Server) cancelScheduledTransfer(ctx *gin.Context) {
+	var req scheduledTransferURIParams
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	scheduled, err := server.store.CancelScheduledTransfer(ctx, db.CancelScheduledTransferParams{
+		ID:    req.ID,
+		Owner: authPayload.Username,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorMessageResponse("scheduled transfer not found or no longer pending"))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, createScheduledTransferResponse(&scheduled))
+}
@@ -0,0 +1,135 @@
+package api
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/go_backend_misc/db/sqlc"
+	"github.com/go_backend_misc/screening"
+	"github.com/go_backend_misc/token"
+)
+
+type screeningMatchResponse struct {
+	ID         int64      `json:"id"`
+	Username   string     `json:"username"`
+	EntryIDs   []string   `json:"entry_ids"`
+	Matches    []string   `json:"matches"`
+	Score      float64    `json:"score"`
+	Status     string     `json:"status"`
+	ReviewedBy string     `json:"reviewed_by,omitempty"`
+	ReviewedAt *time.Time `json:"reviewed_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func createScreeningMatchResponse(match *db.ScreeningMatch) screeningMatchResponse {
+	response := screeningMatchResponse{
+		ID:         match.ID,
+		Username:   match.Username,
+		EntryIDs:   match.EntryIds,
+		Matches:    match.Matches,
+		Score:      match.Score,
+		Status:     match.Status,
+		ReviewedBy: match.ReviewedBy.String,
+		CreatedAt:  match.CreatedAt,
+	}
+	if match.ReviewedAt.Valid {
+		response.ReviewedAt = &match.ReviewedAt.Time
+	}
+	return response
+}
+
+// screenUser screens the name of a user against the sanctions list, at signup and when the name changes;
+// a match goes to the review queue and the request goes on, the user isn't told
+func (server *Server) screenUser(ctx *gin.Context, user *db.User) error {
+	_, err := screening.RecordMatches(ctx, server.store, user, server.screener.Screen(user.FullName))
+	return err
+}
+
+type listScreeningMatchesQueryParams struct {
+	// Status is pending by default, the review queue
+	Status   string `form:"status" binding:"omitempty,oneof=pending cleared confirmed"`
+	Offset   int32  `form:"offset" binding:"min=0"`
+	PageSize int32  `form:"page_size" binding:"required,min=1,max=20"`
+}
+
+// listScreeningMatches lists the matches of a status, oldest first
+func (server *Server) listScreeningMatches(ctx *gin.Context) {
+	var req listScreeningMatchesQueryParams
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if req.Status == "" {
+		req.Status = db.ScreeningMatchPending
+	}
+
+	matches, err := server.store.ListScreeningMatches(ctx, db.ListScreeningMatchesParams{
+		Status: req.Status,
+		Limit:  req.PageSize,
+		Offset: req.Offset,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	response := make([]screeningMatchResponse, 0, len(matches))
+	for i := range matches {
+		response = append(response, createScreeningMatchResponse(&matches[i]))
+	}
+	ctx.JSON(http.StatusOK, response)
+}
+
+type screeningMatchURIParams struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// clearScreeningMatch marks the match a false positive, transfers to the user are no longer held
+// for the entries it matched
+func (server *Server) clearScreeningMatch(ctx *gin.Context) {
+	server.reviewScreeningMatch(ctx, db.ScreeningMatchCleared)
+}
+
+// confirmScreeningMatch marks the match a true one, transfers to the user keep being held
+func (server *Server) confirmScreeningMatch(ctx *gin.Context) {
+	server.reviewScreeningMatch(ctx, db.ScreeningMatchConfirmed)
+}
+
+func (server *Server) reviewScreeningMatch(ctx *gin.Context, status string) {
+	var uri screeningMatchURIParams
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if _, err := server.store.GetScreeningMatch(ctx, uri.ID); err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	match, err := server.store.SettleScreeningMatch(ctx, db.SettleScreeningMatchParams{
+		ID:         uri.ID,
+		Status:     status,
+		ReviewedBy: sql.NullString{String: authPayload.Username, Valid: true},
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			// it was found above, so it's reviewed
+			ctx.JSON(http.StatusConflict, errorResponse(db.ErrScreeningMatchNotPending))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	log.Printf("security: admin %v %v screening match %d of user %v", authPayload.Username, status, match.ID, match.Username)
+	ctx.JSON(http.StatusOK, createScreeningMatchResponse(&match))
+}
@@ -0,0 +1,515 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	mockdb "github.com/go_backend_misc/db/mock"
+	db "github.com/go_backend_misc/db/sqlc"
+	mockmail "github.com/go_backend_misc/mail/mock"
+	"github.com/go_backend_misc/risk"
+	"github.com/go_backend_misc/screening"
+	"github.com/go_backend_misc/util"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+const sanctionedName = "Johnathan Doe Sanctioned"
+
+func newTestScreener(t *testing.T) *screening.Screener {
+	path := filepath.Join(t.TempDir(), "sdn.csv")
+	list := `900001,"DOE SANCTIONED, Johnathan","individual","SDGT",-0- ,-0- ,-0- ,-0- ,-0- ,-0- ,-0- ,-0-` + "\n"
+	require.NoError(t, os.WriteFile(path, []byte(list), 0o600))
+
+	screener, err := screening.NewScreener(path, 0.9)
+	require.NoError(t, err)
+	return screener
+}
+
+func randomScreeningMatch(username string) db.ScreeningMatch {
+	return db.ScreeningMatch{
+		ID:       util.RandomInt(1, 1000),
+		Username: username,
+		EntryIds: []string{"900001"},
+		Matches:  []string{"900001 DOE SANCTIONED, Johnathan [SDGT] 1.00"},
+		Score:    1,
+		Status:   db.ScreeningMatchPending,
+	}
+}
+
+func TestCreateUserScreening(t *testing.T) {
+	user, password := randomUser(t)
+
+	testCases := []struct {
+		name       string
+		fullName   string
+		buildStubs func(store *mockdb.MockStore)
+	}{
+		{
+			name:     "Match",
+			fullName: "Jonathan Doe-Sanctioned",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateScreeningMatch(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.CreateScreeningMatchParams) (db.ScreeningMatch, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.Equal(t, []string{"900001"}, arg.EntryIds)
+						require.Len(t, arg.Matches, 1)
+						require.GreaterOrEqual(t, arg.Score, 0.9)
+						return randomScreeningMatch(user.Username), nil
+					})
+			},
+		},
+		{
+			name:     "No match",
+			fullName: "Jane Roe",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateScreeningMatch(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:     "Recording the match fails",
+			fullName: sanctionedName,
+			buildStubs: func(store *mockdb.MockStore) {
+				// the signup still goes on, the failure is logged
+				store.EXPECT().
+					CreateScreeningMatch(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ScreeningMatch{}, sql.ErrConnDone)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			created := user
+			created.FullName = tc.fullName
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().CreateUser(gomock.Any(), gomock.Any()).Times(1).Return(created, nil)
+			store.EXPECT().
+				ListScreeningEntries(gomock.Any(), db.ListScreeningEntriesParams{Username: user.Username, FullName: tc.fullName}).
+				AnyTimes().
+				Return(nil, nil)
+			store.EXPECT().CreateVerifyEmailToken(gomock.Any(), gomock.Any()).Times(1).Return(db.VerifyEmailToken{}, nil)
+			emailSender := mockmail.NewMockEmailSender(ctrl)
+			emailSender.EXPECT().SendEmail(gomock.Any(), gomock.Any(), gomock.Any()).Times(1).Return(nil)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			server.emailSender = emailSender
+			server.screener = newTestScreener(t)
+
+			data, err := json.Marshal(gin.H{
+				"username":  user.Username,
+				"password":  password,
+				"full_name": tc.fullName,
+				"email":     user.Email,
+			})
+			require.NoError(t, err)
+			request, err := http.NewRequest(http.MethodPost, "/user", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			require.Equal(t, http.StatusCreated, recorder.Code)
+		})
+	}
+}
+
+func TestUpdateCurrentUserScreening(t *testing.T) {
+	user, _ := randomUser(t)
+
+	testCases := []struct {
+		name       string
+		fullName   string
+		entries    []db.ListScreeningEntriesRow
+		buildStubs func(store *mockdb.MockStore)
+	}{
+		{
+			name:     "Renamed to a listed name",
+			fullName: sanctionedName,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateScreeningMatch(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.CreateScreeningMatchParams) (db.ScreeningMatch, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.Equal(t, []string{"900001"}, arg.EntryIds)
+						return randomScreeningMatch(user.Username), nil
+					})
+			},
+		},
+		{
+			name:     "Entry already reviewed",
+			fullName: sanctionedName,
+			entries:  []db.ListScreeningEntriesRow{{EntryID: "900001", Status: db.ScreeningMatchCleared}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateScreeningMatch(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:     "No match",
+			fullName: "Jane Roe",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateScreeningMatch(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			updated := user
+			updated.FullName = tc.fullName
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().UpdateUser(gomock.Any(), gomock.Any()).Times(1).Return(updated, nil)
+			store.EXPECT().
+				ListScreeningEntries(gomock.Any(), db.ListScreeningEntriesParams{Username: user.Username, FullName: tc.fullName}).
+				AnyTimes().
+				Return(tc.entries, nil)
+			tc.buildStubs(store)
+			stubAuthUser(store)
+
+			server := newTestServer(t, store)
+			server.screener = newTestScreener(t)
+
+			data, err := json.Marshal(gin.H{"full_name": tc.fullName})
+			require.NoError(t, err)
+			request, err := http.NewRequest(http.MethodPatch, "/user/me", bytes.NewReader(data))
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, user.Username)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			require.Equal(t, http.StatusOK, recorder.Code)
+		})
+	}
+}
+
+func TestCreateTransferSanctionsScreening(t *testing.T) {
+	fromAccount, toAccount := getAccounts()
+
+	testCases := []struct {
+		name          string
+		fullName      string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "Counterparty matches",
+			fullName: sanctionedName,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListScreeningEntries(gomock.Any(), db.ListScreeningEntriesParams{Username: toAccount.Owner, FullName: sanctionedName}).
+					Times(1).
+					Return(nil, nil)
+				// the counterparty had no match yet, compliance gets one to clear or confirm
+				store.EXPECT().
+					CreateScreeningMatch(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.CreateScreeningMatchParams) (db.ScreeningMatch, error) {
+						require.Equal(t, toAccount.Owner, arg.Username)
+						require.Equal(t, []string{"900001"}, arg.EntryIds)
+						return randomScreeningMatch(toAccount.Owner), nil
+					})
+				store.EXPECT().
+					CreateRiskReview(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.CreateRiskReviewParams) (db.RiskReview, error) {
+						require.Equal(t, string(risk.Hold), arg.Decision)
+						require.Len(t, arg.Reasons, 1)
+						require.Contains(t, arg.Reasons[0], "sanctions: counterparty "+toAccount.Owner+" matches 900001")
+						return randomRiskReview(fromAccount, toAccount), nil
+					})
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusAccepted, recorder.Code)
+			},
+		},
+		{
+			name:     "Match cleared",
+			fullName: sanctionedName,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListScreeningEntries(gomock.Any(), db.ListScreeningEntriesParams{Username: toAccount.Owner, FullName: sanctionedName}).
+					Times(1).
+					Return([]db.ListScreeningEntriesRow{{EntryID: "900001", Status: db.ScreeningMatchCleared}}, nil)
+				store.EXPECT().CreateScreeningMatch(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateRiskReview(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(*getOkTransferResult(fromAccount, toAccount), nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:     "No match",
+			fullName: "Jane Roe",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListScreeningEntries(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateScreeningMatch(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateRiskReview(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(*getOkTransferResult(fromAccount, toAccount), nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().GetAccount(gomock.Any(), fromAccount.ID).AnyTimes().Return(fromAccount, nil)
+			store.EXPECT().GetAccount(gomock.Any(), toAccount.ID).AnyTimes().Return(toAccount, nil)
+			store.EXPECT().
+				GetUserByUsername(gomock.Any(), toAccount.Owner).
+				AnyTimes().
+				Return(db.User{Username: toAccount.Owner, FullName: tc.fullName}, nil)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			server.riskEngine = risk.NewEngine()
+			server.riskEngine.Add(screening.NewRule(newTestScreener(t)), risk.Hold)
+
+			data, err := json.Marshal(gin.H{
+				"from_account_id": fromAccount.ID,
+				"to_account_id":   toAccount.ID,
+				"amount":          "5.00",
+				"currency":        util.USD,
+			})
+			require.NoError(t, err)
+			request, err := http.NewRequest(http.MethodPost, "/transfer", bytes.NewReader(data))
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, fromAccount.Owner)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestListScreeningMatchesAPI(t *testing.T) {
+	match := randomScreeningMatch(util.RandomOwner())
+
+	testCases := []struct {
+		name          string
+		query         string
+		username      string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "Pending by default",
+			query:    "?page_size=5",
+			username: "an_admin",
+			buildStubs: func(store *mockdb.MockStore) {
+				stubAdmin(store)
+				store.EXPECT().
+					ListScreeningMatches(gomock.Any(), db.ListScreeningMatchesParams{Status: db.ScreeningMatchPending, Limit: 5}).
+					Times(1).
+					Return([]db.ScreeningMatch{match}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var response []screeningMatchResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+				require.Len(t, response, 1)
+				require.Equal(t, match.Username, response[0].Username)
+				require.Equal(t, match.Matches, response[0].Matches)
+			},
+		},
+		{
+			name:     "Invalid status",
+			query:    "?page_size=5&status=unknown",
+			username: "an_admin",
+			buildStubs: func(store *mockdb.MockStore) {
+				stubAdmin(store)
+				store.EXPECT().ListScreeningMatches(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:     "Not an admin",
+			query:    "?page_size=5",
+			username: match.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListScreeningMatches(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			stubAuthUser(store)
+
+			server := newTestServer(t, store)
+			request, err := http.NewRequest(http.MethodGet, "/admin/screening-matches"+tc.query, nil)
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, tc.username)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestReviewScreeningMatchAPI(t *testing.T) {
+	match := randomScreeningMatch(util.RandomOwner())
+	cleared := match
+	cleared.Status = db.ScreeningMatchCleared
+	confirmed := match
+	confirmed.Status = db.ScreeningMatchConfirmed
+	reviewedBy := sql.NullString{String: "an_admin", Valid: true}
+
+	testCases := []struct {
+		name          string
+		action        string
+		username      string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "Clear",
+			action:   "clear",
+			username: "an_admin",
+			buildStubs: func(store *mockdb.MockStore) {
+				stubAdmin(store)
+				store.EXPECT().GetScreeningMatch(gomock.Any(), match.ID).Times(1).Return(match, nil)
+				store.EXPECT().
+					SettleScreeningMatch(gomock.Any(), db.SettleScreeningMatchParams{
+						ID:         match.ID,
+						Status:     db.ScreeningMatchCleared,
+						ReviewedBy: reviewedBy,
+					}).
+					Times(1).
+					Return(cleared, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var response screeningMatchResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+				require.Equal(t, db.ScreeningMatchCleared, response.Status)
+			},
+		},
+		{
+			name:     "Confirm",
+			action:   "confirm",
+			username: "an_admin",
+			buildStubs: func(store *mockdb.MockStore) {
+				stubAdmin(store)
+				store.EXPECT().GetScreeningMatch(gomock.Any(), match.ID).Times(1).Return(match, nil)
+				store.EXPECT().
+					SettleScreeningMatch(gomock.Any(), db.SettleScreeningMatchParams{
+						ID:         match.ID,
+						Status:     db.ScreeningMatchConfirmed,
+						ReviewedBy: reviewedBy,
+					}).
+					Times(1).
+					Return(confirmed, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:     "Already reviewed",
+			action:   "clear",
+			username: "an_admin",
+			buildStubs: func(store *mockdb.MockStore) {
+				stubAdmin(store)
+				store.EXPECT().GetScreeningMatch(gomock.Any(), match.ID).Times(1).Return(confirmed, nil)
+				store.EXPECT().
+					SettleScreeningMatch(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ScreeningMatch{}, sql.ErrNoRows)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name:     "Not found",
+			action:   "confirm",
+			username: "an_admin",
+			buildStubs: func(store *mockdb.MockStore) {
+				stubAdmin(store)
+				store.EXPECT().GetScreeningMatch(gomock.Any(), match.ID).Times(1).Return(db.ScreeningMatch{}, sql.ErrNoRows)
+				store.EXPECT().SettleScreeningMatch(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:     "Not an admin",
+			action:   "clear",
+			username: match.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().SettleScreeningMatch(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			stubAuthUser(store)
+
+			server := newTestServer(t, store)
+			url := fmt.Sprintf("/admin/screening-matches/%d/%s", match.ID, tc.action)
+			request, err := http.NewRequest(http.MethodPost, url, nil)
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, tc.username)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
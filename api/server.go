@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 
@@ -9,34 +10,95 @@ import (
 	"github.com/go-playground/validator/v10"
 
 	db "github.com/go_backend_misc/db/sqlc"
+	"github.com/go_backend_misc/fee"
+	"github.com/go_backend_misc/mail"
+	"github.com/go_backend_misc/ratelimit"
+	"github.com/go_backend_misc/risk"
+	"github.com/go_backend_misc/screening"
 	"github.com/go_backend_misc/token"
 	"github.com/go_backend_misc/util"
 )
 
 type Server struct {
-	config     util.Config
-	store      db.Store
-	tokenMaker token.TokenMaker
-	router     *gin.Engine
+	config         util.Config
+	store          db.Store
+	tokenMaker     token.TokenMaker
+	passwordHasher util.PasswordHasher
+	passwordPolicy *util.PasswordPolicy
+	emailSender    mail.EmailSender
+	rateLimitStore ratelimit.Store
+	rateLimits     rateLimits
+	feeSchedule    *fee.Schedule
+	riskEngine     *risk.Engine
+	screener       *screening.Screener
+	router         *gin.Engine
 }
 
 type ServerStatus struct {
 	Message string
 }
 
-func NewServer(config util.Config, store db.Store) (*Server, error) {
+func NewServer(config util.Config, store db.Store, emailSender mail.EmailSender) (*Server, error) {
 	tokenMaker, err := token.NewPasetoMaker(config.TokenSymmetricKey)
 	if err != nil {
 		return nil, fmt.Errorf("cannot create token maker: %w", err)
 	}
+	if len(config.MFAEncryptionKey) != 32 {
+		return nil, fmt.Errorf("invalid mfa encryption key size: must be 32 characters")
+	}
+	passwordHasher, err := util.NewPasswordHasher(config)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create password hasher: %w", err)
+	}
+	passwordPolicy, err := util.NewPasswordPolicy(config)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create password policy: %w", err)
+	}
+	rateLimitStore, err := newRateLimitStore(config, store)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create rate limit store: %w", err)
+	}
+	rateLimits, err := newRateLimits(config)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse rate limits: %w", err)
+	}
+	feeSchedule, err := fee.LoadSchedule(config.FeeScheduleFile)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load fee schedule: %w", err)
+	}
+	riskEngine, err := risk.LoadRules(config.RiskRulesFile)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load risk rules: %w", err)
+	}
+	screener, err := screening.NewScreener(config.SanctionsListFile, config.SanctionsMatchThreshold)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load sanctions list: %w", err)
+	}
+	if screener != nil {
+		// counterparties on the list hold the transfer for review, like the rules that hold
+		if riskEngine == nil {
+			riskEngine = risk.NewEngine()
+		}
+		riskEngine.Add(screening.NewRule(screener), risk.Hold)
+	}
 	server := &Server{
-		config:     config,
-		store:      store,
-		tokenMaker: tokenMaker,
+		config:         config,
+		store:          store,
+		tokenMaker:     tokenMaker,
+		passwordHasher: passwordHasher,
+		passwordPolicy: passwordPolicy,
+		emailSender:    emailSender,
+		rateLimitStore: rateLimitStore,
+		rateLimits:     rateLimits,
+		feeSchedule:    feeSchedule,
+		riskEngine:     riskEngine,
+		screener:       screener,
 	}
 
 	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
 		v.RegisterValidation("currency", validCurrency)
+		v.RegisterValidation("amount", validAmount)
+		v.RegisterValidation("scope", validScope)
 	}
 
 	server.setupRouter()
@@ -48,24 +110,126 @@ func (server *Server) setupRouter() {
 	router := gin.Default()
 
 	router.GET("/status", server.status)
-	router.POST("/user", server.createUser)
-	router.POST("/user/login", server.loginUser)
 
-	authRoutes := router.Group("/").Use(authMiddleware(server.tokenMaker))
-
-	authRoutes.POST("/account", server.createAccount)
-	authRoutes.GET("/account/:id", server.getAccount)
-	authRoutes.GET("/accounts/", server.listAccounts)
-
-	authRoutes.POST("/transfer", server.createTransfer)
+	publicRoutes := router.Group("/").Use(
+		rateLimitMiddleware(server.rateLimitStore, "public", server.rateLimits.public, ipRateLimitKey),
+	)
+	publicRoutes.POST("/user", server.createUser)
+	publicRoutes.GET("/user/verify_email", server.verifyEmail)
+	publicRoutes.GET("/currencies", server.listCurrencies)
+
+	// credential guessing gets a tighter limit, on top of the per-username login throttling
+	loginRoutes := router.Group("/").Use(
+		rateLimitMiddleware(server.rateLimitStore, "login", server.rateLimits.login, ipRateLimitKey),
+	)
+	loginRoutes.POST("/user/login", server.loginUser)
+	loginRoutes.POST("/user/login/mfa", server.loginUserMFA)
+	loginRoutes.POST("/user/password/reset", server.requestPasswordReset)
+	loginRoutes.POST("/user/password/reset/confirm", server.confirmPasswordReset)
+
+	authRoutes := router.Group("/").Use(
+		authMiddleware(server.tokenMaker, server.store),
+		rateLimitMiddleware(server.rateLimitStore, "user", server.rateLimits.authenticated, usernameRateLimitKey),
+	)
+
+	authRoutes.POST("/account", requireScope(scopeAccountsWrite), server.createAccount)
+	authRoutes.GET("/account/:id", requireScope(scopeAccountsRead), server.getAccount)
+	authRoutes.GET("/accounts/:id/balance", requireScope(scopeAccountsRead), server.getAccountBalance)
+	authRoutes.GET("/accounts/:id/limits", requireScope(scopeAccountsRead), server.getAccountLimits)
+	authRoutes.GET("/accounts/", requireScope(scopeAccountsRead), server.listAccounts)
+
+	authRoutes.POST(
+		"/transfer",
+		requireScope(scopeTransfers),
+		rateLimitMiddleware(server.rateLimitStore, "transfers", server.rateLimits.transfers, usernameRateLimitKey),
+		server.createTransfer,
+	)
+	authRoutes.POST(
+		"/transfers/batch",
+		requireScope(scopeTransfers),
+		rateLimitMiddleware(server.rateLimitStore, "transfers", server.rateLimits.transfers, usernameRateLimitKey),
+		server.createBatchTransfer,
+	)
+	authRoutes.POST(
+		"/transfers/:id/reverse",
+		requireScope(scopeTransfers),
+		rateLimitMiddleware(server.rateLimitStore, "transfers", server.rateLimits.transfers, usernameRateLimitKey),
+		server.reverseTransfer,
+	)
+	authRoutes.POST(
+		"/holds",
+		requireScope(scopeTransfers),
+		rateLimitMiddleware(server.rateLimitStore, "transfers", server.rateLimits.transfers, usernameRateLimitKey),
+		server.createHold,
+	)
+	authRoutes.GET("/holds/:id", requireScope(scopeTransfers), server.getHold)
+	authRoutes.POST(
+		"/holds/:id/capture",
+		requireScope(scopeTransfers),
+		rateLimitMiddleware(server.rateLimitStore, "transfers", server.rateLimits.transfers, usernameRateLimitKey),
+		server.captureHold,
+	)
+	authRoutes.POST("/holds/:id/void", requireScope(scopeTransfers), server.voidHold)
+	authRoutes.POST(
+		"/scheduled-transfers",
+		requireScope(scopeTransfers),
+		rateLimitMiddleware(server.rateLimitStore, "transfers", server.rateLimits.transfers, usernameRateLimitKey),
+		server.createScheduledTransfer,
+	)
+	authRoutes.GET("/scheduled-transfers", requireScope(scopeTransfers), server.listScheduledTransfers)
+	authRoutes.DELETE("/scheduled-transfers/:id", requireScope(scopeTransfers), server.cancelScheduledTransfer)
+	authRoutes.POST(
+		"/standing-orders",
+		requireScope(scopeTransfers),
+		rateLimitMiddleware(server.rateLimitStore, "transfers", server.rateLimits.transfers, usernameRateLimitKey),
+		server.createStandingOrder,
+	)
+	authRoutes.GET("/standing-orders", requireScope(scopeTransfers), server.listStandingOrders)
+	authRoutes.GET("/standing-orders/:id", requireScope(scopeTransfers), server.getStandingOrder)
+	authRoutes.GET("/standing-orders/:id/runs", requireScope(scopeTransfers), server.listStandingOrderRuns)
+	authRoutes.POST("/standing-orders/:id/pause", requireScope(scopeTransfers), server.pauseStandingOrder)
+	authRoutes.POST("/standing-orders/:id/resume", requireScope(scopeTransfers), server.resumeStandingOrder)
+	authRoutes.DELETE("/standing-orders/:id", requireScope(scopeTransfers), server.cancelStandingOrder)
+
+	authRoutes.GET("/admin/reconcile", requireScope(scopeSession), server.requireAdmin, server.reconcileLedger)
+	authRoutes.POST("/admin/reconcile", requireScope(scopeSession), server.requireAdmin, server.correctLedger)
+	authRoutes.GET("/admin/risk-reviews", requireScope(scopeSession), server.requireAdmin, server.listRiskReviews)
+	authRoutes.POST("/admin/risk-reviews/:id/approve", requireScope(scopeSession), server.requireAdmin, server.approveRiskReview)
+	authRoutes.POST("/admin/risk-reviews/:id/reject", requireScope(scopeSession), server.requireAdmin, server.rejectRiskReview)
+	authRoutes.GET("/admin/screening-matches", requireScope(scopeSession), server.requireAdmin, server.listScreeningMatches)
+	authRoutes.POST("/admin/screening-matches/:id/clear", requireScope(scopeSession), server.requireAdmin, server.clearScreeningMatch)
+	authRoutes.POST("/admin/screening-matches/:id/confirm", requireScope(scopeSession), server.requireAdmin, server.confirmScreeningMatch)
+
+	authRoutes.POST("/api_key", requireScope(scopeSession), server.createAPIKey)
+	authRoutes.GET("/api_keys/", requireScope(scopeSession), server.listAPIKeys)
+	authRoutes.DELETE("/api_key/:id", requireScope(scopeSession), server.revokeAPIKey)
+
+	authRoutes.POST("/user/mfa/enroll", requireScope(scopeSession), server.enrollMFA)
+	authRoutes.POST("/user/mfa/activate", requireScope(scopeSession), server.activateMFA)
+	authRoutes.GET("/user/me", requireScope(scopeSession), server.getCurrentUser)
+	authRoutes.PATCH("/user/me", requireScope(scopeSession), server.updateCurrentUser)
+	authRoutes.DELETE("/user/me", requireScope(scopeSession), server.eraseUser)
+	authRoutes.GET("/user/me/export", requireScope(scopeSession), server.exportUserData)
+	authRoutes.PUT("/user/me/email", requireScope(scopeSession), server.changeEmail)
+	authRoutes.PUT("/user/password", requireScope(scopeSession), server.changePassword)
+	authRoutes.POST("/user/verify_email/resend", requireScope(scopeSession), server.resendVerificationEmail)
 
 	server.router = router
 }
 
 func (server *Server) Start(address string) error {
+	if server.screener != nil && server.config.SanctionsReloadInterval > 0 {
+		go server.screener.Run(context.Background(), server.config.SanctionsReloadInterval)
+	}
 	return server.router.Run(address)
 }
 
+// RiskEngine is the engine screening the transfers of the server, sanctions included,
+// for the executors making the transfers scheduled through it
+func (server *Server) RiskEngine() *risk.Engine {
+	return server.riskEngine
+}
+
 func errorResponse(err error) gin.H {
 	return gin.H{"error": err.Error()}
 }
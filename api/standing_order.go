@@ -0,0 +1,365 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/go_backend_misc/db/sqlc"
+	"github.com/go_backend_misc/risk"
+	"github.com/go_backend_misc/token"
+	"github.com/go_backend_misc/util"
+)
+
+type createStandingOrderRequest struct {
+	FromAccountID int64  `json:"from_account_id" binding:"required,min=1"`
+	ToAccountID   int64  `json:"to_account_id" binding:"required,min=1"`
+	Amount        string `json:"amount" binding:"required,amount"`
+	Currency      string `json:"currency" binding:"required,currency"`
+	// RRule is an iCalendar recurrence rule like FREQ=MONTHLY;BYMONTHDAY=1;COUNT=12, see util.Recurrence
+	RRule    string    `json:"rrule" binding:"required"`
+	StartsAt time.Time `json:"starts_at" binding:"required"`
+	CatchUp  string    `json:"catch_up" binding:"omitempty,oneof=all latest"`
+	// TOTPCode is required for amounts above the configured step-up amount
+	TOTPCode string `json:"totp_code" binding:"omitempty,alphanum"`
+}
+
+type standingOrderResponse struct {
+	ID            int64     `json:"id"`
+	FromAccountID int64     `json:"from_account_id"`
+	ToAccountID   int64     `json:"to_account_id"`
+	Amount        string    `json:"amount"`
+	Currency      string    `json:"currency"`
+	RRule         string    `json:"rrule"`
+	StartsAt      time.Time `json:"starts_at"`
+	CatchUp       string    `json:"catch_up"`
+	Status        string    `json:"status"`
+	// NextRunAt is only set while the order is active
+	NextRunAt *time.Time `json:"next_run_at,omitempty"`
+	RunCount  int32      `json:"run_count"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func createStandingOrderResponse(order *db.StandingOrder) standingOrderResponse {
+	response := standingOrderResponse{
+		ID:            order.ID,
+		FromAccountID: order.FromAccountID,
+		ToAccountID:   order.ToAccountID,
+		Amount:        util.NewMoney(order.Amount, order.Currency).Decimal(),
+		Currency:      order.Currency,
+		RRule:         order.Rrule,
+		StartsAt:      order.StartsAt,
+		CatchUp:       order.CatchUp,
+		Status:        order.Status,
+		RunCount:      order.RunCount,
+		CreatedAt:     order.CreatedAt,
+	}
+	if order.Status == db.StandingOrderActive {
+		response.NextRunAt = &order.NextRunAt
+	}
+	return response
+}
+
+type standingOrderRunResponse struct {
+	ID           int64     `json:"id"`
+	ScheduledFor time.Time `json:"scheduled_for"`
+	Status       string    `json:"status"`
+	TransferID   *int64    `json:"transfer_id,omitempty"`
+	Error        string    `json:"error,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func createStandingOrderRunResponse(run *db.StandingOrderRun) standingOrderRunResponse {
+	response := standingOrderRunResponse{
+		ID:           run.ID,
+		ScheduledFor: run.ScheduledFor,
+		Status:       run.Status,
+		Error:        run.Error.String,
+		CreatedAt:    run.CreatedAt,
+	}
+	if run.TransferID.Valid {
+		response.TransferID = &run.TransferID.Int64
+	}
+	return response
+}
+
+// createStandingOrder runs the same checks as createScheduledTransfer,
+// the executor checks the accounts again before every run
+func (server *Server) createStandingOrder(ctx *gin.Context) {
+	var req createStandingOrderRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	amount, ok := parseTransferAmount(ctx, req.Amount, req.Currency)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	if !req.StartsAt.After(now) {
+		ctx.JSON(http.StatusBadRequest, errorMessageResponse("starts_at must be in the future"))
+		return
+	}
+	if req.StartsAt.After(now.Add(maxScheduleAhead)) {
+		ctx.JSON(http.StatusBadRequest, errorMessageResponse("starts_at must be within a year"))
+		return
+	}
+	if req.FromAccountID == req.ToAccountID {
+		ctx.JSON(http.StatusBadRequest, errorMessageResponse("cannot transfer to the same account"))
+		return
+	}
+
+	recurrence, err := util.ParseRecurrence(req.RRule, req.StartsAt)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	// the first run is the first occurrence at or after starts_at
+	firstRun := recurrence.Next(recurrence.Start.Add(-time.Second))
+	if firstRun.IsZero() {
+		ctx.JSON(http.StatusBadRequest, errorMessageResponse("rrule has no runs after starts_at"))
+		return
+	}
+	if req.CatchUp == "" {
+		req.CatchUp = db.StandingOrderCatchUpAll
+	}
+
+	fromAccount, isValid := server.validAccount(ctx, req.FromAccountID, req.Currency)
+	if !isValid {
+		return
+	}
+	if _, isValid := server.validDestination(ctx, req.ToAccountID, req.Currency); !isValid {
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if fromAccount.Owner != authPayload.Username {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errors.New("unauthorized user")))
+		return
+	}
+	if !server.requireVerifiedEmail(ctx, server.config.RequireVerifiedEmailForTransfers, authPayload.Username) {
+		return
+	}
+	if !server.requireMFAForAmount(ctx, authPayload.Username, amount, req.TOTPCode) {
+		return
+	}
+	if !server.screenTransferWithoutReview(ctx, &risk.Transfer{
+		Owner:         authPayload.Username,
+		FromAccountID: req.FromAccountID,
+		ToAccountID:   req.ToAccountID,
+		Amount:        amount,
+		Currency:      req.Currency,
+	}, server.feeSchedule.Calculate(req.Currency, amount).Total) {
+		return
+	}
+
+	order, err := server.store.CreateStandingOrder(ctx, db.CreateStandingOrderParams{
+		Owner:         authPayload.Username,
+		FromAccountID: req.FromAccountID,
+		ToAccountID:   req.ToAccountID,
+		Amount:        amount,
+		Currency:      req.Currency,
+		Rrule:         recurrence.String(),
+		StartsAt:      recurrence.Start,
+		CatchUp:       req.CatchUp,
+		NextRunAt:     firstRun,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, createStandingOrderResponse(&order))
+}
+
+type listStandingOrdersQueryParams struct {
+	Offset   int32 `form:"offset" binding:"min=0"`
+	PageSize int32 `form:"page_size" binding:"required,min=1,max=20"`
+}
+
+func (server *Server) listStandingOrders(ctx *gin.Context) {
+	var req listStandingOrdersQueryParams
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	orders, err := server.store.ListStandingOrders(ctx, db.ListStandingOrdersParams{
+		Owner:  authPayload.Username,
+		Limit:  req.PageSize,
+		Offset: req.Offset,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	response := make([]standingOrderResponse, 0, len(orders))
+	for i := range orders {
+		response = append(response, createStandingOrderResponse(&orders[i]))
+	}
+	ctx.JSON(http.StatusOK, response)
+}
+
+type standingOrderURIParams struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// ownStandingOrder loads the standing order in the uri and checks it belongs to the authenticated user
+func (server *Server) ownStandingOrder(ctx *gin.Context) (db.StandingOrder, bool) {
+	var req standingOrderURIParams
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return db.StandingOrder{}, false
+	}
+
+	order, err := server.store.GetStandingOrder(ctx, req.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return order, false
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return order, false
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if order.Owner != authPayload.Username {
+		err := errors.New("standing order doesn't belong to the authenticated user")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return order, false
+	}
+	return order, true
+}
+
+func (server *Server) getStandingOrder(ctx *gin.Context) {
+	order, ok := server.ownStandingOrder(ctx)
+	if !ok {
+		return
+	}
+	ctx.JSON(http.StatusOK, createStandingOrderResponse(&order))
+}
+
+// listStandingOrderRuns is the execution history of the order, most recent run first
+func (server *Server) listStandingOrderRuns(ctx *gin.Context) {
+	var req listStandingOrdersQueryParams
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	order, ok := server.ownStandingOrder(ctx)
+	if !ok {
+		return
+	}
+
+	runs, err := server.store.ListStandingOrderRuns(ctx, db.ListStandingOrderRunsParams{
+		StandingOrderID: order.ID,
+		Limit:           req.PageSize,
+		Offset:          req.Offset,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	response := make([]standingOrderRunResponse, 0, len(runs))
+	for i := range runs {
+		response = append(response, createStandingOrderRunResponse(&runs[i]))
+	}
+	ctx.JSON(http.StatusOK, response)
+}
+
+func (server *Server) pauseStandingOrder(ctx *gin.Context) {
+	var req standingOrderURIParams
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	order, err := server.store.PauseStandingOrder(ctx, db.PauseStandingOrderParams{
+		ID:    req.ID,
+		Owner: authPayload.Username,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorMessageResponse("standing order not found or not active"))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, createStandingOrderResponse(&order))
+}
+
+// resumeStandingOrder doesn't make the runs missed while the order was paused,
+// the order picks up at its first run after now
+func (server *Server) resumeStandingOrder(ctx *gin.Context) {
+	order, ok := server.ownStandingOrder(ctx)
+	if !ok {
+		return
+	}
+	if order.Status != db.StandingOrderPaused {
+		ctx.JSON(http.StatusConflict, errorMessageResponse("standing order is not paused"))
+		return
+	}
+
+	recurrence, err := util.ParseRecurrence(order.Rrule, order.StartsAt)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	nextRun := recurrence.Next(time.Now())
+	if nextRun.IsZero() || (recurrence.Count > 0 && int(order.RunCount) >= recurrence.Count) {
+		ctx.JSON(http.StatusConflict, errorMessageResponse("standing order has no runs left"))
+		return
+	}
+
+	order, err = server.store.ResumeStandingOrder(ctx, db.ResumeStandingOrderParams{
+		ID:        order.ID,
+		Owner:     order.Owner,
+		NextRunAt: nextRun,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			// canceled or resumed since it was loaded
+			ctx.JSON(http.StatusConflict, errorMessageResponse("standing order is not paused"))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, createStandingOrderResponse(&order))
+}
+
+// cancelStandingOrder keeps the order and its runs as history, it just won't run again
+func (server *Server) cancelStandingOrder(ctx *gin.Context) {
+	var req standingOrderURIParams
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	order, err := server.store.CancelStandingOrder(ctx, db.CancelStandingOrderParams{
+		ID:    req.ID,
+		Owner: authPayload.Username,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorMessageResponse("standing order not found or already over"))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, createStandingOrderResponse(&order))
+}
@@ -0,0 +1,382 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	mockdb "github.com/go_backend_misc/db/mock"
+	db "github.com/go_backend_misc/db/sqlc"
+	"github.com/go_backend_misc/util"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestCreateStandingOrderAPI(t *testing.T) {
+	fromAccount, toAccount := getAccounts()
+	startsAt := time.Now().Add(72 * time.Hour).UTC().Truncate(time.Second)
+	recurrence, err := util.ParseRecurrence("FREQ=WEEKLY;COUNT=4", startsAt)
+	require.NoError(t, err)
+
+	validBody := func() gin.H {
+		return gin.H{
+			"from_account_id": fromAccount.ID,
+			"to_account_id":   toAccount.ID,
+			"amount":          "1.00",
+			"currency":        fromAccount.Currency,
+			"rrule":           "freq=weekly;count=4",
+			"starts_at":       startsAt,
+		}
+	}
+
+	testCases := []struct {
+		name          string
+		body          func() gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: validBody,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), fromAccount.ID).Times(1).Return(fromAccount, nil)
+				store.EXPECT().GetAccount(gomock.Any(), toAccount.ID).Times(1).Return(toAccount, nil)
+
+				arg := db.CreateStandingOrderParams{
+					Owner:         fromAccount.Owner,
+					FromAccountID: fromAccount.ID,
+					ToAccountID:   toAccount.ID,
+					Amount:        100,
+					Currency:      fromAccount.Currency,
+					Rrule:         recurrence.String(),
+					StartsAt:      startsAt,
+					CatchUp:       db.StandingOrderCatchUpAll,
+					NextRunAt:     startsAt,
+				}
+				store.EXPECT().
+					CreateStandingOrder(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(db.StandingOrder{
+						ID:            1,
+						Owner:         arg.Owner,
+						FromAccountID: arg.FromAccountID,
+						ToAccountID:   arg.ToAccountID,
+						Amount:        arg.Amount,
+						Currency:      arg.Currency,
+						Rrule:         arg.Rrule,
+						StartsAt:      arg.StartsAt,
+						CatchUp:       arg.CatchUp,
+						Status:        db.StandingOrderActive,
+						NextRunAt:     arg.NextRunAt,
+					}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+
+				var response standingOrderResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+				require.Equal(t, recurrence.String(), response.RRule)
+				require.Equal(t, db.StandingOrderActive, response.Status)
+				require.True(t, startsAt.Equal(*response.NextRunAt))
+			},
+		},
+		{
+			name: "Invalid rrule",
+			body: func() gin.H {
+				body := validBody()
+				body["rrule"] = "FREQ=HOURLY"
+				return body
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateStandingOrder(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "No runs after starts at",
+			body: func() gin.H {
+				body := validBody()
+				body["rrule"] = "FREQ=DAILY;UNTIL=" + time.Now().Format("20060102")
+				return body
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateStandingOrder(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "Starts in the past",
+			body: func() gin.H {
+				body := validBody()
+				body["starts_at"] = time.Now().Add(-time.Hour)
+				return body
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateStandingOrder(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "Invalid catch up",
+			body: func() gin.H {
+				body := validBody()
+				body["catch_up"] = "some"
+				return body
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateStandingOrder(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			stubAuthUser(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body())
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/standing-orders", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, fromAccount.Owner)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestStandingOrderLifecycleAPI(t *testing.T) {
+	username := "test_owner"
+	startsAt := time.Now().Add(-30 * 24 * time.Hour).UTC().Truncate(time.Second)
+	order := db.StandingOrder{
+		ID:        1,
+		Owner:     username,
+		Amount:    100,
+		Currency:  "USD",
+		Rrule:     "FREQ=DAILY",
+		StartsAt:  startsAt,
+		CatchUp:   db.StandingOrderCatchUpAll,
+		Status:    db.StandingOrderPaused,
+		NextRunAt: startsAt,
+		RunCount:  3,
+	}
+
+	testCases := []struct {
+		name          string
+		method        string
+		url           string
+		username      string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "Pause",
+			method:   http.MethodPost,
+			url:      "/standing-orders/1/pause",
+			username: username,
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.PauseStandingOrderParams{ID: 1, Owner: username}
+				store.EXPECT().PauseStandingOrder(gomock.Any(), gomock.Eq(arg)).Times(1).Return(order, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var response standingOrderResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+				require.Equal(t, db.StandingOrderPaused, response.Status)
+				require.Nil(t, response.NextRunAt)
+			},
+		},
+		{
+			name:     "Pause not active",
+			method:   http.MethodPost,
+			url:      "/standing-orders/1/pause",
+			username: username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().PauseStandingOrder(gomock.Any(), gomock.Any()).Times(1).Return(db.StandingOrder{}, sql.ErrNoRows)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:     "Resume skips the runs missed while paused",
+			method:   http.MethodPost,
+			url:      "/standing-orders/1/resume",
+			username: username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetStandingOrder(gomock.Any(), int64(1)).Times(1).Return(order, nil)
+				store.EXPECT().
+					ResumeStandingOrder(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.ResumeStandingOrderParams) (db.StandingOrder, error) {
+						require.True(t, arg.NextRunAt.After(time.Now()))
+						require.True(t, arg.NextRunAt.Before(time.Now().Add(24*time.Hour)))
+						resumed := order
+						resumed.Status = db.StandingOrderActive
+						resumed.NextRunAt = arg.NextRunAt
+						return resumed, nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var response standingOrderResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+				require.Equal(t, db.StandingOrderActive, response.Status)
+				require.NotNil(t, response.NextRunAt)
+			},
+		},
+		{
+			name:     "Resume not paused",
+			method:   http.MethodPost,
+			url:      "/standing-orders/1/resume",
+			username: username,
+			buildStubs: func(store *mockdb.MockStore) {
+				active := order
+				active.Status = db.StandingOrderActive
+				store.EXPECT().GetStandingOrder(gomock.Any(), int64(1)).Times(1).Return(active, nil)
+				store.EXPECT().ResumeStandingOrder(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name:     "Resume with no runs left",
+			method:   http.MethodPost,
+			url:      "/standing-orders/1/resume",
+			username: username,
+			buildStubs: func(store *mockdb.MockStore) {
+				done := order
+				done.Rrule = "FREQ=DAILY;COUNT=3"
+				store.EXPECT().GetStandingOrder(gomock.Any(), int64(1)).Times(1).Return(done, nil)
+				store.EXPECT().ResumeStandingOrder(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name:     "Resume someone else's order",
+			method:   http.MethodPost,
+			url:      "/standing-orders/1/resume",
+			username: "someone_else",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetStandingOrder(gomock.Any(), int64(1)).Times(1).Return(order, nil)
+				store.EXPECT().ResumeStandingOrder(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:     "Cancel",
+			method:   http.MethodDelete,
+			url:      "/standing-orders/1",
+			username: username,
+			buildStubs: func(store *mockdb.MockStore) {
+				canceled := order
+				canceled.Status = db.StandingOrderCanceled
+				arg := db.CancelStandingOrderParams{ID: 1, Owner: username}
+				store.EXPECT().CancelStandingOrder(gomock.Any(), gomock.Eq(arg)).Times(1).Return(canceled, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:     "Get not found",
+			method:   http.MethodGet,
+			url:      "/standing-orders/2",
+			username: username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetStandingOrder(gomock.Any(), int64(2)).Times(1).Return(db.StandingOrder{}, sql.ErrNoRows)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			stubAuthUser(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(tc.method, tc.url, nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, tc.username)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestListStandingOrderRunsAPI(t *testing.T) {
+	username := "test_owner"
+	order := db.StandingOrder{ID: 1, Owner: username, Status: db.StandingOrderActive}
+	runs := []db.StandingOrderRun{
+		{ID: 2, StandingOrderID: 1, Status: db.StandingOrderRunSucceeded, TransferID: db.Int64ToSqlInt64(42)},
+		{ID: 1, StandingOrderID: 1, Status: db.StandingOrderRunFailed, Error: sql.NullString{String: "account 2 not found", Valid: true}},
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetStandingOrder(gomock.Any(), order.ID).Times(1).Return(order, nil)
+	arg := db.ListStandingOrderRunsParams{StandingOrderID: order.ID, Limit: 5, Offset: 0}
+	store.EXPECT().ListStandingOrderRuns(gomock.Any(), gomock.Eq(arg)).Times(1).Return(runs, nil)
+	stubAuthUser(store)
+
+	server := newTestServer(t, store)
+	recorder := httptest.NewRecorder()
+
+	url := fmt.Sprintf("/standing-orders/%d/runs?page_size=5", order.ID)
+	request, err := http.NewRequest(http.MethodGet, url, nil)
+	require.NoError(t, err)
+
+	addAuthorization(t, request, server.tokenMaker, username)
+	server.router.ServeHTTP(recorder, request)
+
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var response []standingOrderRunResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	require.Len(t, response, 2)
+	require.Equal(t, int64(42), *response[0].TransferID)
+	require.Nil(t, response[1].TransferID)
+	require.Equal(t, "account 2 not found", response[1].Error)
+}
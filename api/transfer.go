@@ -3,18 +3,126 @@ package api
 import (
 	"database/sql"
 	"errors"
+	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	db "github.com/go_backend_misc/db/sqlc"
+	"github.com/go_backend_misc/fee"
+	"github.com/go_backend_misc/risk"
 	"github.com/go_backend_misc/token"
+	"github.com/go_backend_misc/util"
 )
 
 type transferRequest struct {
 	FromAccountID int64  `json:"from_account_id" binding:"required,min=1"`
 	ToAccountID   int64  `json:"to_account_id" binding:"required,min=1"`
-	Amount        int64  `json:"amount" binding:"required,gt=0"`
+	Amount        string `json:"amount" binding:"required,amount"`
 	Currency      string `json:"currency" binding:"required,currency"`
+	// TOTPCode is required for amounts above the configured step-up amount
+	TOTPCode string `json:"totp_code" binding:"omitempty,alphanum"`
+}
+
+type transferResponse struct {
+	ID                 int64     `json:"id"`
+	FromAccountID      int64     `json:"from_account_id"`
+	ToAccountID        int64     `json:"to_account_id"`
+	Amount             string    `json:"amount"`
+	Fee                string    `json:"fee"`
+	Currency           string    `json:"currency"`
+	ReversesTransferID *int64    `json:"reverses_transfer_id,omitempty"`
+	CreatedAt          time.Time `json:"created_at"`
+}
+
+// createTransferResponse needs the currency, transfers are in the currency of their accounts
+func createTransferResponse(transfer *db.Transfer, currency string) transferResponse {
+	response := transferResponse{
+		ID:            transfer.ID,
+		FromAccountID: transfer.FromAccountID.Int64,
+		ToAccountID:   transfer.ToAccountID.Int64,
+		Amount:        util.NewMoney(transfer.Amount, currency).Decimal(),
+		Fee:           util.NewMoney(transfer.Fee, currency).Decimal(),
+		Currency:      currency,
+		CreatedAt:     transfer.CreatedAt,
+	}
+	if transfer.ReversesTransferID.Valid {
+		response.ReversesTransferID = &transfer.ReversesTransferID.Int64
+	}
+	return response
+}
+
+type entryResponse struct {
+	ID        int64     `json:"id"`
+	AccountID int64     `json:"account_id"`
+	Amount    string    `json:"amount"`
+	JournalID *int64    `json:"journal_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func createEntryResponse(entry *db.Entry, currency string) *entryResponse {
+	if entry == nil {
+		return nil
+	}
+	response := &entryResponse{
+		ID:        entry.ID,
+		AccountID: entry.AccountID.Int64,
+		Amount:    util.NewMoney(entry.Amount, currency).Decimal(),
+		CreatedAt: entry.CreatedAt,
+	}
+	if entry.JournalID.Valid {
+		response.JournalID = &entry.JournalID.Int64
+	}
+	return response
+}
+
+type transferTxResponse struct {
+	Transfer      transferResponse `json:"transfer"`
+	Journal       db.Journal       `json:"journal"`
+	FromAccount   accountResponse  `json:"from_account"`
+	ToAccount     accountResponse  `json:"to_account"`
+	FromEntry     *entryResponse   `json:"from_entry"`
+	ToEntry       *entryResponse   `json:"to_entry"`
+	FeeEntry      *entryResponse   `json:"fee_entry,omitempty"`
+	HouseFeeEntry *entryResponse   `json:"house_fee_entry,omitempty"`
+}
+
+func createTransferTxResponse(result *db.TransferTxResult) transferTxResponse {
+	currency := result.FromAccount.Currency
+	return transferTxResponse{
+		Transfer:      createTransferResponse(&result.Transfer, currency),
+		Journal:       result.Journal,
+		FromAccount:   createAccountResponse(&result.FromAccount),
+		ToAccount:     createAccountResponse(&result.ToAccount),
+		FromEntry:     createEntryResponse(&result.FromEntry, currency),
+		ToEntry:       createEntryResponse(&result.ToEntry, currency),
+		FeeEntry:      createEntryResponse(result.FeeEntry, currency),
+		HouseFeeEntry: createEntryResponse(result.HouseFeeEntry, currency),
+	}
+}
+
+type feeResponse struct {
+	Currency   string `json:"currency"`
+	Flat       string `json:"flat"`
+	Percentage string `json:"percentage"`
+	Adjustment string `json:"adjustment"`
+	Total      string `json:"total"`
+}
+
+func createFeeResponse(breakdown *fee.Breakdown) feeResponse {
+	return feeResponse{
+		Currency:   breakdown.Currency,
+		Flat:       util.NewMoney(breakdown.Flat, breakdown.Currency).Decimal(),
+		Percentage: util.NewMoney(breakdown.Percentage, breakdown.Currency).Decimal(),
+		Adjustment: util.NewMoney(breakdown.Adjustment, breakdown.Currency).Decimal(),
+		Total:      util.NewMoney(breakdown.Total, breakdown.Currency).Decimal(),
+	}
+}
+
+// transferWithFeeResponse is the response of createTransfer, with the breakdown of the fee
+type transferWithFeeResponse struct {
+	transferTxResponse
+	Fee feeResponse `json:"fee"`
 }
 
 func (server *Server) createTransfer(ginCtx *gin.Context) {
@@ -23,13 +131,17 @@ func (server *Server) createTransfer(ginCtx *gin.Context) {
 		ginCtx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	amount, ok := parseTransferAmount(ginCtx, req.Amount, req.Currency)
+	if !ok {
+		return
+	}
 
 	fromAccount, isValid := server.validAccount(ginCtx, req.FromAccountID, req.Currency)
 	if !isValid {
 		return
 	}
 
-	if _, isValid := server.validAccount(ginCtx, req.ToAccountID, req.Currency); !isValid {
+	if _, isValid := server.validDestination(ginCtx, req.ToAccountID, req.Currency); !isValid {
 		return
 	}
 
@@ -39,19 +151,52 @@ func (server *Server) createTransfer(ginCtx *gin.Context) {
 		return
 	}
 
+	if !server.requireVerifiedEmail(ginCtx, server.config.RequireVerifiedEmailForTransfers, authPayload.Username) {
+		return
+	}
+
+	if !server.requireMFAForAmount(ginCtx, authPayload.Username, amount, req.TOTPCode) {
+		return
+	}
+
+	// the fee is charged on top of the amount, the receiving account gets the whole amount
+	feeBreakdown := server.feeSchedule.Calculate(req.Currency, amount)
+
+	if !server.screenTransfer(ginCtx, &risk.Transfer{
+		Owner:         authPayload.Username,
+		FromAccountID: req.FromAccountID,
+		ToAccountID:   req.ToAccountID,
+		Amount:        amount,
+		Currency:      req.Currency,
+	}, feeBreakdown.Total) {
+		return
+	}
+
 	arg := db.CreateTransferParams{
 		FromAccountID: db.Int64ToSqlInt64(req.FromAccountID),
 		ToAccountID:   db.Int64ToSqlInt64(req.ToAccountID),
-		Amount:        req.Amount,
+		Amount:        amount,
+		Fee:           feeBreakdown.Total,
 	}
 
 	transferResult, err := server.store.TransferTx(ginCtx, arg)
 	if err != nil {
+		if errors.Is(err, db.ErrInsufficientFunds) || errors.Is(err, db.ErrTransferLimitExceeded) {
+			ginCtx.JSON(http.StatusConflict, errorResponse(err))
+			return
+		}
+		if errors.Is(err, db.ErrInvalidDestination) {
+			ginCtx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
 		ginCtx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
-	ginCtx.JSON(http.StatusOK, transferResult)
+	ginCtx.JSON(http.StatusOK, transferWithFeeResponse{
+		transferTxResponse: createTransferTxResponse(&transferResult),
+		Fee:                createFeeResponse(&feeBreakdown),
+	})
 
 }
 
@@ -74,3 +219,15 @@ func (server *Server) validAccount(ctx *gin.Context, accountID int64, currency s
 	return account, true
 
 }
+
+// validDestination checks the receiving account of a transfer like validAccount,
+// system accounts only receive from their own journals
+func (server *Server) validDestination(ctx *gin.Context, accountID int64, currency string) (account db.Account, isValid bool) {
+	account, isValid = server.validAccount(ctx, accountID, currency)
+	if isValid && account.Kind == db.AccountKindSystem {
+		err := fmt.Errorf("%w: account %d is a system account", db.ErrInvalidDestination, account.ID)
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return account, false
+	}
+	return account, isValid
+}
@@ -0,0 +1,312 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/go_backend_misc/db/sqlc"
+	"github.com/go_backend_misc/risk"
+	"github.com/go_backend_misc/token"
+	"github.com/go_backend_misc/util"
+)
+
+const (
+	defaultBatchTransferMaxItems = 500
+
+	batchModeAllOrNothing = "all_or_nothing"
+	batchModeBestEffort   = "best_effort"
+)
+
+// batchTransferCSVHeader is the header of an uploaded batch, one row per item like the JSON items
+var batchTransferCSVHeader = []string{"to_account_id", "amount"}
+
+type batchTransferItemRequest struct {
+	ToAccountID int64  `json:"to_account_id" binding:"required,min=1"`
+	Amount      string `json:"amount" binding:"required,amount"`
+}
+
+// batchTransferRequest is sent as JSON, or as a multipart form with the items in a CSV file
+type batchTransferRequest struct {
+	FromAccountID int64  `json:"from_account_id" form:"from_account_id" binding:"required,min=1"`
+	Currency      string `json:"currency" form:"currency" binding:"required,currency"`
+	// Mode is all_or_nothing by default, best_effort makes the items it can and reports the others
+	Mode string `json:"mode" form:"mode" binding:"omitempty,oneof=all_or_nothing best_effort"`
+	// TOTPCode is required when the total is above the configured step-up amount
+	TOTPCode string                     `json:"totp_code" form:"totp_code" binding:"omitempty,alphanum"`
+	Items    []batchTransferItemRequest `json:"items" form:"-" binding:"omitempty,dive"`
+}
+
+type batchTransferItemResponse struct {
+	Index    int               `json:"index"`
+	Transfer *transferResponse `json:"transfer,omitempty"`
+	Error    string            `json:"error,omitempty"`
+}
+
+type batchTransferResponse struct {
+	FromAccount accountResponse             `json:"from_account"`
+	Items       []batchTransferItemResponse `json:"items"`
+	Succeeded   int                         `json:"succeeded"`
+	Failed      int                         `json:"failed"`
+}
+
+func createBatchTransferResponse(result *db.BatchTransferTxResult, currency string) batchTransferResponse {
+	response := batchTransferResponse{
+		FromAccount: createAccountResponse(&result.FromAccount),
+		Items:       make([]batchTransferItemResponse, len(result.Items)),
+		Succeeded:   result.Succeeded,
+		Failed:      result.Failed,
+	}
+	for i, item := range result.Items {
+		response.Items[i] = batchTransferItemResponse{Index: item.Index, Error: item.Error}
+		if item.Transfer != nil {
+			transfer := createTransferResponse(item.Transfer, currency)
+			response.Items[i].Transfer = &transfer
+		}
+	}
+	return response
+}
+
+// createBatchTransfer makes many transfers from one account, payroll style, in a single database transaction
+// It runs the same checks as createTransfer, with the step-up on the total of the batch
+func (server *Server) createBatchTransfer(ctx *gin.Context) {
+	var req batchTransferRequest
+	if ctx.ContentType() == gin.MIMEMultipartPOSTForm {
+		if err := ctx.ShouldBind(&req); err != nil {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+		items, err := server.readBatchTransferCSV(ctx)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+		req.Items = items
+	} else if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if len(req.Items) == 0 {
+		ctx.JSON(http.StatusBadRequest, errorMessageResponse("the batch has no items"))
+		return
+	}
+	if len(req.Items) > server.batchTransferMaxItems() {
+		ctx.JSON(http.StatusBadRequest, errorMessageResponse(
+			fmt.Sprintf("a batch can't have more than %d items", server.batchTransferMaxItems())))
+		return
+	}
+
+	// each item is a transfer of its own for the limits of the currency
+	currency, _ := util.LookupCurrency(req.Currency)
+	var total int64
+	items := make([]db.BatchTransferItem, len(req.Items))
+	for i, item := range req.Items {
+		amount, err := util.ParseMoney(item.Amount, req.Currency)
+		if err == nil {
+			err = currency.CheckTransferAmount(amount.Amount)
+		}
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, errorResponse(fmt.Errorf("item %d: %w", i, err)))
+			return
+		}
+		if amount.Amount > math.MaxInt64-total {
+			ctx.JSON(http.StatusBadRequest, errorMessageResponse("the batch total is too large"))
+			return
+		}
+		total += amount.Amount
+		items[i] = db.BatchTransferItem{ToAccountID: item.ToAccountID, Amount: amount.Amount}
+	}
+
+	// receiving accounts are checked within the transaction, so best-effort batches can report them per item
+	fromAccount, isValid := server.validAccount(ctx, req.FromAccountID, req.Currency)
+	if !isValid {
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if fromAccount.Owner != authPayload.Username {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errors.New("unauthorized user")))
+		return
+	}
+	if !server.requireVerifiedEmail(ctx, server.config.RequireVerifiedEmailForTransfers, authPayload.Username) {
+		return
+	}
+	if !server.requireMFAForAmount(ctx, authPayload.Username, total, req.TOTPCode) {
+		return
+	}
+
+	allOrNothing := req.Mode != batchModeBestEffort
+	flagged, ok := server.screenBatchTransfer(ctx, &fromAccount, items, allOrNothing)
+	if !ok {
+		return
+	}
+	screenedItems := make([]db.BatchTransferItem, 0, len(items))
+	for i, item := range items {
+		if _, isFlagged := flagged[i]; !isFlagged {
+			screenedItems = append(screenedItems, item)
+		}
+	}
+
+	result, err := server.store.BatchTransferTx(ctx, db.BatchTransferTxParams{
+		FromAccountID: req.FromAccountID,
+		Items:         screenedItems,
+		AllOrNothing:  allOrNothing,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrInsufficientFunds), errors.Is(err, db.ErrTransferLimitExceeded):
+			ctx.JSON(http.StatusConflict, errorResponse(err))
+		case errors.Is(err, db.ErrInvalidBatchItem), errors.Is(err, db.ErrInvalidDestination):
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		default:
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	mergeFlaggedBatchItems(&result, flagged)
+	ctx.JSON(http.StatusOK, createBatchTransferResponse(&result, req.Currency))
+}
+
+// screenBatchTransfer runs the risk rules on every item, the earlier items count like transfers already made
+// Held and denied items of a best-effort batch are recorded like single transfers and returned with the reason
+// they fail, by index; an all-or-nothing batch can't be made in part, any of them refuses it with a 403
+// Items the transaction refuses on its own, to a missing, system or other currency account, aren't screened
+func (server *Server) screenBatchTransfer(ctx *gin.Context, fromAccount *db.Account, items []db.BatchTransferItem, allOrNothing bool) (map[int]string, bool) {
+	flagged := map[int]string{}
+	if server.riskEngine == nil {
+		return flagged, true
+	}
+
+	toAccounts := map[int64]*db.Account{}
+	var earlier []int64
+	for i, item := range items {
+		toAccount, cached := toAccounts[item.ToAccountID]
+		if !cached {
+			account, err := server.store.GetAccount(ctx, item.ToAccountID)
+			if err != nil && err != sql.ErrNoRows {
+				ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+				return nil, false
+			}
+			if err == nil {
+				toAccount = &account
+			}
+			toAccounts[item.ToAccountID] = toAccount
+		}
+		if toAccount == nil || toAccount.Kind == db.AccountKindSystem || toAccount.Currency != fromAccount.Currency ||
+			toAccount.ID == fromAccount.ID {
+			continue
+		}
+
+		decision, review, err := server.riskEngine.Review(ctx, server.store, &risk.Transfer{
+			Owner:         fromAccount.Owner,
+			FromAccountID: fromAccount.ID,
+			ToAccountID:   item.ToAccountID,
+			Amount:        item.Amount,
+			Currency:      fromAccount.Currency,
+			Earlier:       earlier,
+		}, server.feeSchedule.Calculate(fromAccount.Currency, item.Amount).Total, !allOrNothing)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return nil, false
+		}
+		switch {
+		case decision == risk.Allow:
+			earlier = append(earlier, item.Amount)
+		case allOrNothing:
+			ctx.JSON(http.StatusForbidden, errorResponse(fmt.Errorf("item %d: %w", i, errTransferDenied)))
+			return nil, false
+		case decision == risk.Deny:
+			flagged[i] = errTransferDenied.Error()
+		default:
+			flagged[i] = fmt.Sprintf("transfer held for risk review %d", review.ID)
+		}
+	}
+	return flagged, true
+}
+
+// mergeFlaggedBatchItems puts the items left out by the screening back in the result of the transaction,
+// at their index in the request
+func mergeFlaggedBatchItems(result *db.BatchTransferTxResult, flagged map[int]string) {
+	if len(flagged) == 0 {
+		return
+	}
+	items := make([]db.BatchTransferItemResult, len(result.Items)+len(flagged))
+	made := result.Items
+	for index := range items {
+		if reason, isFlagged := flagged[index]; isFlagged {
+			items[index] = db.BatchTransferItemResult{Index: index, Error: reason}
+			continue
+		}
+		items[index] = made[0]
+		items[index].Index = index
+		made = made[1:]
+	}
+	result.Items = items
+	result.Failed += len(flagged)
+}
+
+// readBatchTransferCSV reads the items from the file field, reading stops past the max items
+func (server *Server) readBatchTransferCSV(ctx *gin.Context) ([]batchTransferItemRequest, error) {
+	fileHeader, err := ctx.FormFile("file")
+	if err != nil {
+		return nil, err
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = len(batchTransferCSVHeader)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read the csv header: %w", err)
+	}
+	for i, column := range batchTransferCSVHeader {
+		if strings.TrimSpace(header[i]) != column {
+			return nil, fmt.Errorf("the csv header must be %s", strings.Join(batchTransferCSVHeader, ","))
+		}
+	}
+
+	var items []batchTransferItemRequest
+	for len(items) <= server.batchTransferMaxItems() {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		line, _ := reader.FieldPos(0)
+		toAccountID, err := strconv.ParseInt(record[0], 10, 64)
+		if err != nil || toAccountID < 1 {
+			return nil, fmt.Errorf("line %d: invalid to_account_id %q", line, record[0])
+		}
+		// the decimals are checked against the currency with the JSON items
+		if !util.IsPositiveDecimal(record[1]) {
+			return nil, fmt.Errorf("line %d: invalid amount %q", line, record[1])
+		}
+		items = append(items, batchTransferItemRequest{ToAccountID: toAccountID, Amount: record[1]})
+	}
+	return items, nil
+}
+
+func (server *Server) batchTransferMaxItems() int {
+	if server.config.BatchTransferMaxItems > 0 {
+		return server.config.BatchTransferMaxItems
+	}
+	return defaultBatchTransferMaxItems
+}
@@ -0,0 +1,316 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	mockdb "github.com/go_backend_misc/db/mock"
+	db "github.com/go_backend_misc/db/sqlc"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestCreateBatchTransferAPI(t *testing.T) {
+	fromAccount, toAccount := getAccounts()
+	items := []gin.H{
+		{"to_account_id": toAccount.ID, "amount": "1.00"},
+		{"to_account_id": 789, "amount": "0.50"},
+	}
+	result := db.BatchTransferTxResult{
+		FromAccount: fromAccount,
+		Items: []db.BatchTransferItemResult{
+			{Index: 0, Transfer: &db.Transfer{ID: 1, Amount: 100}},
+			{Index: 1, Error: "invalid batch item: account 789 not found"},
+		},
+		Succeeded: 1,
+		Failed:    1,
+	}
+
+	jsonBody := func(body gin.H) func() (*bytes.Buffer, string) {
+		return func() (*bytes.Buffer, string) {
+			var buffer bytes.Buffer
+			require.NoError(t, json.NewEncoder(&buffer).Encode(body))
+			return &buffer, gin.MIMEJSON
+		}
+	}
+	csvBody := func(csv string) func() (*bytes.Buffer, string) {
+		return func() (*bytes.Buffer, string) {
+			var buffer bytes.Buffer
+			writer := multipart.NewWriter(&buffer)
+			require.NoError(t, writer.WriteField("from_account_id", fmt.Sprint(fromAccount.ID)))
+			require.NoError(t, writer.WriteField("currency", fromAccount.Currency))
+			require.NoError(t, writer.WriteField("mode", batchModeBestEffort))
+			file, err := writer.CreateFormFile("file", "payroll.csv")
+			require.NoError(t, err)
+			_, err = file.Write([]byte(csv))
+			require.NoError(t, err)
+			require.NoError(t, writer.Close())
+			return &buffer, writer.FormDataContentType()
+		}
+	}
+
+	testCases := []struct {
+		name          string
+		username      string
+		body          func() (*bytes.Buffer, string)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "Best effort",
+			username: fromAccount.Owner,
+			body: jsonBody(gin.H{
+				"from_account_id": fromAccount.ID,
+				"currency":        fromAccount.Currency,
+				"mode":            batchModeBestEffort,
+				"items":           items,
+			}),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), fromAccount.ID).Times(1).Return(fromAccount, nil)
+				arg := db.BatchTransferTxParams{
+					FromAccountID: fromAccount.ID,
+					Items: []db.BatchTransferItem{
+						{ToAccountID: toAccount.ID, Amount: 100},
+						{ToAccountID: 789, Amount: 50},
+					},
+				}
+				store.EXPECT().BatchTransferTx(gomock.Any(), gomock.Eq(arg)).Times(1).Return(result, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var response batchTransferResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+				require.Len(t, response.Items, 2)
+				require.Equal(t, "1.00", response.Items[0].Transfer.Amount)
+				require.Equal(t, result.Items[1].Error, response.Items[1].Error)
+				require.Equal(t, 1, response.Failed)
+			},
+		},
+		{
+			name:     "All or nothing by default",
+			username: fromAccount.Owner,
+			body: jsonBody(gin.H{
+				"from_account_id": fromAccount.ID,
+				"currency":        fromAccount.Currency,
+				"items":           items,
+			}),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), fromAccount.ID).Times(1).Return(fromAccount, nil)
+				store.EXPECT().
+					BatchTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.BatchTransferTxParams) (db.BatchTransferTxResult, error) {
+						require.True(t, arg.AllOrNothing)
+						err := fmt.Errorf("%w: account 789 not found", db.ErrInvalidBatchItem)
+						return db.BatchTransferTxResult{}, &db.BatchItemError{Index: 1, Err: err}
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), "item 1")
+			},
+		},
+		{
+			name:     "Insufficient funds",
+			username: fromAccount.Owner,
+			body: jsonBody(gin.H{
+				"from_account_id": fromAccount.ID,
+				"currency":        fromAccount.Currency,
+				"items":           items,
+			}),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), fromAccount.ID).Times(1).Return(fromAccount, nil)
+				store.EXPECT().
+					BatchTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.BatchTransferTxResult{}, &db.BatchItemError{Index: 0, Err: db.ErrInsufficientFunds})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name:     "CSV upload",
+			username: fromAccount.Owner,
+			body:     csvBody("to_account_id,amount\n456,1.00\n789,0.50\n"),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), fromAccount.ID).Times(1).Return(fromAccount, nil)
+				arg := db.BatchTransferTxParams{
+					FromAccountID: fromAccount.ID,
+					Items: []db.BatchTransferItem{
+						{ToAccountID: toAccount.ID, Amount: 100},
+						{ToAccountID: 789, Amount: 50},
+					},
+				}
+				store.EXPECT().BatchTransferTx(gomock.Any(), gomock.Eq(arg)).Times(1).Return(result, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:     "CSV with a bad amount",
+			username: fromAccount.Owner,
+			body:     csvBody("to_account_id,amount\n456,1.00\n789,ten\n"),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().BatchTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), "line 3")
+			},
+		},
+		{
+			name:     "CSV without the header",
+			username: fromAccount.Owner,
+			body:     csvBody("456,1.00\n"),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().BatchTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:     "No items",
+			username: fromAccount.Owner,
+			body: jsonBody(gin.H{
+				"from_account_id": fromAccount.ID,
+				"currency":        fromAccount.Currency,
+			}),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().BatchTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:     "Invalid item",
+			username: fromAccount.Owner,
+			body: jsonBody(gin.H{
+				"from_account_id": fromAccount.ID,
+				"currency":        fromAccount.Currency,
+				"items":           []gin.H{{"to_account_id": toAccount.ID, "amount": "-0.05"}},
+			}),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().BatchTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:     "Too many decimals",
+			username: fromAccount.Owner,
+			body: jsonBody(gin.H{
+				"from_account_id": fromAccount.ID,
+				"currency":        fromAccount.Currency,
+				"items": []gin.H{
+					{"to_account_id": toAccount.ID, "amount": "1.00"},
+					{"to_account_id": 789, "amount": "1.005"},
+				},
+			}),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().BatchTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), "item 1")
+			},
+		},
+		{
+			name:     "Not the owner",
+			username: "someone_else",
+			body: jsonBody(gin.H{
+				"from_account_id": fromAccount.ID,
+				"currency":        fromAccount.Currency,
+				"items":           items,
+			}),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), fromAccount.ID).Times(1).Return(fromAccount, nil)
+				store.EXPECT().BatchTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:     "Total above the step-up amount without a code",
+			username: fromAccount.Owner,
+			body: jsonBody(gin.H{
+				"from_account_id": fromAccount.ID,
+				"currency":        fromAccount.Currency,
+				"items": []gin.H{
+					{"to_account_id": toAccount.ID, "amount": "6.00"},
+					{"to_account_id": 789, "amount": "6.00"},
+				},
+			}),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), fromAccount.ID).Times(1).Return(fromAccount, nil)
+				store.EXPECT().BatchTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			stubAuthUser(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			body, contentType := tc.body()
+			request, err := http.NewRequest(http.MethodPost, "/transfers/batch", body)
+			require.NoError(t, err)
+			request.Header.Set("Content-Type", contentType)
+
+			addAuthorization(t, request, server.tokenMaker, tc.username)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestBatchTransferMaxItems(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().BatchTransferTx(gomock.Any(), gomock.Any()).Times(0)
+	stubAuthUser(store)
+
+	server := newTestServer(t, store)
+	server.config.BatchTransferMaxItems = 2
+
+	items := []gin.H{
+		{"to_account_id": 1, "amount": "0.01"},
+		{"to_account_id": 2, "amount": "0.01"},
+		{"to_account_id": 3, "amount": "0.01"},
+	}
+	var body bytes.Buffer
+	require.NoError(t, json.NewEncoder(&body).Encode(gin.H{"from_account_id": 123, "currency": "USD", "items": items}))
+	request, err := http.NewRequest(http.MethodPost, "/transfers/batch", &body)
+	require.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	addAuthorization(t, request, server.tokenMaker, "test_owner")
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+	require.Contains(t, recorder.Body.String(), "more than 2 items")
+}
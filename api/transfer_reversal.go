@@ -0,0 +1,107 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/go_backend_misc/db/sqlc"
+	"github.com/go_backend_misc/token"
+)
+
+type transferURIParams struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+type reverseTransferRequest struct {
+	// Amount is the amount refunded, a decimal string in the currency of the transfer,
+	// whatever hasn't been refunded yet when omitted
+	Amount string `json:"amount" binding:"omitempty,amount"`
+	// TOTPCode is required for amounts above the configured step-up amount
+	TOTPCode string `json:"totp_code" binding:"omitempty,alphanum"`
+}
+
+// reverseTransfer refunds a transfer back to the sender, the money comes out of the receiving account,
+// so only its owner or an admin can reverse it
+func (server *Server) reverseTransfer(ctx *gin.Context) {
+	var uri transferURIParams
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	// the body is optional, an empty one refunds the whole transfer
+	var req reverseTransferRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	original, err := server.store.GetTransfer(ctx, uri.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	toAccount, err := server.store.GetAccount(ctx, original.ToAccountID.Int64)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if toAccount.Owner != authPayload.Username {
+		isAdmin, err := server.isAdmin(ctx, authPayload.Username)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		if !isAdmin {
+			err := errors.New("only the receiver or an admin can reverse a transfer")
+			ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+			return
+		}
+		log.Printf("security: admin %v reversing transfer %d of %v", authPayload.Username, original.ID, toAccount.Owner)
+	}
+
+	amount, ok := parseOptionalAmount(ctx, req.Amount, toAccount.Currency)
+	if !ok {
+		return
+	}
+
+	// without an amount the refund is at most the whole transfer
+	stepUpAmount := amount
+	if stepUpAmount == 0 {
+		stepUpAmount = original.Amount
+	}
+	if !server.requireMFAForAmount(ctx, authPayload.Username, stepUpAmount, req.TOTPCode) {
+		return
+	}
+
+	result, err := server.store.ReverseTransferTx(ctx, db.ReverseTransferTxParams{
+		TransferID: original.ID,
+		Amount:     amount,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrTransferNotReversible):
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		case errors.Is(err, db.ErrReversalExceedsTransfer), errors.Is(err, db.ErrInsufficientFunds):
+			ctx.JSON(http.StatusConflict, errorResponse(err))
+		default:
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, createTransferTxResponse(&result))
+}
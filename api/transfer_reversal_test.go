@@ -0,0 +1,203 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	mockdb "github.com/go_backend_misc/db/mock"
+	db "github.com/go_backend_misc/db/sqlc"
+	"github.com/go_backend_misc/util"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestReverseTransferAPI(t *testing.T) {
+	fromAccount, toAccount := getAccounts()
+	fromAccount.Owner = "sender"
+	toAccount.Owner = "receiver"
+	original := db.Transfer{
+		ID:            7,
+		FromAccountID: db.Int64ToSqlInt64(fromAccount.ID),
+		ToAccountID:   db.Int64ToSqlInt64(toAccount.ID),
+		Amount:        100,
+	}
+	reversal := db.TransferTxResult{
+		Transfer: db.Transfer{
+			ID:                 8,
+			FromAccountID:      original.ToAccountID,
+			ToAccountID:        original.FromAccountID,
+			Amount:             40,
+			ReversesTransferID: db.Int64ToSqlInt64(original.ID),
+		},
+		FromAccount: toAccount,
+		ToAccount:   fromAccount,
+	}
+
+	stubOriginal := func(store *mockdb.MockStore) {
+		store.EXPECT().GetTransfer(gomock.Any(), original.ID).Times(1).Return(original, nil)
+		store.EXPECT().GetAccount(gomock.Any(), toAccount.ID).Times(1).Return(toAccount, nil)
+	}
+
+	testCases := []struct {
+		name          string
+		username      string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "Partial refund by the receiver",
+			username: toAccount.Owner,
+			body:     gin.H{"amount": "0.40"},
+			buildStubs: func(store *mockdb.MockStore) {
+				stubOriginal(store)
+				arg := db.ReverseTransferTxParams{TransferID: original.ID, Amount: 40}
+				store.EXPECT().ReverseTransferTx(gomock.Any(), gomock.Eq(arg)).Times(1).Return(reversal, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var response transferTxResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+				require.Equal(t, reversal.Transfer.ID, response.Transfer.ID)
+				require.Equal(t, "0.40", response.Transfer.Amount)
+				require.Equal(t, original.ID, *response.Transfer.ReversesTransferID)
+			},
+		},
+		{
+			name:     "Whole refund without a body",
+			username: toAccount.Owner,
+			buildStubs: func(store *mockdb.MockStore) {
+				stubOriginal(store)
+				arg := db.ReverseTransferTxParams{TransferID: original.ID}
+				store.EXPECT().ReverseTransferTx(gomock.Any(), gomock.Eq(arg)).Times(1).Return(reversal, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:     "Admin",
+			username: "an_admin",
+			body:     gin.H{"amount": "0.40"},
+			buildStubs: func(store *mockdb.MockStore) {
+				stubOriginal(store)
+				store.EXPECT().
+					GetUserByUsername(gomock.Any(), "an_admin").
+					AnyTimes().
+					Return(db.User{Username: "an_admin", Role: util.RoleAdmin}, nil)
+				store.EXPECT().ReverseTransferTx(gomock.Any(), gomock.Any()).Times(1).Return(reversal, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:     "Sender can't reverse",
+			username: fromAccount.Owner,
+			body:     gin.H{"amount": "0.40"},
+			buildStubs: func(store *mockdb.MockStore) {
+				stubOriginal(store)
+				store.EXPECT().ReverseTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:     "Exceeds the transfer",
+			username: toAccount.Owner,
+			body:     gin.H{"amount": "0.80"},
+			buildStubs: func(store *mockdb.MockStore) {
+				stubOriginal(store)
+				store.EXPECT().
+					ReverseTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.TransferTxResult{}, fmt.Errorf("%w: 40 of 100 already reversed", db.ErrReversalExceedsTransfer))
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name:     "Reversal of a reversal",
+			username: toAccount.Owner,
+			buildStubs: func(store *mockdb.MockStore) {
+				stubOriginal(store)
+				store.EXPECT().
+					ReverseTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.TransferTxResult{}, db.ErrTransferNotReversible)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:     "Above the step-up amount without a code",
+			username: toAccount.Owner,
+			body:     gin.H{"amount": "50.00"},
+			buildStubs: func(store *mockdb.MockStore) {
+				stubOriginal(store)
+				store.EXPECT().ReverseTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:     "Invalid amount",
+			username: toAccount.Owner,
+			body:     gin.H{"amount": "-0.01"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:     "Transfer not found",
+			username: toAccount.Owner,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfer(gomock.Any(), original.ID).Times(1).Return(db.Transfer{}, sql.ErrNoRows)
+				store.EXPECT().ReverseTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			stubAuthUser(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			var body bytes.Buffer
+			if tc.body != nil {
+				require.NoError(t, json.NewEncoder(&body).Encode(tc.body))
+			}
+			url := fmt.Sprintf("/transfers/%d/reverse", original.ID)
+			request, err := http.NewRequest(http.MethodPost, url, &body)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, tc.username)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
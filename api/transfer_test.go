@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"database/sql"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -12,7 +13,9 @@ import (
 	"github.com/gin-gonic/gin"
 	mockdb "github.com/go_backend_misc/db/mock"
 	db "github.com/go_backend_misc/db/sqlc"
+	"github.com/go_backend_misc/fee"
 	"github.com/go_backend_misc/token"
+	"github.com/go_backend_misc/util"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
 )
@@ -31,7 +34,7 @@ func TestCreateTransfer(t *testing.T) {
 		body: gin.H{
 			"from_account_id": 123,
 			"to_account_id":   456,
-			"amount":          100,
+			"amount":          "1.00",
 		},
 		buildStubs: func(store *mockdb.MockStore) {},
 		setupAuth: func(request *http.Request, tokenMaker token.TokenMaker) {
@@ -51,7 +54,7 @@ func TestCreateTransfer(t *testing.T) {
 		body: gin.H{
 			"from_account_id": 123,
 			"to_account_id":   456,
-			"amount":          100,
+			"amount":          "1.00",
 			"currency":        "USD",
 		},
 		setupAuth: func(request *http.Request, tokenMaker token.TokenMaker) {
@@ -77,7 +80,7 @@ func TestCreateTransfer(t *testing.T) {
 		body: gin.H{
 			"from_account_id": 123,
 			"to_account_id":   456,
-			"amount":          100,
+			"amount":          "1.00",
 			"currency":        "USD",
 		},
 		setupAuth: func(request *http.Request, tokenMaker token.TokenMaker) {
@@ -103,7 +106,7 @@ func TestCreateTransfer(t *testing.T) {
 		body: gin.H{
 			"from_account_id": 123,
 			"to_account_id":   456,
-			"amount":          100,
+			"amount":          "1.00",
 			"currency":        "USD",
 		},
 		setupAuth: func(request *http.Request, tokenMaker token.TokenMaker) {
@@ -136,7 +139,7 @@ func TestCreateTransfer(t *testing.T) {
 		body: gin.H{
 			"from_account_id": 123,
 			"to_account_id":   456,
-			"amount":          100,
+			"amount":          "1.00",
 			"currency":        "USD",
 		},
 		setupAuth: func(request *http.Request, tokenMaker token.TokenMaker) {
@@ -171,10 +174,13 @@ func TestCreateTransfer(t *testing.T) {
 		},
 		checkResponse: func(recorder *httptest.ResponseRecorder) {
 			require.Equal(t, http.StatusOK, recorder.Code)
-			var content db.TransferTxResult
-			json.Unmarshal(recorder.Body.Bytes(), &content)
+			var content transferWithFeeResponse
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &content))
 			fromAccount, toAccount := getAccounts()
-			require.Equal(t, content, *getOkTransferResult(fromAccount, toAccount))
+			require.Equal(t, createTransferTxResponse(getOkTransferResult(fromAccount, toAccount)), content.transferTxResponse)
+			require.Equal(t, "1.00", content.Transfer.Amount)
+			require.Equal(t, "-1.00", content.FromEntry.Amount)
+			require.Equal(t, "3.00", content.FromAccount.Balance)
 		},
 	}
 
@@ -183,7 +189,7 @@ func TestCreateTransfer(t *testing.T) {
 		body: gin.H{
 			"from_account_id": 123,
 			"to_account_id":   456,
-			"amount":          100,
+			"amount":          "1.00",
 			"currency":        "USD",
 		},
 		setupAuth: func(request *http.Request, tokenMaker token.TokenMaker) {
@@ -216,9 +222,87 @@ func TestCreateTransfer(t *testing.T) {
 		},
 	}
 
+	insufficientFunds := transferTestCase{
+		name: "Insufficient funds",
+		body: gin.H{
+			"from_account_id": 123,
+			"to_account_id":   456,
+			"amount":          "1.00",
+			"currency":        "USD",
+		},
+		setupAuth: func(request *http.Request, tokenMaker token.TokenMaker) {
+			fromAccount, _ := getAccounts()
+			addAuthorization(t, request, tokenMaker, fromAccount.Owner)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			fromAccount, toAccount := getAccounts()
+			store.EXPECT().GetAccount(gomock.Any(), fromAccount.ID).Times(1).Return(fromAccount, nil)
+			store.EXPECT().GetAccount(gomock.Any(), toAccount.ID).Times(1).Return(toAccount, nil)
+			store.EXPECT().
+				TransferTx(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(db.TransferTxResult{}, db.ErrInsufficientFunds)
+		},
+		checkResponse: func(recorder *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusConflict, recorder.Code)
+		},
+	}
+
+	systemToAccount := transferTestCase{
+		name: "System ToAccount",
+		body: gin.H{
+			"from_account_id": 123,
+			"to_account_id":   456,
+			"amount":          "1.00",
+			"currency":        "USD",
+		},
+		setupAuth: func(request *http.Request, tokenMaker token.TokenMaker) {
+			fromAccount, _ := getAccounts()
+			addAuthorization(t, request, tokenMaker, fromAccount.Owner)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			fromAccount, toAccount := getAccounts()
+			toAccount.Owner = "_system"
+			toAccount.Kind = db.AccountKindSystem
+			store.EXPECT().GetAccount(gomock.Any(), fromAccount.ID).Times(1).Return(fromAccount, nil)
+			store.EXPECT().GetAccount(gomock.Any(), toAccount.ID).Times(1).Return(toAccount, nil)
+			store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+		},
+		checkResponse: func(recorder *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusBadRequest, recorder.Code)
+		},
+	}
+
+	erasedToAccount := transferTestCase{
+		name: "Erased ToAccount",
+		body: gin.H{
+			"from_account_id": 123,
+			"to_account_id":   456,
+			"amount":          "1.00",
+			"currency":        "USD",
+		},
+		setupAuth: func(request *http.Request, tokenMaker token.TokenMaker) {
+			fromAccount, _ := getAccounts()
+			addAuthorization(t, request, tokenMaker, fromAccount.Owner)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			fromAccount, toAccount := getAccounts()
+			store.EXPECT().GetAccount(gomock.Any(), fromAccount.ID).Times(1).Return(fromAccount, nil)
+			store.EXPECT().GetAccount(gomock.Any(), toAccount.ID).Times(1).Return(toAccount, nil)
+			store.EXPECT().
+				TransferTx(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(db.TransferTxResult{}, db.ErrInvalidDestination)
+		},
+		checkResponse: func(recorder *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusBadRequest, recorder.Code)
+		},
+	}
+
 	testCases := []transferTestCase{
 		invalidBody, noFromAccount, sqlError,
 		currencyMismatch, okCase, noToAccount,
+		insufficientFunds, systemToAccount, erasedToAccount,
 	}
 
 	for _, testCase := range testCases {
@@ -227,6 +311,7 @@ func TestCreateTransfer(t *testing.T) {
 
 		store := mockdb.NewMockStore(ctrl)
 		testCase.buildStubs(store)
+		stubAuthUser(store)
 
 		server := newTestServer(t, store)
 		recorder := httptest.NewRecorder()
@@ -243,6 +328,151 @@ func TestCreateTransfer(t *testing.T) {
 	}
 }
 
+func TestCreateTransferFee(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	fromAccount, toAccount := getAccounts()
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetAccount(gomock.Any(), fromAccount.ID).Times(1).Return(fromAccount, nil)
+	store.EXPECT().GetAccount(gomock.Any(), toAccount.ID).Times(1).Return(toAccount, nil)
+	arg := db.CreateTransferParams{
+		FromAccountID: db.Int64ToSqlInt64(fromAccount.ID),
+		ToAccountID:   db.Int64ToSqlInt64(toAccount.ID),
+		Amount:        100,
+		Fee:           12,
+	}
+	store.EXPECT().
+		TransferTx(gomock.Any(), gomock.Eq(arg)).
+		Times(1).
+		Return(*getOkTransferResult(fromAccount, toAccount), nil)
+	stubAuthUser(store)
+
+	server := newTestServer(t, store)
+	schedule, err := fee.ParseSchedule([]byte("currencies:\n  USD:\n    flat: 2\n    percent: 1\n    min: 12\n"))
+	require.NoError(t, err)
+	server.feeSchedule = schedule
+
+	data, err := json.Marshal(gin.H{
+		"from_account_id": fromAccount.ID,
+		"to_account_id":   toAccount.ID,
+		"amount":          "1.00",
+		"currency":        "USD",
+	})
+	require.NoError(t, err)
+	request, err := http.NewRequest(http.MethodPost, "/transfer", bytes.NewReader(data))
+	require.NoError(t, err)
+	addAuthorization(t, request, server.tokenMaker, fromAccount.Owner)
+
+	recorder := httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var response transferWithFeeResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	expected := feeResponse{Currency: "USD", Flat: "0.02", Percentage: "0.01", Adjustment: "0.09", Total: "0.12"}
+	require.Equal(t, expected, response.Fee)
+}
+
+func TestCreateTransferDecimalAmount(t *testing.T) {
+	testCases := []struct {
+		name           string
+		amount         any
+		currency       string
+		expectedAmount int64
+		expectedError  string
+	}{
+		{name: "Cents", amount: "1.23", currency: util.USD, expectedAmount: 123},
+		{name: "No minor unit", amount: "120", currency: util.JPY, expectedAmount: 120},
+		{name: "Three decimals", amount: "0.105", currency: util.KWD, expectedAmount: 105},
+		{name: "Too many decimals", amount: "1.005", currency: util.USD, expectedError: "too many decimals"},
+		{name: "Decimals without minor unit", amount: "12.5", currency: util.JPY, expectedError: "too many decimals"},
+		{name: "Negative", amount: "-1.00", currency: util.USD, expectedError: "'amount' tag"},
+		{name: "Zero", amount: "0.00", currency: util.USD, expectedError: "'amount' tag"},
+		{name: "Number", amount: 100, currency: util.USD, expectedError: "cannot unmarshal number"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			fromAccount, toAccount := getAccounts()
+			fromAccount.Currency, toAccount.Currency = tc.currency, tc.currency
+			store := mockdb.NewMockStore(ctrl)
+			if tc.expectedError == "" {
+				store.EXPECT().GetAccount(gomock.Any(), fromAccount.ID).Times(1).Return(fromAccount, nil)
+				store.EXPECT().GetAccount(gomock.Any(), toAccount.ID).Times(1).Return(toAccount, nil)
+				arg := db.CreateTransferParams{
+					FromAccountID: db.Int64ToSqlInt64(fromAccount.ID),
+					ToAccountID:   db.Int64ToSqlInt64(toAccount.ID),
+					Amount:        tc.expectedAmount,
+				}
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(*getOkTransferResult(fromAccount, toAccount), nil)
+			} else {
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			}
+			stubAuthUser(store)
+
+			server := newTestServer(t, store)
+			data, err := json.Marshal(gin.H{
+				"from_account_id": fromAccount.ID,
+				"to_account_id":   toAccount.ID,
+				"amount":          tc.amount,
+				"currency":        tc.currency,
+			})
+			require.NoError(t, err)
+			request, err := http.NewRequest(http.MethodPost, "/transfer", bytes.NewReader(data))
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, fromAccount.Owner)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			if tc.expectedError == "" {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				return
+			}
+			require.Equal(t, http.StatusBadRequest, recorder.Code)
+			require.Contains(t, recorder.Body.String(), tc.expectedError)
+		})
+	}
+}
+
+func TestCreateTransferLimitExceeded(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	fromAccount, toAccount := getAccounts()
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetAccount(gomock.Any(), fromAccount.ID).Times(1).Return(fromAccount, nil)
+	store.EXPECT().GetAccount(gomock.Any(), toAccount.ID).Times(1).Return(toAccount, nil)
+	store.EXPECT().
+		TransferTx(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(db.TransferTxResult{}, fmt.Errorf("%w: daily limit of 5.00 USD, 0.50 USD left", db.ErrTransferLimitExceeded))
+	stubAuthUser(store)
+
+	server := newTestServer(t, store)
+	data, err := json.Marshal(gin.H{
+		"from_account_id": fromAccount.ID,
+		"to_account_id":   toAccount.ID,
+		"amount":          "1.00",
+		"currency":        "USD",
+	})
+	require.NoError(t, err)
+	request, err := http.NewRequest(http.MethodPost, "/transfer", bytes.NewReader(data))
+	require.NoError(t, err)
+	addAuthorization(t, request, server.tokenMaker, fromAccount.Owner)
+
+	recorder := httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusConflict, recorder.Code)
+	require.Contains(t, recorder.Body.String(), "daily limit")
+}
+
 func getOkTransferResult(fromAccount db.Account, toAccount db.Account) *db.TransferTxResult {
 	transferResult := db.TransferTxResult{
 		Transfer: db.Transfer{
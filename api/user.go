@@ -2,6 +2,7 @@ package api
 
 import (
 	"database/sql"
+	"log"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -12,22 +13,24 @@ import (
 
 type createUserRequest struct {
 	Username string `json:"username" binding:"required,alphanum"`
-	Password string `json:"password" binding:"required,min=6"`
+	Password string `json:"password" binding:"required"`
 	FullName string `json:"full_name" binding:"required"`
 	Email    string `json:"email" binding:"required,email"`
 }
 
 type userResponse struct {
-	Username string `json:"username" binding:"required,alphanum"`
-	FullName string `json:"full_name" binding:"required"`
-	Email    string `json:"email" binding:"required,email"`
+	Username        string `json:"username" binding:"required,alphanum"`
+	FullName        string `json:"full_name" binding:"required"`
+	Email           string `json:"email" binding:"required,email"`
+	IsEmailVerified bool   `json:"is_email_verified"`
 }
 
 func createUserResponseFromUser(user *db.User) userResponse {
 	return userResponse{
-		Username: user.Username,
-		FullName: user.FullName,
-		Email:    user.Email,
+		Username:        user.Username,
+		FullName:        user.FullName,
+		Email:           user.Email,
+		IsEmailVerified: user.IsEmailVerified,
 	}
 }
 
@@ -38,10 +41,15 @@ func (server *Server) createUser(ginCtx *gin.Context) {
 		return
 	}
 
-	hashedPassword, err := util.HashPassword(req.Password)
+	if !server.checkPasswordPolicy(ginCtx, req.Password, req.Username, req.Email) {
+		return
+	}
+
+	hashedPassword, err := server.passwordHasher.HashPassword(req.Password)
 	if err != nil {
 		msg := "Error while processing password"
 		ginCtx.JSON(http.StatusInternalServerError, errorMessageResponse(msg))
+		return
 	}
 	arg := db.CreateUserParams{
 		Username:       req.Username,
@@ -81,6 +89,12 @@ func (server *Server) createUser(ginCtx *gin.Context) {
 		ginCtx.JSON(http.StatusInternalServerError, errorMessageResponse(msg))
 		return
 	}
+	// the user exists by now, a failed screening is logged for compliance rather than failing the signup
+	if err := server.screenUser(ginCtx, &user); err != nil {
+		log.Printf("security: cannot screen user %v: %v", user.Username, err)
+	}
+	logEmailError(server.sendVerificationEmail(ginCtx, &user), user.Username)
+
 	userResponse := createUserResponseFromUser(&user)
 	ginCtx.JSON(http.StatusCreated, userResponse)
 }
@@ -102,22 +116,74 @@ func (server *Server) loginUser(ctx *gin.Context) {
 		return
 	}
 
+	usernameKey := LoginThrottleUsernameKey(request.Username)
+	ipKey := LoginThrottleIPKey(ctx.ClientIP())
+	if !server.checkLoginThrottle(ctx, usernameKey, ipKey) {
+		return
+	}
+
 	user, err := server.store.GetUserByUsername(ctx, request.Username)
 	if err != nil {
 		if err == sql.ErrNoRows {
+			server.recordLoginFailure(ctx, usernameKey, ipKey)
 			ctx.JSON(http.StatusNotFound, errorMessageResponse("invalid credentials"))
 			return
 		}
 		// unexpected DB error
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
 	}
 
 	err = util.CheckPassword(request.Password, user.HashedPassword)
 	if err != nil {
+		server.recordLoginFailure(ctx, usernameKey, ipKey)
 		ctx.JSON(http.StatusUnauthorized, errorMessageResponse("invalid credentials"))
 		return
 	}
+	// a successful login only clears the username, the IP may still be guessing other accounts
+	server.resetLoginThrottle(ctx, usernameKey)
+	server.rehashPasswordIfNeeded(ctx, &user, request.Password)
+
+	userMFA, err := server.store.GetUserMFA(ctx, user.Username)
+	if err != nil && err != sql.ErrNoRows {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if err == nil && userMFA.Enabled {
+		// the access token is only issued by loginUserMFA
+		server.createMFAChallenge(ctx, user.Username)
+		return
+	}
+
+	server.issueAccessToken(ctx, &user)
+}
+
+// rehashPasswordIfNeeded upgrades hashes made with an outdated algorithm or cost
+// It's only possible at login, when the plain password is known. Failures are logged, the login goes on
+func (server *Server) rehashPasswordIfNeeded(ctx *gin.Context, user *db.User, password string) {
+	if !server.passwordHasher.NeedsRehash(user.HashedPassword) {
+		return
+	}
+
+	hashedPassword, err := server.passwordHasher.HashPassword(password)
+	if err != nil {
+		log.Printf("cannot rehash password username=%v: %v", user.Username, err)
+		return
+	}
+
+	_, err = server.store.RehashUserPassword(ctx, db.RehashUserPasswordParams{
+		Username:          user.Username,
+		OldHashedPassword: user.HashedPassword,
+		NewHashedPassword: hashedPassword,
+	})
+	if err != nil {
+		log.Printf("cannot rehash password username=%v: %v", user.Username, err)
+		return
+	}
+	user.HashedPassword = hashedPassword
+}
 
+func (server *Server) issueAccessToken(ctx *gin.Context, user *db.User) {
 	accessToken, err := server.tokenMaker.CreateToken(user.Username, server.config.AccessTokenDuration)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
@@ -126,7 +192,7 @@ func (server *Server) loginUser(ctx *gin.Context) {
 
 	response := loginUserResponse{
 		AccessToken: accessToken,
-		User:        createUserResponseFromUser(&user),
+		User:        createUserResponseFromUser(user),
 	}
 	ctx.JSON(http.StatusOK, response)
 }
@@ -0,0 +1,201 @@
+package api
+
+import (
+	"archive/zip"
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/go_backend_misc/db/sqlc"
+	"github.com/go_backend_misc/token"
+	"github.com/go_backend_misc/util"
+)
+
+const erasedEmailBytes = 16
+
+type userExportProfile struct {
+	Username          string    `json:"username"`
+	FullName          string    `json:"full_name"`
+	Email             string    `json:"email"`
+	IsEmailVerified   bool      `json:"is_email_verified"`
+	PasswordChangedAt time.Time `json:"password_changed_at"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+// exportUserData sends a ZIP with everything stored about the user:
+// the profile as JSON and the accounts, entries and transfers as CSV
+func (server *Server) exportUserData(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	user, err := server.store.GetUserByUsername(ctx, authPayload.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	accounts, err := server.store.ListAllAccountsByUsername(ctx, user.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	entries, err := server.store.ListEntriesByUsername(ctx, user.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	transfers, err := server.store.ListTransfersByUsername(ctx, user.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	archive, err := buildUserExport(&user, accounts, entries, transfers)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	log.Printf("security: data exported username=%v", user.Username)
+	fileName := fmt.Sprintf("%s-export-%s.zip", user.Username, time.Now().UTC().Format("20060102"))
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+	ctx.Data(http.StatusOK, "application/zip", archive)
+}
+
+func buildUserExport(user *db.User, accounts []db.Account, entries []db.Entry, transfers []db.Transfer) ([]byte, error) {
+	var buffer bytes.Buffer
+	archive := zip.NewWriter(&buffer)
+
+	profileFile, err := archive.Create("profile.json")
+	if err != nil {
+		return nil, err
+	}
+	encoder := json.NewEncoder(profileFile)
+	encoder.SetIndent("", "  ")
+	err = encoder.Encode(userExportProfile{
+		Username:          user.Username,
+		FullName:          user.FullName,
+		Email:             user.Email,
+		IsEmailVerified:   user.IsEmailVerified,
+		PasswordChangedAt: user.PasswordChangedAt,
+		CreatedAt:         user.CreatedAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	accountRows := [][]string{{"id", "owner", "balance", "currency", "created_at"}}
+	for _, account := range accounts {
+		accountRows = append(accountRows, []string{
+			strconv.FormatInt(account.ID, 10),
+			account.Owner,
+			strconv.FormatInt(account.Balance, 10),
+			account.Currency,
+			account.CreatedAt.UTC().Format(time.RFC3339),
+		})
+	}
+
+	entryRows := [][]string{{"id", "account_id", "amount", "created_at"}}
+	for _, entry := range entries {
+		entryRows = append(entryRows, []string{
+			strconv.FormatInt(entry.ID, 10),
+			formatNullInt64(entry.AccountID),
+			strconv.FormatInt(entry.Amount, 10),
+			entry.CreatedAt.UTC().Format(time.RFC3339),
+		})
+	}
+
+	transferRows := [][]string{{"id", "from_account_id", "to_account_id", "amount", "created_at"}}
+	for _, transfer := range transfers {
+		transferRows = append(transferRows, []string{
+			strconv.FormatInt(transfer.ID, 10),
+			formatNullInt64(transfer.FromAccountID),
+			formatNullInt64(transfer.ToAccountID),
+			strconv.FormatInt(transfer.Amount, 10),
+			transfer.CreatedAt.UTC().Format(time.RFC3339),
+		})
+	}
+
+	for name, rows := range map[string][][]string{
+		"accounts.csv":  accountRows,
+		"entries.csv":   entryRows,
+		"transfers.csv": transferRows,
+	} {
+		file, err := archive.Create(name)
+		if err != nil {
+			return nil, err
+		}
+		if err := csv.NewWriter(file).WriteAll(rows); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func formatNullInt64(value sql.NullInt64) string {
+	if !value.Valid {
+		return ""
+	}
+	return strconv.FormatInt(value.Int64, 10)
+}
+
+type eraseUserRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+// eraseUser replaces the user's personal data and drops their credentials
+// Accounts, entries and transfers are kept for accounting, so every balance must be zero first
+func (server *Server) eraseUser(ctx *gin.Context) {
+	var req eraseUserRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	user, err := server.store.GetUserByUsername(ctx, authPayload.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if err := util.CheckPassword(req.Password, user.HashedPassword); err != nil {
+		ctx.JSON(http.StatusUnauthorized, errorMessageResponse("invalid credentials"))
+		return
+	}
+
+	// the email must stay unique, so a random address that can't receive mail takes its place
+	emailID, err := util.RandomSecret(erasedEmailBytes)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	user, err = server.store.EraseUserTx(ctx, db.PseudonymizeUserParams{
+		Username:          user.Username,
+		Email:             fmt.Sprintf("erased-%s@invalid", emailID),
+		PasswordChangedAt: passwordChangedAt(),
+	})
+	if err != nil {
+		switch err {
+		case db.ErrNonZeroBalance:
+			ctx.JSON(http.StatusConflict, errorResponse(err))
+		case sql.ErrNoRows:
+			ctx.JSON(http.StatusNotFound, errorMessageResponse("user is already erased"))
+		default:
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	log.Printf("security: user erased username=%v", user.Username)
+	ctx.JSON(http.StatusOK, createUserResponseFromUser(&user))
+}
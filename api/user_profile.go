@@ -0,0 +1,112 @@
+package api
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/go_backend_misc/db/sqlc"
+	"github.com/go_backend_misc/token"
+	"github.com/go_backend_misc/util"
+	"github.com/lib/pq"
+)
+
+func (server *Server) getCurrentUser(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	user, err := server.store.GetUserByUsername(ctx, authPayload.Username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, createUserResponseFromUser(&user))
+}
+
+type updateCurrentUserRequest struct {
+	FullName *string `json:"full_name" binding:"omitempty,min=1,max=255"`
+}
+
+func (server *Server) updateCurrentUser(ctx *gin.Context) {
+	var req updateCurrentUserRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if req.FullName == nil {
+		ctx.JSON(http.StatusBadRequest, errorMessageResponse("nothing to update"))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	user, err := server.store.UpdateUser(ctx, db.UpdateUserParams{
+		Username: authPayload.Username,
+		FullName: sql.NullString{String: *req.FullName, Valid: true},
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	// a listed person could sign up under a clean name and take theirs afterwards
+	if err := server.screenUser(ctx, &user); err != nil {
+		log.Printf("security: cannot screen user %v: %v", user.Username, err)
+	}
+
+	ctx.JSON(http.StatusOK, createUserResponseFromUser(&user))
+}
+
+type changeEmailRequest struct {
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required"`
+}
+
+// changeEmail asks for the password again, since the email is enough to reset it
+// The new address is unverified until the user follows the link sent to it
+func (server *Server) changeEmail(ctx *gin.Context) {
+	var req changeEmailRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	user, err := server.store.GetUserByUsername(ctx, authPayload.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if err := util.CheckPassword(req.Password, user.HashedPassword); err != nil {
+		ctx.JSON(http.StatusUnauthorized, errorMessageResponse("invalid credentials"))
+		return
+	}
+	if req.Email == user.Email {
+		ctx.JSON(http.StatusBadRequest, errorMessageResponse("email address is unchanged"))
+		return
+	}
+
+	user, err = server.store.UpdateUserEmail(ctx, db.UpdateUserEmailParams{
+		Username: user.Username,
+		Email:    req.Email,
+	})
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code.Name() == "unique_violation" {
+			ctx.JSON(http.StatusConflict, errorMessageResponse("Email already exists"))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	log.Printf("security: email changed username=%v", user.Username)
+	logEmailError(server.sendVerificationEmail(ctx, &user), user.Username)
+	ctx.JSON(http.StatusOK, createUserResponseFromUser(&user))
+}
@@ -4,19 +4,23 @@ import (
 	"bytes"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	mockdb "github.com/go_backend_misc/db/mock"
 	db "github.com/go_backend_misc/db/sqlc"
+	mockmail "github.com/go_backend_misc/mail/mock"
 	"github.com/go_backend_misc/util"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
+	"golang.org/x/crypto/bcrypt"
 )
 
 // implement a custom Matcher for correctly checking the password match
@@ -54,7 +58,7 @@ func TestCreateUserAPI(t *testing.T) {
 	type testCase struct {
 		name          string
 		body          gin.H
-		buildStubs    func(store *mockdb.MockStore)
+		buildStubs    func(store *mockdb.MockStore, emailSender *mockmail.MockEmailSender)
 		checkResponse func(recorder *httptest.ResponseRecorder)
 	}
 
@@ -67,7 +71,7 @@ func TestCreateUserAPI(t *testing.T) {
 				"full_name": user.FullName,
 				"email":     user.Email,
 			},
-			buildStubs: func(store *mockdb.MockStore) {
+			buildStubs: func(store *mockdb.MockStore, emailSender *mockmail.MockEmailSender) {
 				arg := db.CreateUserParams{
 					Username: user.Username,
 					FullName: user.FullName,
@@ -77,6 +81,18 @@ func TestCreateUserAPI(t *testing.T) {
 					CreateUser(gomock.Any(), EqCreateUserParams(arg, password)).
 					Times(1).
 					Return(user, nil)
+				store.EXPECT().
+					CreateVerifyEmailToken(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.CreateVerifyEmailTokenParams) (db.VerifyEmailToken, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.Equal(t, user.EmailIndex.String, arg.EmailIndex)
+						return db.VerifyEmailToken{}, nil
+					})
+				emailSender.EXPECT().
+					SendEmail(gomock.Any(), gomock.Any(), gomock.Eq([]string{user.Email})).
+					Times(1).
+					Return(nil)
 			},
 			checkResponse: func(recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusCreated, recorder.Code)
@@ -91,7 +107,7 @@ func TestCreateUserAPI(t *testing.T) {
 				"full_name": user.FullName,
 				"email":     user.Email,
 			},
-			buildStubs: func(store *mockdb.MockStore) {
+			buildStubs: func(store *mockdb.MockStore, emailSender *mockmail.MockEmailSender) {
 				store.EXPECT().
 					CreateUser(gomock.Any(), gomock.Any()).
 					Times(1).
@@ -109,7 +125,7 @@ func TestCreateUserAPI(t *testing.T) {
 				"full_name": user.FullName,
 				"email":     user.Email,
 			},
-			buildStubs: func(store *mockdb.MockStore) {
+			buildStubs: func(store *mockdb.MockStore, emailSender *mockmail.MockEmailSender) {
 				store.EXPECT().
 					CreateUser(gomock.Any(), gomock.Any()).
 					Times(0)
@@ -126,13 +142,58 @@ func TestCreateUserAPI(t *testing.T) {
 				"full_name": user.FullName,
 				"email":     "invalid_email",
 			},
-			buildStubs: func(store *mockdb.MockStore) {
+			buildStubs: func(store *mockdb.MockStore, emailSender *mockmail.MockEmailSender) {
+				store.EXPECT().
+					CreateUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "Password Violates Policy",
+			body: gin.H{
+				"username":  user.Username,
+				"password":  "abc",
+				"full_name": user.FullName,
+				"email":     user.Email,
+			},
+			buildStubs: func(store *mockdb.MockStore, emailSender *mockmail.MockEmailSender) {
 				store.EXPECT().
 					CreateUser(gomock.Any(), gomock.Any()).
 					Times(0)
 			},
 			checkResponse: func(recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requirePasswordViolation(t, recorder, util.PasswordTooShort)
+			},
+		},
+		{
+			name: "Email delivery error",
+			body: gin.H{
+				"username":  user.Username,
+				"password":  password,
+				"full_name": user.FullName,
+				"email":     user.Email,
+			},
+			buildStubs: func(store *mockdb.MockStore, emailSender *mockmail.MockEmailSender) {
+				store.EXPECT().
+					CreateUser(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					CreateVerifyEmailToken(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.VerifyEmailToken{}, nil)
+				emailSender.EXPECT().
+					SendEmail(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(errors.New("smtp is down"))
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				// the user can ask for a new verification email later
+				require.Equal(t, http.StatusCreated, recorder.Code)
 			},
 		},
 	}
@@ -143,9 +204,11 @@ func TestCreateUserAPI(t *testing.T) {
 			defer ctrl.Finish()
 
 			store := mockdb.NewMockStore(ctrl)
-			testCase.buildStubs(store)
+			emailSender := mockmail.NewMockEmailSender(ctrl)
+			testCase.buildStubs(store, emailSender)
 
 			server := newTestServer(t, store)
+			server.emailSender = emailSender
 			recorder := httptest.NewRecorder()
 
 			data, err := json.Marshal(testCase.body)
@@ -161,8 +224,201 @@ func TestCreateUserAPI(t *testing.T) {
 	}
 }
 
+func TestLoginUserAPI(t *testing.T) {
+	user, password := randomUser(t)
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore, server *Server)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK without MFA",
+			body: gin.H{"username": user.Username, "password": password},
+			buildStubs: func(store *mockdb.MockStore, server *Server) {
+				stubNoLoginThrottle(store)
+				store.EXPECT().
+					GetUserByUsername(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					DeleteLoginThrottle(gomock.Any(), gomock.Eq(LoginThrottleUsernameKey(user.Username))).
+					Times(1)
+				store.EXPECT().
+					GetUserMFA(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(db.UserMfa{}, sql.ErrNoRows)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var response loginUserResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+				require.NotEmpty(t, response.AccessToken)
+			},
+		},
+		{
+			name: "MFA challenge",
+			body: gin.H{"username": user.Username, "password": password},
+			buildStubs: func(store *mockdb.MockStore, server *Server) {
+				userMFA, _ := randomUserMFA(t, server, user.Username)
+				stubNoLoginThrottle(store)
+				store.EXPECT().
+					GetUserByUsername(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					DeleteLoginThrottle(gomock.Any(), gomock.Any()).
+					Times(1)
+				store.EXPECT().
+					GetUserMFA(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(userMFA, nil)
+				store.EXPECT().
+					CreateMFAChallenge(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.CreateMFAChallengeParams) (db.MfaChallenge, error) {
+						return db.MfaChallenge{ID: arg.ID, Username: arg.Username, ExpiresAt: arg.ExpiresAt}, nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var response map[string]any
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+				require.Equal(t, true, response["mfa_required"])
+				require.NotEmpty(t, response["mfa_token"])
+				require.Empty(t, response["token"])
+			},
+		},
+		{
+			name: "Wrong password",
+			body: gin.H{"username": user.Username, "password": password + "x"},
+			buildStubs: func(store *mockdb.MockStore, server *Server) {
+				stubNoLoginThrottle(store)
+				store.EXPECT().
+					GetUserByUsername(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					RecordLoginFailure(gomock.Any(), gomock.Any()).
+					Times(2).
+					Return(db.LoginThrottle{FailedCount: 1}, nil)
+				store.EXPECT().
+					GetUserMFA(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "Internal Error",
+			body: gin.H{"username": user.Username, "password": password},
+			buildStubs: func(store *mockdb.MockStore, server *Server) {
+				stubNoLoginThrottle(store)
+				store.EXPECT().
+					GetUserByUsername(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(db.User{}, sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			name: "Outdated hash is upgraded",
+			body: gin.H{"username": user.Username, "password": password},
+			buildStubs: func(store *mockdb.MockStore, server *Server) {
+				bcryptHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+				require.NoError(t, err)
+				bcryptUser := user
+				bcryptUser.HashedPassword = string(bcryptHash)
+
+				stubNoLoginThrottle(store)
+				store.EXPECT().
+					GetUserByUsername(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(bcryptUser, nil)
+				store.EXPECT().
+					DeleteLoginThrottle(gomock.Any(), gomock.Any()).
+					Times(1)
+				store.EXPECT().
+					RehashUserPassword(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.RehashUserPasswordParams) (int64, error) {
+						require.Equal(t, bcryptUser.HashedPassword, arg.OldHashedPassword)
+						require.NoError(t, util.CheckPassword(password, arg.NewHashedPassword))
+						require.False(t, server.passwordHasher.NeedsRehash(arg.NewHashedPassword))
+						return 1, nil
+					})
+				store.EXPECT().
+					GetUserMFA(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(db.UserMfa{}, sql.ErrNoRows)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "Locked out",
+			body: gin.H{"username": user.Username, "password": password},
+			buildStubs: func(store *mockdb.MockStore, server *Server) {
+				store.EXPECT().
+					GetLoginThrottle(gomock.Any(), gomock.Eq(LoginThrottleUsernameKey(user.Username))).
+					Times(1).
+					Return(db.LoginThrottle{
+						FailedCount:  server.config.LoginMaxFailedAttempts,
+						LastFailedAt: time.Now(),
+					}, nil)
+				store.EXPECT().
+					GetLoginThrottle(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.LoginThrottle{}, sql.ErrNoRows)
+				store.EXPECT().
+					GetUserByUsername(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+				require.NotEmpty(t, recorder.Header().Get("Retry-After"))
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			server := newTestServer(t, store)
+			tc.buildStubs(store, server)
+
+			recorder := httptest.NewRecorder()
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/user/login", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func stubNoLoginThrottle(store *mockdb.MockStore) {
+	store.EXPECT().
+		GetLoginThrottle(gomock.Any(), gomock.Any()).
+		Times(2).
+		Return(db.LoginThrottle{}, sql.ErrNoRows)
+}
+
 func randomUser(t *testing.T) (user db.User, password string) {
-	password = util.RandomString(6)
+	password = util.RandomString(10)
 	hashedPassword, err := util.HashPassword(password)
 	require.NoError(t, err)
 	username := util.RandomOwner()
@@ -171,6 +427,7 @@ func randomUser(t *testing.T) (user db.User, password string) {
 		HashedPassword: hashedPassword,
 		FullName:       util.RandomOwner(),
 		Email:          util.RandomEmail(username),
+		EmailIndex:     sql.NullString{String: util.RandomString(64), Valid: true},
 	}
 	return
 }
@@ -12,3 +12,22 @@ var validCurrency validator.Func = func(fieldLevel validator.FieldLevel) bool {
 	}
 	return false
 }
+
+// validAmount takes decimal strings above zero, their decimals are checked against the currency when parsed
+var validAmount validator.Func = func(fieldLevel validator.FieldLevel) bool {
+	if amount, ok := fieldLevel.Field().Interface().(string); ok {
+		return util.IsPositiveDecimal(amount)
+	}
+	return false
+}
+
+var validScope validator.Func = func(fieldLevel validator.FieldLevel) bool {
+	if scope, ok := fieldLevel.Field().Interface().(string); ok {
+		for _, grantableScope := range grantableScopes {
+			if scope == grantableScope {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/go_backend_misc/db/sqlc"
+	"github.com/go_backend_misc/token"
+	"github.com/go_backend_misc/util"
+)
+
+const verifyEmailTokenBytes = 32
+
+var errEmailNotVerified = errors.New("email address must be verified first")
+
+// sendVerificationEmail stores a one-time token for the user's current email and mails it
+func (server *Server) sendVerificationEmail(ctx *gin.Context, user *db.User) error {
+	verifyToken, err := util.RandomSecret(verifyEmailTokenBytes)
+	if err != nil {
+		return err
+	}
+
+	_, err = server.store.CreateVerifyEmailToken(ctx, db.CreateVerifyEmailTokenParams{
+		HashedToken: util.HashSecret(verifyToken),
+		Username:    user.Username,
+		EmailIndex:  user.EmailIndex.String,
+		ExpiresAt:   time.Now().Add(server.config.VerifyEmailTokenDuration),
+	})
+	if err != nil {
+		return err
+	}
+
+	subject := "Verify your Simple Bank email address"
+	content := fmt.Sprintf(
+		"Hello %s,\n\nConfirm your email address by opening:\n\n/user/verify_email?token=%s\n\n"+
+			"The link expires in %v.\n",
+		user.FullName, verifyToken, server.config.VerifyEmailTokenDuration,
+	)
+	return server.emailSender.SendEmail(subject, content, []string{user.Email})
+}
+
+type verifyEmailQueryParams struct {
+	Token string `form:"token" binding:"required,hexadecimal"`
+}
+
+func (server *Server) verifyEmail(ctx *gin.Context) {
+	var req verifyEmailQueryParams
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	user, err := server.store.VerifyEmailTx(ctx, util.HashSecret(req.Token))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusBadRequest, errorMessageResponse("invalid or expired verification token"))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, createUserResponseFromUser(&user))
+}
+
+func (server *Server) resendVerificationEmail(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	user, err := server.store.GetUserByUsername(ctx, authPayload.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if user.IsEmailVerified {
+		ctx.JSON(http.StatusConflict, errorMessageResponse("email address is already verified"))
+		return
+	}
+
+	if err := server.sendVerificationEmail(ctx, &user); err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorMessageResponse("cannot send verification email"))
+		return
+	}
+	ctx.JSON(http.StatusAccepted, gin.H{"message": "verification email sent"})
+}
+
+// requireVerifiedEmail writes a 403 response and returns false when the policy is enabled
+// and the user's email isn't verified yet
+func (server *Server) requireVerifiedEmail(ctx *gin.Context, required bool, username string) bool {
+	if !required {
+		return true
+	}
+
+	user, err := server.store.GetUserByUsername(ctx, username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return false
+	}
+	if !user.IsEmailVerified {
+		ctx.JSON(http.StatusForbidden, errorResponse(errEmailNotVerified))
+		return false
+	}
+	return true
+}
+
+// logEmailError keeps the request successful when only the email delivery failed,
+// users can ask for a new email later
+func logEmailError(err error, username string) {
+	if err != nil {
+		log.Printf("cannot send email to username=%v: %v", username, err)
+	}
+}
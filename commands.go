@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/go_backend_misc/api"
+	db "github.com/go_backend_misc/db/sqlc"
+	"github.com/go_backend_misc/util"
+)
+
+const usage = `usage: go run . <command> [arguments]
+
+commands:
+  unlock [-ip] <username|address>    clear the failed login and MFA attempts of a user, or the failed logins of a client IP
+  encrypt-users [-batch n]           encrypt the emails and names stored before encryption was enabled
+  set-role <username> <role>         make a user an admin or a customer again
+  set-kyc-tier <username> <tier>     set how far the identity of a user was verified, it picks their default transfer limits
+  set-limits [-per-transaction a] [-daily a] [-monthly a] <account id>
+                                     override the transfer limits of an account, a missing limit keeps the default
+  reconcile [-correct]               print a JSON report of what doesn't add up in the ledger`
+
+func runCommand(config util.Config, store db.Store, args []string) error {
+	switch args[0] {
+	case "unlock":
+		return unlockCommand(store, args[1:])
+	case "encrypt-users":
+		return encryptUsersCommand(store, args[1:])
+	case "set-role":
+		return setRoleCommand(store, args[1:])
+	case "set-kyc-tier":
+		return setKYCTierCommand(store, args[1:])
+	case "set-limits":
+		return setLimitsCommand(store, args[1:])
+	case "reconcile":
+		return reconcileCommand(store, args[1:])
+	default:
+		return fmt.Errorf("unknown command %q\n%s", args[0], usage)
+	}
+}
+
+// unlockCommand lifts a login lockout before it expires, for a user the lockout of their second factor too
+func unlockCommand(store db.Store, args []string) error {
+	flags := flag.NewFlagSet("unlock", flag.ContinueOnError)
+	isIP := flags.Bool("ip", false, "unlock a client IP address instead of a username")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return fmt.Errorf("unlock expects exactly one username or address\n%s", usage)
+	}
+
+	keys := []string{api.LoginThrottleUsernameKey(flags.Arg(0)), api.MFAThrottleKey(flags.Arg(0))}
+	if *isIP {
+		keys = []string{api.LoginThrottleIPKey(flags.Arg(0))}
+	}
+
+	for _, key := range keys {
+		rows, err := store.DeleteLoginThrottle(context.Background(), key)
+		if err != nil {
+			return fmt.Errorf("cannot unlock %v: %w", key, err)
+		}
+		if rows == 0 {
+			log.Printf("%v had no failed attempts", key)
+			continue
+		}
+		log.Printf("security: unlocked %v by admin command", key)
+	}
+	return nil
+}
+
+// encryptUsersCommand backfills the encryption of user PII after migrating, it can be run again safely
+func encryptUsersCommand(store db.Store, args []string) error {
+	flags := flag.NewFlagSet("encrypt-users", flag.ContinueOnError)
+	batchSize := flags.Int("batch", 100, "number of users updated per query")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *batchSize <= 0 {
+		return fmt.Errorf("batch size must be positive\n%s", usage)
+	}
+
+	count, err := store.EncryptUsersPII(context.Background(), int32(*batchSize))
+	if err != nil {
+		return fmt.Errorf("cannot encrypt users after %d updated: %w", count, err)
+	}
+	log.Printf("security: encrypted the PII of %d users", count)
+	return nil
+}
+
+// setRoleCommand is the only way to grant the admin role, there is no endpoint for it
+func setRoleCommand(store db.Store, args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("set-role expects a username and a role\n%s", usage)
+	}
+	username, role := args[0], args[1]
+	if !util.IsSupportedRole(role) {
+		return fmt.Errorf("unknown role %q, expected %v or %v", role, util.RoleCustomer, util.RoleAdmin)
+	}
+
+	rows, err := store.UpdateUserRole(context.Background(), db.UpdateUserRoleParams{
+		Username: username,
+		Role:     role,
+	})
+	if err != nil {
+		return fmt.Errorf("cannot set the role of %v: %w", username, err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("user %v not found", username)
+	}
+	log.Printf("security: set the role of %v to %v by admin command", username, role)
+	return nil
+}
+
+// setKYCTierCommand moves a user to the default transfer limits of another KYC tier
+func setKYCTierCommand(store db.Store, args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("set-kyc-tier expects a username and a tier\n%s", usage)
+	}
+	username := args[0]
+	tier, err := strconv.ParseInt(args[1], 10, 32)
+	if err != nil || tier < 0 {
+		return fmt.Errorf("invalid tier %q, expected a number from 0\n%s", args[1], usage)
+	}
+
+	rows, err := store.UpdateUserKYCTier(context.Background(), db.UpdateUserKYCTierParams{
+		Username: username,
+		KycTier:  int32(tier),
+	})
+	if err != nil {
+		return fmt.Errorf("cannot set the KYC tier of %v: %w", username, err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("user %v not found", username)
+	}
+	log.Printf("security: set the KYC tier of %v to %d by admin command", username, tier)
+	return nil
+}
+
+// setLimitsCommand overrides the transfer limits of one account, amounts are decimals in its currency
+// and 0 is no limit; the limits left out go back to the defaults of the KYC tier of the owner
+func setLimitsCommand(store db.Store, args []string) error {
+	flags := flag.NewFlagSet("set-limits", flag.ContinueOnError)
+	perTransaction := flags.String("per-transaction", "", "the most a single transfer can send")
+	daily := flags.String("daily", "", "the most the account can send within 24 hours")
+	monthly := flags.String("monthly", "", "the most the account can send within 30 days")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return fmt.Errorf("set-limits expects exactly one account id\n%s", usage)
+	}
+	accountID, err := strconv.ParseInt(flags.Arg(0), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid account id %q\n%s", flags.Arg(0), usage)
+	}
+
+	account, err := store.GetAccount(context.Background(), accountID)
+	if err != nil {
+		return fmt.Errorf("cannot find account %d: %w", accountID, err)
+	}
+	if account.Kind == db.AccountKindSystem {
+		return fmt.Errorf("account %d is a system account, it has no limits", accountID)
+	}
+
+	arg := db.SetAccountTransferLimitsParams{AccountID: account.ID}
+	for _, limit := range []struct {
+		name   string
+		amount string
+		value  *sql.NullInt64
+	}{
+		{"per-transaction", *perTransaction, &arg.PerTransaction},
+		{"daily", *daily, &arg.Daily},
+		{"monthly", *monthly, &arg.Monthly},
+	} {
+		if limit.amount == "" {
+			continue
+		}
+		money, err := util.ParseMoney(limit.amount, account.Currency)
+		if err != nil || money.Amount < 0 {
+			return fmt.Errorf("invalid %s limit %q for %s", limit.name, limit.amount, account.Currency)
+		}
+		*limit.value = sql.NullInt64{Int64: money.Amount, Valid: true}
+	}
+
+	if _, err := store.SetAccountTransferLimits(context.Background(), arg); err != nil {
+		return fmt.Errorf("cannot set the limits of account %d: %w", account.ID, err)
+	}
+	log.Printf("security: set the transfer limits of account %d of %v to per-transaction=%q daily=%q monthly=%q by admin command",
+		account.ID, account.Owner, *perTransaction, *daily, *monthly)
+	return nil
+}
+
+// reconcileCommand fails when the ledger doesn't add up, so it can run from cron and alert
+// With -correct it writes a correcting journal to the suspense account for each balance mismatch
+func reconcileCommand(store db.Store, args []string) error {
+	flags := flag.NewFlagSet("reconcile", flag.ContinueOnError)
+	correct := flags.Bool("correct", false, "write correcting journals for the balance mismatches")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	report, err := store.ReconcileLedger(context.Background(), *correct)
+	if err != nil {
+		return fmt.Errorf("cannot reconcile the ledger: %w", err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return err
+	}
+	if *correct {
+		log.Printf("security: wrote %d ledger corrections by admin command", len(report.Corrections))
+	}
+	if !report.Balanced {
+		return errors.New("the ledger doesn't add up, see the report")
+	}
+	return nil
+}
@@ -0,0 +1,15 @@
+CREATE TABLE "api_keys" (
+    "id" uuid PRIMARY KEY,
+    "owner" varchar NOT NULL,
+    "name" varchar NOT NULL,
+    "prefix" varchar UNIQUE NOT NULL,
+    "hashed_key" varchar NOT NULL,
+    "scopes" varchar[] NOT NULL,
+    "expires_at" timestamptz,
+    "revoked_at" timestamptz,
+    "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "api_keys" ADD FOREIGN KEY ("owner") REFERENCES "users" ("username");
+
+CREATE INDEX ON "api_keys" ("owner");
@@ -0,0 +1,33 @@
+CREATE TABLE "user_mfa" (
+    "username" varchar PRIMARY KEY,
+    "encrypted_secret" varchar NOT NULL,
+    "enabled" boolean NOT NULL DEFAULT false,
+    "last_used_step" bigint NOT NULL DEFAULT 0,
+    "enabled_at" timestamptz,
+    "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE "mfa_recovery_codes" (
+    "id" bigserial PRIMARY KEY,
+    "username" varchar NOT NULL,
+    "hashed_code" varchar NOT NULL,
+    "used_at" timestamptz,
+    "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE "mfa_challenges" (
+    "id" uuid PRIMARY KEY,
+    "username" varchar NOT NULL,
+    "failed_attempts" int NOT NULL DEFAULT 0,
+    "expires_at" timestamptz NOT NULL,
+    "consumed_at" timestamptz,
+    "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "user_mfa" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+ALTER TABLE "mfa_recovery_codes" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+ALTER TABLE "mfa_challenges" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+CREATE INDEX ON "mfa_recovery_codes" ("username");
@@ -0,0 +1,6 @@
+-- "key" is either "username:<username>" or "ip:<address>"
+CREATE TABLE "login_throttles" (
+    "key" varchar PRIMARY KEY,
+    "failed_count" int NOT NULL,
+    "last_failed_at" timestamptz NOT NULL DEFAULT (now())
+);
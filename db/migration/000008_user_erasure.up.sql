@@ -0,0 +1,2 @@
+-- erased users keep their row so ledger history stays linked to it, with the personal data replaced
+ALTER TABLE "users" ADD COLUMN "deleted_at" timestamptz;
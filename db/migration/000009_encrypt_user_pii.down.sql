@@ -0,0 +1,8 @@
+-- encrypted values are kept, this only restores the schema
+ALTER TABLE IF EXISTS "verify_email_tokens" RENAME COLUMN "email_index" TO "email";
+
+ALTER TABLE IF EXISTS "users" DROP CONSTRAINT IF EXISTS "users_email_key";
+
+ALTER TABLE IF EXISTS "users" DROP COLUMN IF EXISTS "email_index";
+
+ALTER TABLE IF EXISTS "users" ADD CONSTRAINT "users_email_key" UNIQUE ("email");
@@ -0,0 +1,12 @@
+-- email and full_name now hold ciphertext, so uniqueness and lookups move to a keyed hash of the email
+-- existing rows are encrypted by `go run . encrypt-users` right after migrating
+ALTER TABLE "users" ADD COLUMN "email_index" varchar;
+
+ALTER TABLE "users" DROP CONSTRAINT "users_email_key";
+
+ALTER TABLE "users" ADD CONSTRAINT "users_email_key" UNIQUE ("email_index");
+
+ALTER TABLE "verify_email_tokens" RENAME COLUMN "email" TO "email_index";
+
+-- pending tokens were issued for the plain address, users can ask for a new one
+UPDATE "verify_email_tokens" SET "used_at" = now() WHERE "used_at" IS NULL;
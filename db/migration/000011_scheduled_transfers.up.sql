@@ -0,0 +1,31 @@
+-- status is one of pending, completed, failed or canceled
+CREATE TABLE "scheduled_transfers" (
+    "id" bigserial PRIMARY KEY,
+    "owner" varchar NOT NULL,
+    "from_account_id" bigint NOT NULL,
+    "to_account_id" bigint NOT NULL,
+    "amount" bigint NOT NULL,
+    "currency" varchar NOT NULL,
+    "execute_at" timestamptz NOT NULL,
+    "status" varchar NOT NULL DEFAULT 'pending',
+    "attempts" int NOT NULL DEFAULT 0,
+    "next_attempt_at" timestamptz NOT NULL,
+    "last_error" varchar,
+    "transfer_id" bigint,
+    "executed_at" timestamptz,
+    "created_at" timestamptz NOT NULL DEFAULT (now()),
+    CONSTRAINT "scheduled_transfers_amount_positive" CHECK ("amount" > 0)
+);
+
+ALTER TABLE "scheduled_transfers" ADD FOREIGN KEY ("owner") REFERENCES "users" ("username");
+
+ALTER TABLE "scheduled_transfers" ADD FOREIGN KEY ("from_account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "scheduled_transfers" ADD FOREIGN KEY ("to_account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "scheduled_transfers" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
+
+CREATE INDEX ON "scheduled_transfers" ("owner");
+
+-- the executor polls pending rows by their next attempt
+CREATE INDEX ON "scheduled_transfers" ("next_attempt_at") WHERE "status" = 'pending';
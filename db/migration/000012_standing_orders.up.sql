@@ -0,0 +1,46 @@
+-- status is one of active, paused, finished or canceled
+-- catch_up is all to make every run missed while the executor was down, or latest to skip to the most recent one
+CREATE TABLE "standing_orders" (
+    "id" bigserial PRIMARY KEY,
+    "owner" varchar NOT NULL,
+    "from_account_id" bigint NOT NULL,
+    "to_account_id" bigint NOT NULL,
+    "amount" bigint NOT NULL,
+    "currency" varchar NOT NULL,
+    "rrule" varchar NOT NULL,
+    "starts_at" timestamptz NOT NULL,
+    "catch_up" varchar NOT NULL DEFAULT 'all',
+    "status" varchar NOT NULL DEFAULT 'active',
+    "next_run_at" timestamptz NOT NULL,
+    "run_count" int NOT NULL DEFAULT 0,
+    "created_at" timestamptz NOT NULL DEFAULT (now()),
+    CONSTRAINT "standing_orders_amount_positive" CHECK ("amount" > 0)
+);
+
+ALTER TABLE "standing_orders" ADD FOREIGN KEY ("owner") REFERENCES "users" ("username");
+
+ALTER TABLE "standing_orders" ADD FOREIGN KEY ("from_account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "standing_orders" ADD FOREIGN KEY ("to_account_id") REFERENCES "accounts" ("id");
+
+CREATE INDEX ON "standing_orders" ("owner");
+
+-- the executor polls active orders by their next run
+CREATE INDEX ON "standing_orders" ("next_run_at") WHERE "status" = 'active';
+
+-- one row per occurrence, status is one of succeeded, failed or skipped
+CREATE TABLE "standing_order_runs" (
+    "id" bigserial PRIMARY KEY,
+    "standing_order_id" bigint NOT NULL,
+    "scheduled_for" timestamptz NOT NULL,
+    "status" varchar NOT NULL,
+    "transfer_id" bigint,
+    "error" varchar,
+    "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "standing_order_runs" ADD FOREIGN KEY ("standing_order_id") REFERENCES "standing_orders" ("id");
+
+ALTER TABLE "standing_order_runs" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
+
+CREATE INDEX ON "standing_order_runs" ("standing_order_id", "scheduled_for");
@@ -0,0 +1,2 @@
+ALTER TABLE IF EXISTS "transfers" DROP COLUMN IF EXISTS "reverses_transfer_id";
+ALTER TABLE IF EXISTS "users" DROP COLUMN IF EXISTS "role";
@@ -0,0 +1,9 @@
+-- role is customer or admin, admins can reverse any transfer
+ALTER TABLE "users" ADD COLUMN "role" varchar NOT NULL DEFAULT 'customer';
+
+-- a reversal is a transfer in the opposite direction linked to the transfer it refunds
+ALTER TABLE "transfers" ADD COLUMN "reverses_transfer_id" bigint;
+
+ALTER TABLE "transfers" ADD FOREIGN KEY ("reverses_transfer_id") REFERENCES "transfers" ("id");
+
+CREATE INDEX ON "transfers" ("reverses_transfer_id");
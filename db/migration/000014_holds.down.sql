@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS "holds";
+ALTER TABLE IF EXISTS "accounts" DROP COLUMN IF EXISTS "available_balance";
+ALTER TABLE IF EXISTS "accounts" DROP CONSTRAINT IF EXISTS "accounts_held_balance_non_negative";
+ALTER TABLE IF EXISTS "accounts" DROP COLUMN IF EXISTS "held_balance";
@@ -0,0 +1,33 @@
+-- held_balance is the sum of the active holds on the account, transfers can only spend the available balance
+ALTER TABLE "accounts" ADD COLUMN "held_balance" bigint NOT NULL DEFAULT 0;
+
+ALTER TABLE "accounts" ADD CONSTRAINT "accounts_held_balance_non_negative" CHECK ("held_balance" >= 0);
+
+ALTER TABLE "accounts" ADD COLUMN "available_balance" bigint NOT NULL GENERATED ALWAYS AS ("balance" - "held_balance") STORED;
+
+-- status is one of active, captured, voided or expired
+CREATE TABLE "holds" (
+    "id" bigserial PRIMARY KEY,
+    "account_id" bigint NOT NULL,
+    "to_account_id" bigint NOT NULL,
+    "amount" bigint NOT NULL,
+    "currency" varchar NOT NULL,
+    "status" varchar NOT NULL DEFAULT 'active',
+    "captured_amount" bigint,
+    "transfer_id" bigint,
+    "expires_at" timestamptz NOT NULL,
+    "settled_at" timestamptz,
+    "created_at" timestamptz NOT NULL DEFAULT (now()),
+    CONSTRAINT "holds_amount_positive" CHECK ("amount" > 0)
+);
+
+ALTER TABLE "holds" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "holds" ADD FOREIGN KEY ("to_account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "holds" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
+
+CREATE INDEX ON "holds" ("account_id");
+
+-- the expiry job looks for active holds past their expiry
+CREATE INDEX ON "holds" ("expires_at") WHERE "status" = 'active';
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS "system_accounts";
+DELETE FROM "entries" WHERE "account_id" IN (SELECT "id" FROM "accounts" WHERE "owner" = '_fees');
+DELETE FROM "accounts" WHERE "owner" = '_fees';
+DELETE FROM "users" WHERE "username" = '_fees';
+ALTER TABLE IF EXISTS "transfers" DROP COLUMN IF EXISTS "fee";
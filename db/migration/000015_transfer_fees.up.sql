@@ -0,0 +1,22 @@
+ALTER TABLE "transfers" ADD COLUMN "fee" bigint NOT NULL DEFAULT 0;
+
+-- system users own the house accounts, they can't log in and have a username customers can't register
+INSERT INTO "users" ("username", "hashed_password", "full_name", "email", "role")
+VALUES ('_fees', '!', 'Transfer fees', '_fees', 'system');
+
+INSERT INTO "accounts" ("owner", "balance", "currency")
+VALUES ('_fees', 0, 'USD'), ('_fees', 0, 'EUR'), ('_fees', 0, 'CAD');
+
+-- purpose is fees for now, there is one system account per purpose and currency
+CREATE TABLE "system_accounts" (
+    "purpose" varchar NOT NULL,
+    "currency" varchar NOT NULL,
+    "account_id" bigint UNIQUE NOT NULL,
+    "created_at" timestamptz NOT NULL DEFAULT (now()),
+    PRIMARY KEY ("purpose", "currency")
+);
+
+ALTER TABLE "system_accounts" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+
+INSERT INTO "system_accounts" ("purpose", "currency", "account_id")
+SELECT 'fees', "currency", "id" FROM "accounts" WHERE "owner" = '_fees';
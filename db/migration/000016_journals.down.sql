@@ -0,0 +1,19 @@
+DROP TRIGGER IF EXISTS "entries_journal_balanced" ON "entries";
+DROP FUNCTION IF EXISTS check_journal_balanced();
+ALTER TABLE IF EXISTS "entries" DROP COLUMN IF EXISTS "journal_id";
+DROP TABLE IF EXISTS "journals";
+
+DELETE FROM "system_accounts" WHERE "purpose" IN ('fx', 'suspense');
+DELETE FROM "entries" WHERE "account_id" IN (SELECT "id" FROM "accounts" WHERE "owner" IN ('_fx', '_suspense'));
+DELETE FROM "accounts" WHERE "owner" IN ('_fx', '_suspense');
+
+INSERT INTO "users" ("username", "hashed_password", "full_name", "email", "role")
+VALUES ('_fees', '!', 'Transfer fees', '_fees', 'system');
+
+DROP TRIGGER IF EXISTS "users_own_no_accounts" ON "users";
+DROP FUNCTION IF EXISTS check_user_owns_no_accounts();
+DROP TRIGGER IF EXISTS "accounts_owner_is_user" ON "accounts";
+DROP FUNCTION IF EXISTS check_account_owner();
+ALTER TABLE IF EXISTS "accounts" ADD FOREIGN KEY ("owner") REFERENCES "users" ("username");
+ALTER TABLE IF EXISTS "accounts" DROP CONSTRAINT IF EXISTS "accounts_system_owner";
+ALTER TABLE IF EXISTS "accounts" DROP COLUMN IF EXISTS "kind";
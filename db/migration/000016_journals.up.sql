@@ -0,0 +1,110 @@
+-- kind is user or system, system accounts are the house accounts (fees, fx, suspense) and have no user owner,
+-- their owner is a label starting with an underscore, which usernames can't
+ALTER TABLE "accounts" ADD COLUMN "kind" varchar NOT NULL DEFAULT 'user';
+
+-- the fee accounts become system accounts
+UPDATE "accounts" SET "kind" = 'system' WHERE "owner" = '_fees';
+
+ALTER TABLE "accounts" ADD CONSTRAINT "accounts_system_owner" CHECK (("kind" = 'system') = ("owner" LIKE '\_%'));
+
+-- the owner foreign key only applies to user accounts, so a trigger takes its place
+ALTER TABLE "accounts" DROP CONSTRAINT "accounts_owner_fkey";
+
+CREATE FUNCTION check_account_owner() RETURNS trigger AS $$
+BEGIN
+    IF NEW."kind" = 'user' THEN
+        -- the same lock a foreign key takes on the referenced row
+        PERFORM 1 FROM "users" WHERE "username" = NEW."owner" FOR KEY SHARE;
+        IF NOT FOUND THEN
+            RAISE EXCEPTION 'owner % of the account is not a user', NEW."owner"
+                USING ERRCODE = 'foreign_key_violation';
+        END IF;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER "accounts_owner_is_user"
+BEFORE INSERT OR UPDATE OF "owner", "kind" ON "accounts"
+FOR EACH ROW EXECUTE FUNCTION check_account_owner();
+
+-- the fees user is no longer needed
+DELETE FROM "users" WHERE "username" = '_fees';
+
+CREATE FUNCTION check_user_owns_no_accounts() RETURNS trigger AS $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM "accounts" WHERE "owner" = OLD."username" AND "kind" = 'user') THEN
+        RAISE EXCEPTION 'user % still owns accounts', OLD."username"
+            USING ERRCODE = 'foreign_key_violation';
+    END IF;
+    RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER "users_own_no_accounts"
+BEFORE DELETE ON "users"
+FOR EACH ROW EXECUTE FUNCTION check_user_owns_no_accounts();
+
+INSERT INTO "accounts" ("owner", "balance", "currency", "kind")
+VALUES
+    ('_fx', 0, 'USD', 'system'), ('_fx', 0, 'EUR', 'system'), ('_fx', 0, 'CAD', 'system'),
+    ('_suspense', 0, 'USD', 'system'), ('_suspense', 0, 'EUR', 'system'), ('_suspense', 0, 'CAD', 'system');
+
+-- purpose is fees, fx or suspense
+INSERT INTO "system_accounts" ("purpose", "currency", "account_id")
+SELECT ltrim("owner", '_'), "currency", "id" FROM "accounts" WHERE "owner" IN ('_fx', '_suspense');
+
+-- kind is transfer or adjustment
+CREATE TABLE "journals" (
+    "id" bigserial PRIMARY KEY,
+    "kind" varchar NOT NULL,
+    "transfer_id" bigint,
+    "description" varchar NOT NULL DEFAULT '',
+    "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "journals" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
+
+CREATE INDEX ON "journals" ("transfer_id");
+
+-- entries are the postings of the journals, entries written before journals have none
+ALTER TABLE "entries" ADD COLUMN "journal_id" bigint;
+
+ALTER TABLE "entries" ADD FOREIGN KEY ("journal_id") REFERENCES "journals" ("id");
+
+CREATE INDEX ON "entries" ("journal_id");
+
+-- the postings of a journal are written one by one, so the check waits for the end of the transaction
+CREATE FUNCTION check_journal_balanced() RETURNS trigger AS $$
+DECLARE
+    checked_journal_id bigint;
+    unbalanced record;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        checked_journal_id := OLD."journal_id";
+    ELSE
+        checked_journal_id := NEW."journal_id";
+    END IF;
+    IF checked_journal_id IS NULL THEN
+        RETURN NULL;
+    END IF;
+
+    SELECT "accounts"."currency", SUM("entries"."amount") AS "total" INTO unbalanced
+    FROM "entries"
+    JOIN "accounts" ON "accounts"."id" = "entries"."account_id"
+    WHERE "entries"."journal_id" = checked_journal_id
+    GROUP BY "accounts"."currency"
+    HAVING SUM("entries"."amount") <> 0
+    LIMIT 1;
+    IF FOUND THEN
+        RAISE EXCEPTION 'journal % does not balance in %: off by %', checked_journal_id, unbalanced."currency", unbalanced."total"
+            USING ERRCODE = 'check_violation';
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE CONSTRAINT TRIGGER "entries_journal_balanced"
+AFTER INSERT OR UPDATE OR DELETE ON "entries"
+DEFERRABLE INITIALLY DEFERRED
+FOR EACH ROW EXECUTE FUNCTION check_journal_balanced();
@@ -0,0 +1,16 @@
+-- balance is the sum of the entries of the account created before taken_until, the snapshot job takes one per day
+-- at midnight UTC so balances at a point in time only add up the entries after the latest snapshot
+CREATE TABLE "balance_snapshots" (
+    "account_id" bigint NOT NULL,
+    "taken_until" timestamptz NOT NULL,
+    "balance" bigint NOT NULL,
+    "created_at" timestamptz NOT NULL DEFAULT (now()),
+    PRIMARY KEY ("account_id", "taken_until")
+);
+
+ALTER TABLE "balance_snapshots" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+
+CREATE INDEX ON "balance_snapshots" ("taken_until");
+
+-- balances at a point in time add up the entries of an account in a time range
+CREATE INDEX ON "entries" ("account_id", "created_at");
@@ -0,0 +1,6 @@
+-- fails when the system accounts have entries, they can't be dropped once used
+DELETE FROM "system_accounts" WHERE "currency" IN ('JPY', 'KWD');
+DELETE FROM "balance_snapshots" WHERE "account_id" IN (
+    SELECT "id" FROM "accounts" WHERE "kind" = 'system' AND "currency" IN ('JPY', 'KWD')
+);
+DELETE FROM "accounts" WHERE "kind" = 'system' AND "currency" IN ('JPY', 'KWD');
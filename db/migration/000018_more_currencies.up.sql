@@ -0,0 +1,10 @@
+-- JPY and KWD get the system accounts every supported currency has
+INSERT INTO "accounts" ("owner", "balance", "currency", "kind")
+VALUES
+    ('_fees', 0, 'JPY', 'system'), ('_fees', 0, 'KWD', 'system'),
+    ('_fx', 0, 'JPY', 'system'), ('_fx', 0, 'KWD', 'system'),
+    ('_suspense', 0, 'JPY', 'system'), ('_suspense', 0, 'KWD', 'system');
+
+INSERT INTO "system_accounts" ("purpose", "currency", "account_id")
+SELECT ltrim("owner", '_'), "currency", "id" FROM "accounts"
+WHERE "kind" = 'system' AND "currency" IN ('JPY', 'KWD');
@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS "currencies_system_accounts" ON "currencies";
+DROP FUNCTION IF EXISTS create_currency_system_accounts();
+ALTER TABLE IF EXISTS "accounts" DROP CONSTRAINT IF EXISTS "accounts_currency_fkey";
+DROP TABLE IF EXISTS "currencies";
@@ -0,0 +1,39 @@
+-- exponent is the number of decimals of the minor unit, the transfer amounts are in minor units and 0 is no limit
+-- disabled currencies can't be used for new accounts and transfers, their accounts stay
+CREATE TABLE "currencies" (
+    "code" varchar PRIMARY KEY,
+    "exponent" int NOT NULL,
+    "symbol" varchar NOT NULL DEFAULT '',
+    "enabled" boolean NOT NULL DEFAULT true,
+    "min_transfer_amount" bigint NOT NULL DEFAULT 0,
+    "max_transfer_amount" bigint NOT NULL DEFAULT 0,
+    "created_at" timestamptz NOT NULL DEFAULT (now()),
+    CONSTRAINT "currencies_code_format" CHECK ("code" ~ '^[A-Z]{3}$'),
+    CONSTRAINT "currencies_exponent_range" CHECK ("exponent" BETWEEN 0 AND 18),
+    CONSTRAINT "currencies_transfer_limits" CHECK (
+        "min_transfer_amount" >= 0 AND "max_transfer_amount" >= 0
+        AND ("max_transfer_amount" = 0 OR "max_transfer_amount" >= "min_transfer_amount")
+    )
+);
+
+INSERT INTO "currencies" ("code", "exponent", "symbol")
+VALUES ('USD', 2, '$'), ('EUR', 2, '€'), ('CAD', 2, 'CA$'), ('JPY', 0, '¥'), ('KWD', 3, 'KD');
+
+ALTER TABLE "accounts" ADD FOREIGN KEY ("currency") REFERENCES "currencies" ("code");
+
+-- a new currency gets its system accounts, so adding one is a single insert
+CREATE FUNCTION create_currency_system_accounts() RETURNS trigger AS $$
+BEGIN
+    INSERT INTO "accounts" ("owner", "balance", "currency", "kind")
+    VALUES ('_fees', 0, NEW."code", 'system'), ('_fx', 0, NEW."code", 'system'), ('_suspense', 0, NEW."code", 'system');
+
+    INSERT INTO "system_accounts" ("purpose", "currency", "account_id")
+    SELECT ltrim("owner", '_'), "currency", "id" FROM "accounts"
+    WHERE "kind" = 'system' AND "currency" = NEW."code";
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER "currencies_system_accounts"
+AFTER INSERT ON "currencies"
+FOR EACH ROW EXECUTE FUNCTION create_currency_system_accounts();
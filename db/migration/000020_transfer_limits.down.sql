@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS "transfers_from_account_id_created_at_idx";
+DROP TABLE IF EXISTS "account_transfer_limits";
+DROP TABLE IF EXISTS "transfer_limits";
+ALTER TABLE "users" DROP CONSTRAINT IF EXISTS "users_kyc_tier_range";
+ALTER TABLE "users" DROP COLUMN IF EXISTS "kyc_tier";
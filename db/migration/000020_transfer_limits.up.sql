@@ -0,0 +1,33 @@
+-- kyc_tier is how far the identity of the user was verified, the default transfer limits depend on it
+ALTER TABLE "users" ADD COLUMN "kyc_tier" int NOT NULL DEFAULT 0;
+ALTER TABLE "users" ADD CONSTRAINT "users_kyc_tier_range" CHECK ("kyc_tier" >= 0);
+
+-- the default limits of the transfers out of the accounts of a KYC tier in a currency, in minor units and 0 is no limit
+-- daily and monthly are rolling windows of 24 hours and 30 days over the transfers of the account
+CREATE TABLE "transfer_limits" (
+    "kyc_tier" int NOT NULL,
+    "currency" varchar NOT NULL,
+    "per_transaction" bigint NOT NULL DEFAULT 0,
+    "daily" bigint NOT NULL DEFAULT 0,
+    "monthly" bigint NOT NULL DEFAULT 0,
+    "created_at" timestamptz NOT NULL DEFAULT (now()),
+    PRIMARY KEY ("kyc_tier", "currency"),
+    CONSTRAINT "transfer_limits_positive" CHECK ("per_transaction" >= 0 AND "daily" >= 0 AND "monthly" >= 0)
+);
+
+ALTER TABLE "transfer_limits" ADD FOREIGN KEY ("currency") REFERENCES "currencies" ("code");
+
+-- overrides of the default limits for one account, a NULL limit keeps the default of the account
+CREATE TABLE "account_transfer_limits" (
+    "account_id" bigint PRIMARY KEY,
+    "per_transaction" bigint,
+    "daily" bigint,
+    "monthly" bigint,
+    "created_at" timestamptz NOT NULL DEFAULT (now()),
+    CONSTRAINT "account_transfer_limits_positive" CHECK ("per_transaction" >= 0 AND "daily" >= 0 AND "monthly" >= 0)
+);
+
+ALTER TABLE "account_transfer_limits" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+
+-- the limits add up the transfers of an account in a time window
+CREATE INDEX ON "transfers" ("from_account_id", "created_at");
@@ -0,0 +1,34 @@
+-- the transfers the risk rules held for review or denied, nothing is moved until a held transfer is approved
+-- decision is hold or deny, status is pending, approved or rejected for held transfers and denied for denied ones
+CREATE TABLE "risk_reviews" (
+    "id" bigserial PRIMARY KEY,
+    "owner" varchar NOT NULL,
+    "from_account_id" bigint NOT NULL,
+    "to_account_id" bigint NOT NULL,
+    "amount" bigint NOT NULL,
+    "fee" bigint NOT NULL DEFAULT 0,
+    "currency" varchar NOT NULL,
+    "decision" varchar NOT NULL,
+    "reasons" varchar[] NOT NULL,
+    "status" varchar NOT NULL,
+    "transfer_id" bigint,
+    "reviewed_by" varchar,
+    "reviewed_at" timestamptz,
+    "created_at" timestamptz NOT NULL DEFAULT (now()),
+    CONSTRAINT "risk_reviews_amount_positive" CHECK ("amount" > 0),
+    CONSTRAINT "risk_reviews_decision" CHECK ("decision" IN ('hold', 'deny')),
+    CONSTRAINT "risk_reviews_status" CHECK ("status" IN ('pending', 'approved', 'rejected', 'denied'))
+);
+
+ALTER TABLE "risk_reviews" ADD FOREIGN KEY ("owner") REFERENCES "users" ("username");
+
+ALTER TABLE "risk_reviews" ADD FOREIGN KEY ("from_account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "risk_reviews" ADD FOREIGN KEY ("to_account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "risk_reviews" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
+
+ALTER TABLE "risk_reviews" ADD FOREIGN KEY ("reviewed_by") REFERENCES "users" ("username");
+
+-- the review queue lists the pending reviews, oldest first
+CREATE INDEX ON "risk_reviews" ("status", "id");
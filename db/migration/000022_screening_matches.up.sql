@@ -0,0 +1,24 @@
+-- the users whose name matched the sanctions list at signup, for compliance to review
+-- entry_ids are the list entries matched and matches describes them with their score,
+-- status is pending until the match is cleared as a false positive or confirmed
+CREATE TABLE "screening_matches" (
+    "id" bigserial PRIMARY KEY,
+    "username" varchar NOT NULL,
+    "entry_ids" varchar[] NOT NULL,
+    "matches" varchar[] NOT NULL,
+    "score" double precision NOT NULL,
+    "status" varchar NOT NULL DEFAULT 'pending',
+    "reviewed_by" varchar,
+    "reviewed_at" timestamptz,
+    "created_at" timestamptz NOT NULL DEFAULT (now()),
+    CONSTRAINT "screening_matches_status" CHECK ("status" IN ('pending', 'cleared', 'confirmed'))
+);
+
+ALTER TABLE "screening_matches" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+ALTER TABLE "screening_matches" ADD FOREIGN KEY ("reviewed_by") REFERENCES "users" ("username");
+
+-- the review queue lists the pending matches, oldest first
+CREATE INDEX ON "screening_matches" ("status", "id");
+
+CREATE INDEX ON "screening_matches" ("username");
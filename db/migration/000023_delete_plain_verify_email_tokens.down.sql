@@ -0,0 +1 @@
+-- the deleted tokens were used and held plain addresses, they are not restored
@@ -0,0 +1,4 @@
+-- 000009 renamed the email column of the tokens to email_index but the tokens issued before it kept
+-- the plain address, they were all marked used by then, so they are dropped
+-- tokens issued since hold the keyed hash of the address, 64 hex characters
+DELETE FROM "verify_email_tokens" WHERE "email_index" !~ '^[0-9a-f]{64}$';
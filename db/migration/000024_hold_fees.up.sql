@@ -0,0 +1,4 @@
+-- fee is the fee quoted when the hold was made, it's held with the amount and charged at capture
+ALTER TABLE "holds" ADD COLUMN "fee" bigint NOT NULL DEFAULT 0;
+
+ALTER TABLE "holds" ADD CONSTRAINT "holds_fee_non_negative" CHECK ("fee" >= 0);
@@ -0,0 +1 @@
+ALTER TABLE IF EXISTS "screening_matches" DROP COLUMN IF EXISTS "name_index";
@@ -0,0 +1,6 @@
+-- name_index is the blind index of the full name a match was screened for, so a clearance only
+-- holds for that name and a user who renames is screened afresh
+-- matches recorded before it have no name_index and no longer count, their entries are queued again
+ALTER TABLE "screening_matches" ADD COLUMN "name_index" varchar;
+
+CREATE INDEX ON "screening_matches" ("username", "name_index");
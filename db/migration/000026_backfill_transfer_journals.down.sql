@@ -0,0 +1,7 @@
+UPDATE "entries" SET "journal_id" = NULL
+WHERE "journal_id" IN (
+    SELECT "id" FROM "journals"
+    WHERE "kind" = 'transfer' AND "description" = 'written before journals'
+);
+
+DELETE FROM "journals" WHERE "kind" = 'transfer' AND "description" = 'written before journals';
@@ -0,0 +1,70 @@
+-- the transfers written before journals get one, so reconciliation only goes by journals
+-- the entries of such a transfer are the entries without a journal written in the same database transaction,
+-- on the accounts and for the amounts of its postings; identical entries of one transaction are paired in order
+INSERT INTO "journals" ("kind", "transfer_id", "description", "created_at")
+SELECT 'transfer', "transfers"."id", 'written before journals', "transfers"."created_at"
+FROM "transfers"
+WHERE NOT EXISTS (SELECT 1 FROM "journals" WHERE "journals"."transfer_id" = "transfers"."id");
+
+-- only transfers with every posting found get their entries, so their journals balance;
+-- the others keep an empty journal and their entries stay without one, reconciliation reports both
+WITH "legacy_transfers" AS (
+    SELECT
+        "journals"."id" AS "journal_id",
+        "transfers"."id" AS "transfer_id",
+        "transfers"."created_at",
+        "transfers"."from_account_id",
+        "transfers"."to_account_id",
+        "transfers"."amount",
+        "transfers"."fee",
+        "system_accounts"."account_id" AS "fee_account_id"
+    FROM "journals"
+    JOIN "transfers" ON "transfers"."id" = "journals"."transfer_id"
+    LEFT JOIN "accounts" ON "accounts"."id" = "transfers"."from_account_id"
+    LEFT JOIN "system_accounts" ON "system_accounts"."purpose" = 'fees'
+        AND "system_accounts"."currency" = "accounts"."currency"
+    WHERE "journals"."kind" = 'transfer' AND "journals"."description" = 'written before journals'
+), "legacy_postings" AS (
+    SELECT "journal_id", "transfer_id", "created_at", 1 AS "seq", "from_account_id" AS "account_id", -"amount" AS "amount"
+    FROM "legacy_transfers"
+    UNION ALL
+    SELECT "journal_id", "transfer_id", "created_at", 2, "to_account_id", "amount"
+    FROM "legacy_transfers"
+    UNION ALL
+    SELECT "journal_id", "transfer_id", "created_at", 3, "from_account_id", -"fee"
+    FROM "legacy_transfers" WHERE "fee" > 0
+    UNION ALL
+    SELECT "journal_id", "transfer_id", "created_at", 4, "fee_account_id", "fee"
+    FROM "legacy_transfers" WHERE "fee" > 0
+), "ranked_postings" AS (
+    SELECT
+        *,
+        ROW_NUMBER() OVER (PARTITION BY "created_at", "account_id", "amount" ORDER BY "transfer_id", "seq") AS "position"
+    FROM "legacy_postings"
+), "ranked_entries" AS (
+    SELECT
+        "id",
+        "created_at",
+        "account_id",
+        "amount",
+        ROW_NUMBER() OVER (PARTITION BY "created_at", "account_id", "amount" ORDER BY "id") AS "position"
+    FROM "entries"
+    WHERE "journal_id" IS NULL AND "account_id" IS NOT NULL
+), "matches" AS (
+    SELECT "ranked_postings"."journal_id", "ranked_postings"."transfer_id", "ranked_entries"."id" AS "entry_id"
+    FROM "ranked_postings"
+    JOIN "ranked_entries" ON "ranked_entries"."created_at" = "ranked_postings"."created_at"
+        AND "ranked_entries"."account_id" = "ranked_postings"."account_id"
+        AND "ranked_entries"."amount" = "ranked_postings"."amount"
+        AND "ranked_entries"."position" = "ranked_postings"."position"
+), "complete" AS (
+    SELECT "legacy_postings"."transfer_id"
+    FROM "legacy_postings"
+    GROUP BY "legacy_postings"."transfer_id"
+    HAVING COUNT(*) = (SELECT COUNT(*) FROM "matches" WHERE "matches"."transfer_id" = "legacy_postings"."transfer_id")
+)
+UPDATE "entries"
+SET "journal_id" = "matches"."journal_id"
+FROM "matches"
+JOIN "complete" ON "complete"."transfer_id" = "matches"."transfer_id"
+WHERE "entries"."id" = "matches"."entry_id";
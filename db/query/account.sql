@@ -43,4 +43,29 @@ RETURNING *;
 
 
 -- name: DeleteAccount :exec
-DELETE FROM accounts WHERE id = $1;
\ No newline at end of file
+DELETE FROM accounts WHERE id = $1;
+
+-- name: ListAllAccountsByUsername :many
+SELECT * FROM accounts
+WHERE owner = $1
+ORDER BY id;
+
+-- name: AddAccountHeldBalance :one
+UPDATE accounts
+SET held_balance = held_balance + sqlc.arg(amount)
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: ListBalanceMismatches :many
+-- accounts whose balance isn't the sum of their entries
+SELECT
+    accounts.id,
+    accounts.owner,
+    accounts.currency,
+    accounts.balance,
+    COALESCE(SUM(entries.amount), 0)::bigint AS entries_total
+FROM accounts
+LEFT JOIN entries ON entries.account_id = accounts.id
+GROUP BY accounts.id
+HAVING accounts.balance <> COALESCE(SUM(entries.amount), 0)
+ORDER BY accounts.id;
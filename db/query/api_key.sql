@@ -0,0 +1,34 @@
+-- name: CreateAPIKey :one
+INSERT INTO api_keys (
+    id,
+    owner,
+    name,
+    prefix,
+    hashed_key,
+    scopes,
+    expires_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7
+) RETURNING *;
+
+-- name: GetAPIKeyByPrefix :one
+SELECT * FROM api_keys
+WHERE prefix = $1 LIMIT 1;
+
+-- name: ListAPIKeysByOwner :many
+SELECT * FROM api_keys
+WHERE owner = $1
+ORDER BY created_at
+LIMIT $2
+OFFSET $3;
+
+-- name: RevokeAPIKey :one
+UPDATE api_keys
+SET revoked_at = now()
+WHERE id = $1 AND owner = $2 AND revoked_at IS NULL
+RETURNING *;
+
+-- name: RevokeAPIKeysByOwner :exec
+UPDATE api_keys
+SET revoked_at = now()
+WHERE owner = $1 AND revoked_at IS NULL;
@@ -0,0 +1,41 @@
+-- name: CreateBalanceSnapshots :execrows
+-- snapshots every account created before taken_until, from its previous snapshot and the entries since then
+INSERT INTO balance_snapshots (account_id, taken_until, balance)
+SELECT
+    accounts.id,
+    sqlc.arg(taken_until)::timestamptz,
+    COALESCE(previous.balance, 0) + COALESCE((
+        SELECT SUM(entries.amount) FROM entries
+        WHERE entries.account_id = accounts.id
+            AND entries.created_at >= COALESCE(previous.taken_until, '-infinity'::timestamptz)
+            AND entries.created_at < sqlc.arg(taken_until)::timestamptz
+    ), 0)
+FROM accounts
+LEFT JOIN LATERAL (
+    SELECT balance_snapshots.balance, balance_snapshots.taken_until FROM balance_snapshots
+    WHERE balance_snapshots.account_id = accounts.id
+        AND balance_snapshots.taken_until < sqlc.arg(taken_until)::timestamptz
+    ORDER BY balance_snapshots.taken_until DESC
+    LIMIT 1
+) previous ON true
+WHERE accounts.created_at < sqlc.arg(taken_until)::timestamptz
+ON CONFLICT (account_id, taken_until) DO NOTHING;
+
+-- name: GetLatestBalanceSnapshotTime :one
+SELECT taken_until FROM balance_snapshots
+ORDER BY taken_until DESC
+LIMIT 1;
+
+-- name: GetLatestBalanceSnapshot :one
+-- the latest snapshot of the account that doesn't go past the time
+SELECT * FROM balance_snapshots
+WHERE account_id = sqlc.arg(account_id) AND taken_until <= sqlc.arg(at)::timestamptz
+ORDER BY taken_until DESC
+LIMIT 1;
+
+-- name: GetAccountEntriesTotalBetween :one
+-- entries created from since, inclusive, to until, inclusive
+SELECT COALESCE(SUM(amount), 0)::bigint FROM entries
+WHERE account_id = sqlc.arg(account_id)
+    AND created_at >= sqlc.arg(since)::timestamptz
+    AND created_at <= sqlc.arg(until)::timestamptz;
@@ -1,9 +1,10 @@
 -- name: CreateEntry :one
 INSERT INTO entries (
     account_id,
-    amount
+    amount,
+    journal_id
 ) VALUES (
-    $1, $2
+    $1, $2, $3
 ) RETURNING *;
 
 -- name: GetEntry :one
@@ -15,3 +16,19 @@ SELECT * FROM entries
 ORDER BY id
 LIMIT $1
 OFFSET $2;
+
+-- name: ListEntriesByUsername :many
+SELECT entries.* FROM entries
+JOIN accounts ON accounts.id = entries.account_id
+WHERE accounts.owner = $1
+ORDER BY entries.id;
+
+-- name: GetAccountEntriesTotal :one
+SELECT COALESCE(SUM(amount), 0)::bigint FROM entries
+WHERE account_id = $1;
+
+-- name: ListOrphanEntries :many
+-- entries without an account or a journal, the transfers written before journals got theirs when migrating
+SELECT * FROM entries
+WHERE account_id IS NULL OR journal_id IS NULL
+ORDER BY id;
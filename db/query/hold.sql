@@ -0,0 +1,45 @@
+-- name: CreateHold :one
+INSERT INTO holds (
+    account_id,
+    to_account_id,
+    amount,
+    currency,
+    expires_at,
+    fee
+) VALUES (
+    $1, $2, $3, $4, $5, $6
+) RETURNING *;
+
+-- name: GetHold :one
+SELECT * FROM holds
+WHERE id = $1 LIMIT 1;
+
+-- name: GetHoldForUpdate :one
+SELECT * FROM holds
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: GetExpiredHoldForUpdate :one
+SELECT * FROM holds
+WHERE status = 'active' AND expires_at <= now()
+ORDER BY expires_at, id
+LIMIT 1
+FOR NO KEY UPDATE SKIP LOCKED;
+
+-- name: SettleHold :one
+UPDATE holds
+SET status = sqlc.arg(status),
+    captured_amount = sqlc.arg(captured_amount),
+    transfer_id = sqlc.arg(transfer_id),
+    settled_at = now()
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: ListActiveHoldsByUsernameForUpdate :many
+-- the holds on the accounts of a user and the ones to their accounts
+SELECT * FROM holds
+WHERE status = 'active'
+    AND (account_id IN (SELECT accounts.id FROM accounts WHERE accounts.owner = sqlc.arg(username))
+        OR to_account_id IN (SELECT accounts.id FROM accounts WHERE accounts.owner = sqlc.arg(username)))
+ORDER BY id
+FOR NO KEY UPDATE;
@@ -0,0 +1,23 @@
+-- name: GetLoginThrottle :one
+SELECT * FROM login_throttles
+WHERE key = $1 LIMIT 1;
+
+-- name: RecordLoginFailure :one
+-- failures older than reset_before are forgotten and the count starts again
+INSERT INTO login_throttles (
+    key,
+    failed_count
+) VALUES (
+    sqlc.arg(key), 1
+)
+ON CONFLICT (key) DO UPDATE
+SET failed_count = CASE
+        WHEN login_throttles.last_failed_at < sqlc.arg(reset_before) THEN 1
+        ELSE login_throttles.failed_count + 1
+    END,
+    last_failed_at = now()
+RETURNING *;
+
+-- name: DeleteLoginThrottle :execrows
+DELETE FROM login_throttles
+WHERE key = $1;
@@ -0,0 +1,76 @@
+-- name: UpsertUserMFA :one
+INSERT INTO user_mfa (
+    username,
+    encrypted_secret
+) VALUES (
+    $1, $2
+)
+ON CONFLICT (username) DO UPDATE
+SET encrypted_secret = EXCLUDED.encrypted_secret,
+    enabled = false,
+    last_used_step = 0,
+    enabled_at = NULL
+RETURNING *;
+
+-- name: GetUserMFA :one
+SELECT * FROM user_mfa
+WHERE username = $1 LIMIT 1;
+
+-- name: EnableUserMFA :one
+UPDATE user_mfa
+SET enabled = true,
+    enabled_at = now()
+WHERE username = $1
+RETURNING *;
+
+-- name: UpdateMFALastUsedStep :execrows
+UPDATE user_mfa
+SET last_used_step = sqlc.arg(step)
+WHERE username = sqlc.arg(username) AND last_used_step < sqlc.arg(step);
+
+-- name: CreateMFARecoveryCode :one
+INSERT INTO mfa_recovery_codes (
+    username,
+    hashed_code
+) VALUES (
+    $1, $2
+) RETURNING *;
+
+-- name: DeleteMFARecoveryCodes :exec
+DELETE FROM mfa_recovery_codes
+WHERE username = $1;
+
+-- name: UseMFARecoveryCode :one
+UPDATE mfa_recovery_codes
+SET used_at = now()
+WHERE username = $1 AND hashed_code = $2 AND used_at IS NULL
+RETURNING *;
+
+-- name: CreateMFAChallenge :one
+INSERT INTO mfa_challenges (
+    id,
+    username,
+    expires_at
+) VALUES (
+    $1, $2, $3
+) RETURNING *;
+
+-- name: GetMFAChallenge :one
+SELECT * FROM mfa_challenges
+WHERE id = $1 LIMIT 1;
+
+-- name: IncrementMFAChallengeAttempts :one
+UPDATE mfa_challenges
+SET failed_attempts = failed_attempts + 1
+WHERE id = $1
+RETURNING *;
+
+-- name: ConsumeMFAChallenge :one
+UPDATE mfa_challenges
+SET consumed_at = now()
+WHERE id = $1 AND consumed_at IS NULL AND expires_at > now()
+RETURNING *;
+
+-- name: DeleteUserMFA :exec
+DELETE FROM user_mfa
+WHERE username = $1;
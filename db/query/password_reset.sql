@@ -0,0 +1,27 @@
+-- name: CreatePasswordResetToken :one
+INSERT INTO password_reset_tokens (
+    hashed_token,
+    username,
+    expires_at
+) VALUES (
+    $1, $2, $3
+) RETURNING *;
+
+-- name: UsePasswordResetToken :one
+UPDATE password_reset_tokens
+SET used_at = now()
+WHERE hashed_token = $1 AND used_at IS NULL AND expires_at > now()
+RETURNING *;
+
+-- name: InvalidatePasswordResetTokens :exec
+UPDATE password_reset_tokens
+SET used_at = now()
+WHERE username = $1 AND used_at IS NULL;
+
+-- name: GetUserByPasswordResetToken :one
+SELECT users.* FROM users
+JOIN password_reset_tokens ON password_reset_tokens.username = users.username
+WHERE password_reset_tokens.hashed_token = $1
+    AND password_reset_tokens.used_at IS NULL
+    AND password_reset_tokens.expires_at > now()
+LIMIT 1;
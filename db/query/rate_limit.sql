@@ -0,0 +1,29 @@
+-- name: TakeRateLimitToken :one
+-- refills the bucket for the time elapsed since the last request and takes a token if there's one,
+-- in a single statement so concurrent requests from other instances can't both take the last token
+INSERT INTO rate_limit_buckets AS bucket (
+    key,
+    tokens,
+    allowed,
+    updated_at
+) VALUES (
+    sqlc.arg(key), sqlc.arg(capacity)::float8 - 1, true, now()
+)
+ON CONFLICT (key) DO UPDATE SET
+    allowed = LEAST(
+        sqlc.arg(capacity)::float8,
+        bucket.tokens + EXTRACT(EPOCH FROM now() - bucket.updated_at)::float8 * sqlc.arg(refill_rate)::float8
+    ) >= 1,
+    tokens = LEAST(
+        sqlc.arg(capacity)::float8,
+        bucket.tokens + EXTRACT(EPOCH FROM now() - bucket.updated_at)::float8 * sqlc.arg(refill_rate)::float8
+    ) - CASE WHEN LEAST(
+        sqlc.arg(capacity)::float8,
+        bucket.tokens + EXTRACT(EPOCH FROM now() - bucket.updated_at)::float8 * sqlc.arg(refill_rate)::float8
+    ) >= 1 THEN 1 ELSE 0 END,
+    updated_at = now()
+RETURNING *;
+
+-- name: DeleteIdleRateLimitBuckets :execrows
+DELETE FROM rate_limit_buckets
+WHERE updated_at < sqlc.arg(idle_since);
@@ -0,0 +1,48 @@
+-- name: CreateRiskReview :one
+INSERT INTO risk_reviews (
+    owner,
+    from_account_id,
+    to_account_id,
+    amount,
+    fee,
+    currency,
+    decision,
+    reasons,
+    status
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9
+) RETURNING *;
+
+-- name: GetRiskReview :one
+SELECT * FROM risk_reviews
+WHERE id = $1 LIMIT 1;
+
+-- name: GetRiskReviewForUpdate :one
+-- locks the review so it's approved or rejected once
+SELECT * FROM risk_reviews
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: ListRiskReviews :many
+SELECT * FROM risk_reviews
+WHERE status = $1
+ORDER BY id
+LIMIT $2
+OFFSET $3;
+
+-- name: SettleRiskReview :one
+UPDATE risk_reviews
+SET status = sqlc.arg(status),
+    transfer_id = sqlc.arg(transfer_id),
+    reviewed_by = sqlc.arg(reviewed_by),
+    reviewed_at = now()
+WHERE id = sqlc.arg(id) AND status = 'pending'
+RETURNING *;
+
+-- name: RejectRiskReviewsByUsername :execrows
+-- the pending reviews of an erased user and the ones to their accounts, nobody reviewed them
+UPDATE risk_reviews
+SET status = 'rejected',
+    reviewed_at = now()
+WHERE status = 'pending'
+    AND (risk_reviews.owner = sqlc.arg(username) OR to_account_id IN (SELECT accounts.id FROM accounts WHERE accounts.owner = sqlc.arg(username)));
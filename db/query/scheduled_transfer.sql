@@ -0,0 +1,70 @@
+-- name: CreateScheduledTransfer :one
+INSERT INTO scheduled_transfers (
+    owner,
+    from_account_id,
+    to_account_id,
+    amount,
+    currency,
+    execute_at,
+    next_attempt_at
+) VALUES (
+    sqlc.arg(owner),
+    sqlc.arg(from_account_id),
+    sqlc.arg(to_account_id),
+    sqlc.arg(amount),
+    sqlc.arg(currency),
+    sqlc.arg(execute_at),
+    sqlc.arg(execute_at)
+) RETURNING *;
+
+-- name: GetScheduledTransfer :one
+SELECT * FROM scheduled_transfers
+WHERE id = $1 LIMIT 1;
+
+-- name: ListScheduledTransfers :many
+SELECT * FROM scheduled_transfers
+WHERE owner = $1
+ORDER BY execute_at, id
+LIMIT $2
+OFFSET $3;
+
+-- name: CancelScheduledTransfer :one
+UPDATE scheduled_transfers
+SET status = 'canceled'
+WHERE id = $1 AND owner = $2 AND status = 'pending'
+RETURNING *;
+
+-- name: GetDueScheduledTransferForUpdate :one
+-- SKIP LOCKED lets several executors work through the due rows without waiting on each other
+SELECT * FROM scheduled_transfers
+WHERE status = 'pending' AND next_attempt_at <= now()
+ORDER BY next_attempt_at, id
+LIMIT 1
+FOR UPDATE SKIP LOCKED;
+
+-- name: CompleteScheduledTransfer :one
+UPDATE scheduled_transfers
+SET status = 'completed',
+    attempts = attempts + 1,
+    transfer_id = sqlc.arg(transfer_id),
+    last_error = NULL,
+    executed_at = now()
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: RecordScheduledTransferFailure :one
+-- status stays pending while there are retries left, next_attempt_at is then the next retry
+UPDATE scheduled_transfers
+SET status = sqlc.arg(status),
+    attempts = attempts + 1,
+    last_error = sqlc.arg(last_error),
+    next_attempt_at = sqlc.arg(next_attempt_at)
+WHERE id = sqlc.arg(id) AND status = 'pending'
+RETURNING *;
+
+-- name: CancelScheduledTransfersByUsername :execrows
+-- the pending transfers of an erased user and the ones to their accounts
+UPDATE scheduled_transfers
+SET status = 'canceled'
+WHERE status = 'pending'
+    AND (scheduled_transfers.owner = sqlc.arg(username) OR to_account_id IN (SELECT accounts.id FROM accounts WHERE accounts.owner = sqlc.arg(username)));
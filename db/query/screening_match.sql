@@ -0,0 +1,41 @@
+-- name: CreateScreeningMatch :one
+-- full_name is the name screened, the store keeps its blind index
+INSERT INTO screening_matches (
+    username,
+    entry_ids,
+    matches,
+    score,
+    name_index
+) VALUES (
+    sqlc.arg(username), sqlc.arg(entry_ids), sqlc.arg(matches), sqlc.arg(score), sqlc.arg(full_name)::varchar
+) RETURNING *;
+
+-- name: GetScreeningMatch :one
+SELECT * FROM screening_matches
+WHERE id = $1 LIMIT 1;
+
+-- name: ListScreeningMatches :many
+SELECT * FROM screening_matches
+WHERE status = $1
+ORDER BY id
+LIMIT $2
+OFFSET $3;
+
+-- name: SettleScreeningMatch :one
+UPDATE screening_matches
+SET status = sqlc.arg(status),
+    reviewed_by = sqlc.arg(reviewed_by),
+    reviewed_at = now()
+WHERE id = sqlc.arg(id) AND status = 'pending'
+RETURNING *;
+
+-- name: ListScreeningEntries :many
+-- the list entries matched for the full name of the user, with the status of their latest match
+-- full_name is the name screened, the store looks it up by its blind index
+SELECT DISTINCT ON (entry_id) entry_id::varchar, status
+FROM (
+    SELECT unnest(entry_ids) AS entry_id, status, id
+    FROM screening_matches
+    WHERE username = sqlc.arg(username) AND name_index = sqlc.arg(full_name)::varchar
+) AS entries
+ORDER BY entry_id, id DESC;
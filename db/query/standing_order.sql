@@ -0,0 +1,90 @@
+-- name: CreateStandingOrder :one
+INSERT INTO standing_orders (
+    owner,
+    from_account_id,
+    to_account_id,
+    amount,
+    currency,
+    rrule,
+    starts_at,
+    catch_up,
+    next_run_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9
+) RETURNING *;
+
+-- name: GetStandingOrder :one
+SELECT * FROM standing_orders
+WHERE id = $1 LIMIT 1;
+
+-- name: ListStandingOrders :many
+SELECT * FROM standing_orders
+WHERE owner = $1
+ORDER BY id
+LIMIT $2
+OFFSET $3;
+
+-- name: PauseStandingOrder :one
+UPDATE standing_orders
+SET status = 'paused'
+WHERE id = $1 AND owner = $2 AND status = 'active'
+RETURNING *;
+
+-- name: ResumeStandingOrder :one
+-- runs missed while paused are not made, next_run_at is the first run after resuming
+UPDATE standing_orders
+SET status = 'active',
+    next_run_at = sqlc.arg(next_run_at)
+WHERE id = sqlc.arg(id) AND owner = sqlc.arg(owner) AND status = 'paused'
+RETURNING *;
+
+-- name: CancelStandingOrder :one
+UPDATE standing_orders
+SET status = 'canceled'
+WHERE id = $1 AND owner = $2 AND status IN ('active', 'paused')
+RETURNING *;
+
+-- name: GetDueStandingOrderForUpdate :one
+SELECT * FROM standing_orders
+WHERE status = 'active' AND next_run_at <= sqlc.arg(now)
+ORDER BY next_run_at, id
+LIMIT 1
+FOR UPDATE SKIP LOCKED;
+
+-- name: GetStandingOrderForUpdate :one
+SELECT * FROM standing_orders
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: AdvanceStandingOrder :one
+UPDATE standing_orders
+SET status = sqlc.arg(status),
+    next_run_at = sqlc.arg(next_run_at),
+    run_count = sqlc.arg(run_count)
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: CreateStandingOrderRun :one
+INSERT INTO standing_order_runs (
+    standing_order_id,
+    scheduled_for,
+    status,
+    transfer_id,
+    error
+) VALUES (
+    $1, $2, $3, $4, $5
+) RETURNING *;
+
+-- name: ListStandingOrderRuns :many
+SELECT * FROM standing_order_runs
+WHERE standing_order_id = $1
+ORDER BY scheduled_for DESC, id DESC
+LIMIT $2
+OFFSET $3;
+
+-- name: CancelStandingOrdersByUsername :execrows
+-- the orders of an erased user and the ones to their accounts
+UPDATE standing_orders
+SET status = 'canceled'
+WHERE status IN ('active', 'paused')
+    AND (standing_orders.owner = sqlc.arg(username) OR to_account_id IN (SELECT accounts.id FROM accounts WHERE accounts.owner = sqlc.arg(username)));
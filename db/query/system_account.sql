@@ -0,0 +1,5 @@
+-- name: GetSystemAccountIDForAccount :one
+-- the system account for the purpose in the currency of the account
+SELECT system_accounts.account_id FROM system_accounts
+JOIN accounts ON accounts.currency = system_accounts.currency
+WHERE system_accounts.purpose = sqlc.arg(purpose) AND accounts.id = sqlc.arg(account_id);
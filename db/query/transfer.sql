@@ -2,15 +2,27 @@
 INSERT INTO transfers (
     from_account_id,
     to_account_id,
-    amount
+    amount,
+    reverses_transfer_id,
+    fee
 ) VALUES (
-    $1, $2, $3
+    $1, $2, $3, $4, $5
 ) RETURNING *;
 
 -- name: GetTransfer :one
 SELECT * FROM transfers
 WHERE id = $1 LIMIT 1;
 
+-- name: GetTransferForUpdate :one
+-- locks the transfer so concurrent reversals of it are made one after the other
+SELECT * FROM transfers
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: GetReversedAmount :one
+SELECT COALESCE(SUM(amount), 0)::bigint FROM transfers
+WHERE reverses_transfer_id = $1;
+
 -- name: ListTransfers :many
 SELECT * FROM transfers
 WHERE
@@ -19,3 +31,49 @@ WHERE
 ORDER BY id
 LIMIT $3
 OFFSET $4;
+
+-- name: ListTransfersByUsername :many
+SELECT * FROM transfers
+WHERE
+    from_account_id IN (SELECT accounts.id FROM accounts WHERE accounts.owner = sqlc.arg(owner))
+    OR to_account_id IN (SELECT accounts.id FROM accounts WHERE accounts.owner = sqlc.arg(owner))
+ORDER BY id;
+
+-- name: ListUnbalancedTransfers :many
+-- transfers without exactly their entry pair, and the fee pair when they have a fee, in their journal
+SELECT * FROM (
+    SELECT
+        transfers.id,
+        transfers.from_account_id,
+        transfers.to_account_id,
+        transfers.amount,
+        transfers.fee,
+        journals.id AS journal_id,
+        COUNT(entries.id) AS entry_count,
+        COALESCE(SUM(entries.amount) FILTER (WHERE entries.account_id = transfers.from_account_id), 0)::bigint AS from_total,
+        COALESCE(SUM(entries.amount) FILTER (WHERE entries.account_id = transfers.to_account_id), 0)::bigint AS to_total,
+        -- the fee goes to the receiving account when it's the fee account
+        COALESCE(transfers.to_account_id IN (SELECT account_id FROM system_accounts WHERE purpose = 'fees'), false)::boolean AS to_fee_account
+    FROM transfers
+    LEFT JOIN journals ON journals.transfer_id = transfers.id
+    LEFT JOIN entries ON entries.journal_id = journals.id
+    GROUP BY transfers.id, journals.id
+) AS checked
+WHERE entry_count <> CASE WHEN fee > 0 THEN 4 ELSE 2 END
+    OR from_total <> -(amount + fee)
+    OR to_total <> amount + CASE WHEN to_fee_account THEN fee ELSE 0 END
+ORDER BY id;
+
+-- name: CountTransfersSince :one
+-- the transfers sent from the account since then, those of round amounts when round_to isn't 0
+SELECT COUNT(*) FROM transfers
+WHERE from_account_id = sqlc.arg(account_id)
+    AND created_at > sqlc.arg(since)
+    AND reverses_transfer_id IS NULL
+    AND (sqlc.arg(round_to)::bigint = 0 OR amount % sqlc.arg(round_to)::bigint = 0);
+
+-- name: HasTransferredTo :one
+SELECT EXISTS (
+    SELECT 1 FROM transfers
+    WHERE from_account_id = sqlc.arg(from_account_id) AND to_account_id = sqlc.arg(to_account_id)
+)::boolean;
@@ -0,0 +1,39 @@
+-- name: GetAccountTransferLimits :one
+-- the limits of the account: its overrides, or else the defaults of the KYC tier of its owner in its currency
+SELECT
+    COALESCE(users.kyc_tier, 0)::int AS kyc_tier,
+    COALESCE(account_transfer_limits.per_transaction, transfer_limits.per_transaction, 0)::bigint AS per_transaction,
+    COALESCE(account_transfer_limits.daily, transfer_limits.daily, 0)::bigint AS daily,
+    COALESCE(account_transfer_limits.monthly, transfer_limits.monthly, 0)::bigint AS monthly
+FROM accounts
+LEFT JOIN users ON users.username = accounts.owner
+LEFT JOIN transfer_limits ON transfer_limits.kyc_tier = COALESCE(users.kyc_tier, 0)
+    AND transfer_limits.currency = accounts.currency
+LEFT JOIN account_transfer_limits ON account_transfer_limits.account_id = accounts.id
+WHERE accounts.id = sqlc.arg(account_id);
+
+-- name: GetTransferredTotals :one
+-- the amounts sent from the account since each start, reversals give money back and don't count
+SELECT
+    COALESCE(SUM(amount) FILTER (WHERE created_at > sqlc.arg(day_start)), 0)::bigint AS day_total,
+    COALESCE(SUM(amount), 0)::bigint AS month_total
+FROM transfers
+WHERE from_account_id = sqlc.arg(account_id)
+    AND created_at > sqlc.arg(month_start)
+    AND reverses_transfer_id IS NULL
+    AND id <> sqlc.arg(exclude_transfer_id);
+
+-- name: SetAccountTransferLimits :one
+INSERT INTO account_transfer_limits (
+    account_id,
+    per_transaction,
+    daily,
+    monthly
+) VALUES (
+    $1, $2, $3, $4
+)
+ON CONFLICT (account_id) DO UPDATE SET
+    per_transaction = EXCLUDED.per_transaction,
+    daily = EXCLUDED.daily,
+    monthly = EXCLUDED.monthly
+RETURNING *;
@@ -3,15 +3,100 @@ INSERT INTO users (
     username,
     hashed_password,
     full_name,
-    email
+    email,
+    email_index
 ) VALUES (
     $1,
     $2,
     $3,
-    $4
+    $4,
+    $5
 ) RETURNING *;   
 
 
 -- name: GetUserByUsername :one
 SELECT * FROM users
-WHERE username = $1;
\ No newline at end of file
+WHERE username = $1;
+
+-- name: GetUserByEmail :one
+-- Store looks users up by the blind index of the email, see store_encryption.go
+SELECT * FROM users
+WHERE email_index = sqlc.arg(email)::varchar;
+
+-- name: UpdateUserPassword :one
+UPDATE users
+SET hashed_password = sqlc.arg(hashed_password),
+    password_changed_at = sqlc.arg(password_changed_at)
+WHERE username = sqlc.arg(username)
+RETURNING *;
+
+-- name: MarkUserEmailVerified :one
+-- only verifies the address the token was sent to, in case the email changed since
+UPDATE users
+SET is_email_verified = true
+WHERE username = $1 AND email_index = $2
+RETURNING *;
+
+-- name: RehashUserPassword :execrows
+-- upgrades the hash of an unchanged password, password_changed_at is kept so sessions stay valid
+UPDATE users
+SET hashed_password = sqlc.arg(new_hashed_password)
+WHERE username = sqlc.arg(username) AND hashed_password = sqlc.arg(old_hashed_password);
+
+-- name: UpdateUser :one
+UPDATE users
+SET full_name = COALESCE(sqlc.narg(full_name), full_name)
+WHERE username = sqlc.arg(username)
+RETURNING *;
+
+-- name: UpdateUserEmail :one
+-- a new address always needs to be verified again
+UPDATE users
+SET email = sqlc.arg(email),
+    email_index = sqlc.arg(email_index),
+    is_email_verified = false
+WHERE username = sqlc.arg(username)
+RETURNING *;
+
+-- name: PseudonymizeUser :one
+-- the empty hash matches no password, and moving password_changed_at invalidates every token
+UPDATE users
+SET full_name = '',
+    email = sqlc.arg(email),
+    email_index = sqlc.arg(email_index),
+    hashed_password = '',
+    is_email_verified = false,
+    password_changed_at = sqlc.arg(password_changed_at),
+    deleted_at = now()
+WHERE username = sqlc.arg(username) AND deleted_at IS NULL
+RETURNING *;
+
+-- name: ListUsersWithoutEmailIndex :many
+-- rows written before email encryption was enabled
+SELECT * FROM users
+WHERE email_index IS NULL
+ORDER BY username
+LIMIT $1;
+
+-- name: UpdateUserPII :exec
+UPDATE users
+SET full_name = sqlc.arg(full_name),
+    email = sqlc.arg(email),
+    email_index = sqlc.arg(email_index)
+WHERE username = sqlc.arg(username);
+
+-- name: UpdateUserRole :execrows
+UPDATE users
+SET role = $2
+WHERE username = $1;
+
+-- name: IsUserErased :one
+SELECT EXISTS (
+    SELECT 1 FROM users
+    WHERE username = $1 AND deleted_at IS NOT NULL
+);
+
+-- name: UpdateUserKYCTier :execrows
+UPDATE users
+SET kyc_tier = $2
+WHERE username = $1;
@@ -0,0 +1,20 @@
+-- name: CreateVerifyEmailToken :one
+INSERT INTO verify_email_tokens (
+    hashed_token,
+    username,
+    email_index,
+    expires_at
+) VALUES (
+    $1, $2, $3, $4
+) RETURNING *;
+
+-- name: UseVerifyEmailToken :one
+UPDATE verify_email_tokens
+SET used_at = now()
+WHERE hashed_token = $1 AND used_at IS NULL AND expires_at > now()
+RETURNING *;
+
+-- name: DeleteVerifyEmailTokens :exec
+-- the tokens keep the email index of the address they were sent to, including a pending new address
+DELETE FROM verify_email_tokens
+WHERE username = $1;
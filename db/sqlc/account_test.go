@@ -13,8 +13,9 @@ import (
 func createRandomAccount(userSuffix string) (Account, User, CreateAccountParams, error) {
 	user, _, _ := createRandomUser(userSuffix)
 	arg := CreateAccountParams{
-		Owner:    user.Username,
-		Balance:  util.RandomMoney(),
+		Owner: user.Username,
+		// enough for the transfers of the tests, transfers can't overdraw the account
+		Balance:  util.RandomInt(100, 1000),
 		Currency: util.RandomCurrency(),
 	}
 
@@ -0,0 +1,63 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/go_backend_misc/util"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomAPIKey(t *testing.T, userSuffix string) (ApiKey, CreateAPIKeyParams) {
+	user, _, err := createRandomUser(userSuffix)
+	require.NoError(t, err)
+
+	arg := CreateAPIKeyParams{
+		ID:        uuid.New(),
+		Owner:     user.Username,
+		Name:      util.RandomString(8),
+		Prefix:    util.RandomString(8),
+		HashedKey: util.HashSecret(util.RandomString(32)),
+		Scopes:    []string{"accounts:read", "transfers:write"},
+		ExpiresAt: sql.NullTime{Time: time.Now().Add(time.Hour), Valid: true},
+	}
+	apiKey, err := testQueries.CreateAPIKey(context.Background(), arg)
+	require.NoError(t, err)
+	return apiKey, arg
+}
+
+func TestCreateAPIKey(t *testing.T) {
+	apiKey, arg := createRandomAPIKey(t, "_test_create_api_key")
+	require.Equal(t, arg.ID, apiKey.ID)
+	require.Equal(t, arg.Owner, apiKey.Owner)
+	require.Equal(t, arg.Prefix, apiKey.Prefix)
+	require.Equal(t, arg.HashedKey, apiKey.HashedKey)
+	require.Equal(t, arg.Scopes, apiKey.Scopes)
+	require.False(t, apiKey.RevokedAt.Valid)
+	require.NotZero(t, apiKey.CreatedAt)
+
+	retrievedAPIKey, err := testQueries.GetAPIKeyByPrefix(context.Background(), apiKey.Prefix)
+	require.NoError(t, err)
+	require.Equal(t, apiKey.ID, retrievedAPIKey.ID)
+}
+
+func TestRevokeAPIKey(t *testing.T) {
+	apiKey, _ := createRandomAPIKey(t, "_test_revoke_api_key")
+
+	revokedAPIKey, err := testQueries.RevokeAPIKey(context.Background(), RevokeAPIKeyParams{
+		ID:    apiKey.ID,
+		Owner: apiKey.Owner,
+	})
+	require.NoError(t, err)
+	require.True(t, revokedAPIKey.RevokedAt.Valid)
+
+	// a revoked key can't be revoked twice
+	_, err = testQueries.RevokeAPIKey(context.Background(), RevokeAPIKeyParams{
+		ID:    apiKey.ID,
+		Owner: apiKey.Owner,
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
@@ -0,0 +1,103 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.25.0
+// source: balance_snapshot.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const createBalanceSnapshots = `-- name: CreateBalanceSnapshots :execrows
+INSERT INTO balance_snapshots (account_id, taken_until, balance)
+SELECT
+    accounts.id,
+    $1::timestamptz,
+    COALESCE(previous.balance, 0) + COALESCE((
+        SELECT SUM(entries.amount) FROM entries
+        WHERE entries.account_id = accounts.id
+            AND entries.created_at >= COALESCE(previous.taken_until, '-infinity'::timestamptz)
+            AND entries.created_at < $1::timestamptz
+    ), 0)
+FROM accounts
+LEFT JOIN LATERAL (
+    SELECT balance_snapshots.balance, balance_snapshots.taken_until FROM balance_snapshots
+    WHERE balance_snapshots.account_id = accounts.id
+        AND balance_snapshots.taken_until < $1::timestamptz
+    ORDER BY balance_snapshots.taken_until DESC
+    LIMIT 1
+) previous ON true
+WHERE accounts.created_at < $1::timestamptz
+ON CONFLICT (account_id, taken_until) DO NOTHING
+`
+
+// snapshots every account created before taken_until, from its previous snapshot and the entries since then
+func (q *Queries) CreateBalanceSnapshots(ctx context.Context, takenUntil time.Time) (int64, error) {
+	result, err := q.db.ExecContext(ctx, createBalanceSnapshots, takenUntil)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getAccountEntriesTotalBetween = `-- name: GetAccountEntriesTotalBetween :one
+SELECT COALESCE(SUM(amount), 0)::bigint FROM entries
+WHERE account_id = $1
+    AND created_at >= $2::timestamptz
+    AND created_at <= $3::timestamptz
+`
+
+type GetAccountEntriesTotalBetweenParams struct {
+	AccountID sql.NullInt64 `json:"account_id"`
+	Since     time.Time     `json:"since"`
+	Until     time.Time     `json:"until"`
+}
+
+// entries created from since, inclusive, to until, inclusive
+func (q *Queries) GetAccountEntriesTotalBetween(ctx context.Context, arg GetAccountEntriesTotalBetweenParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, getAccountEntriesTotalBetween, arg.AccountID, arg.Since, arg.Until)
+	var column_1 int64
+	err := row.Scan(&column_1)
+	return column_1, err
+}
+
+const getLatestBalanceSnapshot = `-- name: GetLatestBalanceSnapshot :one
+SELECT account_id, taken_until, balance, created_at FROM balance_snapshots
+WHERE account_id = $1 AND taken_until <= $2::timestamptz
+ORDER BY taken_until DESC
+LIMIT 1
+`
+
+type GetLatestBalanceSnapshotParams struct {
+	AccountID int64     `json:"account_id"`
+	At        time.Time `json:"at"`
+}
+
+// the latest snapshot of the account that doesn't go past the time
+func (q *Queries) GetLatestBalanceSnapshot(ctx context.Context, arg GetLatestBalanceSnapshotParams) (BalanceSnapshot, error) {
+	row := q.db.QueryRowContext(ctx, getLatestBalanceSnapshot, arg.AccountID, arg.At)
+	var i BalanceSnapshot
+	err := row.Scan(
+		&i.AccountID,
+		&i.TakenUntil,
+		&i.Balance,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getLatestBalanceSnapshotTime = `-- name: GetLatestBalanceSnapshotTime :one
+SELECT taken_until FROM balance_snapshots
+ORDER BY taken_until DESC
+LIMIT 1
+`
+
+func (q *Queries) GetLatestBalanceSnapshotTime(ctx context.Context) (time.Time, error) {
+	row := q.db.QueryRowContext(ctx, getLatestBalanceSnapshotTime)
+	var taken_until time.Time
+	err := row.Scan(&taken_until)
+	return taken_until, err
+}
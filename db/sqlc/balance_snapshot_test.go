@@ -0,0 +1,103 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// recomputeBalance adds up every entry of the account up to at, without snapshots
+func recomputeBalance(t *testing.T, accountID int64, at time.Time) int64 {
+	total, err := testQueries.GetAccountEntriesTotalBetween(context.Background(), GetAccountEntriesTotalBetweenParams{
+		AccountID: Int64ToSqlInt64(accountID),
+		Until:     at,
+	})
+	require.NoError(t, err)
+	return total
+}
+
+func TestAccountBalanceAt(t *testing.T) {
+	fromAccount, toAccount := createScheduledTransferAccounts(t, "_test_balance_at")
+	transfer := func(amount int64) TransferTxResult {
+		result, err := testStore.TransferTx(context.Background(), CreateTransferParams{
+			FromAccountID: Int64ToSqlInt64(fromAccount.ID),
+			ToAccountID:   Int64ToSqlInt64(toAccount.ID),
+			Amount:        amount,
+		})
+		require.NoError(t, err)
+		return result
+	}
+
+	first := transfer(10)
+	second := transfer(20)
+	// the first snapshot has the first transfer only
+	firstSnapshot := second.FromEntry.CreatedAt
+	_, err := testQueries.CreateBalanceSnapshots(context.Background(), firstSnapshot)
+	require.NoError(t, err)
+	third := transfer(5)
+	// the second one builds on the first one
+	secondSnapshot := third.FromEntry.CreatedAt.Add(time.Microsecond)
+	_, err = testQueries.CreateBalanceSnapshots(context.Background(), secondSnapshot)
+	require.NoError(t, err)
+	fourth := transfer(1)
+
+	testCases := []struct {
+		name             string
+		at               time.Time
+		expectedFrom     int64
+		expectedSnapshot *time.Time
+	}{
+		{name: "Before any transfer", at: first.FromEntry.CreatedAt.Add(-time.Microsecond), expectedFrom: 0},
+		{name: "First transfer", at: first.FromEntry.CreatedAt, expectedFrom: -10},
+		{name: "Right before a snapshot", at: firstSnapshot.Add(-time.Microsecond), expectedFrom: -10},
+		{name: "At a snapshot", at: firstSnapshot, expectedFrom: -30, expectedSnapshot: &firstSnapshot},
+		{name: "Between snapshots", at: third.FromEntry.CreatedAt, expectedFrom: -35, expectedSnapshot: &firstSnapshot},
+		{name: "Latest snapshot", at: secondSnapshot, expectedFrom: -35, expectedSnapshot: &secondSnapshot},
+		{name: "After the snapshots", at: fourth.FromEntry.CreatedAt, expectedFrom: -36, expectedSnapshot: &secondSnapshot},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, account := range []Account{fromAccount, toAccount} {
+				balance, err := testStore.AccountBalanceAt(context.Background(), account.ID, tc.at)
+				require.NoError(t, err)
+				// snapshots only make it faster, they never change the balance
+				require.Equal(t, recomputeBalance(t, account.ID, tc.at), balance.Balance)
+
+				if tc.expectedSnapshot == nil {
+					require.Nil(t, balance.SnapshotTakenUntil)
+				} else {
+					require.NotNil(t, balance.SnapshotTakenUntil)
+					require.WithinDuration(t, *tc.expectedSnapshot, *balance.SnapshotTakenUntil, 0)
+				}
+			}
+
+			balance, err := testStore.AccountBalanceAt(context.Background(), fromAccount.ID, tc.at)
+			require.NoError(t, err)
+			require.Equal(t, tc.expectedFrom, balance.Balance)
+		})
+	}
+}
+
+func TestCreateBalanceSnapshotsTwice(t *testing.T) {
+	account, _, _, err := createRandomAccount("_test_balance_snapshots_twice")
+	require.NoError(t, err)
+	// snapshots are only taken of the past, later entries could be written before taken_until
+	takenUntil := account.CreatedAt.Add(time.Microsecond)
+
+	accounts, err := testQueries.CreateBalanceSnapshots(context.Background(), takenUntil)
+	require.NoError(t, err)
+	require.Positive(t, accounts)
+
+	// taking a snapshot again leaves the first one
+	_, err = testQueries.CreateBalanceSnapshots(context.Background(), takenUntil)
+	require.NoError(t, err)
+	snapshot, err := testQueries.GetLatestBalanceSnapshot(context.Background(), GetLatestBalanceSnapshotParams{
+		AccountID: account.ID,
+		At:        takenUntil,
+	})
+	require.NoError(t, err)
+	require.Equal(t, recomputeBalance(t, account.ID, takenUntil), snapshot.Balance)
+}
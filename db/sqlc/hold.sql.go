@@ -0,0 +1,228 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.25.0
+// source: hold.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const createHold = `-- name: CreateHold :one
+INSERT INTO holds (
+    account_id,
+    to_account_id,
+    amount,
+    currency,
+    expires_at,
+    fee
+) VALUES (
+    $1, $2, $3, $4, $5, $6
+) RETURNING id, account_id, to_account_id, amount, currency, status, captured_amount, transfer_id, expires_at, settled_at, created_at, fee
+`
+
+type CreateHoldParams struct {
+	AccountID   int64     `json:"account_id"`
+	ToAccountID int64     `json:"to_account_id"`
+	Amount      int64     `json:"amount"`
+	Currency    string    `json:"currency"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	Fee         int64     `json:"fee"`
+}
+
+func (q *Queries) CreateHold(ctx context.Context, arg CreateHoldParams) (Hold, error) {
+	row := q.db.QueryRowContext(ctx, createHold,
+		arg.AccountID,
+		arg.ToAccountID,
+		arg.Amount,
+		arg.Currency,
+		arg.ExpiresAt,
+		arg.Fee,
+	)
+	var i Hold
+	err := row.Scan(
+		&i.ID,
+		&i.AccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.Currency,
+		&i.Status,
+		&i.CapturedAmount,
+		&i.TransferID,
+		&i.ExpiresAt,
+		&i.SettledAt,
+		&i.CreatedAt,
+		&i.Fee,
+	)
+	return i, err
+}
+
+const getExpiredHoldForUpdate = `-- name: GetExpiredHoldForUpdate :one
+SELECT id, account_id, to_account_id, amount, currency, status, captured_amount, transfer_id, expires_at, settled_at, created_at, fee FROM holds
+WHERE status = 'active' AND expires_at <= now()
+ORDER BY expires_at, id
+LIMIT 1
+FOR NO KEY UPDATE SKIP LOCKED
+`
+
+func (q *Queries) GetExpiredHoldForUpdate(ctx context.Context) (Hold, error) {
+	row := q.db.QueryRowContext(ctx, getExpiredHoldForUpdate)
+	var i Hold
+	err := row.Scan(
+		&i.ID,
+		&i.AccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.Currency,
+		&i.Status,
+		&i.CapturedAmount,
+		&i.TransferID,
+		&i.ExpiresAt,
+		&i.SettledAt,
+		&i.CreatedAt,
+		&i.Fee,
+	)
+	return i, err
+}
+
+const getHold = `-- name: GetHold :one
+SELECT id, account_id, to_account_id, amount, currency, status, captured_amount, transfer_id, expires_at, settled_at, created_at, fee FROM holds
+WHERE id = $1 LIMIT 1
+`
+
+func (q *Queries) GetHold(ctx context.Context, id int64) (Hold, error) {
+	row := q.db.QueryRowContext(ctx, getHold, id)
+	var i Hold
+	err := row.Scan(
+		&i.ID,
+		&i.AccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.Currency,
+		&i.Status,
+		&i.CapturedAmount,
+		&i.TransferID,
+		&i.ExpiresAt,
+		&i.SettledAt,
+		&i.CreatedAt,
+		&i.Fee,
+	)
+	return i, err
+}
+
+const getHoldForUpdate = `-- name: GetHoldForUpdate :one
+SELECT id, account_id, to_account_id, amount, currency, status, captured_amount, transfer_id, expires_at, settled_at, created_at, fee FROM holds
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE
+`
+
+func (q *Queries) GetHoldForUpdate(ctx context.Context, id int64) (Hold, error) {
+	row := q.db.QueryRowContext(ctx, getHoldForUpdate, id)
+	var i Hold
+	err := row.Scan(
+		&i.ID,
+		&i.AccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.Currency,
+		&i.Status,
+		&i.CapturedAmount,
+		&i.TransferID,
+		&i.ExpiresAt,
+		&i.SettledAt,
+		&i.CreatedAt,
+		&i.Fee,
+	)
+	return i, err
+}
+
+const listActiveHoldsByUsernameForUpdate = `-- name: ListActiveHoldsByUsernameForUpdate :many
+SELECT id, account_id, to_account_id, amount, currency, status, captured_amount, transfer_id, expires_at, settled_at, created_at, fee FROM holds
+WHERE status = 'active'
+    AND (account_id IN (SELECT accounts.id FROM accounts WHERE accounts.owner = $1)
+        OR to_account_id IN (SELECT accounts.id FROM accounts WHERE accounts.owner = $1))
+ORDER BY id
+FOR NO KEY UPDATE
+`
+
+// the holds on the accounts of a user and the ones to their accounts
+func (q *Queries) ListActiveHoldsByUsernameForUpdate(ctx context.Context, username string) ([]Hold, error) {
+	rows, err := q.db.QueryContext(ctx, listActiveHoldsByUsernameForUpdate, username)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Hold
+	for rows.Next() {
+		var i Hold
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.ToAccountID,
+			&i.Amount,
+			&i.Currency,
+			&i.Status,
+			&i.CapturedAmount,
+			&i.TransferID,
+			&i.ExpiresAt,
+			&i.SettledAt,
+			&i.CreatedAt,
+			&i.Fee,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const settleHold = `-- name: SettleHold :one
+UPDATE holds
+SET status = $1,
+    captured_amount = $2,
+    transfer_id = $3,
+    settled_at = now()
+WHERE id = $4
+RETURNING id, account_id, to_account_id, amount, currency, status, captured_amount, transfer_id, expires_at, settled_at, created_at, fee
+`
+
+type SettleHoldParams struct {
+	Status         string        `json:"status"`
+	CapturedAmount sql.NullInt64 `json:"captured_amount"`
+	TransferID     sql.NullInt64 `json:"transfer_id"`
+	ID             int64         `json:"id"`
+}
+
+func (q *Queries) SettleHold(ctx context.Context, arg SettleHoldParams) (Hold, error) {
+	row := q.db.QueryRowContext(ctx, settleHold,
+		arg.Status,
+		arg.CapturedAmount,
+		arg.TransferID,
+		arg.ID,
+	)
+	var i Hold
+	err := row.Scan(
+		&i.ID,
+		&i.AccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.Currency,
+		&i.Status,
+		&i.CapturedAmount,
+		&i.TransferID,
+		&i.ExpiresAt,
+		&i.SettledAt,
+		&i.CreatedAt,
+		&i.Fee,
+	)
+	return i, err
+}
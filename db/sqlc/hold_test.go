@@ -0,0 +1,190 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomHold(t *testing.T, userSuffix string, amount int64, expiresAt time.Time) (Hold, Account, Account) {
+	fromAccount, toAccount := createScheduledTransferAccounts(t, userSuffix)
+
+	hold, err := testStore.CreateHoldTx(context.Background(), CreateHoldParams{
+		AccountID:   fromAccount.ID,
+		ToAccountID: toAccount.ID,
+		Amount:      amount,
+		Currency:    fromAccount.Currency,
+		ExpiresAt:   expiresAt,
+	})
+	require.NoError(t, err)
+	require.Equal(t, HoldActive, hold.Status)
+	return hold, fromAccount, toAccount
+}
+
+func TestCreateHoldTx(t *testing.T) {
+	hold, fromAccount, toAccount := createRandomHold(t, "_test_create_hold", 50, time.Now().Add(time.Hour))
+
+	account, err := testQueries.GetAccount(context.Background(), fromAccount.ID)
+	require.NoError(t, err)
+	require.Equal(t, fromAccount.Balance, account.Balance)
+	require.Equal(t, hold.Amount, account.HeldBalance)
+	require.Equal(t, fromAccount.Balance-hold.Amount, account.AvailableBalance)
+
+	// the held amount can't be spent by a transfer
+	_, err = testStore.TransferTx(context.Background(), CreateTransferParams{
+		FromAccountID: Int64ToSqlInt64(fromAccount.ID),
+		ToAccountID:   Int64ToSqlInt64(toAccount.ID),
+		Amount:        account.AvailableBalance + 1,
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	_, err = testStore.CreateHoldTx(context.Background(), CreateHoldParams{
+		AccountID:   fromAccount.ID,
+		ToAccountID: toAccount.ID,
+		Amount:      account.AvailableBalance + 1,
+		Currency:    fromAccount.Currency,
+		ExpiresAt:   time.Now().Add(time.Hour),
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+}
+
+func TestCaptureHoldTx(t *testing.T) {
+	hold, fromAccount, toAccount := createRandomHold(t, "_test_capture_hold", 50, time.Now().Add(time.Hour))
+
+	_, err := testStore.CaptureHoldTx(context.Background(), CaptureHoldTxParams{HoldID: hold.ID, Amount: 51})
+	require.ErrorIs(t, err, ErrHoldCaptureExceeds)
+
+	result, err := testStore.CaptureHoldTx(context.Background(), CaptureHoldTxParams{HoldID: hold.ID, Amount: 30})
+	runTransferTxTests(t, err, &fromAccount, &toAccount, result.TransferTxResult, 30, testStore)
+	require.Equal(t, HoldCaptured, result.Hold.Status)
+	require.Equal(t, int64(30), result.Hold.CapturedAmount.Int64)
+	require.Equal(t, result.Transfer.ID, result.Hold.TransferID.Int64)
+
+	// the rest of the hold is released
+	require.Zero(t, result.FromAccount.HeldBalance)
+	require.Equal(t, fromAccount.Balance-30, result.FromAccount.AvailableBalance)
+
+	_, err = testStore.CaptureHoldTx(context.Background(), CaptureHoldTxParams{HoldID: hold.ID})
+	require.ErrorIs(t, err, ErrHoldNotActive)
+}
+
+func TestCaptureHoldTxConcurrent(t *testing.T) {
+	fromAccount, toAccount := createScheduledTransferAccounts(t, "_test_capture_hold_concurrent")
+	payer, err := testQueries.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    toAccount.Owner,
+		Balance:  1000,
+		Currency: fromAccount.Currency,
+	})
+	require.NoError(t, err)
+
+	n := 5
+	holds := make([]Hold, n)
+	for i := range holds {
+		holds[i], err = testStore.CreateHoldTx(context.Background(), CreateHoldParams{
+			AccountID:   fromAccount.ID,
+			ToAccountID: toAccount.ID,
+			Amount:      1,
+			Currency:    fromAccount.Currency,
+			ExpiresAt:   time.Now().Add(time.Hour),
+			Fee:         testFee,
+		})
+		require.NoError(t, err)
+	}
+
+	// captures lock the fee account with the held account, like the fee-charging transfers
+	// into the held account, in ID order
+	errs := make(chan error, 2*n)
+	for i := 0; i < n; i++ {
+		go func(hold Hold) {
+			_, err := testStore.CaptureHoldTx(context.Background(), CaptureHoldTxParams{HoldID: hold.ID})
+			errs <- err
+		}(holds[i])
+		go func() {
+			_, err := testFeeStore.TransferTx(context.Background(), CreateTransferParams{
+				FromAccountID: Int64ToSqlInt64(payer.ID),
+				ToAccountID:   Int64ToSqlInt64(fromAccount.ID),
+				Amount:        1,
+				Fee:           testFee,
+			})
+			errs <- err
+		}()
+	}
+	for i := 0; i < 2*n; i++ {
+		require.NoError(t, <-errs)
+	}
+
+	account, err := testQueries.GetAccount(context.Background(), fromAccount.ID)
+	require.NoError(t, err)
+	require.Zero(t, account.HeldBalance)
+}
+
+func TestCaptureHoldTxFee(t *testing.T) {
+	fromAccount, toAccount := createScheduledTransferAccounts(t, "_test_capture_hold_fee")
+
+	// the whole available balance can be authorized, fee included
+	hold, err := testStore.CreateHoldTx(context.Background(), CreateHoldParams{
+		AccountID:   fromAccount.ID,
+		ToAccountID: toAccount.ID,
+		Amount:      fromAccount.Balance - testFee,
+		Currency:    fromAccount.Currency,
+		ExpiresAt:   time.Now().Add(time.Hour),
+		Fee:         testFee,
+	})
+	require.NoError(t, err)
+	account, err := testQueries.GetAccount(context.Background(), fromAccount.ID)
+	require.NoError(t, err)
+	require.Equal(t, fromAccount.Balance, account.HeldBalance)
+	require.Zero(t, account.AvailableBalance)
+
+	// the fee held is charged at capture, on top of the captured amount
+	result, err := testStore.CaptureHoldTx(context.Background(), CaptureHoldTxParams{HoldID: hold.ID})
+	require.NoError(t, err)
+	requireFeeEntry(t, result.Transfer, testFee)
+	require.Zero(t, result.FromAccount.Balance)
+	require.Zero(t, result.FromAccount.HeldBalance)
+}
+
+func TestVoidHoldTx(t *testing.T) {
+	hold, fromAccount, _ := createRandomHold(t, "_test_void_hold", 50, time.Now().Add(time.Hour))
+
+	voided, err := testStore.VoidHoldTx(context.Background(), hold.ID)
+	require.NoError(t, err)
+	require.Equal(t, HoldVoided, voided.Status)
+	require.True(t, voided.SettledAt.Valid)
+
+	account, err := testQueries.GetAccount(context.Background(), fromAccount.ID)
+	require.NoError(t, err)
+	require.Equal(t, fromAccount.Balance, account.AvailableBalance)
+
+	_, err = testStore.VoidHoldTx(context.Background(), hold.ID)
+	require.ErrorIs(t, err, ErrHoldNotActive)
+}
+
+func TestExpireHoldTx(t *testing.T) {
+	hold, fromAccount, _ := createRandomHold(t, "_test_expire_hold", 50, time.Now().Add(time.Second))
+	time.Sleep(1100 * time.Millisecond)
+
+	// an expired hold can't be captured even before the expiry job releases it
+	_, err := testStore.CaptureHoldTx(context.Background(), CaptureHoldTxParams{HoldID: hold.ID})
+	require.ErrorIs(t, err, ErrHoldNotActive)
+
+	// other expired holds may be left over from previous runs
+	for i := 0; i < 100; i++ {
+		expired, err := testStore.ExpireHoldTx(context.Background())
+		if err == sql.ErrNoRows {
+			break
+		}
+		require.NoError(t, err)
+		if expired.ID == hold.ID {
+			require.Equal(t, HoldExpired, expired.Status)
+			break
+		}
+	}
+
+	account, err := testQueries.GetAccount(context.Background(), fromAccount.ID)
+	require.NoError(t, err)
+	require.Zero(t, account.HeldBalance)
+}
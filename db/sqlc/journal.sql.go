@@ -0,0 +1,93 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.25.0
+// source: journal.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+)
+
+const createJournal = `-- name: CreateJournal :one
+INSERT INTO journals (
+    kind,
+    transfer_id,
+    description
+) VALUES (
+    $1, $2, $3
+) RETURNING id, kind, transfer_id, description, created_at
+`
+
+type CreateJournalParams struct {
+	Kind        string        `json:"kind"`
+	TransferID  sql.NullInt64 `json:"transfer_id"`
+	Description string        `json:"description"`
+}
+
+func (q *Queries) CreateJournal(ctx context.Context, arg CreateJournalParams) (Journal, error) {
+	row := q.db.QueryRowContext(ctx, createJournal, arg.Kind, arg.TransferID, arg.Description)
+	var i Journal
+	err := row.Scan(
+		&i.ID,
+		&i.Kind,
+		&i.TransferID,
+		&i.Description,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getJournal = `-- name: GetJournal :one
+SELECT id, kind, transfer_id, description, created_at FROM journals
+WHERE id = $1 LIMIT 1
+`
+
+func (q *Queries) GetJournal(ctx context.Context, id int64) (Journal, error) {
+	row := q.db.QueryRowContext(ctx, getJournal, id)
+	var i Journal
+	err := row.Scan(
+		&i.ID,
+		&i.Kind,
+		&i.TransferID,
+		&i.Description,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const listJournalEntries = `-- name: ListJournalEntries :many
+SELECT id, account_id, amount, created_at, journal_id FROM entries
+WHERE journal_id = $1
+ORDER BY id
+`
+
+func (q *Queries) ListJournalEntries(ctx context.Context, journalID sql.NullInt64) ([]Entry, error) {
+	rows, err := q.db.QueryContext(ctx, listJournalEntries, journalID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Entry
+	for rows.Next() {
+		var i Entry
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.JournalID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,149 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func getSystemAccount(t *testing.T, purpose string, account Account) Account {
+	id, err := testQueries.GetSystemAccountIDForAccount(context.Background(), GetSystemAccountIDForAccountParams{
+		Purpose:   purpose,
+		AccountID: account.ID,
+	})
+	require.NoError(t, err)
+	systemAccount, err := testQueries.GetAccount(context.Background(), id)
+	require.NoError(t, err)
+	require.Equal(t, AccountKindSystem, systemAccount.Kind)
+	require.Equal(t, account.Currency, systemAccount.Currency)
+	return systemAccount
+}
+
+func TestTransferTxJournal(t *testing.T) {
+	fromAccount, toAccount := createScheduledTransferAccounts(t, "_test_transfer_journal")
+
+	result, err := testStore.TransferTx(context.Background(), CreateTransferParams{
+		FromAccountID: Int64ToSqlInt64(fromAccount.ID),
+		ToAccountID:   Int64ToSqlInt64(toAccount.ID),
+		Amount:        10,
+	})
+	require.NoError(t, err)
+	require.Equal(t, JournalTransfer, result.Journal.Kind)
+	require.Equal(t, result.Transfer.ID, result.Journal.TransferID.Int64)
+
+	entries, err := testQueries.ListJournalEntries(context.Background(), Int64ToSqlInt64(result.Journal.ID))
+	require.NoError(t, err)
+	require.Equal(t, []Entry{result.FromEntry, result.ToEntry}, entries)
+}
+
+func TestTransferTxToSystemAccount(t *testing.T) {
+	account, _, _, err := createRandomAccount("_test_transfer_to_system")
+	require.NoError(t, err)
+	feeAccount := getSystemAccount(t, SystemAccountFees, account)
+
+	_, err = testStore.TransferTx(context.Background(), CreateTransferParams{
+		FromAccountID: Int64ToSqlInt64(account.ID),
+		ToAccountID:   Int64ToSqlInt64(feeAccount.ID),
+		Amount:        1,
+	})
+	require.ErrorIs(t, err, ErrInvalidDestination)
+
+	unchanged, err := testQueries.GetAccount(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, account.Balance, unchanged.Balance)
+}
+
+func TestPostJournalTx(t *testing.T) {
+	account, _, _, err := createRandomAccount("_test_post_journal")
+	require.NoError(t, err)
+	suspense := getSystemAccount(t, SystemAccountSuspense, account)
+
+	// system accounts can go below zero
+	result, err := testStore.PostJournalTx(context.Background(), PostJournalTxParams{
+		Kind:        JournalAdjustment,
+		Description: "credit under investigation",
+		Postings: []Posting{
+			{AccountID: suspense.ID, Amount: -5},
+			{AccountID: account.ID, Amount: 5},
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Entries, 2)
+	require.Equal(t, account.Balance+5, result.Accounts[account.ID].Balance)
+	require.Equal(t, suspense.Balance-5, result.Accounts[suspense.ID].Balance)
+
+	// user accounts can't
+	_, err = testStore.PostJournalTx(context.Background(), PostJournalTxParams{
+		Kind: JournalAdjustment,
+		Postings: []Posting{
+			{AccountID: account.ID, Amount: -(account.Balance + 6)},
+			{AccountID: suspense.ID, Amount: account.Balance + 6},
+		},
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+}
+
+func TestPostJournalTxUnbalanced(t *testing.T) {
+	account, _, _, err := createRandomAccount("_test_post_journal_unbalanced")
+	require.NoError(t, err)
+	suspense := getSystemAccount(t, SystemAccountSuspense, account)
+
+	_, err = testStore.PostJournalTx(context.Background(), PostJournalTxParams{
+		Kind: JournalAdjustment,
+		Postings: []Posting{
+			{AccountID: suspense.ID, Amount: -5},
+			{AccountID: account.ID, Amount: 4},
+		},
+	})
+	require.ErrorIs(t, err, ErrUnbalancedJournal)
+
+	unchanged, err := testQueries.GetAccount(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, account.Balance, unchanged.Balance)
+}
+
+func TestPostJournalTxPerCurrency(t *testing.T) {
+	accounts := createUserAccounts(t, "_test_post_journal_fx", "USD", "EUR")
+	usdAccount, eurAccount := accounts[0], accounts[1]
+	usdFX := getSystemAccount(t, SystemAccountFX, usdAccount)
+	eurFX := getSystemAccount(t, SystemAccountFX, eurAccount)
+
+	// an exchange balances in each currency through the FX accounts
+	_, err := testStore.PostJournalTx(context.Background(), PostJournalTxParams{
+		Kind: JournalAdjustment,
+		Postings: []Posting{
+			{AccountID: usdAccount.ID, Amount: -100},
+			{AccountID: usdFX.ID, Amount: 100},
+			{AccountID: eurFX.ID, Amount: -90},
+			{AccountID: eurAccount.ID, Amount: 90},
+		},
+	})
+	require.NoError(t, err)
+
+	// the same amounts across currencies sum to zero but don't balance
+	_, err = testStore.PostJournalTx(context.Background(), PostJournalTxParams{
+		Kind: JournalAdjustment,
+		Postings: []Posting{
+			{AccountID: usdAccount.ID, Amount: -10},
+			{AccountID: eurAccount.ID, Amount: 10},
+		},
+	})
+	require.ErrorIs(t, err, ErrUnbalancedJournal)
+}
+
+func createUserAccounts(t *testing.T, userSuffix string, currencies ...string) []Account {
+	user, _, err := createRandomUser(userSuffix)
+	require.NoError(t, err)
+
+	accounts := make([]Account, len(currencies))
+	for i, currency := range currencies {
+		accounts[i], err = testQueries.CreateAccount(context.Background(), CreateAccountParams{
+			Owner:    user.Username,
+			Balance:  1000,
+			Currency: currency,
+		})
+		require.NoError(t, err)
+	}
+	return accounts
+}
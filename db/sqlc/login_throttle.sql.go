@@ -0,0 +1,65 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.25.0
+// source: login_throttle.sql
+
+package db
+
+import (
+	"context"
+	"time"
+)
+
+const deleteLoginThrottle = `-- name: DeleteLoginThrottle :execrows
+DELETE FROM login_throttles
+WHERE key = $1
+`
+
+func (q *Queries) DeleteLoginThrottle(ctx context.Context, key string) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteLoginThrottle, key)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getLoginThrottle = `-- name: GetLoginThrottle :one
+SELECT key, failed_count, last_failed_at FROM login_throttles
+WHERE key = $1 LIMIT 1
+`
+
+func (q *Queries) GetLoginThrottle(ctx context.Context, key string) (LoginThrottle, error) {
+	row := q.db.QueryRowContext(ctx, getLoginThrottle, key)
+	var i LoginThrottle
+	err := row.Scan(&i.Key, &i.FailedCount, &i.LastFailedAt)
+	return i, err
+}
+
+const recordLoginFailure = `-- name: RecordLoginFailure :one
+INSERT INTO login_throttles (
+    key,
+    failed_count
+) VALUES (
+    $1, 1
+)
+ON CONFLICT (key) DO UPDATE
+SET failed_count = CASE
+        WHEN login_throttles.last_failed_at < $2 THEN 1
+        ELSE login_throttles.failed_count + 1
+    END,
+    last_failed_at = now()
+RETURNING key, failed_count, last_failed_at
+`
+
+type RecordLoginFailureParams struct {
+	Key         string    `json:"key"`
+	ResetBefore time.Time `json:"reset_before"`
+}
+
+// failures older than reset_before are forgotten and the count starts again
+func (q *Queries) RecordLoginFailure(ctx context.Context, arg RecordLoginFailureParams) (LoginThrottle, error) {
+	row := q.db.QueryRowContext(ctx, recordLoginFailure, arg.Key, arg.ResetBefore)
+	var i LoginThrottle
+	err := row.Scan(&i.Key, &i.FailedCount, &i.LastFailedAt)
+	return i, err
+}
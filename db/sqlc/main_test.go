@@ -6,12 +6,20 @@ import (
 	"os"
 	"testing"
 
+	"github.com/go_backend_misc/fee"
 	// requires the _ identifier to avoid the "imported and not used" error
 	"github.com/go_backend_misc/util"
 	_ "github.com/lib/pq"
 )
 
 var testQueries *Queries
+var testStore Store
+
+// testFeeStore charges testFee on every transfer it posts
+var testFeeStore Store
+
+const testFee int64 = 3
+
 var testDB *sql.DB
 var testUser User
 
@@ -26,6 +34,12 @@ func TestMain(m *testing.M) {
 	}
 
 	testQueries = New(testDB)
+	fieldEncryptor, err := util.NewFieldEncryptor(config)
+	if err != nil {
+		log.Fatalf("cannot create field encryptor: %v", err)
+	}
+	testStore = NewStore(testDB, fieldEncryptor, nil)
+	testFeeStore = NewStore(testDB, fieldEncryptor, &fee.Schedule{Default: &fee.Rule{Flat: testFee}})
 
 	// to reuse a user
 	// testUser, _, _ = createRandomUser("_test_create_account")
@@ -0,0 +1,270 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.25.0
+// source: mfa.sql
+
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const consumeMFAChallenge = `-- name: ConsumeMFAChallenge :one
+UPDATE mfa_challenges
+SET consumed_at = now()
+WHERE id = $1 AND consumed_at IS NULL AND expires_at > now()
+RETURNING id, username, failed_attempts, expires_at, consumed_at, created_at
+`
+
+func (q *Queries) ConsumeMFAChallenge(ctx context.Context, id uuid.UUID) (MfaChallenge, error) {
+	row := q.db.QueryRowContext(ctx, consumeMFAChallenge, id)
+	var i MfaChallenge
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.FailedAttempts,
+		&i.ExpiresAt,
+		&i.ConsumedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createMFAChallenge = `-- name: CreateMFAChallenge :one
+INSERT INTO mfa_challenges (
+    id,
+    username,
+    expires_at
+) VALUES (
+    $1, $2, $3
+) RETURNING id, username, failed_attempts, expires_at, consumed_at, created_at
+`
+
+type CreateMFAChallengeParams struct {
+	ID        uuid.UUID `json:"id"`
+	Username  string    `json:"username"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func (q *Queries) CreateMFAChallenge(ctx context.Context, arg CreateMFAChallengeParams) (MfaChallenge, error) {
+	row := q.db.QueryRowContext(ctx, createMFAChallenge, arg.ID, arg.Username, arg.ExpiresAt)
+	var i MfaChallenge
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.FailedAttempts,
+		&i.ExpiresAt,
+		&i.ConsumedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createMFARecoveryCode = `-- name: CreateMFARecoveryCode :one
+INSERT INTO mfa_recovery_codes (
+    username,
+    hashed_code
+) VALUES (
+    $1, $2
+) RETURNING id, username, hashed_code, used_at, created_at
+`
+
+type CreateMFARecoveryCodeParams struct {
+	Username   string `json:"username"`
+	HashedCode string `json:"hashed_code"`
+}
+
+func (q *Queries) CreateMFARecoveryCode(ctx context.Context, arg CreateMFARecoveryCodeParams) (MfaRecoveryCode, error) {
+	row := q.db.QueryRowContext(ctx, createMFARecoveryCode, arg.Username, arg.HashedCode)
+	var i MfaRecoveryCode
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.HashedCode,
+		&i.UsedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const deleteMFARecoveryCodes = `-- name: DeleteMFARecoveryCodes :exec
+DELETE FROM mfa_recovery_codes
+WHERE username = $1
+`
+
+func (q *Queries) DeleteMFARecoveryCodes(ctx context.Context, username string) error {
+	_, err := q.db.ExecContext(ctx, deleteMFARecoveryCodes, username)
+	return err
+}
+
+const deleteUserMFA = `-- name: DeleteUserMFA :exec
+DELETE FROM user_mfa
+WHERE username = $1
+`
+
+func (q *Queries) DeleteUserMFA(ctx context.Context, username string) error {
+	_, err := q.db.ExecContext(ctx, deleteUserMFA, username)
+	return err
+}
+
+const enableUserMFA = `-- name: EnableUserMFA :one
+UPDATE user_mfa
+SET enabled = true,
+    enabled_at = now()
+WHERE username = $1
+RETURNING username, encrypted_secret, enabled, last_used_step, enabled_at, created_at
+`
+
+func (q *Queries) EnableUserMFA(ctx context.Context, username string) (UserMfa, error) {
+	row := q.db.QueryRowContext(ctx, enableUserMFA, username)
+	var i UserMfa
+	err := row.Scan(
+		&i.Username,
+		&i.EncryptedSecret,
+		&i.Enabled,
+		&i.LastUsedStep,
+		&i.EnabledAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getMFAChallenge = `-- name: GetMFAChallenge :one
+SELECT id, username, failed_attempts, expires_at, consumed_at, created_at FROM mfa_challenges
+WHERE id = $1 LIMIT 1
+`
+
+func (q *Queries) GetMFAChallenge(ctx context.Context, id uuid.UUID) (MfaChallenge, error) {
+	row := q.db.QueryRowContext(ctx, getMFAChallenge, id)
+	var i MfaChallenge
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.FailedAttempts,
+		&i.ExpiresAt,
+		&i.ConsumedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getUserMFA = `-- name: GetUserMFA :one
+SELECT username, encrypted_secret, enabled, last_used_step, enabled_at, created_at FROM user_mfa
+WHERE username = $1 LIMIT 1
+`
+
+func (q *Queries) GetUserMFA(ctx context.Context, username string) (UserMfa, error) {
+	row := q.db.QueryRowContext(ctx, getUserMFA, username)
+	var i UserMfa
+	err := row.Scan(
+		&i.Username,
+		&i.EncryptedSecret,
+		&i.Enabled,
+		&i.LastUsedStep,
+		&i.EnabledAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const incrementMFAChallengeAttempts = `-- name: IncrementMFAChallengeAttempts :one
+UPDATE mfa_challenges
+SET failed_attempts = failed_attempts + 1
+WHERE id = $1
+RETURNING id, username, failed_attempts, expires_at, consumed_at, created_at
+`
+
+func (q *Queries) IncrementMFAChallengeAttempts(ctx context.Context, id uuid.UUID) (MfaChallenge, error) {
+	row := q.db.QueryRowContext(ctx, incrementMFAChallengeAttempts, id)
+	var i MfaChallenge
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.FailedAttempts,
+		&i.ExpiresAt,
+		&i.ConsumedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const updateMFALastUsedStep = `-- name: UpdateMFALastUsedStep :execrows
+UPDATE user_mfa
+SET last_used_step = $1
+WHERE username = $2 AND last_used_step < $1
+`
+
+type UpdateMFALastUsedStepParams struct {
+	Step     int64  `json:"step"`
+	Username string `json:"username"`
+}
+
+func (q *Queries) UpdateMFALastUsedStep(ctx context.Context, arg UpdateMFALastUsedStepParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, updateMFALastUsedStep, arg.Step, arg.Username)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const upsertUserMFA = `-- name: UpsertUserMFA :one
+INSERT INTO user_mfa (
+    username,
+    encrypted_secret
+) VALUES (
+    $1, $2
+)
+ON CONFLICT (username) DO UPDATE
+SET encrypted_secret = EXCLUDED.encrypted_secret,
+    enabled = false,
+    last_used_step = 0,
+    enabled_at = NULL
+RETURNING username, encrypted_secret, enabled, last_used_step, enabled_at, created_at
+`
+
+type UpsertUserMFAParams struct {
+	Username        string `json:"username"`
+	EncryptedSecret string `json:"encrypted_secret"`
+}
+
+func (q *Queries) UpsertUserMFA(ctx context.Context, arg UpsertUserMFAParams) (UserMfa, error) {
+	row := q.db.QueryRowContext(ctx, upsertUserMFA, arg.Username, arg.EncryptedSecret)
+	var i UserMfa
+	err := row.Scan(
+		&i.Username,
+		&i.EncryptedSecret,
+		&i.Enabled,
+		&i.LastUsedStep,
+		&i.EnabledAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const useMFARecoveryCode = `-- name: UseMFARecoveryCode :one
+UPDATE mfa_recovery_codes
+SET used_at = now()
+WHERE username = $1 AND hashed_code = $2 AND used_at IS NULL
+RETURNING id, username, hashed_code, used_at, created_at
+`
+
+type UseMFARecoveryCodeParams struct {
+	Username   string `json:"username"`
+	HashedCode string `json:"hashed_code"`
+}
+
+func (q *Queries) UseMFARecoveryCode(ctx context.Context, arg UseMFARecoveryCodeParams) (MfaRecoveryCode, error) {
+	row := q.db.QueryRowContext(ctx, useMFARecoveryCode, arg.Username, arg.HashedCode)
+	var i MfaRecoveryCode
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.HashedCode,
+		&i.UsedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
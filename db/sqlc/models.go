@@ -7,14 +7,56 @@ package db
 import (
 	"database/sql"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 type Account struct {
-	ID        int64     `json:"id"`
-	Owner     string    `json:"owner"`
-	Balance   int64     `json:"balance"`
-	Currency  string    `json:"currency"`
-	CreatedAt time.Time `json:"created_at"`
+	ID               int64     `json:"id"`
+	Owner            string    `json:"owner"`
+	Balance          int64     `json:"balance"`
+	Currency         string    `json:"currency"`
+	CreatedAt        time.Time `json:"created_at"`
+	HeldBalance      int64     `json:"held_balance"`
+	AvailableBalance int64     `json:"available_balance"`
+	Kind             string    `json:"kind"`
+}
+
+type AccountTransferLimit struct {
+	AccountID      int64         `json:"account_id"`
+	PerTransaction sql.NullInt64 `json:"per_transaction"`
+	Daily          sql.NullInt64 `json:"daily"`
+	Monthly        sql.NullInt64 `json:"monthly"`
+	CreatedAt      time.Time     `json:"created_at"`
+}
+
+type ApiKey struct {
+	ID        uuid.UUID    `json:"id"`
+	Owner     string       `json:"owner"`
+	Name      string       `json:"name"`
+	Prefix    string       `json:"prefix"`
+	HashedKey string       `json:"hashed_key"`
+	Scopes    []string     `json:"scopes"`
+	ExpiresAt sql.NullTime `json:"expires_at"`
+	RevokedAt sql.NullTime `json:"revoked_at"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+type BalanceSnapshot struct {
+	AccountID  int64     `json:"account_id"`
+	TakenUntil time.Time `json:"taken_until"`
+	Balance    int64     `json:"balance"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type Currency struct {
+	Code              string    `json:"code"`
+	Exponent          int32     `json:"exponent"`
+	Symbol            string    `json:"symbol"`
+	Enabled           bool      `json:"enabled"`
+	MinTransferAmount int64     `json:"min_transfer_amount"`
+	MaxTransferAmount int64     `json:"max_transfer_amount"`
+	CreatedAt         time.Time `json:"created_at"`
 }
 
 type Entry struct {
@@ -22,21 +64,197 @@ type Entry struct {
 	AccountID sql.NullInt64 `json:"account_id"`
 	Amount    int64         `json:"amount"`
 	CreatedAt time.Time     `json:"created_at"`
+	JournalID sql.NullInt64 `json:"journal_id"`
+}
+
+type Hold struct {
+	ID             int64         `json:"id"`
+	AccountID      int64         `json:"account_id"`
+	ToAccountID    int64         `json:"to_account_id"`
+	Amount         int64         `json:"amount"`
+	Currency       string        `json:"currency"`
+	Status         string        `json:"status"`
+	CapturedAmount sql.NullInt64 `json:"captured_amount"`
+	TransferID     sql.NullInt64 `json:"transfer_id"`
+	ExpiresAt      time.Time     `json:"expires_at"`
+	SettledAt      sql.NullTime  `json:"settled_at"`
+	CreatedAt      time.Time     `json:"created_at"`
+	Fee            int64         `json:"fee"`
+}
+
+type Journal struct {
+	ID          int64         `json:"id"`
+	Kind        string        `json:"kind"`
+	TransferID  sql.NullInt64 `json:"transfer_id"`
+	Description string        `json:"description"`
+	CreatedAt   time.Time     `json:"created_at"`
+}
+
+type LoginThrottle struct {
+	Key          string    `json:"key"`
+	FailedCount  int32     `json:"failed_count"`
+	LastFailedAt time.Time `json:"last_failed_at"`
+}
+
+type MfaChallenge struct {
+	ID             uuid.UUID    `json:"id"`
+	Username       string       `json:"username"`
+	FailedAttempts int32        `json:"failed_attempts"`
+	ExpiresAt      time.Time    `json:"expires_at"`
+	ConsumedAt     sql.NullTime `json:"consumed_at"`
+	CreatedAt      time.Time    `json:"created_at"`
+}
+
+type MfaRecoveryCode struct {
+	ID         int64        `json:"id"`
+	Username   string       `json:"username"`
+	HashedCode string       `json:"hashed_code"`
+	UsedAt     sql.NullTime `json:"used_at"`
+	CreatedAt  time.Time    `json:"created_at"`
+}
+
+type PasswordResetToken struct {
+	HashedToken string       `json:"hashed_token"`
+	Username    string       `json:"username"`
+	ExpiresAt   time.Time    `json:"expires_at"`
+	UsedAt      sql.NullTime `json:"used_at"`
+	CreatedAt   time.Time    `json:"created_at"`
+}
+
+type RateLimitBucket struct {
+	Key       string    `json:"key"`
+	Tokens    float64   `json:"tokens"`
+	Allowed   bool      `json:"allowed"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type RiskReview struct {
+	ID            int64          `json:"id"`
+	Owner         string         `json:"owner"`
+	FromAccountID int64          `json:"from_account_id"`
+	ToAccountID   int64          `json:"to_account_id"`
+	Amount        int64          `json:"amount"`
+	Fee           int64          `json:"fee"`
+	Currency      string         `json:"currency"`
+	Decision      string         `json:"decision"`
+	Reasons       []string       `json:"reasons"`
+	Status        string         `json:"status"`
+	TransferID    sql.NullInt64  `json:"transfer_id"`
+	ReviewedBy    sql.NullString `json:"reviewed_by"`
+	ReviewedAt    sql.NullTime   `json:"reviewed_at"`
+	CreatedAt     time.Time      `json:"created_at"`
+}
+
+type ScheduledTransfer struct {
+	ID            int64          `json:"id"`
+	Owner         string         `json:"owner"`
+	FromAccountID int64          `json:"from_account_id"`
+	ToAccountID   int64          `json:"to_account_id"`
+	Amount        int64          `json:"amount"`
+	Currency      string         `json:"currency"`
+	ExecuteAt     time.Time      `json:"execute_at"`
+	Status        string         `json:"status"`
+	Attempts      int32          `json:"attempts"`
+	NextAttemptAt time.Time      `json:"next_attempt_at"`
+	LastError     sql.NullString `json:"last_error"`
+	TransferID    sql.NullInt64  `json:"transfer_id"`
+	ExecutedAt    sql.NullTime   `json:"executed_at"`
+	CreatedAt     time.Time      `json:"created_at"`
+}
+
+type ScreeningMatch struct {
+	ID         int64          `json:"id"`
+	Username   string         `json:"username"`
+	EntryIds   []string       `json:"entry_ids"`
+	Matches    []string       `json:"matches"`
+	Score      float64        `json:"score"`
+	Status     string         `json:"status"`
+	ReviewedBy sql.NullString `json:"reviewed_by"`
+	ReviewedAt sql.NullTime   `json:"reviewed_at"`
+	CreatedAt  time.Time      `json:"created_at"`
+	NameIndex  sql.NullString `json:"name_index"`
+}
+
+type StandingOrder struct {
+	ID            int64     `json:"id"`
+	Owner         string    `json:"owner"`
+	FromAccountID int64     `json:"from_account_id"`
+	ToAccountID   int64     `json:"to_account_id"`
+	Amount        int64     `json:"amount"`
+	Currency      string    `json:"currency"`
+	Rrule         string    `json:"rrule"`
+	StartsAt      time.Time `json:"starts_at"`
+	CatchUp       string    `json:"catch_up"`
+	Status        string    `json:"status"`
+	NextRunAt     time.Time `json:"next_run_at"`
+	RunCount      int32     `json:"run_count"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type StandingOrderRun struct {
+	ID              int64          `json:"id"`
+	StandingOrderID int64          `json:"standing_order_id"`
+	ScheduledFor    time.Time      `json:"scheduled_for"`
+	Status          string         `json:"status"`
+	TransferID      sql.NullInt64  `json:"transfer_id"`
+	Error           sql.NullString `json:"error"`
+	CreatedAt       time.Time      `json:"created_at"`
+}
+
+type SystemAccount struct {
+	Purpose   string    `json:"purpose"`
+	Currency  string    `json:"currency"`
+	AccountID int64     `json:"account_id"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
 type Transfer struct {
-	ID            int64         `json:"id"`
-	FromAccountID sql.NullInt64 `json:"from_account_id"`
-	ToAccountID   sql.NullInt64 `json:"to_account_id"`
-	Amount        int64         `json:"amount"`
-	CreatedAt     time.Time     `json:"created_at"`
+	ID                 int64         `json:"id"`
+	FromAccountID      sql.NullInt64 `json:"from_account_id"`
+	ToAccountID        sql.NullInt64 `json:"to_account_id"`
+	Amount             int64         `json:"amount"`
+	CreatedAt          time.Time     `json:"created_at"`
+	ReversesTransferID sql.NullInt64 `json:"reverses_transfer_id"`
+	Fee                int64         `json:"fee"`
+}
+
+type TransferLimit struct {
+	KycTier        int32     `json:"kyc_tier"`
+	Currency       string    `json:"currency"`
+	PerTransaction int64     `json:"per_transaction"`
+	Daily          int64     `json:"daily"`
+	Monthly        int64     `json:"monthly"`
+	CreatedAt      time.Time `json:"created_at"`
 }
 
 type User struct {
-	Username          string    `json:"username"`
-	HashedPassword    string    `json:"hashed_password"`
-	FullName          string    `json:"full_name"`
-	Email             string    `json:"email"`
-	PasswordChangedAt time.Time `json:"password_changed_at"`
-	CreatedAt         time.Time `json:"created_at"`
+	Username          string         `json:"username"`
+	HashedPassword    string         `json:"hashed_password"`
+	FullName          string         `json:"full_name"`
+	Email             string         `json:"email"`
+	PasswordChangedAt time.Time      `json:"password_changed_at"`
+	CreatedAt         time.Time      `json:"created_at"`
+	IsEmailVerified   bool           `json:"is_email_verified"`
+	DeletedAt         sql.NullTime   `json:"deleted_at"`
+	EmailIndex        sql.NullString `json:"email_index"`
+	Role              string         `json:"role"`
+	KycTier           int32          `json:"kyc_tier"`
+}
+
+type UserMfa struct {
+	Username        string       `json:"username"`
+	EncryptedSecret string       `json:"encrypted_secret"`
+	Enabled         bool         `json:"enabled"`
+	LastUsedStep    int64        `json:"last_used_step"`
+	EnabledAt       sql.NullTime `json:"enabled_at"`
+	CreatedAt       time.Time    `json:"created_at"`
+}
+
+type VerifyEmailToken struct {
+	HashedToken string       `json:"hashed_token"`
+	Username    string       `json:"username"`
+	EmailIndex  string       `json:"email_index"`
+	ExpiresAt   time.Time    `json:"expires_at"`
+	UsedAt      sql.NullTime `json:"used_at"`
+	CreatedAt   time.Time    `json:"created_at"`
 }
@@ -6,25 +6,163 @@ package db
 
 import (
 	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
 )
 
 type Querier interface {
 	AddAccountBalance(ctx context.Context, arg AddAccountBalanceParams) (Account, error)
+	AddAccountHeldBalance(ctx context.Context, arg AddAccountHeldBalanceParams) (Account, error)
+	AdvanceStandingOrder(ctx context.Context, arg AdvanceStandingOrderParams) (StandingOrder, error)
+	CancelScheduledTransfer(ctx context.Context, arg CancelScheduledTransferParams) (ScheduledTransfer, error)
+	// the pending transfers of an erased user and the ones to their accounts
+	CancelScheduledTransfersByUsername(ctx context.Context, username string) (int64, error)
+	CancelStandingOrder(ctx context.Context, arg CancelStandingOrderParams) (StandingOrder, error)
+	// the orders of an erased user and the ones to their accounts
+	CancelStandingOrdersByUsername(ctx context.Context, username string) (int64, error)
+	CompleteScheduledTransfer(ctx context.Context, arg CompleteScheduledTransferParams) (ScheduledTransfer, error)
+	ConsumeMFAChallenge(ctx context.Context, id uuid.UUID) (MfaChallenge, error)
+	// the transfers sent from the account since then, those of round amounts when round_to isn't 0
+	CountTransfersSince(ctx context.Context, arg CountTransfersSinceParams) (int64, error)
+	CreateAPIKey(ctx context.Context, arg CreateAPIKeyParams) (ApiKey, error)
 	CreateAccount(ctx context.Context, arg CreateAccountParams) (Account, error)
+	// snapshots every account created before taken_until, from its previous snapshot and the entries since then
+	CreateBalanceSnapshots(ctx context.Context, takenUntil time.Time) (int64, error)
 	CreateEntry(ctx context.Context, arg CreateEntryParams) (Entry, error)
+	CreateHold(ctx context.Context, arg CreateHoldParams) (Hold, error)
+	CreateJournal(ctx context.Context, arg CreateJournalParams) (Journal, error)
+	CreateMFAChallenge(ctx context.Context, arg CreateMFAChallengeParams) (MfaChallenge, error)
+	CreateMFARecoveryCode(ctx context.Context, arg CreateMFARecoveryCodeParams) (MfaRecoveryCode, error)
+	CreatePasswordResetToken(ctx context.Context, arg CreatePasswordResetTokenParams) (PasswordResetToken, error)
+	CreateRiskReview(ctx context.Context, arg CreateRiskReviewParams) (RiskReview, error)
+	CreateScheduledTransfer(ctx context.Context, arg CreateScheduledTransferParams) (ScheduledTransfer, error)
+	// full_name is the name screened, the store keeps its blind index
+	CreateScreeningMatch(ctx context.Context, arg CreateScreeningMatchParams) (ScreeningMatch, error)
+	CreateStandingOrder(ctx context.Context, arg CreateStandingOrderParams) (StandingOrder, error)
+	CreateStandingOrderRun(ctx context.Context, arg CreateStandingOrderRunParams) (StandingOrderRun, error)
 	CreateTransfer(ctx context.Context, arg CreateTransferParams) (Transfer, error)
 	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
+	CreateVerifyEmailToken(ctx context.Context, arg CreateVerifyEmailTokenParams) (VerifyEmailToken, error)
 	DeleteAccount(ctx context.Context, id int64) error
+	DeleteIdleRateLimitBuckets(ctx context.Context, idleSince time.Time) (int64, error)
+	DeleteLoginThrottle(ctx context.Context, key string) (int64, error)
+	DeleteMFARecoveryCodes(ctx context.Context, username string) error
+	DeleteUserMFA(ctx context.Context, username string) error
+	// the tokens keep the email index of the address they were sent to, including a pending new address
+	DeleteVerifyEmailTokens(ctx context.Context, username string) error
+	EnableUserMFA(ctx context.Context, username string) (UserMfa, error)
+	GetAPIKeyByPrefix(ctx context.Context, prefix string) (ApiKey, error)
 	GetAccount(ctx context.Context, id int64) (Account, error)
+	GetAccountEntriesTotal(ctx context.Context, accountID sql.NullInt64) (int64, error)
+	// entries created from since, inclusive, to until, inclusive
+	GetAccountEntriesTotalBetween(ctx context.Context, arg GetAccountEntriesTotalBetweenParams) (int64, error)
 	GetAccountForUpdate(ctx context.Context, id int64) (Account, error)
+	// the limits of the account: its overrides, or else the defaults of the KYC tier of its owner in its currency
+	GetAccountTransferLimits(ctx context.Context, accountID int64) (GetAccountTransferLimitsRow, error)
+	// SKIP LOCKED lets several executors work through the due rows without waiting on each other
+	GetDueScheduledTransferForUpdate(ctx context.Context) (ScheduledTransfer, error)
+	GetDueStandingOrderForUpdate(ctx context.Context, now time.Time) (StandingOrder, error)
 	GetEntry(ctx context.Context, id int64) (Entry, error)
+	GetExpiredHoldForUpdate(ctx context.Context) (Hold, error)
+	GetHold(ctx context.Context, id int64) (Hold, error)
+	GetHoldForUpdate(ctx context.Context, id int64) (Hold, error)
+	GetJournal(ctx context.Context, id int64) (Journal, error)
+	// the latest snapshot of the account that doesn't go past the time
+	GetLatestBalanceSnapshot(ctx context.Context, arg GetLatestBalanceSnapshotParams) (BalanceSnapshot, error)
+	GetLatestBalanceSnapshotTime(ctx context.Context) (time.Time, error)
+	GetLoginThrottle(ctx context.Context, key string) (LoginThrottle, error)
+	GetMFAChallenge(ctx context.Context, id uuid.UUID) (MfaChallenge, error)
+	GetReversedAmount(ctx context.Context, reversesTransferID sql.NullInt64) (int64, error)
+	GetRiskReview(ctx context.Context, id int64) (RiskReview, error)
+	// locks the review so it's approved or rejected once
+	GetRiskReviewForUpdate(ctx context.Context, id int64) (RiskReview, error)
+	GetScheduledTransfer(ctx context.Context, id int64) (ScheduledTransfer, error)
+	GetScreeningMatch(ctx context.Context, id int64) (ScreeningMatch, error)
+	GetStandingOrder(ctx context.Context, id int64) (StandingOrder, error)
+	GetStandingOrderForUpdate(ctx context.Context, id int64) (StandingOrder, error)
+	// the system account for the purpose in the currency of the account
+	GetSystemAccountIDForAccount(ctx context.Context, arg GetSystemAccountIDForAccountParams) (int64, error)
 	GetTransfer(ctx context.Context, id int64) (Transfer, error)
+	// locks the transfer so concurrent reversals of it are made one after the other
+	GetTransferForUpdate(ctx context.Context, id int64) (Transfer, error)
+	// the amounts sent from the account since each start, reversals give money back and don't count
+	GetTransferredTotals(ctx context.Context, arg GetTransferredTotalsParams) (GetTransferredTotalsRow, error)
+	// Store looks users up by the blind index of the email, see store_encryption.go
+	GetUserByEmail(ctx context.Context, email string) (User, error)
+	GetUserByPasswordResetToken(ctx context.Context, hashedToken string) (User, error)
 	GetUserByUsername(ctx context.Context, username string) (User, error)
+	GetUserMFA(ctx context.Context, username string) (UserMfa, error)
+	HasTransferredTo(ctx context.Context, arg HasTransferredToParams) (bool, error)
+	IncrementMFAChallengeAttempts(ctx context.Context, id uuid.UUID) (MfaChallenge, error)
+	InvalidatePasswordResetTokens(ctx context.Context, username string) error
+	IsUserErased(ctx context.Context, username string) (bool, error)
+	ListAPIKeysByOwner(ctx context.Context, arg ListAPIKeysByOwnerParams) ([]ApiKey, error)
 	ListAccounts(ctx context.Context, arg ListAccountsParams) ([]Account, error)
 	ListAccountsByUsername(ctx context.Context, arg ListAccountsByUsernameParams) ([]Account, error)
+	// the holds on the accounts of a user and the ones to their accounts
+	ListActiveHoldsByUsernameForUpdate(ctx context.Context, username string) ([]Hold, error)
+	ListAllAccountsByUsername(ctx context.Context, owner string) ([]Account, error)
+	// accounts whose balance isn't the sum of their entries
+	ListBalanceMismatches(ctx context.Context) ([]ListBalanceMismatchesRow, error)
+	ListCurrencies(ctx context.Context) ([]Currency, error)
 	ListEntries(ctx context.Context, arg ListEntriesParams) ([]Entry, error)
+	ListEntriesByUsername(ctx context.Context, owner string) ([]Entry, error)
+	ListJournalEntries(ctx context.Context, journalID sql.NullInt64) ([]Entry, error)
+	// entries without an account or a journal, the transfers written before journals got theirs when migrating
+	ListOrphanEntries(ctx context.Context) ([]Entry, error)
+	ListRiskReviews(ctx context.Context, arg ListRiskReviewsParams) ([]RiskReview, error)
+	ListScheduledTransfers(ctx context.Context, arg ListScheduledTransfersParams) ([]ScheduledTransfer, error)
+	// the list entries matched for the full name of the user, with the status of their latest match
+	// full_name is the name screened, the store looks it up by its blind index
+	ListScreeningEntries(ctx context.Context, arg ListScreeningEntriesParams) ([]ListScreeningEntriesRow, error)
+	ListScreeningMatches(ctx context.Context, arg ListScreeningMatchesParams) ([]ScreeningMatch, error)
+	ListStandingOrderRuns(ctx context.Context, arg ListStandingOrderRunsParams) ([]StandingOrderRun, error)
+	ListStandingOrders(ctx context.Context, arg ListStandingOrdersParams) ([]StandingOrder, error)
 	ListTransfers(ctx context.Context, arg ListTransfersParams) ([]Transfer, error)
+	ListTransfersByUsername(ctx context.Context, owner string) ([]Transfer, error)
+	// transfers without exactly their entry pair, and the fee pair when they have a fee, in their journal
+	ListUnbalancedTransfers(ctx context.Context) ([]ListUnbalancedTransfersRow, error)
+	// rows written before email encryption was enabled
+	ListUsersWithoutEmailIndex(ctx context.Context, limit int32) ([]User, error)
+	// only verifies the address the token was sent to, in case the email changed since
+	MarkUserEmailVerified(ctx context.Context, arg MarkUserEmailVerifiedParams) (User, error)
+	PauseStandingOrder(ctx context.Context, arg PauseStandingOrderParams) (StandingOrder, error)
+	// the empty hash matches no password, and moving password_changed_at invalidates every token
+	PseudonymizeUser(ctx context.Context, arg PseudonymizeUserParams) (User, error)
+	// failures older than reset_before are forgotten and the count starts again
+	RecordLoginFailure(ctx context.Context, arg RecordLoginFailureParams) (LoginThrottle, error)
+	// status stays pending while there are retries left, next_attempt_at is then the next retry
+	RecordScheduledTransferFailure(ctx context.Context, arg RecordScheduledTransferFailureParams) (ScheduledTransfer, error)
+	// upgrades the hash of an unchanged password, password_changed_at is kept so sessions stay valid
+	RehashUserPassword(ctx context.Context, arg RehashUserPasswordParams) (int64, error)
+	// the pending reviews of an erased user and the ones to their accounts, nobody reviewed them
+	RejectRiskReviewsByUsername(ctx context.Context, username string) (int64, error)
+	// runs missed while paused are not made, next_run_at is the first run after resuming
+	ResumeStandingOrder(ctx context.Context, arg ResumeStandingOrderParams) (StandingOrder, error)
+	RevokeAPIKey(ctx context.Context, arg RevokeAPIKeyParams) (ApiKey, error)
+	RevokeAPIKeysByOwner(ctx context.Context, owner string) error
+	SetAccountTransferLimits(ctx context.Context, arg SetAccountTransferLimitsParams) (AccountTransferLimit, error)
+	SettleHold(ctx context.Context, arg SettleHoldParams) (Hold, error)
+	SettleRiskReview(ctx context.Context, arg SettleRiskReviewParams) (RiskReview, error)
+	SettleScreeningMatch(ctx context.Context, arg SettleScreeningMatchParams) (ScreeningMatch, error)
+	// refills the bucket for the time elapsed since the last request and takes a token if there's one,
+	// in a single statement so concurrent requests from other instances can't both take the last token
+	TakeRateLimitToken(ctx context.Context, arg TakeRateLimitTokenParams) (RateLimitBucket, error)
 	UpdateAccount(ctx context.Context, arg UpdateAccountParams) (Account, error)
+	UpdateMFALastUsedStep(ctx context.Context, arg UpdateMFALastUsedStepParams) (int64, error)
+	UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error)
+	// a new address always needs to be verified again
+	UpdateUserEmail(ctx context.Context, arg UpdateUserEmailParams) (User, error)
+	UpdateUserKYCTier(ctx context.Context, arg UpdateUserKYCTierParams) (int64, error)
+	UpdateUserPII(ctx context.Context, arg UpdateUserPIIParams) error
+	UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) (User, error)
+	UpdateUserRole(ctx context.Context, arg UpdateUserRoleParams) (int64, error)
+	UpsertUserMFA(ctx context.Context, arg UpsertUserMFAParams) (UserMfa, error)
+	UseMFARecoveryCode(ctx context.Context, arg UseMFARecoveryCodeParams) (MfaRecoveryCode, error)
+	UsePasswordResetToken(ctx context.Context, hashedToken string) (PasswordResetToken, error)
+	UseVerifyEmailToken(ctx context.Context, hashedToken string) (VerifyEmailToken, error)
 }
 
 var _ Querier = (*Queries)(nil)
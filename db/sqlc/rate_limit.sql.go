@@ -0,0 +1,69 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.25.0
+// source: rate_limit.sql
+
+package db
+
+import (
+	"context"
+	"time"
+)
+
+const deleteIdleRateLimitBuckets = `-- name: DeleteIdleRateLimitBuckets :execrows
+DELETE FROM rate_limit_buckets
+WHERE updated_at < $1
+`
+
+func (q *Queries) DeleteIdleRateLimitBuckets(ctx context.Context, idleSince time.Time) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteIdleRateLimitBuckets, idleSince)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const takeRateLimitToken = `-- name: TakeRateLimitToken :one
+INSERT INTO rate_limit_buckets AS bucket (
+    key,
+    tokens,
+    allowed,
+    updated_at
+) VALUES (
+    $1, $2::float8 - 1, true, now()
+)
+ON CONFLICT (key) DO UPDATE SET
+    allowed = LEAST(
+        $2::float8,
+        bucket.tokens + EXTRACT(EPOCH FROM now() - bucket.updated_at)::float8 * $3::float8
+    ) >= 1,
+    tokens = LEAST(
+        $2::float8,
+        bucket.tokens + EXTRACT(EPOCH FROM now() - bucket.updated_at)::float8 * $3::float8
+    ) - CASE WHEN LEAST(
+        $2::float8,
+        bucket.tokens + EXTRACT(EPOCH FROM now() - bucket.updated_at)::float8 * $3::float8
+    ) >= 1 THEN 1 ELSE 0 END,
+    updated_at = now()
+RETURNING key, tokens, allowed, updated_at
+`
+
+type TakeRateLimitTokenParams struct {
+	Key        string  `json:"key"`
+	Capacity   float64 `json:"capacity"`
+	RefillRate float64 `json:"refill_rate"`
+}
+
+// refills the bucket for the time elapsed since the last request and takes a token if there's one,
+// in a single statement so concurrent requests from other instances can't both take the last token
+func (q *Queries) TakeRateLimitToken(ctx context.Context, arg TakeRateLimitTokenParams) (RateLimitBucket, error) {
+	row := q.db.QueryRowContext(ctx, takeRateLimitToken, arg.Key, arg.Capacity, arg.RefillRate)
+	var i RateLimitBucket
+	err := row.Scan(
+		&i.Key,
+		&i.Tokens,
+		&i.Allowed,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
@@ -0,0 +1,38 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go_backend_misc/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTakeRateLimitToken(t *testing.T) {
+	arg := TakeRateLimitTokenParams{
+		Key:      "test:" + util.RandomString(12),
+		Capacity: 2,
+		// slow enough that no token is earned back during the test
+		RefillRate: 0.0001,
+	}
+
+	bucket, err := testQueries.TakeRateLimitToken(context.Background(), arg)
+	require.NoError(t, err)
+	require.True(t, bucket.Allowed)
+	require.InDelta(t, 1, bucket.Tokens, 0.01)
+
+	bucket, err = testQueries.TakeRateLimitToken(context.Background(), arg)
+	require.NoError(t, err)
+	require.True(t, bucket.Allowed)
+	require.InDelta(t, 0, bucket.Tokens, 0.01)
+
+	bucket, err = testQueries.TakeRateLimitToken(context.Background(), arg)
+	require.NoError(t, err)
+	require.False(t, bucket.Allowed)
+	require.GreaterOrEqual(t, bucket.Tokens, 0.0)
+
+	rows, err := testQueries.DeleteIdleRateLimitBuckets(context.Background(), time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, rows, int64(1))
+}
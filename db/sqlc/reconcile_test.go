@@ -0,0 +1,109 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCorrectBalanceTx(t *testing.T) {
+	// accounts made straight in the database start with a balance but no entries
+	account, _, _, err := createRandomAccount("_test_correct_balance")
+	require.NoError(t, err)
+	suspense := getSystemAccount(t, SystemAccountSuspense, account)
+
+	report, err := testStore.ReconcileLedger(context.Background(), false)
+	require.NoError(t, err)
+	require.False(t, report.Balanced)
+	require.Contains(t, report.BalanceMismatches, ListBalanceMismatchesRow{
+		ID:           account.ID,
+		Owner:        account.Owner,
+		Currency:     account.Currency,
+		Balance:      account.Balance,
+		EntriesTotal: 0,
+	})
+	require.Empty(t, report.Corrections)
+
+	journal, err := testStore.CorrectBalanceTx(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, JournalCorrection, journal.Kind)
+
+	entries, err := testQueries.ListJournalEntries(context.Background(), Int64ToSqlInt64(journal.ID))
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	require.Equal(t, account.Balance, entries[0].Amount)
+	require.Equal(t, -account.Balance, entries[1].Amount)
+
+	// the entries now add up to the balance, which didn't move
+	total, err := testQueries.GetAccountEntriesTotal(context.Background(), Int64ToSqlInt64(account.ID))
+	require.NoError(t, err)
+	require.Equal(t, account.Balance, total)
+	unchanged, err := testQueries.GetAccount(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, account.Balance, unchanged.Balance)
+	updatedSuspense, err := testQueries.GetAccount(context.Background(), suspense.ID)
+	require.NoError(t, err)
+	require.Equal(t, suspense.Balance-account.Balance, updatedSuspense.Balance)
+
+	// a second correction has nothing to do
+	journal, err = testStore.CorrectBalanceTx(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Zero(t, journal.ID)
+}
+
+func TestListUnbalancedTransfers(t *testing.T) {
+	fromAccount, toAccount := createScheduledTransferAccounts(t, "_test_unbalanced_transfers")
+	result, err := testStore.TransferTx(context.Background(), CreateTransferParams{
+		FromAccountID: Int64ToSqlInt64(fromAccount.ID),
+		ToAccountID:   Int64ToSqlInt64(toAccount.ID),
+		Amount:        10,
+	})
+	require.NoError(t, err)
+
+	// a transfer without entries
+	orphan, err := testQueries.CreateTransfer(context.Background(), CreateTransferParams{
+		FromAccountID: Int64ToSqlInt64(fromAccount.ID),
+		ToAccountID:   Int64ToSqlInt64(toAccount.ID),
+		Amount:        10,
+	})
+	require.NoError(t, err)
+
+	transfers, err := testQueries.ListUnbalancedTransfers(context.Background())
+	require.NoError(t, err)
+	ids := make([]int64, len(transfers))
+	for i, transfer := range transfers {
+		ids[i] = transfer.ID
+	}
+	require.Contains(t, ids, orphan.ID)
+	require.NotContains(t, ids, result.Transfer.ID)
+}
+
+func TestListOrphanEntries(t *testing.T) {
+	fromAccount, toAccount := createScheduledTransferAccounts(t, "_test_orphan_entries")
+
+	// an entry without a journal in the transaction of a transfer doesn't belong to it
+	tx, err := testDB.Begin()
+	require.NoError(t, err)
+	queries := testQueries.WithTx(tx)
+	_, err = queries.CreateTransfer(context.Background(), CreateTransferParams{
+		FromAccountID: Int64ToSqlInt64(fromAccount.ID),
+		ToAccountID:   Int64ToSqlInt64(toAccount.ID),
+		Amount:        10,
+	})
+	require.NoError(t, err)
+	entry, err := queries.CreateEntry(context.Background(), CreateEntryParams{
+		AccountID: Int64ToSqlInt64(fromAccount.ID),
+		Amount:    -10,
+	})
+	require.NoError(t, err)
+	require.NoError(t, tx.Commit())
+
+	entries, err := testQueries.ListOrphanEntries(context.Background())
+	require.NoError(t, err)
+	ids := make([]int64, len(entries))
+	for i, orphan := range entries {
+		ids[i] = orphan.ID
+	}
+	require.Contains(t, ids, entry.ID)
+}
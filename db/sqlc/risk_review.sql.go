@@ -0,0 +1,242 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.25.0
+// source: risk_review.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+const createRiskReview = `-- name: CreateRiskReview :one
+INSERT INTO risk_reviews (
+    owner,
+    from_account_id,
+    to_account_id,
+    amount,
+    fee,
+    currency,
+    decision,
+    reasons,
+    status
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9
+) RETURNING id, owner, from_account_id, to_account_id, amount, fee, currency, decision, reasons, status, transfer_id, reviewed_by, reviewed_at, created_at
+`
+
+type CreateRiskReviewParams struct {
+	Owner         string   `json:"owner"`
+	FromAccountID int64    `json:"from_account_id"`
+	ToAccountID   int64    `json:"to_account_id"`
+	Amount        int64    `json:"amount"`
+	Fee           int64    `json:"fee"`
+	Currency      string   `json:"currency"`
+	Decision      string   `json:"decision"`
+	Reasons       []string `json:"reasons"`
+	Status        string   `json:"status"`
+}
+
+func (q *Queries) CreateRiskReview(ctx context.Context, arg CreateRiskReviewParams) (RiskReview, error) {
+	row := q.db.QueryRowContext(ctx, createRiskReview,
+		arg.Owner,
+		arg.FromAccountID,
+		arg.ToAccountID,
+		arg.Amount,
+		arg.Fee,
+		arg.Currency,
+		arg.Decision,
+		pq.Array(arg.Reasons),
+		arg.Status,
+	)
+	var i RiskReview
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.Fee,
+		&i.Currency,
+		&i.Decision,
+		pq.Array(&i.Reasons),
+		&i.Status,
+		&i.TransferID,
+		&i.ReviewedBy,
+		&i.ReviewedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getRiskReview = `-- name: GetRiskReview :one
+SELECT id, owner, from_account_id, to_account_id, amount, fee, currency, decision, reasons, status, transfer_id, reviewed_by, reviewed_at, created_at FROM risk_reviews
+WHERE id = $1 LIMIT 1
+`
+
+func (q *Queries) GetRiskReview(ctx context.Context, id int64) (RiskReview, error) {
+	row := q.db.QueryRowContext(ctx, getRiskReview, id)
+	var i RiskReview
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.Fee,
+		&i.Currency,
+		&i.Decision,
+		pq.Array(&i.Reasons),
+		&i.Status,
+		&i.TransferID,
+		&i.ReviewedBy,
+		&i.ReviewedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getRiskReviewForUpdate = `-- name: GetRiskReviewForUpdate :one
+SELECT id, owner, from_account_id, to_account_id, amount, fee, currency, decision, reasons, status, transfer_id, reviewed_by, reviewed_at, created_at FROM risk_reviews
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE
+`
+
+// locks the review so it's approved or rejected once
+func (q *Queries) GetRiskReviewForUpdate(ctx context.Context, id int64) (RiskReview, error) {
+	row := q.db.QueryRowContext(ctx, getRiskReviewForUpdate, id)
+	var i RiskReview
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.Fee,
+		&i.Currency,
+		&i.Decision,
+		pq.Array(&i.Reasons),
+		&i.Status,
+		&i.TransferID,
+		&i.ReviewedBy,
+		&i.ReviewedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const listRiskReviews = `-- name: ListRiskReviews :many
+SELECT id, owner, from_account_id, to_account_id, amount, fee, currency, decision, reasons, status, transfer_id, reviewed_by, reviewed_at, created_at FROM risk_reviews
+WHERE status = $1
+ORDER BY id
+LIMIT $2
+OFFSET $3
+`
+
+type ListRiskReviewsParams struct {
+	Status string `json:"status"`
+	Limit  int32  `json:"limit"`
+	Offset int32  `json:"offset"`
+}
+
+func (q *Queries) ListRiskReviews(ctx context.Context, arg ListRiskReviewsParams) ([]RiskReview, error) {
+	rows, err := q.db.QueryContext(ctx, listRiskReviews, arg.Status, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []RiskReview
+	for rows.Next() {
+		var i RiskReview
+		if err := rows.Scan(
+			&i.ID,
+			&i.Owner,
+			&i.FromAccountID,
+			&i.ToAccountID,
+			&i.Amount,
+			&i.Fee,
+			&i.Currency,
+			&i.Decision,
+			pq.Array(&i.Reasons),
+			&i.Status,
+			&i.TransferID,
+			&i.ReviewedBy,
+			&i.ReviewedAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const rejectRiskReviewsByUsername = `-- name: RejectRiskReviewsByUsername :execrows
+UPDATE risk_reviews
+SET status = 'rejected',
+    reviewed_at = now()
+WHERE status = 'pending'
+    AND (risk_reviews.owner = $1 OR to_account_id IN (SELECT accounts.id FROM accounts WHERE accounts.owner = $1))
+`
+
+// the pending reviews of an erased user and the ones to their accounts, nobody reviewed them
+func (q *Queries) RejectRiskReviewsByUsername(ctx context.Context, username string) (int64, error) {
+	result, err := q.db.ExecContext(ctx, rejectRiskReviewsByUsername, username)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const settleRiskReview = `-- name: SettleRiskReview :one
+UPDATE risk_reviews
+SET status = $1,
+    transfer_id = $2,
+    reviewed_by = $3,
+    reviewed_at = now()
+WHERE id = $4 AND status = 'pending'
+RETURNING id, owner, from_account_id, to_account_id, amount, fee, currency, decision, reasons, status, transfer_id, reviewed_by, reviewed_at, created_at
+`
+
+type SettleRiskReviewParams struct {
+	Status     string         `json:"status"`
+	TransferID sql.NullInt64  `json:"transfer_id"`
+	ReviewedBy sql.NullString `json:"reviewed_by"`
+	ID         int64          `json:"id"`
+}
+
+func (q *Queries) SettleRiskReview(ctx context.Context, arg SettleRiskReviewParams) (RiskReview, error) {
+	row := q.db.QueryRowContext(ctx, settleRiskReview,
+		arg.Status,
+		arg.TransferID,
+		arg.ReviewedBy,
+		arg.ID,
+	)
+	var i RiskReview
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.Fee,
+		&i.Currency,
+		&i.Decision,
+		pq.Array(&i.Reasons),
+		&i.Status,
+		&i.TransferID,
+		&i.ReviewedBy,
+		&i.ReviewedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
@@ -0,0 +1,79 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomRiskReview(t *testing.T, fromAccount Account, toAccount Account) RiskReview {
+	review, err := testQueries.CreateRiskReview(context.Background(), CreateRiskReviewParams{
+		Owner:         fromAccount.Owner,
+		FromAccountID: fromAccount.ID,
+		ToAccountID:   toAccount.ID,
+		Amount:        10,
+		Fee:           0,
+		Currency:      fromAccount.Currency,
+		Decision:      "hold",
+		Reasons:       []string{"new_payee: first transfer to the account"},
+		Status:        RiskReviewPending,
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"new_payee: first transfer to the account"}, review.Reasons)
+	return review
+}
+
+func TestApproveRiskReviewTx(t *testing.T) {
+	fromAccount, toAccount := createScheduledTransferAccounts(t, "_test_approve_risk_review")
+	review := createRandomRiskReview(t, fromAccount, toAccount)
+	reviewer := toAccount.Owner
+
+	result, err := testStore.ApproveRiskReviewTx(context.Background(), ApproveRiskReviewTxParams{
+		ID:         review.ID,
+		ReviewedBy: reviewer,
+	})
+	require.NoError(t, err)
+	require.Equal(t, RiskReviewApproved, result.Review.Status)
+	require.Equal(t, result.Transfer.ID, result.Review.TransferID.Int64)
+	require.Equal(t, reviewer, result.Review.ReviewedBy.String)
+	require.True(t, result.Review.ReviewedAt.Valid)
+	require.Equal(t, review.Amount, result.Transfer.Amount)
+	require.Equal(t, fromAccount.Balance-review.Amount, result.FromAccount.Balance)
+
+	// a review is approved once
+	_, err = testStore.ApproveRiskReviewTx(context.Background(), ApproveRiskReviewTxParams{
+		ID:         review.ID,
+		ReviewedBy: reviewer,
+	})
+	require.ErrorIs(t, err, ErrRiskReviewNotPending)
+}
+
+func TestRejectRiskReview(t *testing.T) {
+	fromAccount, toAccount := createScheduledTransferAccounts(t, "_test_reject_risk_review")
+	review := createRandomRiskReview(t, fromAccount, toAccount)
+	reviewedBy := sql.NullString{String: toAccount.Owner, Valid: true}
+
+	rejected, err := testQueries.SettleRiskReview(context.Background(), SettleRiskReviewParams{
+		ID:         review.ID,
+		Status:     RiskReviewRejected,
+		ReviewedBy: reviewedBy,
+	})
+	require.NoError(t, err)
+	require.Equal(t, RiskReviewRejected, rejected.Status)
+	require.False(t, rejected.TransferID.Valid)
+
+	// settled reviews can't be approved anymore
+	_, err = testStore.ApproveRiskReviewTx(context.Background(), ApproveRiskReviewTxParams{
+		ID:         review.ID,
+		ReviewedBy: toAccount.Owner,
+	})
+	require.ErrorIs(t, err, ErrRiskReviewNotPending)
+	_, err = testQueries.SettleRiskReview(context.Background(), SettleRiskReviewParams{
+		ID:         review.ID,
+		Status:     RiskReviewRejected,
+		ReviewedBy: reviewedBy,
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
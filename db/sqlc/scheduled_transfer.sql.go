@@ -0,0 +1,313 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.25.0
+// source: scheduled_transfer.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const cancelScheduledTransfer = `-- name: CancelScheduledTransfer :one
+UPDATE scheduled_transfers
+SET status = 'canceled'
+WHERE id = $1 AND owner = $2 AND status = 'pending'
+RETURNING id, owner, from_account_id, to_account_id, amount, currency, execute_at, status, attempts, next_attempt_at, last_error, transfer_id, executed_at, created_at
+`
+
+type CancelScheduledTransferParams struct {
+	ID    int64  `json:"id"`
+	Owner string `json:"owner"`
+}
+
+func (q *Queries) CancelScheduledTransfer(ctx context.Context, arg CancelScheduledTransferParams) (ScheduledTransfer, error) {
+	row := q.db.QueryRowContext(ctx, cancelScheduledTransfer, arg.ID, arg.Owner)
+	var i ScheduledTransfer
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.Currency,
+		&i.ExecuteAt,
+		&i.Status,
+		&i.Attempts,
+		&i.NextAttemptAt,
+		&i.LastError,
+		&i.TransferID,
+		&i.ExecutedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const cancelScheduledTransfersByUsername = `-- name: CancelScheduledTransfersByUsername :execrows
+UPDATE scheduled_transfers
+SET status = 'canceled'
+WHERE status = 'pending'
+    AND (scheduled_transfers.owner = $1 OR to_account_id IN (SELECT accounts.id FROM accounts WHERE accounts.owner = $1))
+`
+
+// the pending transfers of an erased user and the ones to their accounts
+func (q *Queries) CancelScheduledTransfersByUsername(ctx context.Context, username string) (int64, error) {
+	result, err := q.db.ExecContext(ctx, cancelScheduledTransfersByUsername, username)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const completeScheduledTransfer = `-- name: CompleteScheduledTransfer :one
+UPDATE scheduled_transfers
+SET status = 'completed',
+    attempts = attempts + 1,
+    transfer_id = $1,
+    last_error = NULL,
+    executed_at = now()
+WHERE id = $2
+RETURNING id, owner, from_account_id, to_account_id, amount, currency, execute_at, status, attempts, next_attempt_at, last_error, transfer_id, executed_at, created_at
+`
+
+type CompleteScheduledTransferParams struct {
+	TransferID sql.NullInt64 `json:"transfer_id"`
+	ID         int64         `json:"id"`
+}
+
+func (q *Queries) CompleteScheduledTransfer(ctx context.Context, arg CompleteScheduledTransferParams) (ScheduledTransfer, error) {
+	row := q.db.QueryRowContext(ctx, completeScheduledTransfer, arg.TransferID, arg.ID)
+	var i ScheduledTransfer
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.Currency,
+		&i.ExecuteAt,
+		&i.Status,
+		&i.Attempts,
+		&i.NextAttemptAt,
+		&i.LastError,
+		&i.TransferID,
+		&i.ExecutedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createScheduledTransfer = `-- name: CreateScheduledTransfer :one
+INSERT INTO scheduled_transfers (
+    owner,
+    from_account_id,
+    to_account_id,
+    amount,
+    currency,
+    execute_at,
+    next_attempt_at
+) VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $6
+) RETURNING id, owner, from_account_id, to_account_id, amount, currency, execute_at, status, attempts, next_attempt_at, last_error, transfer_id, executed_at, created_at
+`
+
+type CreateScheduledTransferParams struct {
+	Owner         string    `json:"owner"`
+	FromAccountID int64     `json:"from_account_id"`
+	ToAccountID   int64     `json:"to_account_id"`
+	Amount        int64     `json:"amount"`
+	Currency      string    `json:"currency"`
+	ExecuteAt     time.Time `json:"execute_at"`
+}
+
+func (q *Queries) CreateScheduledTransfer(ctx context.Context, arg CreateScheduledTransferParams) (ScheduledTransfer, error) {
+	row := q.db.QueryRowContext(ctx, createScheduledTransfer,
+		arg.Owner,
+		arg.FromAccountID,
+		arg.ToAccountID,
+		arg.Amount,
+		arg.Currency,
+		arg.ExecuteAt,
+	)
+	var i ScheduledTransfer
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.Currency,
+		&i.ExecuteAt,
+		&i.Status,
+		&i.Attempts,
+		&i.NextAttemptAt,
+		&i.LastError,
+		&i.TransferID,
+		&i.ExecutedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getDueScheduledTransferForUpdate = `-- name: GetDueScheduledTransferForUpdate :one
+SELECT id, owner, from_account_id, to_account_id, amount, currency, execute_at, status, attempts, next_attempt_at, last_error, transfer_id, executed_at, created_at FROM scheduled_transfers
+WHERE status = 'pending' AND next_attempt_at <= now()
+ORDER BY next_attempt_at, id
+LIMIT 1
+FOR UPDATE SKIP LOCKED
+`
+
+// SKIP LOCKED lets several executors work through the due rows without waiting on each other
+func (q *Queries) GetDueScheduledTransferForUpdate(ctx context.Context) (ScheduledTransfer, error) {
+	row := q.db.QueryRowContext(ctx, getDueScheduledTransferForUpdate)
+	var i ScheduledTransfer
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.Currency,
+		&i.ExecuteAt,
+		&i.Status,
+		&i.Attempts,
+		&i.NextAttemptAt,
+		&i.LastError,
+		&i.TransferID,
+		&i.ExecutedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getScheduledTransfer = `-- name: GetScheduledTransfer :one
+SELECT id, owner, from_account_id, to_account_id, amount, currency, execute_at, status, attempts, next_attempt_at, last_error, transfer_id, executed_at, created_at FROM scheduled_transfers
+WHERE id = $1 LIMIT 1
+`
+
+func (q *Queries) GetScheduledTransfer(ctx context.Context, id int64) (ScheduledTransfer, error) {
+	row := q.db.QueryRowContext(ctx, getScheduledTransfer, id)
+	var i ScheduledTransfer
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.Currency,
+		&i.ExecuteAt,
+		&i.Status,
+		&i.Attempts,
+		&i.NextAttemptAt,
+		&i.LastError,
+		&i.TransferID,
+		&i.ExecutedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const listScheduledTransfers = `-- name: ListScheduledTransfers :many
+SELECT id, owner, from_account_id, to_account_id, amount, currency, execute_at, status, attempts, next_attempt_at, last_error, transfer_id, executed_at, created_at FROM scheduled_transfers
+WHERE owner = $1
+ORDER BY execute_at, id
+LIMIT $2
+OFFSET $3
+`
+
+type ListScheduledTransfersParams struct {
+	Owner  string `json:"owner"`
+	Limit  int32  `json:"limit"`
+	Offset int32  `json:"offset"`
+}
+
+func (q *Queries) ListScheduledTransfers(ctx context.Context, arg ListScheduledTransfersParams) ([]ScheduledTransfer, error) {
+	rows, err := q.db.QueryContext(ctx, listScheduledTransfers, arg.Owner, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ScheduledTransfer
+	for rows.Next() {
+		var i ScheduledTransfer
+		if err := rows.Scan(
+			&i.ID,
+			&i.Owner,
+			&i.FromAccountID,
+			&i.ToAccountID,
+			&i.Amount,
+			&i.Currency,
+			&i.ExecuteAt,
+			&i.Status,
+			&i.Attempts,
+			&i.NextAttemptAt,
+			&i.LastError,
+			&i.TransferID,
+			&i.ExecutedAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const recordScheduledTransferFailure = `-- name: RecordScheduledTransferFailure :one
+UPDATE scheduled_transfers
+SET status = $1,
+    attempts = attempts + 1,
+    last_error = $2,
+    next_attempt_at = $3
+WHERE id = $4 AND status = 'pending'
+RETURNING id, owner, from_account_id, to_account_id, amount, currency, execute_at, status, attempts, next_attempt_at, last_error, transfer_id, executed_at, created_at
+`
+
+type RecordScheduledTransferFailureParams struct {
+	Status        string         `json:"status"`
+	LastError     sql.NullString `json:"last_error"`
+	NextAttemptAt time.Time      `json:"next_attempt_at"`
+	ID            int64          `json:"id"`
+}
+
+// status stays pending while there are retries left, next_attempt_at is then the next retry
+func (q *Queries) RecordScheduledTransferFailure(ctx context.Context, arg RecordScheduledTransferFailureParams) (ScheduledTransfer, error) {
+	row := q.db.QueryRowContext(ctx, recordScheduledTransferFailure,
+		arg.Status,
+		arg.LastError,
+		arg.NextAttemptAt,
+		arg.ID,
+	)
+	var i ScheduledTransfer
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.Currency,
+		&i.ExecuteAt,
+		&i.Status,
+		&i.Attempts,
+		&i.NextAttemptAt,
+		&i.LastError,
+		&i.TransferID,
+		&i.ExecutedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
@@ -0,0 +1,171 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/go_backend_misc/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createScheduledTransferAccounts(t *testing.T, userSuffix string) (Account, Account) {
+	fromAccount, _, _, err := createRandomAccount(userSuffix + "_1")
+	require.NoError(t, err)
+	toUser, _, err := createRandomUser(userSuffix + "_2")
+	require.NoError(t, err)
+
+	// the transfer is only valid between accounts in the same currency
+	toAccount, err := testQueries.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    toUser.Username,
+		Balance:  util.RandomMoney(),
+		Currency: fromAccount.Currency,
+	})
+	require.NoError(t, err)
+	return fromAccount, toAccount
+}
+
+func TestExecuteScheduledTransferTx(t *testing.T) {
+	fromAccount, toAccount := createScheduledTransferAccounts(t, "_test_scheduled_transfer")
+	var amount int64 = 10
+
+	scheduled, err := testQueries.CreateScheduledTransfer(context.Background(), CreateScheduledTransferParams{
+		Owner:         fromAccount.Owner,
+		FromAccountID: fromAccount.ID,
+		ToAccountID:   toAccount.ID,
+		Amount:        amount,
+		Currency:      fromAccount.Currency,
+		ExecuteAt:     time.Now().Add(-time.Second),
+	})
+	require.NoError(t, err)
+	require.Equal(t, ScheduledTransferPending, scheduled.Status)
+
+	// other due rows may be left over from previous runs, work through them until ours is executed
+	for i := 0; i < 100; i++ {
+		executed, result, err := testStore.ExecuteScheduledTransferTx(context.Background(), nil)
+		if err == sql.ErrNoRows {
+			break
+		}
+		if executed.ID != scheduled.ID {
+			continue
+		}
+		require.NoError(t, err)
+		require.Equal(t, ScheduledTransferCompleted, executed.Status)
+		require.Equal(t, int32(1), executed.Attempts)
+		require.Equal(t, result.Transfer.ID, executed.TransferID.Int64)
+		runTransferTxTests(t, err, &fromAccount, &toAccount, result, amount, testStore)
+		break
+	}
+
+	scheduled, err = testQueries.GetScheduledTransfer(context.Background(), scheduled.ID)
+	require.NoError(t, err)
+	require.Equal(t, ScheduledTransferCompleted, scheduled.Status)
+	require.True(t, scheduled.ExecutedAt.Valid)
+}
+
+func TestCancelScheduledTransfer(t *testing.T) {
+	fromAccount, toAccount := createScheduledTransferAccounts(t, "_test_cancel_scheduled_transfer")
+
+	scheduled, err := testQueries.CreateScheduledTransfer(context.Background(), CreateScheduledTransferParams{
+		Owner:         fromAccount.Owner,
+		FromAccountID: fromAccount.ID,
+		ToAccountID:   toAccount.ID,
+		Amount:        10,
+		Currency:      fromAccount.Currency,
+		ExecuteAt:     time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	canceled, err := testQueries.CancelScheduledTransfer(context.Background(), CancelScheduledTransferParams{
+		ID:    scheduled.ID,
+		Owner: fromAccount.Owner,
+	})
+	require.NoError(t, err)
+	require.Equal(t, ScheduledTransferCanceled, canceled.Status)
+
+	// a canceled transfer can't be canceled again
+	_, err = testQueries.CancelScheduledTransfer(context.Background(), CancelScheduledTransferParams{
+		ID:    scheduled.ID,
+		Owner: fromAccount.Owner,
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestExecuteScheduledTransferTxFee(t *testing.T) {
+	fromAccount, toAccount := createScheduledTransferAccounts(t, "_test_scheduled_transfer_fee")
+
+	scheduled, err := testQueries.CreateScheduledTransfer(context.Background(), CreateScheduledTransferParams{
+		Owner:         fromAccount.Owner,
+		FromAccountID: fromAccount.ID,
+		ToAccountID:   toAccount.ID,
+		Amount:        10,
+		Currency:      fromAccount.Currency,
+		ExecuteAt:     time.Now().Add(-time.Second),
+	})
+	require.NoError(t, err)
+
+	// other due rows may be left over from previous runs, work through them until ours is executed
+	for i := 0; i < 100; i++ {
+		executed, result, err := testFeeStore.ExecuteScheduledTransferTx(context.Background(), nil)
+		if err == sql.ErrNoRows {
+			break
+		}
+		if executed.ID != scheduled.ID {
+			continue
+		}
+		require.NoError(t, err)
+		requireFeeEntry(t, result.Transfer, testFee)
+		require.Equal(t, fromAccount.Balance-scheduled.Amount-testFee, result.FromAccount.Balance)
+		return
+	}
+	t.Fatalf("scheduled transfer %d was not executed", scheduled.ID)
+}
+
+func TestExecuteScheduledTransferTxScreened(t *testing.T) {
+	fromAccount, toAccount := createScheduledTransferAccounts(t, "_test_scheduled_transfer_screened")
+
+	scheduled, err := testQueries.CreateScheduledTransfer(context.Background(), CreateScheduledTransferParams{
+		Owner:         fromAccount.Owner,
+		FromAccountID: fromAccount.ID,
+		ToAccountID:   toAccount.ID,
+		Amount:        10,
+		Currency:      fromAccount.Currency,
+		ExecuteAt:     time.Now().Add(-time.Second),
+	})
+	require.NoError(t, err)
+
+	screen := func(ctx context.Context, owner string, currency string, arg CreateTransferParams) error {
+		if arg.FromAccountID.Int64 == fromAccount.ID {
+			require.Equal(t, fromAccount.Owner, owner)
+			require.Equal(t, fromAccount.Currency, currency)
+			require.Equal(t, toAccount.ID, arg.ToAccountID.Int64)
+			return ErrTransferDenied
+		}
+		return nil
+	}
+
+	// other due rows may be left over from previous runs, work through them until ours is refused
+	refused := false
+	for i := 0; i < 100 && !refused; i++ {
+		executed, _, err := testStore.ExecuteScheduledTransferTx(context.Background(), screen)
+		if err == sql.ErrNoRows {
+			break
+		}
+		if executed.ID == scheduled.ID {
+			require.ErrorIs(t, err, ErrTransferDenied)
+			refused = true
+		}
+	}
+	require.True(t, refused)
+
+	// nothing was written, the caller records the failure
+	account, err := testQueries.GetAccount(context.Background(), fromAccount.ID)
+	require.NoError(t, err)
+	require.Equal(t, fromAccount.Balance, account.Balance)
+	_, err = testQueries.CancelScheduledTransfer(context.Background(), CancelScheduledTransferParams{
+		ID:    scheduled.ID,
+		Owner: fromAccount.Owner,
+	})
+	require.NoError(t, err)
+}
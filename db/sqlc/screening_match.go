@@ -0,0 +1,13 @@
+package db
+
+import "errors"
+
+const (
+	ScreeningMatchPending = "pending"
+	// ScreeningMatchCleared is a false positive, transfers to the user aren't held for the entries it matched
+	ScreeningMatchCleared   = "cleared"
+	ScreeningMatchConfirmed = "confirmed"
+)
+
+// ErrScreeningMatchNotPending is returned when clearing or confirming a match that was already reviewed
+var ErrScreeningMatchNotPending = errors.New("screening match is no longer pending")
@@ -0,0 +1,207 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.25.0
+// source: screening_match.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+const createScreeningMatch = `-- name: CreateScreeningMatch :one
+INSERT INTO screening_matches (
+    username,
+    entry_ids,
+    matches,
+    score,
+    name_index
+) VALUES (
+    $1, $2, $3, $4, $5::varchar
+) RETURNING id, username, entry_ids, matches, score, status, reviewed_by, reviewed_at, created_at, name_index
+`
+
+type CreateScreeningMatchParams struct {
+	Username string   `json:"username"`
+	EntryIds []string `json:"entry_ids"`
+	Matches  []string `json:"matches"`
+	Score    float64  `json:"score"`
+	FullName string   `json:"full_name"`
+}
+
+// full_name is the name screened, the store keeps its blind index
+func (q *Queries) CreateScreeningMatch(ctx context.Context, arg CreateScreeningMatchParams) (ScreeningMatch, error) {
+	row := q.db.QueryRowContext(ctx, createScreeningMatch,
+		arg.Username,
+		pq.Array(arg.EntryIds),
+		pq.Array(arg.Matches),
+		arg.Score,
+		arg.FullName,
+	)
+	var i ScreeningMatch
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		pq.Array(&i.EntryIds),
+		pq.Array(&i.Matches),
+		&i.Score,
+		&i.Status,
+		&i.ReviewedBy,
+		&i.ReviewedAt,
+		&i.CreatedAt,
+		&i.NameIndex,
+	)
+	return i, err
+}
+
+const getScreeningMatch = `-- name: GetScreeningMatch :one
+SELECT id, username, entry_ids, matches, score, status, reviewed_by, reviewed_at, created_at, name_index FROM screening_matches
+WHERE id = $1 LIMIT 1
+`
+
+func (q *Queries) GetScreeningMatch(ctx context.Context, id int64) (ScreeningMatch, error) {
+	row := q.db.QueryRowContext(ctx, getScreeningMatch, id)
+	var i ScreeningMatch
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		pq.Array(&i.EntryIds),
+		pq.Array(&i.Matches),
+		&i.Score,
+		&i.Status,
+		&i.ReviewedBy,
+		&i.ReviewedAt,
+		&i.CreatedAt,
+		&i.NameIndex,
+	)
+	return i, err
+}
+
+const listScreeningEntries = `-- name: ListScreeningEntries :many
+SELECT DISTINCT ON (entry_id) entry_id::varchar, status
+FROM (
+    SELECT unnest(entry_ids) AS entry_id, status, id
+    FROM screening_matches
+    WHERE username = $1 AND name_index = $2::varchar
+) AS entries
+ORDER BY entry_id, id DESC
+`
+
+type ListScreeningEntriesParams struct {
+	Username string `json:"username"`
+	FullName string `json:"full_name"`
+}
+
+type ListScreeningEntriesRow struct {
+	EntryID string `json:"entry_id"`
+	Status  string `json:"status"`
+}
+
+// the list entries matched for the full name of the user, with the status of their latest match
+// full_name is the name screened, the store looks it up by its blind index
+func (q *Queries) ListScreeningEntries(ctx context.Context, arg ListScreeningEntriesParams) ([]ListScreeningEntriesRow, error) {
+	rows, err := q.db.QueryContext(ctx, listScreeningEntries, arg.Username, arg.FullName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListScreeningEntriesRow
+	for rows.Next() {
+		var i ListScreeningEntriesRow
+		if err := rows.Scan(&i.EntryID, &i.Status); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listScreeningMatches = `-- name: ListScreeningMatches :many
+SELECT id, username, entry_ids, matches, score, status, reviewed_by, reviewed_at, created_at, name_index FROM screening_matches
+WHERE status = $1
+ORDER BY id
+LIMIT $2
+OFFSET $3
+`
+
+type ListScreeningMatchesParams struct {
+	Status string `json:"status"`
+	Limit  int32  `json:"limit"`
+	Offset int32  `json:"offset"`
+}
+
+func (q *Queries) ListScreeningMatches(ctx context.Context, arg ListScreeningMatchesParams) ([]ScreeningMatch, error) {
+	rows, err := q.db.QueryContext(ctx, listScreeningMatches, arg.Status, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ScreeningMatch
+	for rows.Next() {
+		var i ScreeningMatch
+		if err := rows.Scan(
+			&i.ID,
+			&i.Username,
+			pq.Array(&i.EntryIds),
+			pq.Array(&i.Matches),
+			&i.Score,
+			&i.Status,
+			&i.ReviewedBy,
+			&i.ReviewedAt,
+			&i.CreatedAt,
+			&i.NameIndex,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const settleScreeningMatch = `-- name: SettleScreeningMatch :one
+UPDATE screening_matches
+SET status = $1,
+    reviewed_by = $2,
+    reviewed_at = now()
+WHERE id = $3 AND status = 'pending'
+RETURNING id, username, entry_ids, matches, score, status, reviewed_by, reviewed_at, created_at, name_index
+`
+
+type SettleScreeningMatchParams struct {
+	Status     string         `json:"status"`
+	ReviewedBy sql.NullString `json:"reviewed_by"`
+	ID         int64          `json:"id"`
+}
+
+func (q *Queries) SettleScreeningMatch(ctx context.Context, arg SettleScreeningMatchParams) (ScreeningMatch, error) {
+	row := q.db.QueryRowContext(ctx, settleScreeningMatch, arg.Status, arg.ReviewedBy, arg.ID)
+	var i ScreeningMatch
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		pq.Array(&i.EntryIds),
+		pq.Array(&i.Matches),
+		&i.Score,
+		&i.Status,
+		&i.ReviewedBy,
+		&i.ReviewedAt,
+		&i.CreatedAt,
+		&i.NameIndex,
+	)
+	return i, err
+}
@@ -0,0 +1,122 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const screenedName = "Johnathan Doe Sanctioned"
+
+func createRandomScreeningMatch(t *testing.T, username string, entryIDs ...string) ScreeningMatch {
+	return createScreeningMatchForName(t, username, screenedName, entryIDs...)
+}
+
+func createScreeningMatchForName(t *testing.T, username string, fullName string, entryIDs ...string) ScreeningMatch {
+	matches := make([]string, len(entryIDs))
+	for i, entryID := range entryIDs {
+		matches[i] = entryID + " DOE SANCTIONED, Johnathan [SDGT] 0.95"
+	}
+	match, err := testStore.CreateScreeningMatch(context.Background(), CreateScreeningMatchParams{
+		Username: username,
+		EntryIds: entryIDs,
+		Matches:  matches,
+		Score:    0.95,
+		FullName: fullName,
+	})
+	require.NoError(t, err)
+	require.Equal(t, ScreeningMatchPending, match.Status)
+	require.Equal(t, entryIDs, match.EntryIds)
+	// the name itself isn't kept
+	require.NotEqual(t, fullName, match.NameIndex.String)
+	return match
+}
+
+func listScreeningEntryStatuses(t *testing.T, username string, fullName string) map[string]string {
+	entries, err := testStore.ListScreeningEntries(context.Background(), ListScreeningEntriesParams{
+		Username: username,
+		FullName: fullName,
+	})
+	require.NoError(t, err)
+	statuses := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		statuses[entry.EntryID] = entry.Status
+	}
+	return statuses
+}
+
+func TestSettleScreeningMatch(t *testing.T) {
+	user, _, err := createRandomUser("_test_screening_match")
+	require.NoError(t, err)
+	reviewer, _, err := createRandomUser("_test_screening_reviewer")
+	require.NoError(t, err)
+	reviewedBy := sql.NullString{String: reviewer.Username, Valid: true}
+
+	cleared := createRandomScreeningMatch(t, user.Username, "900001", "900002")
+	confirmed := createRandomScreeningMatch(t, user.Username, "900003")
+	createRandomScreeningMatch(t, user.Username, "900004")
+
+	cleared, err = testQueries.SettleScreeningMatch(context.Background(), SettleScreeningMatchParams{
+		ID:         cleared.ID,
+		Status:     ScreeningMatchCleared,
+		ReviewedBy: reviewedBy,
+	})
+	require.NoError(t, err)
+	require.Equal(t, ScreeningMatchCleared, cleared.Status)
+	require.Equal(t, reviewer.Username, cleared.ReviewedBy.String)
+	require.True(t, cleared.ReviewedAt.Valid)
+
+	_, err = testQueries.SettleScreeningMatch(context.Background(), SettleScreeningMatchParams{
+		ID:         confirmed.ID,
+		Status:     ScreeningMatchConfirmed,
+		ReviewedBy: reviewedBy,
+	})
+	require.NoError(t, err)
+
+	// a match is reviewed once
+	_, err = testQueries.SettleScreeningMatch(context.Background(), SettleScreeningMatchParams{
+		ID:         confirmed.ID,
+		Status:     ScreeningMatchCleared,
+		ReviewedBy: reviewedBy,
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	// each entry comes with the status of its match
+	require.Equal(t, map[string]string{
+		"900001": ScreeningMatchCleared,
+		"900002": ScreeningMatchCleared,
+		"900003": ScreeningMatchConfirmed,
+		"900004": ScreeningMatchPending,
+	}, listScreeningEntryStatuses(t, user.Username, screenedName))
+}
+
+func TestListScreeningEntriesLatestForName(t *testing.T) {
+	user, _, err := createRandomUser("_test_screening_entries")
+	require.NoError(t, err)
+	reviewer, _, err := createRandomUser("_test_screening_entries_reviewer")
+	require.NoError(t, err)
+	settle := func(match ScreeningMatch, status string) {
+		_, err := testQueries.SettleScreeningMatch(context.Background(), SettleScreeningMatchParams{
+			ID:         match.ID,
+			Status:     status,
+			ReviewedBy: sql.NullString{String: reviewer.Username, Valid: true},
+		})
+		require.NoError(t, err)
+	}
+
+	// the old name is cleared against the entry
+	oldName := "Jon Doe Sanctioned"
+	settle(createScreeningMatchForName(t, user.Username, oldName, "900001"), ScreeningMatchCleared)
+	require.Equal(t, map[string]string{"900001": ScreeningMatchCleared}, listScreeningEntryStatuses(t, user.Username, oldName))
+
+	// the clearance doesn't carry over to the new name, whose match is confirmed
+	require.Empty(t, listScreeningEntryStatuses(t, user.Username, screenedName))
+	settle(createRandomScreeningMatch(t, user.Username, "900001"), ScreeningMatchConfirmed)
+	require.Equal(t, map[string]string{"900001": ScreeningMatchConfirmed}, listScreeningEntryStatuses(t, user.Username, screenedName))
+
+	// the latest match of a name counts
+	createScreeningMatchForName(t, user.Username, oldName, "900001")
+	require.Equal(t, map[string]string{"900001": ScreeningMatchPending}, listScreeningEntryStatuses(t, user.Username, oldName))
+}
@@ -0,0 +1,450 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.25.0
+// source: standing_order.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const advanceStandingOrder = `-- name: AdvanceStandingOrder :one
+UPDATE standing_orders
+SET status = $1,
+    next_run_at = $2,
+    run_count = $3
+WHERE id = $4
+RETURNING id, owner, from_account_id, to_account_id, amount, currency, rrule, starts_at, catch_up, status, next_run_at, run_count, created_at
+`
+
+type AdvanceStandingOrderParams struct {
+	Status    string    `json:"status"`
+	NextRunAt time.Time `json:"next_run_at"`
+	RunCount  int32     `json:"run_count"`
+	ID        int64     `json:"id"`
+}
+
+func (q *Queries) AdvanceStandingOrder(ctx context.Context, arg AdvanceStandingOrderParams) (StandingOrder, error) {
+	row := q.db.QueryRowContext(ctx, advanceStandingOrder,
+		arg.Status,
+		arg.NextRunAt,
+		arg.RunCount,
+		arg.ID,
+	)
+	var i StandingOrder
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.Currency,
+		&i.Rrule,
+		&i.StartsAt,
+		&i.CatchUp,
+		&i.Status,
+		&i.NextRunAt,
+		&i.RunCount,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const cancelStandingOrder = `-- name: CancelStandingOrder :one
+UPDATE standing_orders
+SET status = 'canceled'
+WHERE id = $1 AND owner = $2 AND status IN ('active', 'paused')
+RETURNING id, owner, from_account_id, to_account_id, amount, currency, rrule, starts_at, catch_up, status, next_run_at, run_count, created_at
+`
+
+type CancelStandingOrderParams struct {
+	ID    int64  `json:"id"`
+	Owner string `json:"owner"`
+}
+
+func (q *Queries) CancelStandingOrder(ctx context.Context, arg CancelStandingOrderParams) (StandingOrder, error) {
+	row := q.db.QueryRowContext(ctx, cancelStandingOrder, arg.ID, arg.Owner)
+	var i StandingOrder
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.Currency,
+		&i.Rrule,
+		&i.StartsAt,
+		&i.CatchUp,
+		&i.Status,
+		&i.NextRunAt,
+		&i.RunCount,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const cancelStandingOrdersByUsername = `-- name: CancelStandingOrdersByUsername :execrows
+UPDATE standing_orders
+SET status = 'canceled'
+WHERE status IN ('active', 'paused')
+    AND (standing_orders.owner = $1 OR to_account_id IN (SELECT accounts.id FROM accounts WHERE accounts.owner = $1))
+`
+
+// the orders of an erased user and the ones to their accounts
+func (q *Queries) CancelStandingOrdersByUsername(ctx context.Context, username string) (int64, error) {
+	result, err := q.db.ExecContext(ctx, cancelStandingOrdersByUsername, username)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const createStandingOrder = `-- name: CreateStandingOrder :one
+INSERT INTO standing_orders (
+    owner,
+    from_account_id,
+    to_account_id,
+    amount,
+    currency,
+    rrule,
+    starts_at,
+    catch_up,
+    next_run_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9
+) RETURNING id, owner, from_account_id, to_account_id, amount, currency, rrule, starts_at, catch_up, status, next_run_at, run_count, created_at
+`
+
+type CreateStandingOrderParams struct {
+	Owner         string    `json:"owner"`
+	FromAccountID int64     `json:"from_account_id"`
+	ToAccountID   int64     `json:"to_account_id"`
+	Amount        int64     `json:"amount"`
+	Currency      string    `json:"currency"`
+	Rrule         string    `json:"rrule"`
+	StartsAt      time.Time `json:"starts_at"`
+	CatchUp       string    `json:"catch_up"`
+	NextRunAt     time.Time `json:"next_run_at"`
+}
+
+func (q *Queries) CreateStandingOrder(ctx context.Context, arg CreateStandingOrderParams) (StandingOrder, error) {
+	row := q.db.QueryRowContext(ctx, createStandingOrder,
+		arg.Owner,
+		arg.FromAccountID,
+		arg.ToAccountID,
+		arg.Amount,
+		arg.Currency,
+		arg.Rrule,
+		arg.StartsAt,
+		arg.CatchUp,
+		arg.NextRunAt,
+	)
+	var i StandingOrder
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.Currency,
+		&i.Rrule,
+		&i.StartsAt,
+		&i.CatchUp,
+		&i.Status,
+		&i.NextRunAt,
+		&i.RunCount,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createStandingOrderRun = `-- name: CreateStandingOrderRun :one
+INSERT INTO standing_order_runs (
+    standing_order_id,
+    scheduled_for,
+    status,
+    transfer_id,
+    error
+) VALUES (
+    $1, $2, $3, $4, $5
+) RETURNING id, standing_order_id, scheduled_for, status, transfer_id, error, created_at
+`
+
+type CreateStandingOrderRunParams struct {
+	StandingOrderID int64          `json:"standing_order_id"`
+	ScheduledFor    time.Time      `json:"scheduled_for"`
+	Status          string         `json:"status"`
+	TransferID      sql.NullInt64  `json:"transfer_id"`
+	Error           sql.NullString `json:"error"`
+}
+
+func (q *Queries) CreateStandingOrderRun(ctx context.Context, arg CreateStandingOrderRunParams) (StandingOrderRun, error) {
+	row := q.db.QueryRowContext(ctx, createStandingOrderRun,
+		arg.StandingOrderID,
+		arg.ScheduledFor,
+		arg.Status,
+		arg.TransferID,
+		arg.Error,
+	)
+	var i StandingOrderRun
+	err := row.Scan(
+		&i.ID,
+		&i.StandingOrderID,
+		&i.ScheduledFor,
+		&i.Status,
+		&i.TransferID,
+		&i.Error,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getDueStandingOrderForUpdate = `-- name: GetDueStandingOrderForUpdate :one
+SELECT id, owner, from_account_id, to_account_id, amount, currency, rrule, starts_at, catch_up, status, next_run_at, run_count, created_at FROM standing_orders
+WHERE status = 'active' AND next_run_at <= $1
+ORDER BY next_run_at, id
+LIMIT 1
+FOR UPDATE SKIP LOCKED
+`
+
+func (q *Queries) GetDueStandingOrderForUpdate(ctx context.Context, now time.Time) (StandingOrder, error) {
+	row := q.db.QueryRowContext(ctx, getDueStandingOrderForUpdate, now)
+	var i StandingOrder
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.Currency,
+		&i.Rrule,
+		&i.StartsAt,
+		&i.CatchUp,
+		&i.Status,
+		&i.NextRunAt,
+		&i.RunCount,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getStandingOrder = `-- name: GetStandingOrder :one
+SELECT id, owner, from_account_id, to_account_id, amount, currency, rrule, starts_at, catch_up, status, next_run_at, run_count, created_at FROM standing_orders
+WHERE id = $1 LIMIT 1
+`
+
+func (q *Queries) GetStandingOrder(ctx context.Context, id int64) (StandingOrder, error) {
+	row := q.db.QueryRowContext(ctx, getStandingOrder, id)
+	var i StandingOrder
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.Currency,
+		&i.Rrule,
+		&i.StartsAt,
+		&i.CatchUp,
+		&i.Status,
+		&i.NextRunAt,
+		&i.RunCount,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getStandingOrderForUpdate = `-- name: GetStandingOrderForUpdate :one
+SELECT id, owner, from_account_id, to_account_id, amount, currency, rrule, starts_at, catch_up, status, next_run_at, run_count, created_at FROM standing_orders
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE
+`
+
+func (q *Queries) GetStandingOrderForUpdate(ctx context.Context, id int64) (StandingOrder, error) {
+	row := q.db.QueryRowContext(ctx, getStandingOrderForUpdate, id)
+	var i StandingOrder
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.Currency,
+		&i.Rrule,
+		&i.StartsAt,
+		&i.CatchUp,
+		&i.Status,
+		&i.NextRunAt,
+		&i.RunCount,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const listStandingOrderRuns = `-- name: ListStandingOrderRuns :many
+SELECT id, standing_order_id, scheduled_for, status, transfer_id, error, created_at FROM standing_order_runs
+WHERE standing_order_id = $1
+ORDER BY scheduled_for DESC, id DESC
+LIMIT $2
+OFFSET $3
+`
+
+type ListStandingOrderRunsParams struct {
+	StandingOrderID int64 `json:"standing_order_id"`
+	Limit           int32 `json:"limit"`
+	Offset          int32 `json:"offset"`
+}
+
+func (q *Queries) ListStandingOrderRuns(ctx context.Context, arg ListStandingOrderRunsParams) ([]StandingOrderRun, error) {
+	rows, err := q.db.QueryContext(ctx, listStandingOrderRuns, arg.StandingOrderID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []StandingOrderRun
+	for rows.Next() {
+		var i StandingOrderRun
+		if err := rows.Scan(
+			&i.ID,
+			&i.StandingOrderID,
+			&i.ScheduledFor,
+			&i.Status,
+			&i.TransferID,
+			&i.Error,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listStandingOrders = `-- name: ListStandingOrders :many
+SELECT id, owner, from_account_id, to_account_id, amount, currency, rrule, starts_at, catch_up, status, next_run_at, run_count, created_at FROM standing_orders
+WHERE owner = $1
+ORDER BY id
+LIMIT $2
+OFFSET $3
+`
+
+type ListStandingOrdersParams struct {
+	Owner  string `json:"owner"`
+	Limit  int32  `json:"limit"`
+	Offset int32  `json:"offset"`
+}
+
+func (q *Queries) ListStandingOrders(ctx context.Context, arg ListStandingOrdersParams) ([]StandingOrder, error) {
+	rows, err := q.db.QueryContext(ctx, listStandingOrders, arg.Owner, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []StandingOrder
+	for rows.Next() {
+		var i StandingOrder
+		if err := rows.Scan(
+			&i.ID,
+			&i.Owner,
+			&i.FromAccountID,
+			&i.ToAccountID,
+			&i.Amount,
+			&i.Currency,
+			&i.Rrule,
+			&i.StartsAt,
+			&i.CatchUp,
+			&i.Status,
+			&i.NextRunAt,
+			&i.RunCount,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const pauseStandingOrder = `-- name: PauseStandingOrder :one
+UPDATE standing_orders
+SET status = 'paused'
+WHERE id = $1 AND owner = $2 AND status = 'active'
+RETURNING id, owner, from_account_id, to_account_id, amount, currency, rrule, starts_at, catch_up, status, next_run_at, run_count, created_at
+`
+
+type PauseStandingOrderParams struct {
+	ID    int64  `json:"id"`
+	Owner string `json:"owner"`
+}
+
+func (q *Queries) PauseStandingOrder(ctx context.Context, arg PauseStandingOrderParams) (StandingOrder, error) {
+	row := q.db.QueryRowContext(ctx, pauseStandingOrder, arg.ID, arg.Owner)
+	var i StandingOrder
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.Currency,
+		&i.Rrule,
+		&i.StartsAt,
+		&i.CatchUp,
+		&i.Status,
+		&i.NextRunAt,
+		&i.RunCount,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const resumeStandingOrder = `-- name: ResumeStandingOrder :one
+UPDATE standing_orders
+SET status = 'active',
+    next_run_at = $1
+WHERE id = $2 AND owner = $3 AND status = 'paused'
+RETURNING id, owner, from_account_id, to_account_id, amount, currency, rrule, starts_at, catch_up, status, next_run_at, run_count, created_at
+`
+
+type ResumeStandingOrderParams struct {
+	NextRunAt time.Time `json:"next_run_at"`
+	ID        int64     `json:"id"`
+	Owner     string    `json:"owner"`
+}
+
+// runs missed while paused are not made, next_run_at is the first run after resuming
+func (q *Queries) ResumeStandingOrder(ctx context.Context, arg ResumeStandingOrderParams) (StandingOrder, error) {
+	row := q.db.QueryRowContext(ctx, resumeStandingOrder, arg.NextRunAt, arg.ID, arg.Owner)
+	var i StandingOrder
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.Currency,
+		&i.Rrule,
+		&i.StartsAt,
+		&i.CatchUp,
+		&i.Status,
+		&i.NextRunAt,
+		&i.RunCount,
+		&i.CreatedAt,
+	)
+	return i, err
+}
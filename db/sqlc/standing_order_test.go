@@ -0,0 +1,127 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomStandingOrder(t *testing.T, userSuffix string, rrule string, catchUp string, startsAt time.Time) (StandingOrder, Account, Account) {
+	fromAccount, toAccount := createScheduledTransferAccounts(t, userSuffix)
+
+	order, err := testQueries.CreateStandingOrder(context.Background(), CreateStandingOrderParams{
+		Owner:         fromAccount.Owner,
+		FromAccountID: fromAccount.ID,
+		ToAccountID:   toAccount.ID,
+		Amount:        10,
+		Currency:      fromAccount.Currency,
+		Rrule:         rrule,
+		StartsAt:      startsAt,
+		CatchUp:       catchUp,
+		NextRunAt:     startsAt,
+	})
+	require.NoError(t, err)
+	require.Equal(t, StandingOrderActive, order.Status)
+	return order, fromAccount, toAccount
+}
+
+// executeStandingOrder works through the due standing orders until the given one has run,
+// other due rows may be left over from previous runs
+func executeStandingOrder(t *testing.T, store Store, orderID int64, now time.Time) (StandingOrder, StandingOrderRun) {
+	for i := 0; i < 100; i++ {
+		order, run, err := store.ExecuteStandingOrderTx(context.Background(), now, nil)
+		if err == sql.ErrNoRows {
+			break
+		}
+		if order.ID == orderID {
+			require.NoError(t, err)
+			return order, run
+		}
+	}
+	t.Fatalf("standing order %d was not executed", orderID)
+	return StandingOrder{}, StandingOrderRun{}
+}
+
+func TestExecuteStandingOrderTxCatchUpLatest(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	startsAt := now.Add(-72*time.Hour - time.Minute)
+	order, fromAccount, _ := createRandomStandingOrder(t, "_test_standing_order_latest", "FREQ=DAILY", StandingOrderCatchUpLatest, startsAt)
+
+	executed, run := executeStandingOrder(t, testStore, order.ID, now)
+	require.Equal(t, StandingOrderRunSucceeded, run.Status)
+	require.True(t, run.TransferID.Valid)
+	require.True(t, startsAt.Add(72*time.Hour).Equal(run.ScheduledFor))
+	require.Equal(t, int32(1), executed.RunCount)
+	require.True(t, startsAt.Add(96*time.Hour).Equal(executed.NextRunAt))
+
+	runs, err := testQueries.ListStandingOrderRuns(context.Background(), ListStandingOrderRunsParams{
+		StandingOrderID: order.ID,
+		Limit:           10,
+	})
+	require.NoError(t, err)
+	require.Len(t, runs, 4)
+	for _, skipped := range runs[1:] {
+		require.Equal(t, StandingOrderRunSkipped, skipped.Status)
+	}
+
+	account, err := testQueries.GetAccount(context.Background(), fromAccount.ID)
+	require.NoError(t, err)
+	require.Equal(t, fromAccount.Balance-order.Amount, account.Balance)
+}
+
+func TestExecuteStandingOrderTxFinishes(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	startsAt := now.Add(-25 * time.Hour)
+	order, _, _ := createRandomStandingOrder(t, "_test_standing_order_count", "FREQ=DAILY;COUNT=2", StandingOrderCatchUpAll, startsAt)
+
+	executed, _ := executeStandingOrder(t, testStore, order.ID, now)
+	require.Equal(t, StandingOrderActive, executed.Status)
+	executed, _ = executeStandingOrder(t, testStore, order.ID, now)
+	require.Equal(t, StandingOrderFinished, executed.Status)
+	require.Equal(t, int32(2), executed.RunCount)
+}
+
+func TestExecuteStandingOrderTxFee(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	order, fromAccount, _ := createRandomStandingOrder(t, "_test_standing_order_fee", "FREQ=WEEKLY", StandingOrderCatchUpAll, now.Add(-time.Minute))
+
+	_, run := executeStandingOrder(t, testFeeStore, order.ID, now)
+	require.Equal(t, StandingOrderRunSucceeded, run.Status)
+	transfer, err := testQueries.GetTransfer(context.Background(), run.TransferID.Int64)
+	require.NoError(t, err)
+	requireFeeEntry(t, transfer, testFee)
+
+	account, err := testQueries.GetAccount(context.Background(), fromAccount.ID)
+	require.NoError(t, err)
+	require.Equal(t, fromAccount.Balance-order.Amount-testFee, account.Balance)
+}
+
+func TestRecordStandingOrderFailureTx(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	startsAt := now.Add(-time.Hour)
+	order, _, _ := createRandomStandingOrder(t, "_test_standing_order_failure", "FREQ=WEEKLY", StandingOrderCatchUpAll, startsAt)
+
+	failed, run, err := testStore.RecordStandingOrderFailureTx(context.Background(), RecordStandingOrderFailureTxParams{
+		ID:        order.ID,
+		NextRunAt: order.NextRunAt,
+		Error:     "account not found",
+		Now:       now,
+	})
+	require.NoError(t, err)
+	require.Equal(t, StandingOrderRunFailed, run.Status)
+	require.Equal(t, "account not found", run.Error.String)
+	require.Equal(t, int32(0), failed.RunCount)
+	require.True(t, startsAt.Add(7*24*time.Hour).Equal(failed.NextRunAt))
+
+	// the order moved on, the same failure is not recorded twice
+	_, _, err = testStore.RecordStandingOrderFailureTx(context.Background(), RecordStandingOrderFailureTxParams{
+		ID:        order.ID,
+		NextRunAt: order.NextRunAt,
+		Error:     "account not found",
+		Now:       now,
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
@@ -3,28 +3,65 @@ package db
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"time"
+
+	"github.com/go_backend_misc/fee"
+	"github.com/go_backend_misc/util"
 )
 
 type Store interface {
 	// interface composition: Store embeds Querier (in Go, vs inheritance)
 	Querier
 	TransferTx(ctx context.Context, arg CreateTransferParams) (result TransferTxResult, err error)
+	PostJournalTx(ctx context.Context, arg PostJournalTxParams) (PostJournalTxResult, error)
+	ReconcileLedger(ctx context.Context, correct bool) (LedgerReport, error)
+	CorrectBalanceTx(ctx context.Context, accountID int64) (Journal, error)
+	AccountBalanceAt(ctx context.Context, accountID int64, at time.Time) (AccountBalance, error)
+	TransferAllowance(ctx context.Context, accountID int64) (TransferAllowance, error)
+	EnrollMFATx(ctx context.Context, arg EnrollMFATxParams) (UserMfa, error)
+	ResetPasswordTx(ctx context.Context, arg ResetPasswordTxParams) (User, error)
+	VerifyEmailTx(ctx context.Context, hashedToken string) (User, error)
+	EraseUserTx(ctx context.Context, arg PseudonymizeUserParams) (User, error)
+	EncryptUsersPII(ctx context.Context, batchSize int32) (int, error)
+	ExecuteScheduledTransferTx(ctx context.Context, screen ScreenTransfer) (ScheduledTransfer, TransferTxResult, error)
+	CreateHoldTx(ctx context.Context, arg CreateHoldParams) (Hold, error)
+	CaptureHoldTx(ctx context.Context, arg CaptureHoldTxParams) (CaptureHoldTxResult, error)
+	VoidHoldTx(ctx context.Context, holdID int64) (Hold, error)
+	ExpireHoldTx(ctx context.Context) (Hold, error)
+	ReverseTransferTx(ctx context.Context, arg ReverseTransferTxParams) (TransferTxResult, error)
+	BatchTransferTx(ctx context.Context, arg BatchTransferTxParams) (BatchTransferTxResult, error)
+	ExecuteStandingOrderTx(ctx context.Context, now time.Time, screen ScreenTransfer) (StandingOrder, StandingOrderRun, error)
+	RecordStandingOrderFailureTx(ctx context.Context, arg RecordStandingOrderFailureTxParams) (StandingOrder, StandingOrderRun, error)
+	ApproveRiskReviewTx(ctx context.Context, arg ApproveRiskReviewTxParams) (ApproveRiskReviewTxResult, error)
 }
 
 type SQLStore struct {
 	// struct composition: SQLStore embeds Queries (in Go, vs inheritance)
 	*Queries
-	db *sql.DB
+	db             *sql.DB
+	fieldEncryptor *util.FieldEncryptor
+	feeSchedule    *fee.Schedule
 }
 
-func NewStore(db *sql.DB) Store {
+// NewStore encrypts user emails and names with the field encryptor, see store_encryption.go
+// The fee schedule is charged on the transfers the store makes on its own: scheduled transfers,
+// standing orders and batch items; a nil schedule makes them free
+func NewStore(db *sql.DB, fieldEncryptor *util.FieldEncryptor, feeSchedule *fee.Schedule) Store {
 	return &SQLStore{
-		Queries: New(db),
-		db:      db,
+		Queries:        New(db),
+		db:             db,
+		fieldEncryptor: fieldEncryptor,
+		feeSchedule:    feeSchedule,
 	}
 }
 
+// transferFee is the fee of a transfer the store makes on its own, TransferTx charges the fee its caller quoted
+func (store *SQLStore) transferFee(currency string, amount int64) int64 {
+	return store.feeSchedule.Calculate(currency, amount).Total
+}
+
 // execTx runs a function within a database transaction
 // This function is unexported (lowercase), so it can only be called from within the db package
 func (store *SQLStore) executeTransaction(ctx context.Context, innerFunction func(*Queries) error) error {
@@ -47,12 +84,23 @@ func (store *SQLStore) executeTransaction(ctx context.Context, innerFunction fun
 
 }
 
+// ErrInsufficientFunds means the transfer is more than the available balance, the balance minus the active holds
+var ErrInsufficientFunds = errors.New("insufficient funds")
+
+// ErrInvalidDestination means the receiving account can't be paid into, a system account
+// or an account of an erased user
+var ErrInvalidDestination = errors.New("account can't receive transfers")
+
 type TransferTxResult struct {
-	Transfer    Transfer `json: "from_account_id"`
-	FromAccount Account  `json: "from_account"`
-	ToAccount   Account  `json: "to_account"`
-	FromEntry   Entry    `json: "from_entry"`
-	ToEntry     Entry    `json: "to_entry"`
+	Transfer    Transfer `json:"transfer"`
+	Journal     Journal  `json:"journal"`
+	FromAccount Account  `json:"from_account"`
+	ToAccount   Account  `json:"to_account"`
+	FromEntry   Entry    `json:"from_entry"`
+	ToEntry     Entry    `json:"to_entry"`
+	// FeeEntry and HouseFeeEntry are the fee of the transfer taken from the sender and booked to the fee account
+	FeeEntry      *Entry `json:"fee_entry,omitempty"`
+	HouseFeeEntry *Entry `json:"house_fee_entry,omitempty"`
 }
 
 // txKey is a custom key to store the transaction name in the context
@@ -61,74 +109,104 @@ var txKey = struct{}{}
 
 // TransferTx performs a money transfer from one account to the other
 // It creates a transfer record, add account entries, and update accounts' balance within a single database transaction
+// It's a convenience over PostJournalTx that writes the journal of the transfer
 func (store *SQLStore) TransferTx(ctx context.Context, arg CreateTransferParams) (result TransferTxResult, err error) {
 	txErr := store.executeTransaction(ctx, func(queries *Queries) error {
 		// used for debugging: txName := ctx.Value(txKey)
-		result.Transfer, err = queries.CreateTransfer(ctx, CreateTransferParams{
-			FromAccountID: arg.FromAccountID,
-			ToAccountID:   arg.ToAccountID,
-			Amount:        arg.Amount,
-		})
-		if err != nil {
-			return err
-		}
-
-		result.FromEntry, err = queries.CreateEntry(ctx, CreateEntryParams{
-			AccountID: arg.FromAccountID,
-			Amount:    -arg.Amount,
-		})
-		if err != nil {
-			return err
-		}
-
-		result.ToEntry, err = queries.CreateEntry(ctx, CreateEntryParams{
-			AccountID: arg.ToAccountID,
-			Amount:    arg.Amount,
-		})
-		if err != nil {
-			return err
-		}
-
-		// update in the same ID order to avoid deadlocks
-		if arg.FromAccountID.Int64 < arg.ToAccountID.Int64 {
-			result.FromAccount, result.ToAccount, err = moveMoney(
-				ctx, queries, arg.FromAccountID.Int64, -arg.Amount, arg.ToAccountID.Int64, +arg.Amount,
-			)
-		} else {
-			result.ToAccount, result.FromAccount, err = moveMoney(
-				ctx, queries, arg.ToAccountID.Int64, +arg.Amount, arg.FromAccountID.Int64, -arg.Amount,
-			)
-		}
-
-		return nil
+		result, err = transfer(ctx, queries, arg)
+		return err
 	})
 
 	return result, txErr
 }
 
-func moveMoney(
-	ctx context.Context,
-	q *Queries,
-	accountID1 int64,
-	amount1 int64,
-	accountID2 int64,
-	amount2 int64,
-) (account1 Account, account2 Account, err error) {
-	account1, err = q.AddAccountBalance(ctx, AddAccountBalanceParams{
-		ID:     accountID1,
-		Amount: amount1,
+// transfer runs the statements of a transfer on queries bound to an open transaction
+// It writes a journal with the two postings of the transfer, and two more for the fee when there is one:
+// the sender pays the fee on top of the amount, to the fee account of the currency
+// The amount must fit in the transfer limits of the sender, reversals give money back and aren't limited
+// System accounts can't be the receiving account, only reversals pay back into them,
+// and neither can the accounts of erased users
+func transfer(ctx context.Context, queries *Queries, arg CreateTransferParams) (result TransferTxResult, err error) {
+	result.Transfer, err = queries.CreateTransfer(ctx, CreateTransferParams{
+		FromAccountID:      arg.FromAccountID,
+		ToAccountID:        arg.ToAccountID,
+		Amount:             arg.Amount,
+		ReversesTransferID: arg.ReversesTransferID,
+		Fee:                arg.Fee,
 	})
 	if err != nil {
 		return
 	}
 
-	account2, err = q.AddAccountBalance(ctx, AddAccountBalanceParams{
-		ID:     accountID2,
-		Amount: amount2,
+	postings := []Posting{
+		{AccountID: arg.FromAccountID.Int64, Amount: -arg.Amount},
+		{AccountID: arg.ToAccountID.Int64, Amount: arg.Amount},
+	}
+	if arg.Fee > 0 {
+		feeAccountID, err := queries.GetSystemAccountIDForAccount(ctx, GetSystemAccountIDForAccountParams{
+			Purpose:   SystemAccountFees,
+			AccountID: arg.FromAccountID.Int64,
+		})
+		if err != nil {
+			return result, fmt.Errorf("cannot find the fee account: %w", err)
+		}
+		postings = append(postings,
+			Posting{AccountID: arg.FromAccountID.Int64, Amount: -arg.Fee},
+			Posting{AccountID: feeAccountID, Amount: arg.Fee},
+		)
+	}
+
+	journal, err := postJournal(ctx, queries, PostJournalTxParams{
+		Kind:       JournalTransfer,
+		TransferID: Int64ToSqlInt64(result.Transfer.ID),
+		Postings:   postings,
 	})
 	if err != nil {
-		return
+		return result, err
+	}
+
+	result.Journal = journal.Journal
+	result.FromAccount = journal.Accounts[arg.FromAccountID.Int64]
+	result.ToAccount = journal.Accounts[arg.ToAccountID.Int64]
+	result.FromEntry, result.ToEntry = journal.Entries[0], journal.Entries[1]
+	if arg.Fee > 0 {
+		result.FeeEntry, result.HouseFeeEntry = &journal.Entries[2], &journal.Entries[3]
+	}
+
+	// checked after the journal too, which holds the row lock of the receiver, like erasing its owner does
+	if err := checkDestination(ctx, queries, &result.ToAccount, arg.ReversesTransferID.Valid); err != nil {
+		return result, err
+	}
+
+	// checked after the journal, which holds the row lock of the sender, like the available balance
+	if !arg.ReversesTransferID.Valid {
+		allowance, err := transferAllowance(ctx, queries, &result.FromAccount, time.Now(), result.Transfer.ID)
+		if err != nil {
+			return result, err
+		}
+		if err := allowance.Check(arg.Amount); err != nil {
+			return result, fmt.Errorf("%w: account %d", err, result.FromAccount.ID)
+		}
 	}
+	return result, nil
+}
 
-	return
+// checkDestination refuses the system accounts, the money a reversal gives back aside,
+// and the accounts of erased users, nobody is left to move what is paid into them
+func checkDestination(ctx context.Context, queries *Queries, account *Account, isReversal bool) error {
+	if account.Kind == AccountKindSystem {
+		if isReversal {
+			return nil
+		}
+		return fmt.Errorf("%w: account %d is a system account", ErrInvalidDestination, account.ID)
+	}
+
+	isErased, err := queries.IsUserErased(ctx, account.Owner)
+	if err != nil {
+		return err
+	}
+	if isErased {
+		return fmt.Errorf("%w: account %d belongs to an erased user", ErrInvalidDestination, account.ID)
+	}
+	return nil
 }
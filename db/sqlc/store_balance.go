@@ -0,0 +1,49 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// AccountBalance is the balance of an account at a point in time, the sum of its entries up to then
+type AccountBalance struct {
+	AccountID int64     `json:"account_id"`
+	At        time.Time `json:"at"`
+	Balance   int64     `json:"balance"`
+	// SnapshotTakenUntil is the snapshot the balance starts from, the entries before it weren't read
+	SnapshotTakenUntil *time.Time `json:"snapshot_taken_until,omitempty"`
+}
+
+// AccountBalanceAt adds up the entries of the account created up to at, starting from the latest snapshot before it
+// Snapshots and entries are never updated, so it doesn't need a transaction
+func (store *SQLStore) AccountBalanceAt(ctx context.Context, accountID int64, at time.Time) (AccountBalance, error) {
+	balance := AccountBalance{AccountID: accountID, At: at}
+
+	// without a snapshot every entry is added up
+	var since time.Time
+	snapshot, err := store.GetLatestBalanceSnapshot(ctx, GetLatestBalanceSnapshotParams{
+		AccountID: accountID,
+		At:        at,
+	})
+	switch err {
+	case nil:
+		balance.Balance = snapshot.Balance
+		balance.SnapshotTakenUntil = &snapshot.TakenUntil
+		since = snapshot.TakenUntil
+	case sql.ErrNoRows:
+	default:
+		return balance, err
+	}
+
+	total, err := store.GetAccountEntriesTotalBetween(ctx, GetAccountEntriesTotalBetweenParams{
+		AccountID: Int64ToSqlInt64(accountID),
+		Since:     since,
+		Until:     at,
+	})
+	if err != nil {
+		return balance, err
+	}
+	balance.Balance += total
+	return balance, nil
+}
@@ -0,0 +1,157 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// The methods below shadow the generated queries that read or write users, so the rest of the app
+// only sees plain emails and names while the database stores them encrypted.
+// Emails are looked up and kept unique through their blind index
+
+func (store *SQLStore) encryptedEmail(email string) (encrypted string, emailIndex sql.NullString, err error) {
+	encrypted, err = store.fieldEncryptor.EncryptField(email)
+	if err != nil {
+		return "", sql.NullString{}, err
+	}
+	return encrypted, sql.NullString{String: store.fieldEncryptor.BlindIndex(email), Valid: true}, nil
+}
+
+func (store *SQLStore) decryptUser(user User, err error) (User, error) {
+	if err != nil {
+		return user, err
+	}
+
+	user.Email, err = store.fieldEncryptor.DecryptField(user.Email)
+	if err != nil {
+		return User{}, fmt.Errorf("cannot decrypt email of user %v: %w", user.Username, err)
+	}
+	user.FullName, err = store.fieldEncryptor.DecryptField(user.FullName)
+	if err != nil {
+		return User{}, fmt.Errorf("cannot decrypt full name of user %v: %w", user.Username, err)
+	}
+
+	// rows written before encryption was enabled get their index from the backfill
+	if !user.EmailIndex.Valid {
+		user.EmailIndex = sql.NullString{String: store.fieldEncryptor.BlindIndex(user.Email), Valid: true}
+	}
+	return user, nil
+}
+
+func (store *SQLStore) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
+	var err error
+	arg.Email, arg.EmailIndex, err = store.encryptedEmail(arg.Email)
+	if err != nil {
+		return User{}, err
+	}
+	arg.FullName, err = store.fieldEncryptor.EncryptField(arg.FullName)
+	if err != nil {
+		return User{}, err
+	}
+	return store.decryptUser(store.Queries.CreateUser(ctx, arg))
+}
+
+func (store *SQLStore) GetUserByUsername(ctx context.Context, username string) (User, error) {
+	return store.decryptUser(store.Queries.GetUserByUsername(ctx, username))
+}
+
+func (store *SQLStore) GetUserByEmail(ctx context.Context, email string) (User, error) {
+	return store.decryptUser(store.Queries.GetUserByEmail(ctx, store.fieldEncryptor.BlindIndex(email)))
+}
+
+func (store *SQLStore) GetUserByPasswordResetToken(ctx context.Context, hashedToken string) (User, error) {
+	return store.decryptUser(store.Queries.GetUserByPasswordResetToken(ctx, hashedToken))
+}
+
+func (store *SQLStore) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error) {
+	if arg.FullName.Valid {
+		encrypted, err := store.fieldEncryptor.EncryptField(arg.FullName.String)
+		if err != nil {
+			return User{}, err
+		}
+		arg.FullName.String = encrypted
+	}
+	return store.decryptUser(store.Queries.UpdateUser(ctx, arg))
+}
+
+func (store *SQLStore) UpdateUserEmail(ctx context.Context, arg UpdateUserEmailParams) (User, error) {
+	var err error
+	arg.Email, arg.EmailIndex, err = store.encryptedEmail(arg.Email)
+	if err != nil {
+		return User{}, err
+	}
+	return store.decryptUser(store.Queries.UpdateUserEmail(ctx, arg))
+}
+
+func (store *SQLStore) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) (User, error) {
+	return store.decryptUser(store.Queries.UpdateUserPassword(ctx, arg))
+}
+
+func (store *SQLStore) MarkUserEmailVerified(ctx context.Context, arg MarkUserEmailVerifiedParams) (User, error) {
+	return store.decryptUser(store.Queries.MarkUserEmailVerified(ctx, arg))
+}
+
+func (store *SQLStore) PseudonymizeUser(ctx context.Context, arg PseudonymizeUserParams) (User, error) {
+	arg, err := store.encryptPseudonymizeUserParams(arg)
+	if err != nil {
+		return User{}, err
+	}
+	return store.decryptUser(store.Queries.PseudonymizeUser(ctx, arg))
+}
+
+func (store *SQLStore) encryptPseudonymizeUserParams(arg PseudonymizeUserParams) (PseudonymizeUserParams, error) {
+	var err error
+	arg.Email, arg.EmailIndex, err = store.encryptedEmail(arg.Email)
+	return arg, err
+}
+
+// Screening matches keep the blind index of the name screened rather than the name
+
+func (store *SQLStore) CreateScreeningMatch(ctx context.Context, arg CreateScreeningMatchParams) (ScreeningMatch, error) {
+	arg.FullName = store.fieldEncryptor.BlindIndex(arg.FullName)
+	return store.Queries.CreateScreeningMatch(ctx, arg)
+}
+
+func (store *SQLStore) ListScreeningEntries(ctx context.Context, arg ListScreeningEntriesParams) ([]ListScreeningEntriesRow, error) {
+	arg.FullName = store.fieldEncryptor.BlindIndex(arg.FullName)
+	return store.Queries.ListScreeningEntries(ctx, arg)
+}
+
+// EncryptUsersPII encrypts the users written before encryption was enabled, in batches,
+// and returns how many were updated
+func (store *SQLStore) EncryptUsersPII(ctx context.Context, batchSize int32) (int, error) {
+	encryptedCount := 0
+	for {
+		// read the raw rows, a half-finished previous run may have left some values already encrypted
+		users, err := store.Queries.ListUsersWithoutEmailIndex(ctx, batchSize)
+		if err != nil {
+			return encryptedCount, err
+		}
+		if len(users) == 0 {
+			return encryptedCount, nil
+		}
+
+		for _, user := range users {
+			user, err = store.decryptUser(user, nil)
+			if err != nil {
+				return encryptedCount, err
+			}
+
+			arg := UpdateUserPIIParams{Username: user.Username}
+			arg.Email, arg.EmailIndex, err = store.encryptedEmail(user.Email)
+			if err != nil {
+				return encryptedCount, err
+			}
+			arg.FullName, err = store.fieldEncryptor.EncryptField(user.FullName)
+			if err != nil {
+				return encryptedCount, err
+			}
+
+			if err := store.Queries.UpdateUserPII(ctx, arg); err != nil {
+				return encryptedCount, fmt.Errorf("cannot encrypt user %v: %w", user.Username, err)
+			}
+			encryptedCount++
+		}
+	}
+}
@@ -44,7 +44,7 @@ func runTransferTxTests(
 }
 
 func TestTransferTx(t *testing.T) {
-	store := NewStore(testDB)
+	store := testStore
 	fromAccountTest, _, _, _ := createRandomAccount("_test_transfer_tx_1")
 	toAccountTest, _, _, _ := createRandomAccount("_test_transfer_tx_2")
 	fromId := sql.NullInt64{
@@ -72,8 +72,68 @@ func TestTransferTx(t *testing.T) {
 	require.Equal(t, toAccountTest.Balance+amount, dbAccountTo.Balance)
 }
 
+func TestTransferTxFee(t *testing.T) {
+	fromAccount, toAccount := createScheduledTransferAccounts(t, "_test_transfer_tx_fee")
+	feeAccountID, err := testQueries.GetSystemAccountIDForAccount(context.Background(), GetSystemAccountIDForAccountParams{
+		Purpose:   SystemAccountFees,
+		AccountID: fromAccount.ID,
+	})
+	require.NoError(t, err)
+	feeAccount, err := testQueries.GetAccount(context.Background(), feeAccountID)
+	require.NoError(t, err)
+
+	var amount, fee int64 = 10, 3
+	result, err := testStore.TransferTx(context.Background(), CreateTransferParams{
+		FromAccountID: Int64ToSqlInt64(fromAccount.ID),
+		ToAccountID:   Int64ToSqlInt64(toAccount.ID),
+		Amount:        amount,
+		Fee:           fee,
+	})
+	runTransferTxTests(t, err, &fromAccount, &toAccount, result, amount, testStore)
+	require.Equal(t, fee, result.Transfer.Fee)
+	require.Equal(t, -fee, result.FeeEntry.Amount)
+	require.Equal(t, fromAccount.ID, result.FeeEntry.AccountID.Int64)
+	require.Equal(t, fee, result.HouseFeeEntry.Amount)
+	require.Equal(t, feeAccountID, result.HouseFeeEntry.AccountID.Int64)
+
+	// the sender pays the fee on top of the amount
+	require.Equal(t, fromAccount.Balance-amount-fee, result.FromAccount.Balance)
+	require.Equal(t, toAccount.Balance+amount, result.ToAccount.Balance)
+	updatedFeeAccount, err := testQueries.GetAccount(context.Background(), feeAccountID)
+	require.NoError(t, err)
+	require.Equal(t, feeAccount.Balance+fee, updatedFeeAccount.Balance)
+
+	// the fee counts towards the available balance
+	_, err = testStore.TransferTx(context.Background(), CreateTransferParams{
+		FromAccountID: Int64ToSqlInt64(fromAccount.ID),
+		ToAccountID:   Int64ToSqlInt64(toAccount.ID),
+		Amount:        result.FromAccount.AvailableBalance,
+		Fee:           fee,
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+}
+
+// requireFeeEntry checks that the fee of a transfer is booked from the sender to its fee account
+func requireFeeEntry(t *testing.T, transfer Transfer, fee int64) {
+	require.Equal(t, fee, transfer.Fee)
+
+	feeAccountID, err := testQueries.GetSystemAccountIDForAccount(context.Background(), GetSystemAccountIDForAccountParams{
+		Purpose:   SystemAccountFees,
+		AccountID: transfer.FromAccountID.Int64,
+	})
+	require.NoError(t, err)
+
+	var feeEntries int
+	err = testDB.QueryRow(`SELECT count(*) FROM entries JOIN journals ON journals.id = entries.journal_id
+		WHERE journals.transfer_id = $1
+		AND ((entries.account_id = $2 AND entries.amount = $3) OR (entries.account_id = $4 AND entries.amount = $5))`,
+		transfer.ID, transfer.FromAccountID.Int64, -fee, feeAccountID, fee).Scan(&feeEntries)
+	require.NoError(t, err)
+	require.Equal(t, 2, feeEntries)
+}
+
 func TestTransferTxConcurrent(t *testing.T) {
-	store := NewStore(testDB)
+	store := testStore
 	accountFromTest, _, _, _ := createRandomAccount("_test_transfer_tx_1")
 	accountToTest, _, _, _ := createRandomAccount("_test_transfer_tx_2")
 	fromId := sql.NullInt64{
@@ -123,7 +183,7 @@ func TestTransferTxConcurrent(t *testing.T) {
 }
 
 func TestTransferTxConcurrentCrossAmounts(t *testing.T) {
-	store := NewStore(testDB)
+	store := testStore
 	account1, _, _, _ := createRandomAccount("_test_transfer_tx_1")
 	account2, _, _, _ := createRandomAccount("_test_transfer_tx_2")
 
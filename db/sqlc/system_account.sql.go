@@ -0,0 +1,29 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.25.0
+// source: system_account.sql
+
+package db
+
+import (
+	"context"
+)
+
+const getSystemAccountIDForAccount = `-- name: GetSystemAccountIDForAccount :one
+SELECT system_accounts.account_id FROM system_accounts
+JOIN accounts ON accounts.currency = system_accounts.currency
+WHERE system_accounts.purpose = $1 AND accounts.id = $2
+`
+
+type GetSystemAccountIDForAccountParams struct {
+	Purpose   string `json:"purpose"`
+	AccountID int64  `json:"account_id"`
+}
+
+// the system account for the purpose in the currency of the account
+func (q *Queries) GetSystemAccountIDForAccount(ctx context.Context, arg GetSystemAccountIDForAccountParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, getSystemAccountIDForAccount, arg.Purpose, arg.AccountID)
+	var account_id int64
+	err := row.Scan(&account_id)
+	return account_id, err
+}
@@ -8,26 +8,59 @@ package db
 import (
 	"context"
 	"database/sql"
+	"time"
 )
 
+const countTransfersSince = `-- name: CountTransfersSince :one
+SELECT COUNT(*) FROM transfers
+WHERE from_account_id = $1
+    AND created_at > $2
+    AND reverses_transfer_id IS NULL
+    AND ($3::bigint = 0 OR amount % $3::bigint = 0)
+`
+
+type CountTransfersSinceParams struct {
+	AccountID sql.NullInt64 `json:"account_id"`
+	Since     time.Time     `json:"since"`
+	RoundTo   int64         `json:"round_to"`
+}
+
+// the transfers sent from the account since then, those of round amounts when round_to isn't 0
+func (q *Queries) CountTransfersSince(ctx context.Context, arg CountTransfersSinceParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countTransfersSince, arg.AccountID, arg.Since, arg.RoundTo)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createTransfer = `-- name: CreateTransfer :one
 INSERT INTO transfers (
     from_account_id,
     to_account_id,
-    amount
+    amount,
+    reverses_transfer_id,
+    fee
 ) VALUES (
-    $1, $2, $3
-) RETURNING id, from_account_id, to_account_id, amount, created_at
+    $1, $2, $3, $4, $5
+) RETURNING id, from_account_id, to_account_id, amount, created_at, reverses_transfer_id, fee
 `
 
 type CreateTransferParams struct {
-	FromAccountID sql.NullInt64 `json:"from_account_id"`
-	ToAccountID   sql.NullInt64 `json:"to_account_id"`
-	Amount        int64         `json:"amount"`
+	FromAccountID      sql.NullInt64 `json:"from_account_id"`
+	ToAccountID        sql.NullInt64 `json:"to_account_id"`
+	Amount             int64         `json:"amount"`
+	ReversesTransferID sql.NullInt64 `json:"reverses_transfer_id"`
+	Fee                int64         `json:"fee"`
 }
 
 func (q *Queries) CreateTransfer(ctx context.Context, arg CreateTransferParams) (Transfer, error) {
-	row := q.db.QueryRowContext(ctx, createTransfer, arg.FromAccountID, arg.ToAccountID, arg.Amount)
+	row := q.db.QueryRowContext(ctx, createTransfer,
+		arg.FromAccountID,
+		arg.ToAccountID,
+		arg.Amount,
+		arg.ReversesTransferID,
+		arg.Fee,
+	)
 	var i Transfer
 	err := row.Scan(
 		&i.ID,
@@ -35,12 +68,26 @@ func (q *Queries) CreateTransfer(ctx context.Context, arg CreateTransferParams)
 		&i.ToAccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.ReversesTransferID,
+		&i.Fee,
 	)
 	return i, err
 }
 
+const getReversedAmount = `-- name: GetReversedAmount :one
+SELECT COALESCE(SUM(amount), 0)::bigint FROM transfers
+WHERE reverses_transfer_id = $1
+`
+
+func (q *Queries) GetReversedAmount(ctx context.Context, reversesTransferID sql.NullInt64) (int64, error) {
+	row := q.db.QueryRowContext(ctx, getReversedAmount, reversesTransferID)
+	var column_1 int64
+	err := row.Scan(&column_1)
+	return column_1, err
+}
+
 const getTransfer = `-- name: GetTransfer :one
-SELECT id, from_account_id, to_account_id, amount, created_at FROM transfers
+SELECT id, from_account_id, to_account_id, amount, created_at, reverses_transfer_id, fee FROM transfers
 WHERE id = $1 LIMIT 1
 `
 
@@ -53,12 +100,55 @@ func (q *Queries) GetTransfer(ctx context.Context, id int64) (Transfer, error) {
 		&i.ToAccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.ReversesTransferID,
+		&i.Fee,
 	)
 	return i, err
 }
 
+const getTransferForUpdate = `-- name: GetTransferForUpdate :one
+SELECT id, from_account_id, to_account_id, amount, created_at, reverses_transfer_id, fee FROM transfers
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE
+`
+
+// locks the transfer so concurrent reversals of it are made one after the other
+func (q *Queries) GetTransferForUpdate(ctx context.Context, id int64) (Transfer, error) {
+	row := q.db.QueryRowContext(ctx, getTransferForUpdate, id)
+	var i Transfer
+	err := row.Scan(
+		&i.ID,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.CreatedAt,
+		&i.ReversesTransferID,
+		&i.Fee,
+	)
+	return i, err
+}
+
+const hasTransferredTo = `-- name: HasTransferredTo :one
+SELECT EXISTS (
+    SELECT 1 FROM transfers
+    WHERE from_account_id = $1 AND to_account_id = $2
+)::boolean
+`
+
+type HasTransferredToParams struct {
+	FromAccountID sql.NullInt64 `json:"from_account_id"`
+	ToAccountID   sql.NullInt64 `json:"to_account_id"`
+}
+
+func (q *Queries) HasTransferredTo(ctx context.Context, arg HasTransferredToParams) (bool, error) {
+	row := q.db.QueryRowContext(ctx, hasTransferredTo, arg.FromAccountID, arg.ToAccountID)
+	var column_1 bool
+	err := row.Scan(&column_1)
+	return column_1, err
+}
+
 const listTransfers = `-- name: ListTransfers :many
-SELECT id, from_account_id, to_account_id, amount, created_at FROM transfers
+SELECT id, from_account_id, to_account_id, amount, created_at, reverses_transfer_id, fee FROM transfers
 WHERE
     from_account_id = $1
     OR to_account_id = $2
@@ -94,6 +184,120 @@ func (q *Queries) ListTransfers(ctx context.Context, arg ListTransfersParams) ([
 			&i.ToAccountID,
 			&i.Amount,
 			&i.CreatedAt,
+			&i.ReversesTransferID,
+			&i.Fee,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTransfersByUsername = `-- name: ListTransfersByUsername :many
+SELECT id, from_account_id, to_account_id, amount, created_at, reverses_transfer_id, fee FROM transfers
+WHERE
+    from_account_id IN (SELECT accounts.id FROM accounts WHERE accounts.owner = $1)
+    OR to_account_id IN (SELECT accounts.id FROM accounts WHERE accounts.owner = $1)
+ORDER BY id
+`
+
+func (q *Queries) ListTransfersByUsername(ctx context.Context, owner string) ([]Transfer, error) {
+	rows, err := q.db.QueryContext(ctx, listTransfersByUsername, owner)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Transfer
+	for rows.Next() {
+		var i Transfer
+		if err := rows.Scan(
+			&i.ID,
+			&i.FromAccountID,
+			&i.ToAccountID,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.ReversesTransferID,
+			&i.Fee,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUnbalancedTransfers = `-- name: ListUnbalancedTransfers :many
+SELECT id, from_account_id, to_account_id, amount, fee, journal_id, entry_count, from_total, to_total, to_fee_account FROM (
+    SELECT
+        transfers.id,
+        transfers.from_account_id,
+        transfers.to_account_id,
+        transfers.amount,
+        transfers.fee,
+        journals.id AS journal_id,
+        COUNT(entries.id) AS entry_count,
+        COALESCE(SUM(entries.amount) FILTER (WHERE entries.account_id = transfers.from_account_id), 0)::bigint AS from_total,
+        COALESCE(SUM(entries.amount) FILTER (WHERE entries.account_id = transfers.to_account_id), 0)::bigint AS to_total,
+        -- the fee goes to the receiving account when it's the fee account
+        COALESCE(transfers.to_account_id IN (SELECT account_id FROM system_accounts WHERE purpose = 'fees'), false)::boolean AS to_fee_account
+    FROM transfers
+    LEFT JOIN journals ON journals.transfer_id = transfers.id
+    LEFT JOIN entries ON entries.journal_id = journals.id
+    GROUP BY transfers.id, journals.id
+) AS checked
+WHERE entry_count <> CASE WHEN fee > 0 THEN 4 ELSE 2 END
+    OR from_total <> -(amount + fee)
+    OR to_total <> amount + CASE WHEN to_fee_account THEN fee ELSE 0 END
+ORDER BY id
+`
+
+type ListUnbalancedTransfersRow struct {
+	ID            int64         `json:"id"`
+	FromAccountID sql.NullInt64 `json:"from_account_id"`
+	ToAccountID   sql.NullInt64 `json:"to_account_id"`
+	Amount        int64         `json:"amount"`
+	Fee           int64         `json:"fee"`
+	JournalID     sql.NullInt64 `json:"journal_id"`
+	EntryCount    int64         `json:"entry_count"`
+	FromTotal     int64         `json:"from_total"`
+	ToTotal       int64         `json:"to_total"`
+	ToFeeAccount  bool          `json:"to_fee_account"`
+}
+
+// transfers without exactly their entry pair, and the fee pair when they have a fee, in their journal
+func (q *Queries) ListUnbalancedTransfers(ctx context.Context) ([]ListUnbalancedTransfersRow, error) {
+	rows, err := q.db.QueryContext(ctx, listUnbalancedTransfers)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListUnbalancedTransfersRow
+	for rows.Next() {
+		var i ListUnbalancedTransfersRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.FromAccountID,
+			&i.ToAccountID,
+			&i.Amount,
+			&i.Fee,
+			&i.JournalID,
+			&i.EntryCount,
+			&i.FromTotal,
+			&i.ToTotal,
+			&i.ToFeeAccount,
 		); err != nil {
 			return nil, err
 		}
@@ -0,0 +1,148 @@
+package db
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func createBatchTransferAccounts(t *testing.T, userSuffix string) (Account, Account, Account) {
+	fromAccount, toAccount := createScheduledTransferAccounts(t, userSuffix)
+	otherAccount, err := testQueries.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    toAccount.Owner,
+		Balance:  10,
+		Currency: fromAccount.Currency,
+	})
+	require.NoError(t, err)
+	return fromAccount, toAccount, otherAccount
+}
+
+func TestBatchTransferTxBestEffort(t *testing.T) {
+	fromAccount, toAccount, otherAccount := createBatchTransferAccounts(t, "_test_batch_best_effort")
+	feeAccountID, err := testQueries.GetSystemAccountIDForAccount(context.Background(), GetSystemAccountIDForAccountParams{
+		Purpose:   SystemAccountFees,
+		AccountID: fromAccount.ID,
+	})
+	require.NoError(t, err)
+
+	result, err := testStore.BatchTransferTx(context.Background(), BatchTransferTxParams{
+		FromAccountID: fromAccount.ID,
+		Items: []BatchTransferItem{
+			{ToAccountID: toAccount.ID, Amount: 10},
+			{ToAccountID: math.MaxInt64, Amount: 10},
+			{ToAccountID: otherAccount.ID, Amount: fromAccount.Balance},
+			{ToAccountID: otherAccount.ID, Amount: 5},
+			{ToAccountID: fromAccount.ID, Amount: 5},
+			{ToAccountID: feeAccountID, Amount: 5},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, result.Succeeded)
+	require.Equal(t, 4, result.Failed)
+	require.NotNil(t, result.Items[0].Transfer)
+	require.Contains(t, result.Items[1].Error, ErrInvalidBatchItem.Error())
+	require.Contains(t, result.Items[2].Error, ErrInsufficientFunds.Error())
+	require.NotNil(t, result.Items[3].Transfer)
+	require.Contains(t, result.Items[4].Error, ErrInvalidBatchItem.Error())
+	require.Contains(t, result.Items[5].Error, "system account")
+	require.Equal(t, fromAccount.Balance-15, result.FromAccount.Balance)
+
+	// each item has its own journal, posted like a single transfer
+	for _, item := range []BatchTransferItemResult{result.Items[0], result.Items[3]} {
+		var entries int
+		err := testDB.QueryRow(`SELECT count(*) FROM entries JOIN journals ON journals.id = entries.journal_id
+			WHERE journals.transfer_id = $1`, item.Transfer.ID).Scan(&entries)
+		require.NoError(t, err)
+		require.Equal(t, 2, entries)
+	}
+
+	account, err := testQueries.GetAccount(context.Background(), toAccount.ID)
+	require.NoError(t, err)
+	require.Equal(t, toAccount.Balance+10, account.Balance)
+	account, err = testQueries.GetAccount(context.Background(), otherAccount.ID)
+	require.NoError(t, err)
+	require.Equal(t, otherAccount.Balance+5, account.Balance)
+}
+
+func TestBatchTransferTxFee(t *testing.T) {
+	fromAccount, toAccount, otherAccount := createBatchTransferAccounts(t, "_test_batch_fee")
+
+	result, err := testFeeStore.BatchTransferTx(context.Background(), BatchTransferTxParams{
+		FromAccountID: fromAccount.ID,
+		Items: []BatchTransferItem{
+			{ToAccountID: toAccount.ID, Amount: 10},
+			{ToAccountID: otherAccount.ID, Amount: 5},
+			// the amount is available but the fee on top of it isn't
+			{ToAccountID: otherAccount.ID, Amount: fromAccount.Balance - 15 - 2*testFee},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, result.Succeeded)
+	requireFeeEntry(t, *result.Items[0].Transfer, testFee)
+	requireFeeEntry(t, *result.Items[1].Transfer, testFee)
+	require.Contains(t, result.Items[2].Error, ErrInsufficientFunds.Error())
+	require.Equal(t, fromAccount.Balance-15-2*testFee, result.FromAccount.Balance)
+}
+
+func TestBatchTransferTxAllOrNothing(t *testing.T) {
+	fromAccount, toAccount, otherAccount := createBatchTransferAccounts(t, "_test_batch_all_or_nothing")
+
+	_, err := testStore.BatchTransferTx(context.Background(), BatchTransferTxParams{
+		FromAccountID: fromAccount.ID,
+		Items: []BatchTransferItem{
+			{ToAccountID: toAccount.ID, Amount: 10},
+			{ToAccountID: otherAccount.ID, Amount: fromAccount.Balance},
+		},
+		AllOrNothing: true,
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+	var itemErr *BatchItemError
+	require.ErrorAs(t, err, &itemErr)
+	require.Equal(t, 1, itemErr.Index)
+
+	// nothing was made
+	account, err := testQueries.GetAccount(context.Background(), fromAccount.ID)
+	require.NoError(t, err)
+	require.Equal(t, fromAccount.Balance, account.Balance)
+	account, err = testQueries.GetAccount(context.Background(), toAccount.ID)
+	require.NoError(t, err)
+	require.Equal(t, toAccount.Balance, account.Balance)
+}
+
+func TestBatchTransferTxConcurrent(t *testing.T) {
+	fromAccount, toAccount, otherAccount := createBatchTransferAccounts(t, "_test_batch_concurrent")
+
+	// batches and transfers in opposite directions lock the same accounts, they must not deadlock
+	n := 5
+	errs := make(chan error, 2*n)
+	for i := 0; i < n; i++ {
+		go func() {
+			_, err := testStore.BatchTransferTx(context.Background(), BatchTransferTxParams{
+				FromAccountID: fromAccount.ID,
+				Items: []BatchTransferItem{
+					{ToAccountID: otherAccount.ID, Amount: 1},
+					{ToAccountID: toAccount.ID, Amount: 1},
+				},
+				AllOrNothing: true,
+			})
+			errs <- err
+		}()
+		go func() {
+			_, err := testStore.TransferTx(context.Background(), CreateTransferParams{
+				FromAccountID: Int64ToSqlInt64(otherAccount.ID),
+				ToAccountID:   Int64ToSqlInt64(fromAccount.ID),
+				Amount:        1,
+			})
+			errs <- err
+		}()
+	}
+	for i := 0; i < 2*n; i++ {
+		require.NoError(t, <-errs)
+	}
+
+	account, err := testQueries.GetAccount(context.Background(), fromAccount.ID)
+	require.NoError(t, err)
+	require.Equal(t, fromAccount.Balance-int64(n), account.Balance)
+}
@@ -0,0 +1,123 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.25.0
+// source: transfer_limit.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const getAccountTransferLimits = `-- name: GetAccountTransferLimits :one
+SELECT
+    COALESCE(users.kyc_tier, 0)::int AS kyc_tier,
+    COALESCE(account_transfer_limits.per_transaction, transfer_limits.per_transaction, 0)::bigint AS per_transaction,
+    COALESCE(account_transfer_limits.daily, transfer_limits.daily, 0)::bigint AS daily,
+    COALESCE(account_transfer_limits.monthly, transfer_limits.monthly, 0)::bigint AS monthly
+FROM accounts
+LEFT JOIN users ON users.username = accounts.owner
+LEFT JOIN transfer_limits ON transfer_limits.kyc_tier = COALESCE(users.kyc_tier, 0)
+    AND transfer_limits.currency = accounts.currency
+LEFT JOIN account_transfer_limits ON account_transfer_limits.account_id = accounts.id
+WHERE accounts.id = $1
+`
+
+type GetAccountTransferLimitsRow struct {
+	KycTier        int32 `json:"kyc_tier"`
+	PerTransaction int64 `json:"per_transaction"`
+	Daily          int64 `json:"daily"`
+	Monthly        int64 `json:"monthly"`
+}
+
+// the limits of the account: its overrides, or else the defaults of the KYC tier of its owner in its currency
+func (q *Queries) GetAccountTransferLimits(ctx context.Context, accountID int64) (GetAccountTransferLimitsRow, error) {
+	row := q.db.QueryRowContext(ctx, getAccountTransferLimits, accountID)
+	var i GetAccountTransferLimitsRow
+	err := row.Scan(
+		&i.KycTier,
+		&i.PerTransaction,
+		&i.Daily,
+		&i.Monthly,
+	)
+	return i, err
+}
+
+const getTransferredTotals = `-- name: GetTransferredTotals :one
+SELECT
+    COALESCE(SUM(amount) FILTER (WHERE created_at > $1), 0)::bigint AS day_total,
+    COALESCE(SUM(amount), 0)::bigint AS month_total
+FROM transfers
+WHERE from_account_id = $2
+    AND created_at > $3
+    AND reverses_transfer_id IS NULL
+    AND id <> $4
+`
+
+type GetTransferredTotalsParams struct {
+	DayStart          time.Time     `json:"day_start"`
+	AccountID         sql.NullInt64 `json:"account_id"`
+	MonthStart        time.Time     `json:"month_start"`
+	ExcludeTransferID int64         `json:"exclude_transfer_id"`
+}
+
+type GetTransferredTotalsRow struct {
+	DayTotal   int64 `json:"day_total"`
+	MonthTotal int64 `json:"month_total"`
+}
+
+// the amounts sent from the account since each start, reversals give money back and don't count
+func (q *Queries) GetTransferredTotals(ctx context.Context, arg GetTransferredTotalsParams) (GetTransferredTotalsRow, error) {
+	row := q.db.QueryRowContext(ctx, getTransferredTotals,
+		arg.DayStart,
+		arg.AccountID,
+		arg.MonthStart,
+		arg.ExcludeTransferID,
+	)
+	var i GetTransferredTotalsRow
+	err := row.Scan(&i.DayTotal, &i.MonthTotal)
+	return i, err
+}
+
+const setAccountTransferLimits = `-- name: SetAccountTransferLimits :one
+INSERT INTO account_transfer_limits (
+    account_id,
+    per_transaction,
+    daily,
+    monthly
+) VALUES (
+    $1, $2, $3, $4
+)
+ON CONFLICT (account_id) DO UPDATE SET
+    per_transaction = EXCLUDED.per_transaction,
+    daily = EXCLUDED.daily,
+    monthly = EXCLUDED.monthly
+RETURNING account_id, per_transaction, daily, monthly, created_at
+`
+
+type SetAccountTransferLimitsParams struct {
+	AccountID      int64         `json:"account_id"`
+	PerTransaction sql.NullInt64 `json:"per_transaction"`
+	Daily          sql.NullInt64 `json:"daily"`
+	Monthly        sql.NullInt64 `json:"monthly"`
+}
+
+func (q *Queries) SetAccountTransferLimits(ctx context.Context, arg SetAccountTransferLimitsParams) (AccountTransferLimit, error) {
+	row := q.db.QueryRowContext(ctx, setAccountTransferLimits,
+		arg.AccountID,
+		arg.PerTransaction,
+		arg.Daily,
+		arg.Monthly,
+	)
+	var i AccountTransferLimit
+	err := row.Scan(
+		&i.AccountID,
+		&i.PerTransaction,
+		&i.Daily,
+		&i.Monthly,
+		&i.CreatedAt,
+	)
+	return i, err
+}
@@ -0,0 +1,131 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/go_backend_misc/util"
+	"github.com/stretchr/testify/require"
+)
+
+func overrideTransferLimits(t *testing.T, accountID int64, perTransaction, daily, monthly sql.NullInt64) {
+	_, err := testQueries.SetAccountTransferLimits(context.Background(), SetAccountTransferLimitsParams{
+		AccountID:      accountID,
+		PerTransaction: perTransaction,
+		Daily:          daily,
+		Monthly:        monthly,
+	})
+	require.NoError(t, err)
+}
+
+func TestTransferTxLimits(t *testing.T) {
+	fromAccount, toAccount := createScheduledTransferAccounts(t, "_test_transfer_limits")
+	overrideTransferLimits(t, fromAccount.ID, Int64ToSqlInt64(20), Int64ToSqlInt64(30), sql.NullInt64{})
+	transfer := func(amount int64) error {
+		_, err := testStore.TransferTx(context.Background(), CreateTransferParams{
+			FromAccountID: Int64ToSqlInt64(fromAccount.ID),
+			ToAccountID:   Int64ToSqlInt64(toAccount.ID),
+			Amount:        amount,
+		})
+		return err
+	}
+
+	require.ErrorIs(t, transfer(25), ErrTransferLimitExceeded)
+	require.NoError(t, transfer(20))
+	require.ErrorIs(t, transfer(15), ErrTransferLimitExceeded)
+	require.NoError(t, transfer(10))
+
+	allowance, err := testStore.TransferAllowance(context.Background(), fromAccount.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(20), allowance.PerTransaction)
+	require.Equal(t, LimitUsage{Limit: 30, Used: 30, Remaining: 0, Since: allowance.Daily.Since}, allowance.Daily)
+	// the monthly limit is the default, none
+	require.Equal(t, int64(0), allowance.Monthly.Limit)
+	require.Equal(t, int64(30), allowance.Monthly.Used)
+
+	// the refused transfers left nothing behind
+	account, err := testQueries.GetAccount(context.Background(), fromAccount.ID)
+	require.NoError(t, err)
+	require.Equal(t, fromAccount.Balance-30, account.Balance)
+}
+
+func TestTransferTxLimitsKYCTier(t *testing.T) {
+	fromAccount, toAccount := createScheduledTransferAccounts(t, "_test_transfer_limits_tier")
+	tier := int32(util.RandomInt(1000, 1_000_000))
+	rows, err := testQueries.UpdateUserKYCTier(context.Background(), UpdateUserKYCTierParams{
+		Username: fromAccount.Owner,
+		KycTier:  tier,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), rows)
+	_, err = testDB.Exec("INSERT INTO transfer_limits (kyc_tier, currency, daily) VALUES ($1, $2, 15)", tier, fromAccount.Currency)
+	require.NoError(t, err)
+
+	arg := CreateTransferParams{
+		FromAccountID: Int64ToSqlInt64(fromAccount.ID),
+		ToAccountID:   Int64ToSqlInt64(toAccount.ID),
+		Amount:        10,
+	}
+	_, err = testStore.TransferTx(context.Background(), arg)
+	require.NoError(t, err)
+	_, err = testStore.TransferTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrTransferLimitExceeded)
+
+	// the override of the account takes precedence
+	overrideTransferLimits(t, fromAccount.ID, sql.NullInt64{}, Int64ToSqlInt64(20), sql.NullInt64{})
+	_, err = testStore.TransferTx(context.Background(), arg)
+	require.NoError(t, err)
+
+	allowance, err := testStore.TransferAllowance(context.Background(), fromAccount.ID)
+	require.NoError(t, err)
+	require.Equal(t, tier, allowance.KYCTier)
+	require.Equal(t, int64(20), allowance.Daily.Remaining+allowance.Daily.Used)
+}
+
+func TestTransferTxLimitsConcurrent(t *testing.T) {
+	fromAccount, toAccount := createScheduledTransferAccounts(t, "_test_transfer_limits_concurrent")
+	overrideTransferLimits(t, fromAccount.ID, sql.NullInt64{}, Int64ToSqlInt64(50), sql.NullInt64{})
+
+	n := 10
+	errs := make(chan error)
+	for i := 0; i < n; i++ {
+		go func() {
+			_, err := testStore.TransferTx(context.Background(), CreateTransferParams{
+				FromAccountID: Int64ToSqlInt64(fromAccount.ID),
+				ToAccountID:   Int64ToSqlInt64(toAccount.ID),
+				Amount:        10,
+			})
+			errs <- err
+		}()
+	}
+
+	succeeded := 0
+	for i := 0; i < n; i++ {
+		err := <-errs
+		if err == nil {
+			succeeded++
+			continue
+		}
+		require.ErrorIs(t, err, ErrTransferLimitExceeded)
+	}
+	require.Equal(t, 5, succeeded)
+}
+
+func TestBatchTransferTxLimits(t *testing.T) {
+	fromAccount, toAccount, otherAccount := createBatchTransferAccounts(t, "_test_batch_transfer_limits")
+	overrideTransferLimits(t, fromAccount.ID, sql.NullInt64{}, Int64ToSqlInt64(30), sql.NullInt64{})
+
+	result, err := testStore.BatchTransferTx(context.Background(), BatchTransferTxParams{
+		FromAccountID: fromAccount.ID,
+		Items: []BatchTransferItem{
+			{ToAccountID: toAccount.ID, Amount: 20},
+			{ToAccountID: otherAccount.ID, Amount: 20},
+			{ToAccountID: otherAccount.ID, Amount: 10},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, result.Succeeded)
+	require.Contains(t, result.Items[1].Error, ErrTransferLimitExceeded.Error())
+	require.Equal(t, fromAccount.Balance-30, result.FromAccount.Balance)
+}
@@ -0,0 +1,80 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomTransfer(t *testing.T, userSuffix string, amount int64) (Transfer, Account, Account) {
+	fromAccount, toAccount := createScheduledTransferAccounts(t, userSuffix)
+	result, err := testStore.TransferTx(context.Background(), CreateTransferParams{
+		FromAccountID: Int64ToSqlInt64(fromAccount.ID),
+		ToAccountID:   Int64ToSqlInt64(toAccount.ID),
+		Amount:        amount,
+	})
+	require.NoError(t, err)
+	return result.Transfer, result.FromAccount, result.ToAccount
+}
+
+func TestReverseTransferTx(t *testing.T) {
+	original, fromAccount, toAccount := createRandomTransfer(t, "_test_reverse_transfer", 10)
+
+	result, err := testStore.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: original.ID,
+		Amount:     4,
+	})
+	runTransferTxTests(t, err, &toAccount, &fromAccount, result, 4, testStore)
+	require.Equal(t, original.ID, result.Transfer.ReversesTransferID.Int64)
+	require.Equal(t, fromAccount.Balance+4, result.ToAccount.Balance)
+	require.Equal(t, toAccount.Balance-4, result.FromAccount.Balance)
+
+	// without an amount, the rest of the transfer is refunded
+	result, err = testStore.ReverseTransferTx(context.Background(), ReverseTransferTxParams{TransferID: original.ID})
+	require.NoError(t, err)
+	require.Equal(t, int64(6), result.Transfer.Amount)
+
+	_, err = testStore.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: original.ID,
+		Amount:     1,
+	})
+	require.ErrorIs(t, err, ErrReversalExceedsTransfer)
+
+	_, err = testStore.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: result.Transfer.ID,
+		Amount:     1,
+	})
+	require.ErrorIs(t, err, ErrTransferNotReversible)
+}
+
+func TestReverseTransferTxConcurrent(t *testing.T) {
+	original, _, _ := createRandomTransfer(t, "_test_reverse_transfer_concurrent", 10)
+
+	n := 5
+	errs := make(chan error)
+	for i := 0; i < n; i++ {
+		go func() {
+			_, err := testStore.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+				TransferID: original.ID,
+				Amount:     4,
+			})
+			errs <- err
+		}()
+	}
+
+	succeeded := 0
+	for i := 0; i < n; i++ {
+		err := <-errs
+		if err == nil {
+			succeeded++
+			continue
+		}
+		require.ErrorIs(t, err, ErrReversalExceedsTransfer)
+	}
+	require.Equal(t, 2, succeeded)
+
+	reversed, err := testQueries.GetReversedAmount(context.Background(), Int64ToSqlInt64(original.ID))
+	require.NoError(t, err)
+	require.Equal(t, int64(8), reversed)
+}
@@ -0,0 +1,197 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ErrInvalidBatchItem is returned for items to a missing account, to an account in another currency
+// or to the source account itself
+var ErrInvalidBatchItem = errors.New("invalid batch item")
+
+// BatchItemError is the failure of an item, it fails the whole batch in all-or-nothing mode
+type BatchItemError struct {
+	Index int
+	Err   error
+}
+
+func (e *BatchItemError) Error() string {
+	return fmt.Sprintf("item %d: %v", e.Index, e.Err)
+}
+
+func (e *BatchItemError) Unwrap() error {
+	return e.Err
+}
+
+type BatchTransferItem struct {
+	ToAccountID int64 `json:"to_account_id"`
+	Amount      int64 `json:"amount"`
+}
+
+type BatchTransferTxParams struct {
+	FromAccountID int64
+	Items         []BatchTransferItem
+	// AllOrNothing fails the batch on the first failed item, otherwise the other items are still made
+	AllOrNothing bool
+}
+
+type BatchTransferItemResult struct {
+	Index    int       `json:"index"`
+	Transfer *Transfer `json:"transfer,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+type BatchTransferTxResult struct {
+	FromAccount Account                   `json:"from_account"`
+	Items       []BatchTransferItemResult `json:"items"`
+	Succeeded   int                       `json:"succeeded"`
+	Failed      int                       `json:"failed"`
+}
+
+// BatchTransferTx makes many transfers from one account within a single database transaction
+// Every account is locked once up front, in ID order like TransferTx, then each item is a transfer
+// with its own journal; items are checked before they're made, so a failed item doesn't write anything
+// and the items count in the transfer limits of the source account one after the other
+func (store *SQLStore) BatchTransferTx(ctx context.Context, arg BatchTransferTxParams) (result BatchTransferTxResult, err error) {
+	txErr := store.executeTransaction(ctx, func(queries *Queries) error {
+		result = BatchTransferTxResult{Items: make([]BatchTransferItemResult, len(arg.Items))}
+
+		accounts, err := lockBatchAccounts(ctx, queries, &arg, store.feeSchedule != nil)
+		if err != nil {
+			return err
+		}
+		fromAccount, ok := accounts[arg.FromAccountID]
+		if !ok {
+			return sql.ErrNoRows
+		}
+		result.FromAccount = fromAccount
+
+		allowance, err := transferAllowance(ctx, queries, &fromAccount, time.Now(), 0)
+		if err != nil {
+			return err
+		}
+
+		for i, item := range arg.Items {
+			result.Items[i].Index = i
+
+			fee := store.transferFee(fromAccount.Currency, item.Amount)
+			itemErr := validateBatchItem(&result.FromAccount, accounts, item, fee)
+			if itemErr == nil {
+				toAccount := accounts[item.ToAccountID]
+				itemErr = checkDestination(ctx, queries, &toAccount, false)
+				if itemErr != nil && !errors.Is(itemErr, ErrInvalidDestination) {
+					return itemErr
+				}
+			}
+			if itemErr == nil {
+				itemErr = allowance.Check(item.Amount)
+			}
+			if itemErr != nil {
+				if arg.AllOrNothing {
+					return &BatchItemError{Index: i, Err: itemErr}
+				}
+				result.Items[i].Error = itemErr.Error()
+				result.Failed++
+				continue
+			}
+
+			transferResult, err := transfer(ctx, queries, CreateTransferParams{
+				FromAccountID: Int64ToSqlInt64(arg.FromAccountID),
+				ToAccountID:   Int64ToSqlInt64(item.ToAccountID),
+				Amount:        item.Amount,
+				Fee:           fee,
+			})
+			if err != nil {
+				return err
+			}
+			result.Items[i].Transfer = &transferResult.Transfer
+			result.Succeeded++
+			result.FromAccount = transferResult.FromAccount
+			allowance.spend(item.Amount)
+		}
+		return nil
+	})
+
+	return result, txErr
+}
+
+// lockBatchAccounts locks the source and every receiving account in ID order, accounts that don't exist
+// are left out of the map
+// With fees, the fee account of the source is locked in the same order, transfers update it with their accounts
+func lockBatchAccounts(ctx context.Context, queries *Queries, arg *BatchTransferTxParams, withFees bool) (map[int64]Account, error) {
+	ids := []int64{arg.FromAccountID}
+	if withFees {
+		feeAccountID, ok, err := getFeeAccountID(ctx, queries, arg.FromAccountID)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			ids = append(ids, feeAccountID)
+		}
+	}
+	for _, item := range arg.Items {
+		ids = append(ids, item.ToAccountID)
+	}
+	return lockAccounts(ctx, queries, ids)
+}
+
+// getFeeAccountID returns the fee account of the currency of the account, ok is false when there is none
+func getFeeAccountID(ctx context.Context, queries *Queries, accountID int64) (feeAccountID int64, ok bool, err error) {
+	feeAccountID, err = queries.GetSystemAccountIDForAccount(ctx, GetSystemAccountIDForAccountParams{
+		Purpose:   SystemAccountFees,
+		AccountID: accountID,
+	})
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	return feeAccountID, err == nil, err
+}
+
+// lockAccounts locks the accounts once each in ID order, like transfers do, accounts that don't exist
+// are left out of the map
+func lockAccounts(ctx context.Context, queries *Queries, ids []int64) (map[int64]Account, error) {
+	sorted := make([]int64, 0, len(ids))
+	seen := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			sorted = append(sorted, id)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	accounts := make(map[int64]Account, len(sorted))
+	for _, id := range sorted {
+		account, err := queries.GetAccountForUpdate(ctx, id)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		accounts[id] = account
+	}
+	return accounts, nil
+}
+
+// validateBatchItem checks an item and its fee against the source account as the previous items left it
+func validateBatchItem(fromAccount *Account, accounts map[int64]Account, item BatchTransferItem, fee int64) error {
+	toAccount, ok := accounts[item.ToAccountID]
+	switch {
+	case !ok:
+		return fmt.Errorf("%w: account %d not found", ErrInvalidBatchItem, item.ToAccountID)
+	case toAccount.ID == fromAccount.ID:
+		return fmt.Errorf("%w: cannot transfer to the same account", ErrInvalidBatchItem)
+	case toAccount.Kind == AccountKindSystem:
+		return fmt.Errorf("%w: account %d is a system account", ErrInvalidBatchItem, toAccount.ID)
+	case toAccount.Currency != fromAccount.Currency:
+		return fmt.Errorf("%w: account %d currency mismatch", ErrInvalidBatchItem, toAccount.ID)
+	case item.Amount+fee > fromAccount.AvailableBalance:
+		return fmt.Errorf("%w: account %d", ErrInsufficientFunds, fromAccount.ID)
+	}
+	return nil
+}
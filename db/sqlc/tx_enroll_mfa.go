@@ -0,0 +1,42 @@
+package db
+
+import "context"
+
+type EnrollMFATxParams struct {
+	Username            string
+	EncryptedSecret     string
+	HashedRecoveryCodes []string
+}
+
+// EnrollMFATx stores a new (not yet enabled) TOTP secret for the user
+// and replaces their recovery codes within a single database transaction
+func (store *SQLStore) EnrollMFATx(ctx context.Context, arg EnrollMFATxParams) (userMFA UserMfa, err error) {
+	txErr := store.executeTransaction(ctx, func(queries *Queries) error {
+		userMFA, err = queries.UpsertUserMFA(ctx, UpsertUserMFAParams{
+			Username:        arg.Username,
+			EncryptedSecret: arg.EncryptedSecret,
+		})
+		if err != nil {
+			return err
+		}
+
+		err = queries.DeleteMFARecoveryCodes(ctx, arg.Username)
+		if err != nil {
+			return err
+		}
+
+		for _, hashedCode := range arg.HashedRecoveryCodes {
+			_, err = queries.CreateMFARecoveryCode(ctx, CreateMFARecoveryCodeParams{
+				Username:   arg.Username,
+				HashedCode: hashedCode,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	return userMFA, txErr
+}
@@ -0,0 +1,86 @@
+package db
+
+import (
+	"context"
+	"errors"
+)
+
+var ErrNonZeroBalance = errors.New("every account balance must be zero")
+
+// EraseUserTx pseudonymizes the user and drops their credentials and email verification tokens
+// within a single database transaction
+// Accounts, entries and transfers are kept for accounting, so the accounts are locked
+// to make sure no money is left in them while the user is erased, and nothing is paid into them afterwards:
+// the scheduled transfers, standing orders, holds and pending risk reviews of the user and the ones
+// to their accounts are canceled, voided or rejected with them
+func (store *SQLStore) EraseUserTx(ctx context.Context, arg PseudonymizeUserParams) (user User, err error) {
+	arg, err = store.encryptPseudonymizeUserParams(arg)
+	if err != nil {
+		return User{}, err
+	}
+
+	txErr := store.executeTransaction(ctx, func(queries *Queries) error {
+		// claimed before the accounts, like the executors and the approvals do
+		if _, err := queries.CancelScheduledTransfersByUsername(ctx, arg.Username); err != nil {
+			return err
+		}
+		if _, err := queries.CancelStandingOrdersByUsername(ctx, arg.Username); err != nil {
+			return err
+		}
+		if _, err := queries.RejectRiskReviewsByUsername(ctx, arg.Username); err != nil {
+			return err
+		}
+		holds, err := queries.ListActiveHoldsByUsernameForUpdate(ctx, arg.Username)
+		if err != nil {
+			return err
+		}
+
+		accounts, err := queries.ListAllAccountsByUsername(ctx, arg.Username)
+		if err != nil {
+			return err
+		}
+		ids := make([]int64, 0, len(accounts)+len(holds))
+		for _, account := range accounts {
+			ids = append(ids, account.ID)
+		}
+		for _, hold := range holds {
+			ids = append(ids, hold.AccountID)
+		}
+		locked, err := lockAccounts(ctx, queries, ids)
+		if err != nil {
+			return err
+		}
+		for _, account := range accounts {
+			if locked[account.ID].Balance != 0 {
+				return ErrNonZeroBalance
+			}
+		}
+		for i := range holds {
+			if _, err := releaseHold(ctx, queries, &holds[i], HoldVoided); err != nil {
+				return err
+			}
+		}
+
+		user, err = queries.PseudonymizeUser(ctx, arg)
+		if err != nil {
+			return err
+		}
+
+		if err := queries.RevokeAPIKeysByOwner(ctx, arg.Username); err != nil {
+			return err
+		}
+		if err := queries.DeleteMFARecoveryCodes(ctx, arg.Username); err != nil {
+			return err
+		}
+		if err := queries.DeleteUserMFA(ctx, arg.Username); err != nil {
+			return err
+		}
+		if err := queries.InvalidatePasswordResetTokens(ctx, arg.Username); err != nil {
+			return err
+		}
+		// used tokens too, they keep the addresses they were sent to
+		return queries.DeleteVerifyEmailTokens(ctx, arg.Username)
+	})
+
+	return store.decryptUser(user, txErr)
+}
@@ -0,0 +1,180 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	HoldActive   = "active"
+	HoldCaptured = "captured"
+	HoldVoided   = "voided"
+	HoldExpired  = "expired"
+)
+
+var (
+	// ErrHoldNotActive is returned when capturing or voiding a hold that was already settled or has expired
+	ErrHoldNotActive = errors.New("hold is no longer active")
+	// ErrHoldCaptureExceeds means the capture is more than the amount held
+	ErrHoldCaptureExceeds = errors.New("capture can't exceed the amount held")
+)
+
+// CreateHoldTx reserves the amount and the fee on the account, the money stays in the account
+// but can't be spent by other transfers until the hold is captured, voided or expires
+func (store *SQLStore) CreateHoldTx(ctx context.Context, arg CreateHoldParams) (hold Hold, err error) {
+	txErr := store.executeTransaction(ctx, func(queries *Queries) error {
+		// the capture checks the account again, this refuses the holds that could never be captured
+		toAccount, err := queries.GetAccount(ctx, arg.ToAccountID)
+		if err != nil {
+			return err
+		}
+		if err := checkDestination(ctx, queries, &toAccount, false); err != nil {
+			return err
+		}
+
+		account, err := queries.AddAccountHeldBalance(ctx, AddAccountHeldBalanceParams{
+			ID:     arg.AccountID,
+			Amount: arg.Amount + arg.Fee,
+		})
+		if err != nil {
+			return err
+		}
+		if account.AvailableBalance < 0 {
+			return fmt.Errorf("%w: account %d", ErrInsufficientFunds, account.ID)
+		}
+
+		hold, err = queries.CreateHold(ctx, arg)
+		return err
+	})
+
+	return hold, txErr
+}
+
+type CaptureHoldTxParams struct {
+	HoldID int64
+	// Amount is the amount captured, 0 captures the whole hold
+	Amount int64
+}
+
+type CaptureHoldTxResult struct {
+	Hold Hold `json:"hold"`
+	TransferTxResult
+}
+
+// CaptureHoldTx releases the hold and makes a transfer of the captured amount to the account of the hold
+// within a single database transaction, the rest of a partial capture is released
+// The fee held with the amount is charged whatever is captured, it was quoted when the hold was made
+func (store *SQLStore) CaptureHoldTx(ctx context.Context, arg CaptureHoldTxParams) (result CaptureHoldTxResult, err error) {
+	txErr := store.executeTransaction(ctx, func(queries *Queries) error {
+		hold, err := getActiveHoldForUpdate(ctx, queries, arg.HoldID)
+		if err != nil {
+			return err
+		}
+		amount := arg.Amount
+		if amount == 0 {
+			amount = hold.Amount
+		}
+		if amount > hold.Amount {
+			return fmt.Errorf("%w: %d held", ErrHoldCaptureExceeds, hold.Amount)
+		}
+
+		// releasing the hold locks the held account before the transfer locks the others,
+		// so lock every account of the capture first in the order transfers would
+		ids := []int64{hold.AccountID, hold.ToAccountID}
+		if hold.Fee > 0 {
+			feeAccountID, ok, err := getFeeAccountID(ctx, queries, hold.AccountID)
+			if err != nil {
+				return err
+			}
+			if ok {
+				ids = append(ids, feeAccountID)
+			}
+		}
+		if _, err := lockAccounts(ctx, queries, ids); err != nil {
+			return err
+		}
+		if _, err := queries.AddAccountHeldBalance(ctx, AddAccountHeldBalanceParams{
+			ID:     hold.AccountID,
+			Amount: -(hold.Amount + hold.Fee),
+		}); err != nil {
+			return err
+		}
+
+		result.TransferTxResult, err = transfer(ctx, queries, CreateTransferParams{
+			FromAccountID: Int64ToSqlInt64(hold.AccountID),
+			ToAccountID:   Int64ToSqlInt64(hold.ToAccountID),
+			Amount:        amount,
+			Fee:           hold.Fee,
+		})
+		if err != nil {
+			return err
+		}
+
+		result.Hold, err = queries.SettleHold(ctx, SettleHoldParams{
+			ID:             hold.ID,
+			Status:         HoldCaptured,
+			CapturedAmount: Int64ToSqlInt64(amount),
+			TransferID:     Int64ToSqlInt64(result.Transfer.ID),
+		})
+		return err
+	})
+
+	return result, txErr
+}
+
+// VoidHoldTx releases the whole hold without moving money
+func (store *SQLStore) VoidHoldTx(ctx context.Context, holdID int64) (hold Hold, err error) {
+	txErr := store.executeTransaction(ctx, func(queries *Queries) error {
+		hold, err = getActiveHoldForUpdate(ctx, queries, holdID)
+		if err != nil {
+			return err
+		}
+		hold, err = releaseHold(ctx, queries, &hold, HoldVoided)
+		return err
+	})
+
+	return hold, txErr
+}
+
+// ExpireHoldTx releases the next hold past its expiry, it returns sql.ErrNoRows when none is left
+// Several expiry jobs can run at once, each hold is claimed by a single one
+func (store *SQLStore) ExpireHoldTx(ctx context.Context) (hold Hold, err error) {
+	txErr := store.executeTransaction(ctx, func(queries *Queries) error {
+		hold, err = queries.GetExpiredHoldForUpdate(ctx)
+		if err != nil {
+			return err
+		}
+		hold, err = releaseHold(ctx, queries, &hold, HoldExpired)
+		return err
+	})
+
+	return hold, txErr
+}
+
+// getActiveHoldForUpdate treats holds past their expiry as expired, even before the expiry job releases them
+func getActiveHoldForUpdate(ctx context.Context, queries *Queries, holdID int64) (Hold, error) {
+	hold, err := queries.GetHoldForUpdate(ctx, holdID)
+	if err != nil {
+		return hold, err
+	}
+	if hold.Status != HoldActive || !hold.ExpiresAt.After(time.Now()) {
+		return hold, fmt.Errorf("%w: hold %d", ErrHoldNotActive, hold.ID)
+	}
+	return hold, nil
+}
+
+func releaseHold(ctx context.Context, queries *Queries, hold *Hold, status string) (Hold, error) {
+	if _, err := queries.AddAccountHeldBalance(ctx, AddAccountHeldBalanceParams{
+		ID:     hold.AccountID,
+		Amount: -(hold.Amount + hold.Fee),
+	}); err != nil {
+		return *hold, err
+	}
+
+	return queries.SettleHold(ctx, SettleHoldParams{
+		ID:     hold.ID,
+		Status: status,
+	})
+}
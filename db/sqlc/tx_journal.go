@@ -0,0 +1,138 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+const (
+	AccountKindUser   = "user"
+	AccountKindSystem = "system"
+)
+
+// Purposes of the system accounts, there is one per currency for each
+const (
+	SystemAccountFees     = "fees"
+	SystemAccountFX       = "fx"
+	SystemAccountSuspense = "suspense"
+)
+
+const (
+	JournalTransfer   = "transfer"
+	JournalAdjustment = "adjustment"
+	// JournalCorrection is written by the reconciliation, see ReconcileLedger
+	JournalCorrection = "correction"
+)
+
+// ErrUnbalancedJournal means the postings of a journal don't sum to zero in some currency,
+// the database refuses those journals too when the transaction commits
+var ErrUnbalancedJournal = errors.New("journal postings don't balance")
+
+// Posting moves Amount into the account, or out of it when negative
+type Posting struct {
+	AccountID int64 `json:"account_id"`
+	Amount    int64 `json:"amount"`
+}
+
+type PostJournalTxParams struct {
+	Kind        string
+	TransferID  sql.NullInt64
+	Description string
+	Postings    []Posting
+}
+
+type PostJournalTxResult struct {
+	Journal Journal `json:"journal"`
+	// Entries are in the order of the postings
+	Entries []Entry `json:"entries"`
+	// Accounts are the updated accounts of the postings
+	Accounts map[int64]Account `json:"accounts"`
+}
+
+// PostJournalTx writes a journal and updates the balances of its accounts within a single database transaction
+func (store *SQLStore) PostJournalTx(ctx context.Context, arg PostJournalTxParams) (result PostJournalTxResult, err error) {
+	txErr := store.executeTransaction(ctx, func(queries *Queries) error {
+		result, err = postJournal(ctx, queries, arg)
+		return err
+	})
+
+	return result, txErr
+}
+
+// postJournal runs the statements of a journal on queries bound to an open transaction
+// User accounts can't be left with a negative available balance, system accounts can
+func postJournal(ctx context.Context, queries *Queries, arg PostJournalTxParams) (result PostJournalTxResult, err error) {
+	result.Journal, err = queries.CreateJournal(ctx, CreateJournalParams{
+		Kind:        arg.Kind,
+		TransferID:  arg.TransferID,
+		Description: arg.Description,
+	})
+	if err != nil {
+		return
+	}
+
+	result.Entries = make([]Entry, len(arg.Postings))
+	for i, posting := range arg.Postings {
+		result.Entries[i], err = queries.CreateEntry(ctx, CreateEntryParams{
+			AccountID: Int64ToSqlInt64(posting.AccountID),
+			Amount:    posting.Amount,
+			JournalID: Int64ToSqlInt64(result.Journal.ID),
+		})
+		if err != nil {
+			return
+		}
+	}
+
+	result.Accounts, err = addAccountBalances(ctx, queries, arg.Postings)
+	if err != nil {
+		return
+	}
+
+	totals := make(map[string]int64)
+	for _, posting := range arg.Postings {
+		totals[result.Accounts[posting.AccountID].Currency] += posting.Amount
+	}
+	for currency, total := range totals {
+		if total != 0 {
+			return result, fmt.Errorf("%w: off by %d %s", ErrUnbalancedJournal, total, currency)
+		}
+	}
+
+	// checked after the update, which holds the row lock, so concurrent journals can't overdraw together
+	for _, posting := range arg.Postings {
+		account := result.Accounts[posting.AccountID]
+		if posting.Amount < 0 && account.Kind == AccountKindUser && account.AvailableBalance < 0 {
+			return result, fmt.Errorf("%w: account %d", ErrInsufficientFunds, account.ID)
+		}
+	}
+	return result, nil
+}
+
+// addAccountBalances updates the accounts in ID order to avoid deadlocks, postings to the same account are added up
+func addAccountBalances(ctx context.Context, q *Queries, postings []Posting) (map[int64]Account, error) {
+	amounts := make(map[int64]int64, len(postings))
+	ids := make([]int64, 0, len(postings))
+	for _, posting := range postings {
+		if _, ok := amounts[posting.AccountID]; !ok {
+			ids = append(ids, posting.AccountID)
+		}
+		amounts[posting.AccountID] += posting.Amount
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	accounts := make(map[int64]Account, len(ids))
+	for _, id := range ids {
+		account, err := q.AddAccountBalance(ctx, AddAccountBalanceParams{
+			ID:     id,
+			Amount: amounts[id],
+		})
+		if err != nil {
+			return nil, err
+		}
+		accounts[id] = account
+	}
+	return accounts, nil
+}
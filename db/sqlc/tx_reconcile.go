@@ -0,0 +1,144 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// LedgerReport lists what doesn't add up in the ledger
+type LedgerReport struct {
+	CheckedAt time.Time `json:"checked_at"`
+	Balanced  bool      `json:"balanced"`
+	// BalanceMismatches are accounts whose balance isn't the sum of their entries
+	BalanceMismatches []ListBalanceMismatchesRow `json:"balance_mismatches"`
+	// OrphanEntries belong to no account or to no journal
+	OrphanEntries []Entry `json:"orphan_entries"`
+	// UnbalancedTransfers don't have exactly their entry pair, and their fee pair
+	UnbalancedTransfers []ListUnbalancedTransfersRow `json:"unbalanced_transfers"`
+	// Corrections are the journals written for the balance mismatches, when asked to correct them
+	Corrections []Journal `json:"corrections,omitempty"`
+}
+
+// ReconcileLedger scans the whole ledger, with correct it writes a correcting journal for each balance mismatch
+// Orphan entries and unbalanced transfers are only reported, they need someone to look into them
+func (store *SQLStore) ReconcileLedger(ctx context.Context, correct bool) (report LedgerReport, err error) {
+	report = LedgerReport{
+		CheckedAt:           time.Now(),
+		BalanceMismatches:   []ListBalanceMismatchesRow{},
+		OrphanEntries:       []Entry{},
+		UnbalancedTransfers: []ListUnbalancedTransfersRow{},
+	}
+
+	mismatches, err := store.ListBalanceMismatches(ctx)
+	if err != nil {
+		return report, fmt.Errorf("cannot check balances: %w", err)
+	}
+	report.BalanceMismatches = append(report.BalanceMismatches, mismatches...)
+
+	orphans, err := store.ListOrphanEntries(ctx)
+	if err != nil {
+		return report, fmt.Errorf("cannot check entries: %w", err)
+	}
+	report.OrphanEntries = append(report.OrphanEntries, orphans...)
+
+	transfers, err := store.ListUnbalancedTransfers(ctx)
+	if err != nil {
+		return report, fmt.Errorf("cannot check transfers: %w", err)
+	}
+	report.UnbalancedTransfers = append(report.UnbalancedTransfers, transfers...)
+
+	report.Balanced = len(mismatches) == 0 && len(orphans) == 0 && len(transfers) == 0
+	if !correct {
+		return report, nil
+	}
+
+	for _, mismatch := range mismatches {
+		journal, err := store.CorrectBalanceTx(ctx, mismatch.ID)
+		if err != nil {
+			return report, fmt.Errorf("cannot correct account %d: %w", mismatch.ID, err)
+		}
+		if journal.ID != 0 {
+			report.Corrections = append(report.Corrections, journal)
+		}
+	}
+	return report, nil
+}
+
+// CorrectBalanceTx brings the entries of the account to its balance, the difference goes to the suspense account
+// of the currency until someone finds out where it comes from
+// It returns an empty journal when the account no longer needs a correction, or is a suspense account
+func (store *SQLStore) CorrectBalanceTx(ctx context.Context, accountID int64) (journal Journal, err error) {
+	txErr := store.executeTransaction(ctx, func(queries *Queries) error {
+		suspenseID, err := queries.GetSystemAccountIDForAccount(ctx, GetSystemAccountIDForAccountParams{
+			Purpose:   SystemAccountSuspense,
+			AccountID: accountID,
+		})
+		if err != nil {
+			return fmt.Errorf("cannot find the suspense account: %w", err)
+		}
+		if suspenseID == accountID {
+			// it can't be corrected against itself, it stays in the report
+			return nil
+		}
+
+		// lock both accounts in ID order, like transfers
+		var account Account
+		for _, id := range sortedIDs(accountID, suspenseID) {
+			locked, err := queries.GetAccountForUpdate(ctx, id)
+			if err != nil {
+				return err
+			}
+			if id == accountID {
+				account = locked
+			}
+		}
+
+		total, err := queries.GetAccountEntriesTotal(ctx, Int64ToSqlInt64(accountID))
+		if err != nil {
+			return err
+		}
+		difference := account.Balance - total
+		if difference == 0 {
+			return nil
+		}
+
+		journal, err = queries.CreateJournal(ctx, CreateJournalParams{
+			Kind:        JournalCorrection,
+			Description: fmt.Sprintf("balance %d, entries %d", account.Balance, total),
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := queries.CreateEntry(ctx, CreateEntryParams{
+			AccountID: Int64ToSqlInt64(accountID),
+			Amount:    difference,
+			JournalID: Int64ToSqlInt64(journal.ID),
+		}); err != nil {
+			return err
+		}
+		if _, err := queries.CreateEntry(ctx, CreateEntryParams{
+			AccountID: Int64ToSqlInt64(suspenseID),
+			Amount:    -difference,
+			JournalID: Int64ToSqlInt64(journal.ID),
+		}); err != nil {
+			return err
+		}
+
+		// the balance of the account was right, only the suspense account moves
+		_, err = queries.AddAccountBalance(ctx, AddAccountBalanceParams{
+			ID:     suspenseID,
+			Amount: -difference,
+		})
+		return err
+	})
+
+	return journal, txErr
+}
+
+func sortedIDs(id1, id2 int64) []int64 {
+	if id1 < id2 {
+		return []int64{id1, id2}
+	}
+	return []int64{id2, id1}
+}
@@ -0,0 +1,36 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+type ResetPasswordTxParams struct {
+	HashedToken       string
+	HashedPassword    string
+	PasswordChangedAt time.Time
+}
+
+// ResetPasswordTx redeems a one-time reset token and sets the new password
+// within a single database transaction. Any other pending token of the user is invalidated
+func (store *SQLStore) ResetPasswordTx(ctx context.Context, arg ResetPasswordTxParams) (user User, err error) {
+	txErr := store.executeTransaction(ctx, func(queries *Queries) error {
+		resetToken, err := queries.UsePasswordResetToken(ctx, arg.HashedToken)
+		if err != nil {
+			return err
+		}
+
+		user, err = queries.UpdateUserPassword(ctx, UpdateUserPasswordParams{
+			Username:          resetToken.Username,
+			HashedPassword:    arg.HashedPassword,
+			PasswordChangedAt: arg.PasswordChangedAt,
+		})
+		if err != nil {
+			return err
+		}
+
+		return queries.InvalidatePasswordResetTokens(ctx, resetToken.Username)
+	})
+
+	return store.decryptUser(user, txErr)
+}
@@ -0,0 +1,58 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrTransferNotReversible is returned for reversals of a reversal
+	ErrTransferNotReversible = errors.New("a reversal can't be reversed")
+	// ErrReversalExceedsTransfer means the reversals of a transfer would add up to more than its amount
+	ErrReversalExceedsTransfer = errors.New("reversals can't exceed the amount of the transfer")
+)
+
+type ReverseTransferTxParams struct {
+	TransferID int64
+	// Amount is the amount refunded, 0 refunds whatever hasn't been refunded yet
+	Amount int64
+}
+
+// ReverseTransferTx refunds all or part of a transfer with a transfer in the opposite direction,
+// linked to the original by reverses_transfer_id, within a single database transaction
+// The original transfer is locked, so concurrent reversals can't add up to more than its amount
+func (store *SQLStore) ReverseTransferTx(ctx context.Context, arg ReverseTransferTxParams) (result TransferTxResult, err error) {
+	txErr := store.executeTransaction(ctx, func(queries *Queries) error {
+		original, err := queries.GetTransferForUpdate(ctx, arg.TransferID)
+		if err != nil {
+			return err
+		}
+		if original.ReversesTransferID.Valid {
+			return ErrTransferNotReversible
+		}
+
+		reversed, err := queries.GetReversedAmount(ctx, Int64ToSqlInt64(original.ID))
+		if err != nil {
+			return err
+		}
+		remaining := original.Amount - reversed
+		amount := arg.Amount
+		if amount == 0 {
+			amount = remaining
+		}
+		if amount <= 0 || amount > remaining {
+			return fmt.Errorf("%w: %d of %d already reversed", ErrReversalExceedsTransfer, reversed, original.Amount)
+		}
+
+		result, err = transfer(ctx, queries, CreateTransferParams{
+			FromAccountID:      original.ToAccountID,
+			ToAccountID:        original.FromAccountID,
+			Amount:             amount,
+			ReversesTransferID: Int64ToSqlInt64(original.ID),
+		})
+		return err
+	})
+
+	return result, txErr
+}
@@ -0,0 +1,62 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+const (
+	RiskReviewPending  = "pending"
+	RiskReviewApproved = "approved"
+	RiskReviewRejected = "rejected"
+	// RiskReviewDenied is the status of the transfers the rules denied, they only stay for the record
+	RiskReviewDenied = "denied"
+)
+
+// ErrRiskReviewNotPending is returned when approving or rejecting a review that was already settled
+var ErrRiskReviewNotPending = errors.New("risk review is no longer pending")
+
+type ApproveRiskReviewTxParams struct {
+	ID         int64
+	ReviewedBy string
+}
+
+type ApproveRiskReviewTxResult struct {
+	Review RiskReview `json:"review"`
+	TransferTxResult
+}
+
+// ApproveRiskReviewTx makes the held transfer of the review and marks it approved within a single database
+// transaction, the transfer is checked against the balance and the limits of the sender as of the approval
+func (store *SQLStore) ApproveRiskReviewTx(ctx context.Context, arg ApproveRiskReviewTxParams) (result ApproveRiskReviewTxResult, err error) {
+	txErr := store.executeTransaction(ctx, func(queries *Queries) error {
+		review, err := queries.GetRiskReviewForUpdate(ctx, arg.ID)
+		if err != nil {
+			return err
+		}
+		if review.Status != RiskReviewPending {
+			return ErrRiskReviewNotPending
+		}
+
+		result.TransferTxResult, err = transfer(ctx, queries, CreateTransferParams{
+			FromAccountID: Int64ToSqlInt64(review.FromAccountID),
+			ToAccountID:   Int64ToSqlInt64(review.ToAccountID),
+			Amount:        review.Amount,
+			Fee:           review.Fee,
+		})
+		if err != nil {
+			return err
+		}
+
+		result.Review, err = queries.SettleRiskReview(ctx, SettleRiskReviewParams{
+			ID:         review.ID,
+			Status:     RiskReviewApproved,
+			TransferID: Int64ToSqlInt64(result.Transfer.ID),
+			ReviewedBy: sql.NullString{String: arg.ReviewedBy, Valid: true},
+		})
+		return err
+	})
+
+	return result, txErr
+}
@@ -0,0 +1,105 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+const (
+	ScheduledTransferPending   = "pending"
+	ScheduledTransferCompleted = "completed"
+	ScheduledTransferFailed    = "failed"
+	ScheduledTransferCanceled  = "canceled"
+)
+
+// ErrScheduledTransferInvalid means a scheduled transfer or a standing order run can't be made anymore,
+// retrying won't help
+var ErrScheduledTransferInvalid = errors.New("scheduled transfer is no longer valid")
+
+// ErrTransferDenied means the risk rules refused a scheduled transfer or a standing order run when it was due
+var ErrTransferDenied = errors.New("transfer denied")
+
+// ScreenTransfer looks at a scheduled transfer or a standing order run once it's claimed, before it's made,
+// an error refuses the transfer. It runs outside the transaction of the transfer, what it records is kept
+// when the transfer is refused
+type ScreenTransfer func(ctx context.Context, owner string, currency string, arg CreateTransferParams) error
+
+// ExecuteScheduledTransferTx claims the next due scheduled transfer, screens it when screen isn't nil,
+// makes the transfer and marks it completed within a single database transaction
+// It returns sql.ErrNoRows when nothing is due. When the transfer fails, the returned scheduled transfer
+// is the claimed one, so the caller can record the failure
+func (store *SQLStore) ExecuteScheduledTransferTx(ctx context.Context, screen ScreenTransfer) (scheduled ScheduledTransfer, result TransferTxResult, err error) {
+	txErr := store.executeTransaction(ctx, func(queries *Queries) error {
+		scheduled, err = queries.GetDueScheduledTransferForUpdate(ctx)
+		if err != nil {
+			return err
+		}
+
+		err := validateTransferAccounts(ctx, queries, scheduled.Owner, scheduled.FromAccountID, scheduled.ToAccountID, scheduled.Currency)
+		if err != nil {
+			return err
+		}
+
+		arg := CreateTransferParams{
+			FromAccountID: Int64ToSqlInt64(scheduled.FromAccountID),
+			ToAccountID:   Int64ToSqlInt64(scheduled.ToAccountID),
+			Amount:        scheduled.Amount,
+			Fee:           store.transferFee(scheduled.Currency, scheduled.Amount),
+		}
+		if screen != nil {
+			if err := screen(ctx, scheduled.Owner, scheduled.Currency, arg); err != nil {
+				return err
+			}
+		}
+		result, err = transfer(ctx, queries, arg)
+		if err != nil {
+			return err
+		}
+
+		completed, err := queries.CompleteScheduledTransfer(ctx, CompleteScheduledTransferParams{
+			ID:         scheduled.ID,
+			TransferID: Int64ToSqlInt64(result.Transfer.ID),
+		})
+		if err != nil {
+			return err
+		}
+		scheduled = completed
+		return nil
+	})
+
+	return scheduled, result, txErr
+}
+
+// validateTransferAccounts checks the accounts again, they may have changed since the transfer was scheduled
+func validateTransferAccounts(ctx context.Context, queries *Queries, owner string, fromAccountID int64, toAccountID int64, currency string) error {
+	fromAccount, err := getTransferAccount(ctx, queries, fromAccountID)
+	if err != nil {
+		return err
+	}
+	if fromAccount.Owner != owner {
+		return fmt.Errorf("%w: account %d doesn't belong to %v", ErrScheduledTransferInvalid, fromAccount.ID, owner)
+	}
+
+	toAccount, err := getTransferAccount(ctx, queries, toAccountID)
+	if err != nil {
+		return err
+	}
+
+	for _, account := range []Account{fromAccount, toAccount} {
+		if account.Currency != currency {
+			return fmt.Errorf("%w: account %d currency mismatch", ErrScheduledTransferInvalid, account.ID)
+		}
+	}
+	return nil
+}
+
+func getTransferAccount(ctx context.Context, queries *Queries, accountID int64) (Account, error) {
+	account, err := queries.GetAccount(ctx, accountID)
+	if err == sql.ErrNoRows {
+		// not to be confused with no scheduled transfer being due
+		return account, fmt.Errorf("%w: account %d not found", ErrScheduledTransferInvalid, accountID)
+	}
+	return account, err
+}
@@ -0,0 +1,196 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/go_backend_misc/util"
+)
+
+const (
+	StandingOrderActive   = "active"
+	StandingOrderPaused   = "paused"
+	StandingOrderFinished = "finished"
+	StandingOrderCanceled = "canceled"
+)
+
+const (
+	// StandingOrderCatchUpAll makes every run missed while the executor was down, one after the other
+	StandingOrderCatchUpAll = "all"
+	// StandingOrderCatchUpLatest records the missed runs as skipped and only makes the most recent one
+	StandingOrderCatchUpLatest = "latest"
+)
+
+const (
+	StandingOrderRunSucceeded = "succeeded"
+	StandingOrderRunFailed    = "failed"
+	StandingOrderRunSkipped   = "skipped"
+)
+
+// ExecuteStandingOrderTx claims the next due standing order, screens the run when screen isn't nil,
+// makes the same transfer as TransferTx, records the run and moves the order to its next run
+// within a single database transaction
+// It returns sql.ErrNoRows when nothing is due. When the transfer fails, the returned standing order
+// is the claimed one, so the caller can record the failure
+func (store *SQLStore) ExecuteStandingOrderTx(ctx context.Context, now time.Time, screen ScreenTransfer) (order StandingOrder, run StandingOrderRun, err error) {
+	txErr := store.executeTransaction(ctx, func(queries *Queries) error {
+		order, err = queries.GetDueStandingOrderForUpdate(ctx, now)
+		if err != nil {
+			return err
+		}
+
+		recurrence, err := standingOrderRecurrence(&order)
+		if err != nil {
+			return err
+		}
+		scheduledFor, err := skipMissedRuns(ctx, queries, &order, recurrence, now)
+		if err != nil {
+			return err
+		}
+
+		err = validateTransferAccounts(ctx, queries, order.Owner, order.FromAccountID, order.ToAccountID, order.Currency)
+		if err != nil {
+			return err
+		}
+		arg := CreateTransferParams{
+			FromAccountID: Int64ToSqlInt64(order.FromAccountID),
+			ToAccountID:   Int64ToSqlInt64(order.ToAccountID),
+			Amount:        order.Amount,
+			Fee:           store.transferFee(order.Currency, order.Amount),
+		}
+		if screen != nil {
+			if err := screen(ctx, order.Owner, order.Currency, arg); err != nil {
+				return err
+			}
+		}
+		result, err := transfer(ctx, queries, arg)
+		if err != nil {
+			return err
+		}
+
+		run, err = queries.CreateStandingOrderRun(ctx, CreateStandingOrderRunParams{
+			StandingOrderID: order.ID,
+			ScheduledFor:    scheduledFor,
+			Status:          StandingOrderRunSucceeded,
+			TransferID:      Int64ToSqlInt64(result.Transfer.ID),
+		})
+		if err != nil {
+			return err
+		}
+
+		advanced, err := moveStandingOrderForward(ctx, queries, &order, recurrence, scheduledFor, order.RunCount+1)
+		if err != nil {
+			return err
+		}
+		order = advanced
+		return nil
+	})
+
+	return order, run, txErr
+}
+
+type RecordStandingOrderFailureTxParams struct {
+	ID int64
+	// NextRunAt is the run that failed, nothing is recorded if the order has moved on since
+	NextRunAt time.Time
+	Error     string
+	Now       time.Time
+}
+
+// RecordStandingOrderFailureTx records a failed run and moves the order to its next run,
+// failed runs don't count towards the COUNT of the rule
+// It returns sql.ErrNoRows when the order was paused, canceled or already moved on
+func (store *SQLStore) RecordStandingOrderFailureTx(ctx context.Context, arg RecordStandingOrderFailureTxParams) (order StandingOrder, run StandingOrderRun, err error) {
+	txErr := store.executeTransaction(ctx, func(queries *Queries) error {
+		order, err = queries.GetStandingOrderForUpdate(ctx, arg.ID)
+		if err != nil {
+			return err
+		}
+		if order.Status != StandingOrderActive || !order.NextRunAt.Equal(arg.NextRunAt) {
+			return sql.ErrNoRows
+		}
+
+		recurrence, err := standingOrderRecurrence(&order)
+		if err != nil {
+			return err
+		}
+		scheduledFor, err := skipMissedRuns(ctx, queries, &order, recurrence, arg.Now)
+		if err != nil {
+			return err
+		}
+
+		run, err = queries.CreateStandingOrderRun(ctx, CreateStandingOrderRunParams{
+			StandingOrderID: order.ID,
+			ScheduledFor:    scheduledFor,
+			Status:          StandingOrderRunFailed,
+			Error:           sql.NullString{String: arg.Error, Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+
+		order, err = moveStandingOrderForward(ctx, queries, &order, recurrence, scheduledFor, order.RunCount)
+		return err
+	})
+
+	return order, run, txErr
+}
+
+func standingOrderRecurrence(order *StandingOrder) (util.Recurrence, error) {
+	recurrence, err := util.ParseRecurrence(order.Rrule, order.StartsAt)
+	if err != nil {
+		return recurrence, fmt.Errorf("%w: %v", ErrScheduledTransferInvalid, err)
+	}
+	return recurrence, nil
+}
+
+// skipMissedRuns returns the run to make now. With the latest catch up, every missed run but the most recent
+// is recorded as skipped, with the all catch up the executor makes them one by one
+func skipMissedRuns(ctx context.Context, queries *Queries, order *StandingOrder, recurrence util.Recurrence, now time.Time) (time.Time, error) {
+	scheduledFor := order.NextRunAt
+	if order.CatchUp != StandingOrderCatchUpLatest {
+		return scheduledFor, nil
+	}
+
+	for {
+		next := recurrence.Next(scheduledFor)
+		if next.IsZero() || next.After(now) {
+			return scheduledFor, nil
+		}
+
+		_, err := queries.CreateStandingOrderRun(ctx, CreateStandingOrderRunParams{
+			StandingOrderID: order.ID,
+			ScheduledFor:    scheduledFor,
+			Status:          StandingOrderRunSkipped,
+		})
+		if err != nil {
+			return scheduledFor, err
+		}
+		scheduledFor = next
+	}
+}
+
+// moveStandingOrderForward moves the order to the run after scheduledFor, or finishes it
+// once the rule has no runs left
+func moveStandingOrderForward(
+	ctx context.Context,
+	queries *Queries,
+	order *StandingOrder,
+	recurrence util.Recurrence,
+	scheduledFor time.Time,
+	runCount int32,
+) (StandingOrder, error) {
+	arg := AdvanceStandingOrderParams{
+		ID:        order.ID,
+		Status:    StandingOrderActive,
+		NextRunAt: recurrence.Next(scheduledFor),
+		RunCount:  runCount,
+	}
+	if arg.NextRunAt.IsZero() || (recurrence.Count > 0 && int(runCount) >= recurrence.Count) {
+		arg.Status = StandingOrderFinished
+		arg.NextRunAt = scheduledFor
+	}
+	return queries.AdvanceStandingOrder(ctx, arg)
+}
@@ -0,0 +1,116 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go_backend_misc/util"
+)
+
+// The rolling windows of the daily and monthly transfer limits
+const (
+	DailyLimitWindow   = 24 * time.Hour
+	MonthlyLimitWindow = 30 * 24 * time.Hour
+)
+
+// ErrTransferLimitExceeded means the transfer is above a limit of the sending account, see TransferAllowance
+var ErrTransferLimitExceeded = errors.New("transfer limit exceeded")
+
+// LimitUsage is how much of a rolling window limit was used since Since, a zero Limit is no limit
+type LimitUsage struct {
+	Limit     int64     `json:"limit"`
+	Used      int64     `json:"used"`
+	Remaining int64     `json:"remaining"`
+	Since     time.Time `json:"since"`
+}
+
+func newLimitUsage(limit int64, used int64, since time.Time) LimitUsage {
+	usage := LimitUsage{Limit: limit, Since: since}
+	usage.spend(used)
+	return usage
+}
+
+func (usage *LimitUsage) spend(amount int64) {
+	usage.Used += amount
+	if usage.Limit > 0 {
+		usage.Remaining = max(usage.Limit-usage.Used, 0)
+	}
+}
+
+// TransferAllowance is what an account can still send under its limits, in minor units of its currency
+// The limits are the overrides of the account, or else the defaults of the KYC tier of its owner in its currency,
+// system accounts have none
+type TransferAllowance struct {
+	AccountID      int64      `json:"account_id"`
+	Currency       string     `json:"currency"`
+	KYCTier        int32      `json:"kyc_tier"`
+	PerTransaction int64      `json:"per_transaction"`
+	Daily          LimitUsage `json:"daily"`
+	Monthly        LimitUsage `json:"monthly"`
+}
+
+// Check tells whether a transfer of amount fits in the allowance
+func (allowance *TransferAllowance) Check(amount int64) error {
+	if allowance.PerTransaction > 0 && amount > allowance.PerTransaction {
+		return fmt.Errorf("%w: per transaction limit of %s", ErrTransferLimitExceeded,
+			util.NewMoney(allowance.PerTransaction, allowance.Currency))
+	}
+	if allowance.Daily.Limit > 0 && amount > allowance.Daily.Remaining {
+		return fmt.Errorf("%w: daily limit of %s, %s left", ErrTransferLimitExceeded,
+			util.NewMoney(allowance.Daily.Limit, allowance.Currency), util.NewMoney(allowance.Daily.Remaining, allowance.Currency))
+	}
+	if allowance.Monthly.Limit > 0 && amount > allowance.Monthly.Remaining {
+		return fmt.Errorf("%w: monthly limit of %s, %s left", ErrTransferLimitExceeded,
+			util.NewMoney(allowance.Monthly.Limit, allowance.Currency), util.NewMoney(allowance.Monthly.Remaining, allowance.Currency))
+	}
+	return nil
+}
+
+// spend counts a transfer of amount in the windows
+func (allowance *TransferAllowance) spend(amount int64) {
+	allowance.Daily.spend(amount)
+	allowance.Monthly.spend(amount)
+}
+
+// TransferAllowance reads the limits of the account and what it sent within their windows, it doesn't lock anything
+// so it's only a hint for the account owner, transfers check their limits again within their transaction
+func (store *SQLStore) TransferAllowance(ctx context.Context, accountID int64) (TransferAllowance, error) {
+	account, err := store.GetAccount(ctx, accountID)
+	if err != nil {
+		return TransferAllowance{}, err
+	}
+	return transferAllowance(ctx, store.Queries, &account, time.Now(), 0)
+}
+
+// transferAllowance adds up the transfers of the account up to now, but the one of excludeTransferID
+// Callers hold the row lock of the account so the transfers of concurrent transactions are either committed
+// and counted, or waiting for the lock
+func transferAllowance(ctx context.Context, queries *Queries, account *Account, now time.Time, excludeTransferID int64) (TransferAllowance, error) {
+	allowance := TransferAllowance{AccountID: account.ID, Currency: account.Currency}
+	if account.Kind == AccountKindSystem {
+		return allowance, nil
+	}
+
+	limits, err := queries.GetAccountTransferLimits(ctx, account.ID)
+	if err != nil {
+		return allowance, err
+	}
+	dayStart, monthStart := now.Add(-DailyLimitWindow), now.Add(-MonthlyLimitWindow)
+	totals, err := queries.GetTransferredTotals(ctx, GetTransferredTotalsParams{
+		AccountID:         Int64ToSqlInt64(account.ID),
+		DayStart:          dayStart,
+		MonthStart:        monthStart,
+		ExcludeTransferID: excludeTransferID,
+	})
+	if err != nil {
+		return allowance, err
+	}
+
+	allowance.KYCTier = limits.KycTier
+	allowance.PerTransaction = limits.PerTransaction
+	allowance.Daily = newLimitUsage(limits.Daily, totals.DayTotal, dayStart)
+	allowance.Monthly = newLimitUsage(limits.Monthly, totals.MonthTotal, monthStart)
+	return allowance, nil
+}
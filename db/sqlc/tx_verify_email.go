@@ -0,0 +1,25 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+)
+
+// VerifyEmailTx redeems a one-time verification token and marks the user's email as verified
+// within a single database transaction
+func (store *SQLStore) VerifyEmailTx(ctx context.Context, hashedToken string) (user User, err error) {
+	txErr := store.executeTransaction(ctx, func(queries *Queries) error {
+		verifyEmailToken, err := queries.UseVerifyEmailToken(ctx, hashedToken)
+		if err != nil {
+			return err
+		}
+
+		user, err = queries.MarkUserEmailVerified(ctx, MarkUserEmailVerifiedParams{
+			Username:   verifyEmailToken.Username,
+			EmailIndex: sql.NullString{String: verifyEmailToken.EmailIndex, Valid: true},
+		})
+		return err
+	})
+
+	return store.decryptUser(user, txErr)
+}
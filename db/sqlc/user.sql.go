@@ -7,6 +7,8 @@ package db
 
 import (
 	"context"
+	"database/sql"
+	"time"
 )
 
 const createUser = `-- name: CreateUser :one
@@ -14,20 +16,23 @@ INSERT INTO users (
     username,
     hashed_password,
     full_name,
-    email
+    email,
+    email_index
 ) VALUES (
     $1,
     $2,
     $3,
-    $4
-) RETURNING username, hashed_password, full_name, email, password_changed_at, created_at
+    $4,
+    $5
+) RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, is_email_verified, deleted_at, email_index, role, kyc_tier
 `
 
 type CreateUserParams struct {
-	Username       string `json:"username"`
-	HashedPassword string `json:"hashed_password"`
-	FullName       string `json:"full_name"`
-	Email          string `json:"email"`
+	Username       string         `json:"username"`
+	HashedPassword string         `json:"hashed_password"`
+	FullName       string         `json:"full_name"`
+	Email          string         `json:"email"`
+	EmailIndex     sql.NullString `json:"email_index"`
 }
 
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
@@ -36,6 +41,7 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		arg.HashedPassword,
 		arg.FullName,
 		arg.Email,
+		arg.EmailIndex,
 	)
 	var i User
 	err := row.Scan(
@@ -45,12 +51,42 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.Email,
 		&i.PasswordChangedAt,
 		&i.CreatedAt,
+		&i.IsEmailVerified,
+		&i.DeletedAt,
+		&i.EmailIndex,
+		&i.Role,
+		&i.KycTier,
+	)
+	return i, err
+}
+
+const getUserByEmail = `-- name: GetUserByEmail :one
+SELECT username, hashed_password, full_name, email, password_changed_at, created_at, is_email_verified, deleted_at, email_index, role, kyc_tier FROM users
+WHERE email_index = $1::varchar
+`
+
+// Store looks users up by the blind index of the email, see store_encryption.go
+func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByEmail, email)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.IsEmailVerified,
+		&i.DeletedAt,
+		&i.EmailIndex,
+		&i.Role,
+		&i.KycTier,
 	)
 	return i, err
 }
 
 const getUserByUsername = `-- name: GetUserByUsername :one
-SELECT username, hashed_password, full_name, email, password_changed_at, created_at FROM users
+SELECT username, hashed_password, full_name, email, password_changed_at, created_at, is_email_verified, deleted_at, email_index, role, kyc_tier FROM users
 WHERE username = $1
 `
 
@@ -64,6 +100,328 @@ func (q *Queries) GetUserByUsername(ctx context.Context, username string) (User,
 		&i.Email,
 		&i.PasswordChangedAt,
 		&i.CreatedAt,
+		&i.IsEmailVerified,
+		&i.DeletedAt,
+		&i.EmailIndex,
+		&i.Role,
+		&i.KycTier,
+	)
+	return i, err
+}
+
+const isUserErased = `-- name: IsUserErased :one
+SELECT EXISTS (
+    SELECT 1 FROM users
+    WHERE username = $1 AND deleted_at IS NOT NULL
+)
+`
+
+func (q *Queries) IsUserErased(ctx context.Context, username string) (bool, error) {
+	row := q.db.QueryRowContext(ctx, isUserErased, username)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const listUsersWithoutEmailIndex = `-- name: ListUsersWithoutEmailIndex :many
+SELECT username, hashed_password, full_name, email, password_changed_at, created_at, is_email_verified, deleted_at, email_index, role, kyc_tier FROM users
+WHERE email_index IS NULL
+ORDER BY username
+LIMIT $1
+`
+
+// rows written before email encryption was enabled
+func (q *Queries) ListUsersWithoutEmailIndex(ctx context.Context, limit int32) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, listUsersWithoutEmailIndex, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.Username,
+			&i.HashedPassword,
+			&i.FullName,
+			&i.Email,
+			&i.PasswordChangedAt,
+			&i.CreatedAt,
+			&i.IsEmailVerified,
+			&i.DeletedAt,
+			&i.EmailIndex,
+			&i.Role,
+			&i.KycTier,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markUserEmailVerified = `-- name: MarkUserEmailVerified :one
+UPDATE users
+SET is_email_verified = true
+WHERE username = $1 AND email_index = $2
+RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, is_email_verified, deleted_at, email_index, role, kyc_tier
+`
+
+type MarkUserEmailVerifiedParams struct {
+	Username   string         `json:"username"`
+	EmailIndex sql.NullString `json:"email_index"`
+}
+
+// only verifies the address the token was sent to, in case the email changed since
+func (q *Queries) MarkUserEmailVerified(ctx context.Context, arg MarkUserEmailVerifiedParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, markUserEmailVerified, arg.Username, arg.EmailIndex)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.IsEmailVerified,
+		&i.DeletedAt,
+		&i.EmailIndex,
+		&i.Role,
+		&i.KycTier,
+	)
+	return i, err
+}
+
+const pseudonymizeUser = `-- name: PseudonymizeUser :one
+UPDATE users
+SET full_name = '',
+    email = $1,
+    email_index = $2,
+    hashed_password = '',
+    is_email_verified = false,
+    password_changed_at = $3,
+    deleted_at = now()
+WHERE username = $4 AND deleted_at IS NULL
+RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, is_email_verified, deleted_at, email_index, role, kyc_tier
+`
+
+type PseudonymizeUserParams struct {
+	Email             string         `json:"email"`
+	EmailIndex        sql.NullString `json:"email_index"`
+	PasswordChangedAt time.Time      `json:"password_changed_at"`
+	Username          string         `json:"username"`
+}
+
+// the empty hash matches no password, and moving password_changed_at invalidates every token
+func (q *Queries) PseudonymizeUser(ctx context.Context, arg PseudonymizeUserParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, pseudonymizeUser,
+		arg.Email,
+		arg.EmailIndex,
+		arg.PasswordChangedAt,
+		arg.Username,
+	)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.IsEmailVerified,
+		&i.DeletedAt,
+		&i.EmailIndex,
+		&i.Role,
+		&i.KycTier,
+	)
+	return i, err
+}
+
+const rehashUserPassword = `-- name: RehashUserPassword :execrows
+UPDATE users
+SET hashed_password = $1
+WHERE username = $2 AND hashed_password = $3
+`
+
+type RehashUserPasswordParams struct {
+	NewHashedPassword string `json:"new_hashed_password"`
+	Username          string `json:"username"`
+	OldHashedPassword string `json:"old_hashed_password"`
+}
+
+// upgrades the hash of an unchanged password, password_changed_at is kept so sessions stay valid
+func (q *Queries) RehashUserPassword(ctx context.Context, arg RehashUserPasswordParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, rehashUserPassword, arg.NewHashedPassword, arg.Username, arg.OldHashedPassword)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const updateUser = `-- name: UpdateUser :one
+UPDATE users
+SET full_name = COALESCE($1, full_name)
+WHERE username = $2
+RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, is_email_verified, deleted_at, email_index, role, kyc_tier
+`
+
+type UpdateUserParams struct {
+	FullName sql.NullString `json:"full_name"`
+	Username string         `json:"username"`
+}
+
+func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUser, arg.FullName, arg.Username)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.IsEmailVerified,
+		&i.DeletedAt,
+		&i.EmailIndex,
+		&i.Role,
+		&i.KycTier,
+	)
+	return i, err
+}
+
+const updateUserEmail = `-- name: UpdateUserEmail :one
+UPDATE users
+SET email = $1,
+    email_index = $2,
+    is_email_verified = false
+WHERE username = $3
+RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, is_email_verified, deleted_at, email_index, role, kyc_tier
+`
+
+type UpdateUserEmailParams struct {
+	Email      string         `json:"email"`
+	EmailIndex sql.NullString `json:"email_index"`
+	Username   string         `json:"username"`
+}
+
+// a new address always needs to be verified again
+func (q *Queries) UpdateUserEmail(ctx context.Context, arg UpdateUserEmailParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserEmail, arg.Email, arg.EmailIndex, arg.Username)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.IsEmailVerified,
+		&i.DeletedAt,
+		&i.EmailIndex,
+		&i.Role,
+		&i.KycTier,
+	)
+	return i, err
+}
+
+const updateUserKYCTier = `-- name: UpdateUserKYCTier :execrows
+UPDATE users
+SET kyc_tier = $2
+WHERE username = $1
+`
+
+type UpdateUserKYCTierParams struct {
+	Username string `json:"username"`
+	KycTier  int32  `json:"kyc_tier"`
+}
+
+func (q *Queries) UpdateUserKYCTier(ctx context.Context, arg UpdateUserKYCTierParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, updateUserKYCTier, arg.Username, arg.KycTier)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const updateUserPII = `-- name: UpdateUserPII :exec
+UPDATE users
+SET full_name = $1,
+    email = $2,
+    email_index = $3
+WHERE username = $4
+`
+
+type UpdateUserPIIParams struct {
+	FullName   string         `json:"full_name"`
+	Email      string         `json:"email"`
+	EmailIndex sql.NullString `json:"email_index"`
+	Username   string         `json:"username"`
+}
+
+func (q *Queries) UpdateUserPII(ctx context.Context, arg UpdateUserPIIParams) error {
+	_, err := q.db.ExecContext(ctx, updateUserPII,
+		arg.FullName,
+		arg.Email,
+		arg.EmailIndex,
+		arg.Username,
+	)
+	return err
+}
+
+const updateUserPassword = `-- name: UpdateUserPassword :one
+UPDATE users
+SET hashed_password = $1,
+    password_changed_at = $2
+WHERE username = $3
+RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, is_email_verified, deleted_at, email_index, role, kyc_tier
+`
+
+type UpdateUserPasswordParams struct {
+	HashedPassword    string    `json:"hashed_password"`
+	PasswordChangedAt time.Time `json:"password_changed_at"`
+	Username          string    `json:"username"`
+}
+
+func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserPassword, arg.HashedPassword, arg.PasswordChangedAt, arg.Username)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.IsEmailVerified,
+		&i.DeletedAt,
+		&i.EmailIndex,
+		&i.Role,
+		&i.KycTier,
 	)
 	return i, err
 }
+
+const updateUserRole = `-- name: UpdateUserRole :execrows
+UPDATE users
+SET role = $2
+WHERE username = $1
+`
+
+type UpdateUserRoleParams struct {
+	Username string `json:"username"`
+	Role     string `json:"role"`
+}
+
+func (q *Queries) UpdateUserRole(ctx context.Context, arg UpdateUserRoleParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, updateUserRole, arg.Username, arg.Role)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
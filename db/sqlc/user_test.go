@@ -2,7 +2,9 @@ package db
 
 import (
 	"context"
+	"database/sql"
 	"testing"
+	"time"
 
 	"github.com/go_backend_misc/util"
 	"github.com/stretchr/testify/require"
@@ -21,7 +23,7 @@ func createRandomUser(userSuffix string) (User, CreateUserParams, error) {
 		FullName:       util.RandomString(10) + " " + util.RandomString(10),
 		Email:          util.RandomEmail(username),
 	}
-	user, err := testQueries.CreateUser(context.Background(), arg)
+	user, err := testStore.CreateUser(context.Background(), arg)
 	return user, arg, err
 }
 
@@ -44,7 +46,7 @@ func TestCreateUser(t *testing.T) {
 
 func TestGetUser(t *testing.T) {
 	createdUser, _, _ := createRandomUser("_test_get_user")
-	retrievedUser, err := testQueries.GetUserByUsername(context.Background(), createdUser.Username)
+	retrievedUser, err := testStore.GetUserByUsername(context.Background(), createdUser.Username)
 	require.NoError(t, err)
 	require.Equal(t, createdUser.Username, retrievedUser.Username)
 	require.Equal(t, createdUser.Email, retrievedUser.Email)
@@ -53,3 +55,263 @@ func TestGetUser(t *testing.T) {
 	require.Equal(t, createdUser.CreatedAt, retrievedUser.CreatedAt)
 	require.Equal(t, createdUser.PasswordChangedAt, retrievedUser.PasswordChangedAt)
 }
+
+func TestResetPasswordTx(t *testing.T) {
+	store := testStore
+	user, _, err := createRandomUser("_test_reset_password")
+	require.NoError(t, err)
+
+	hashedToken := util.HashSecret(util.RandomString(32))
+	_, err = testQueries.CreatePasswordResetToken(context.Background(), CreatePasswordResetTokenParams{
+		HashedToken: hashedToken,
+		Username:    user.Username,
+		ExpiresAt:   time.Now().Add(time.Minute),
+	})
+	require.NoError(t, err)
+
+	arg := ResetPasswordTxParams{
+		HashedToken:       hashedToken,
+		HashedPassword:    util.RandomString(20),
+		PasswordChangedAt: time.Now().UTC(),
+	}
+	updatedUser, err := store.ResetPasswordTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.HashedPassword, updatedUser.HashedPassword)
+	require.WithinDuration(t, arg.PasswordChangedAt, updatedUser.PasswordChangedAt, time.Millisecond)
+
+	// the token can only be used once
+	_, err = store.ResetPasswordTx(context.Background(), arg)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestUpdateUser(t *testing.T) {
+	createdUser, _, err := createRandomUser("_test_update_user")
+	require.NoError(t, err)
+
+	unchangedUser, err := testStore.UpdateUser(context.Background(), UpdateUserParams{
+		Username: createdUser.Username,
+	})
+	require.NoError(t, err)
+	require.Equal(t, createdUser.FullName, unchangedUser.FullName)
+
+	newFullName := util.RandomOwner()
+	updatedUser, err := testStore.UpdateUser(context.Background(), UpdateUserParams{
+		Username: createdUser.Username,
+		FullName: sql.NullString{String: newFullName, Valid: true},
+	})
+	require.NoError(t, err)
+	require.Equal(t, newFullName, updatedUser.FullName)
+	require.Equal(t, createdUser.Email, updatedUser.Email)
+}
+
+func TestUpdateUserEmail(t *testing.T) {
+	createdUser, _, err := createRandomUser("_test_update_user_email")
+	require.NoError(t, err)
+	verifiedUser, err := testStore.MarkUserEmailVerified(context.Background(), MarkUserEmailVerifiedParams{
+		Username:   createdUser.Username,
+		EmailIndex: createdUser.EmailIndex,
+	})
+	require.NoError(t, err)
+	require.True(t, verifiedUser.IsEmailVerified)
+
+	newEmail := util.RandomEmail(createdUser.Username)
+	updatedUser, err := testStore.UpdateUserEmail(context.Background(), UpdateUserEmailParams{
+		Username: createdUser.Username,
+		Email:    newEmail,
+	})
+	require.NoError(t, err)
+	require.Equal(t, newEmail, updatedUser.Email)
+	require.NotEqual(t, createdUser.EmailIndex, updatedUser.EmailIndex)
+	require.False(t, updatedUser.IsEmailVerified)
+}
+
+func TestEraseUserTx(t *testing.T) {
+	store := testStore
+	account, user, _, err := createRandomAccount("_test_erase_user")
+	require.NoError(t, err)
+
+	arg := PseudonymizeUserParams{
+		Username:          user.Username,
+		Email:             util.RandomString(16) + "@invalid",
+		PasswordChangedAt: time.Now().UTC(),
+	}
+
+	_, err = store.EraseUserTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrNonZeroBalance)
+
+	_, err = testQueries.UpdateAccount(context.Background(), UpdateAccountParams{ID: account.ID, Balance: 0})
+	require.NoError(t, err)
+
+	// a used token of the signup address and a pending one of a new address
+	for _, used := range []bool{true, false} {
+		token, err := testQueries.CreateVerifyEmailToken(context.Background(), CreateVerifyEmailTokenParams{
+			HashedToken: util.RandomString(32),
+			Username:    user.Username,
+			EmailIndex:  util.RandomString(64),
+			ExpiresAt:   time.Now().Add(time.Hour),
+		})
+		require.NoError(t, err)
+		if used {
+			_, err = testQueries.UseVerifyEmailToken(context.Background(), token.HashedToken)
+			require.NoError(t, err)
+		}
+	}
+
+	erasedUser, err := store.EraseUserTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, user.Username, erasedUser.Username)
+	require.Equal(t, arg.Email, erasedUser.Email)
+	require.Empty(t, erasedUser.FullName)
+	require.Empty(t, erasedUser.HashedPassword)
+	require.True(t, erasedUser.DeletedAt.Valid)
+
+	// no address is left in the verification tokens
+	var tokens int
+	err = testDB.QueryRow("SELECT count(*) FROM verify_email_tokens WHERE username = $1", user.Username).Scan(&tokens)
+	require.NoError(t, err)
+	require.Zero(t, tokens)
+
+	// the ledger is kept
+	_, err = testQueries.GetAccount(context.Background(), account.ID)
+	require.NoError(t, err)
+
+	_, err = store.EraseUserTx(context.Background(), arg)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestEraseUserTxSettlesPayments(t *testing.T) {
+	hold, fromAccount, toAccount := createRandomHold(t, "_test_erase_user_payments", 10, time.Now().Add(time.Hour))
+	_, err := testQueries.UpdateAccount(context.Background(), UpdateAccountParams{ID: toAccount.ID, Balance: 0})
+	require.NoError(t, err)
+
+	scheduled, err := testQueries.CreateScheduledTransfer(context.Background(), CreateScheduledTransferParams{
+		Owner:         fromAccount.Owner,
+		FromAccountID: fromAccount.ID,
+		ToAccountID:   toAccount.ID,
+		Amount:        10,
+		Currency:      fromAccount.Currency,
+		ExecuteAt:     time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+	startsAt := time.Now().Add(time.Hour)
+	order, err := testQueries.CreateStandingOrder(context.Background(), CreateStandingOrderParams{
+		Owner:         fromAccount.Owner,
+		FromAccountID: fromAccount.ID,
+		ToAccountID:   toAccount.ID,
+		Amount:        10,
+		Currency:      fromAccount.Currency,
+		Rrule:         "FREQ=DAILY",
+		StartsAt:      startsAt,
+		CatchUp:       StandingOrderCatchUpAll,
+		NextRunAt:     startsAt,
+	})
+	require.NoError(t, err)
+	review, err := testQueries.CreateRiskReview(context.Background(), CreateRiskReviewParams{
+		Owner:         fromAccount.Owner,
+		FromAccountID: fromAccount.ID,
+		ToAccountID:   toAccount.ID,
+		Amount:        10,
+		Currency:      fromAccount.Currency,
+		Decision:      "hold",
+		Reasons:       []string{"new_payee: first transfer to the account"},
+		Status:        RiskReviewPending,
+	})
+	require.NoError(t, err)
+
+	// the receiver is erased, what was on its way to their account is called off
+	_, err = testStore.EraseUserTx(context.Background(), PseudonymizeUserParams{
+		Username:          toAccount.Owner,
+		Email:             util.RandomString(16) + "@invalid",
+		PasswordChangedAt: time.Now().UTC(),
+	})
+	require.NoError(t, err)
+
+	hold, err = testQueries.GetHold(context.Background(), hold.ID)
+	require.NoError(t, err)
+	require.Equal(t, HoldVoided, hold.Status)
+	account, err := testQueries.GetAccount(context.Background(), fromAccount.ID)
+	require.NoError(t, err)
+	require.Zero(t, account.HeldBalance)
+
+	scheduled, err = testQueries.GetScheduledTransfer(context.Background(), scheduled.ID)
+	require.NoError(t, err)
+	require.Equal(t, ScheduledTransferCanceled, scheduled.Status)
+	order, err = testQueries.GetStandingOrder(context.Background(), order.ID)
+	require.NoError(t, err)
+	require.Equal(t, StandingOrderCanceled, order.Status)
+	review, err = testQueries.GetRiskReview(context.Background(), review.ID)
+	require.NoError(t, err)
+	require.Equal(t, RiskReviewRejected, review.Status)
+
+	// and nothing can be paid into it anymore
+	_, err = testStore.TransferTx(context.Background(), CreateTransferParams{
+		FromAccountID: Int64ToSqlInt64(fromAccount.ID),
+		ToAccountID:   Int64ToSqlInt64(toAccount.ID),
+		Amount:        10,
+	})
+	require.ErrorIs(t, err, ErrInvalidDestination)
+	_, err = testStore.CreateHoldTx(context.Background(), CreateHoldParams{
+		AccountID:   fromAccount.ID,
+		ToAccountID: toAccount.ID,
+		Amount:      10,
+		Currency:    fromAccount.Currency,
+		ExpiresAt:   time.Now().Add(time.Hour),
+	})
+	require.ErrorIs(t, err, ErrInvalidDestination)
+}
+
+func TestUserPIIIsEncrypted(t *testing.T) {
+	createdUser, arg, err := createRandomUser("_test_user_pii")
+	require.NoError(t, err)
+	require.Equal(t, arg.Email, createdUser.Email)
+	require.Equal(t, arg.FullName, createdUser.FullName)
+
+	rawUser, err := testQueries.GetUserByUsername(context.Background(), createdUser.Username)
+	require.NoError(t, err)
+	require.True(t, util.IsEncryptedField(rawUser.Email))
+	require.True(t, util.IsEncryptedField(rawUser.FullName))
+	require.NotContains(t, rawUser.Email, arg.Email)
+	require.Equal(t, createdUser.EmailIndex, rawUser.EmailIndex)
+
+	retrievedUser, err := testStore.GetUserByEmail(context.Background(), arg.Email)
+	require.NoError(t, err)
+	require.Equal(t, createdUser.Username, retrievedUser.Username)
+
+	// the blind index keeps emails unique
+	duplicateArg := arg
+	duplicateArg.Username = util.RandomString(7) + "_test_user_pii"
+	_, err = testStore.CreateUser(context.Background(), duplicateArg)
+	require.Error(t, err)
+}
+
+func TestEncryptUsersPII(t *testing.T) {
+	username := util.RandomString(7) + "_test_encrypt_pii"
+	email := util.RandomEmail(username)
+	fullName := util.RandomOwner()
+
+	// a row written before encryption was enabled
+	_, err := testQueries.CreateUser(context.Background(), CreateUserParams{
+		Username:       username,
+		HashedPassword: "hash",
+		FullName:       fullName,
+		Email:          email,
+	})
+	require.NoError(t, err)
+
+	legacyUser, err := testStore.GetUserByUsername(context.Background(), username)
+	require.NoError(t, err)
+	require.Equal(t, email, legacyUser.Email)
+
+	count, err := testStore.EncryptUsersPII(context.Background(), 10)
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, count, 1)
+
+	rawUser, err := testQueries.GetUserByUsername(context.Background(), username)
+	require.NoError(t, err)
+	require.True(t, util.IsEncryptedField(rawUser.Email))
+	require.True(t, rawUser.EmailIndex.Valid)
+
+	encryptedUser, err := testStore.GetUserByEmail(context.Background(), email)
+	require.NoError(t, err)
+	require.Equal(t, fullName, encryptedUser.FullName)
+}
@@ -0,0 +1,80 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.25.0
+// source: verify_email.sql
+
+package db
+
+import (
+	"context"
+	"time"
+)
+
+const createVerifyEmailToken = `-- name: CreateVerifyEmailToken :one
+INSERT INTO verify_email_tokens (
+    hashed_token,
+    username,
+    email_index,
+    expires_at
+) VALUES (
+    $1, $2, $3, $4
+) RETURNING hashed_token, username, email_index, expires_at, used_at, created_at
+`
+
+type CreateVerifyEmailTokenParams struct {
+	HashedToken string    `json:"hashed_token"`
+	Username    string    `json:"username"`
+	EmailIndex  string    `json:"email_index"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+func (q *Queries) CreateVerifyEmailToken(ctx context.Context, arg CreateVerifyEmailTokenParams) (VerifyEmailToken, error) {
+	row := q.db.QueryRowContext(ctx, createVerifyEmailToken,
+		arg.HashedToken,
+		arg.Username,
+		arg.EmailIndex,
+		arg.ExpiresAt,
+	)
+	var i VerifyEmailToken
+	err := row.Scan(
+		&i.HashedToken,
+		&i.Username,
+		&i.EmailIndex,
+		&i.ExpiresAt,
+		&i.UsedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const deleteVerifyEmailTokens = `-- name: DeleteVerifyEmailTokens :exec
+DELETE FROM verify_email_tokens
+WHERE username = $1
+`
+
+// the tokens keep the email index of the address they were sent to, including a pending new address
+func (q *Queries) DeleteVerifyEmailTokens(ctx context.Context, username string) error {
+	_, err := q.db.ExecContext(ctx, deleteVerifyEmailTokens, username)
+	return err
+}
+
+const useVerifyEmailToken = `-- name: UseVerifyEmailToken :one
+UPDATE verify_email_tokens
+SET used_at = now()
+WHERE hashed_token = $1 AND used_at IS NULL AND expires_at > now()
+RETURNING hashed_token, username, email_index, expires_at, used_at, created_at
+`
+
+func (q *Queries) UseVerifyEmailToken(ctx context.Context, hashedToken string) (VerifyEmailToken, error) {
+	row := q.db.QueryRowContext(ctx, useVerifyEmailToken, hashedToken)
+	var i VerifyEmailToken
+	err := row.Scan(
+		&i.HashedToken,
+		&i.Username,
+		&i.EmailIndex,
+		&i.ExpiresAt,
+		&i.UsedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
@@ -0,0 +1,176 @@
+package fee
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/go_backend_misc/util"
+	"gopkg.in/yaml.v3"
+)
+
+// basisPointsPerUnit is the amount a rate in basis points is divided by, percentages have two decimals at most
+const basisPointsPerUnit = 10000
+
+var ErrInvalidSchedule = errors.New("invalid fee schedule")
+
+// Tier is an amount band of a tiered rule, the first tier with UpTo at or above the amount applies
+// and amounts above every tier use the last one
+type Tier struct {
+	// UpTo is the largest amount of the tier, 0 for the last one which has no limit
+	UpTo    int64   `yaml:"up_to"`
+	Flat    int64   `yaml:"flat"`
+	Percent float64 `yaml:"percent"`
+}
+
+// Rule adds a flat fee and a percentage of the amount, or those of the tier of the amount,
+// and brings the sum within Min and Max
+// Amounts are in minor units of the currency, like the transfer amounts
+type Rule struct {
+	Flat    int64   `yaml:"flat"`
+	Percent float64 `yaml:"percent"`
+	Tiers   []Tier  `yaml:"tiers"`
+	Min     int64   `yaml:"min"`
+	// Max is 0 when the fee has no maximum
+	Max int64 `yaml:"max"`
+}
+
+// Schedule holds the rule of each currency, currencies without one use Default
+// A nil Schedule, or one without a rule for the currency, makes transfers free
+type Schedule struct {
+	Default    *Rule           `yaml:"default"`
+	Currencies map[string]Rule `yaml:"currencies"`
+}
+
+// Breakdown is how the fee of a transfer adds up
+type Breakdown struct {
+	Currency   string `json:"currency"`
+	Flat       int64  `json:"flat"`
+	Percentage int64  `json:"percentage"`
+	// Adjustment brings the fee up to the minimum or down to the maximum of the rule
+	Adjustment int64 `json:"adjustment"`
+	Total      int64 `json:"total"`
+}
+
+// LoadSchedule reads a YAML fee schedule, an empty path means no fees
+func LoadSchedule(path string) (*Schedule, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read fee schedule: %w", err)
+	}
+	return ParseSchedule(data)
+}
+
+func ParseSchedule(data []byte) (*Schedule, error) {
+	var schedule Schedule
+	if err := yaml.Unmarshal(data, &schedule); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSchedule, err)
+	}
+
+	if schedule.Default != nil {
+		if err := schedule.Default.validate(); err != nil {
+			return nil, fmt.Errorf("%w: default: %v", ErrInvalidSchedule, err)
+		}
+	}
+	for currency, rule := range schedule.Currencies {
+		if !util.IsSupportedCurrency(currency) {
+			return nil, fmt.Errorf("%w: unsupported currency %s", ErrInvalidSchedule, currency)
+		}
+		if err := rule.validate(); err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidSchedule, currency, err)
+		}
+	}
+	return &schedule, nil
+}
+
+func (rule *Rule) validate() error {
+	if err := validateCharge(rule.Flat, rule.Percent); err != nil {
+		return err
+	}
+	if len(rule.Tiers) > 0 && (rule.Flat != 0 || rule.Percent != 0) {
+		return errors.New("flat and percent are set by the tiers of a tiered rule")
+	}
+	for i, tier := range rule.Tiers {
+		if err := validateCharge(tier.Flat, tier.Percent); err != nil {
+			return fmt.Errorf("tier %d: %v", i, err)
+		}
+		last := i == len(rule.Tiers)-1
+		switch {
+		case tier.UpTo < 0, tier.UpTo == 0 && !last:
+			return fmt.Errorf("tier %d: up_to must be positive, only the last tier can leave it out", i)
+		case i > 0 && tier.UpTo != 0 && tier.UpTo <= rule.Tiers[i-1].UpTo:
+			return fmt.Errorf("tier %d: up_to must be above the previous tier", i)
+		}
+	}
+	if rule.Min < 0 || rule.Max < 0 {
+		return errors.New("min and max can't be negative")
+	}
+	if rule.Max != 0 && rule.Min > rule.Max {
+		return errors.New("min is above max")
+	}
+	return nil
+}
+
+func validateCharge(flat int64, percent float64) error {
+	if flat < 0 {
+		return errors.New("flat can't be negative")
+	}
+	if percent < 0 || percent > 100 {
+		return errors.New("percent must be between 0 and 100")
+	}
+	// allow for the binary representation of decimals like 0.29
+	if basisPoints := percent * 100; math.Abs(basisPoints-math.Round(basisPoints)) > 1e-6 {
+		return errors.New("percent can't have more than two decimals")
+	}
+	return nil
+}
+
+// Calculate returns the fee of a transfer of amount in currency
+func (schedule *Schedule) Calculate(currency string, amount int64) Breakdown {
+	breakdown := Breakdown{Currency: currency}
+	rule := schedule.rule(currency)
+	if rule == nil {
+		return breakdown
+	}
+
+	flat, percent := rule.Flat, rule.Percent
+	for _, tier := range rule.Tiers {
+		flat, percent = tier.Flat, tier.Percent
+		if tier.UpTo == 0 || amount <= tier.UpTo {
+			break
+		}
+	}
+
+	breakdown.Flat = flat
+	breakdown.Percentage = percentageOf(amount, int64(math.Round(percent*100)))
+	total := breakdown.Flat + breakdown.Percentage
+	switch {
+	case total < rule.Min:
+		breakdown.Adjustment = rule.Min - total
+	case rule.Max != 0 && total > rule.Max:
+		breakdown.Adjustment = rule.Max - total
+	}
+	breakdown.Total = total + breakdown.Adjustment
+	return breakdown
+}
+
+func (schedule *Schedule) rule(currency string) *Rule {
+	if schedule == nil {
+		return nil
+	}
+	if rule, ok := schedule.Currencies[currency]; ok {
+		return &rule
+	}
+	return schedule.Default
+}
+
+// percentageOf rounds half up, splitting the amount so large amounts can't overflow
+func percentageOf(amount int64, basisPoints int64) int64 {
+	whole := amount / basisPointsPerUnit * basisPoints
+	rest := (amount%basisPointsPerUnit*basisPoints + basisPointsPerUnit/2) / basisPointsPerUnit
+	return whole + rest
+}
@@ -0,0 +1,145 @@
+package fee
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testSchedule = `
+default:
+  flat: 10
+currencies:
+  USD:
+    flat: 25
+    percent: 0.5
+    min: 50
+    max: 1000
+  EUR:
+    tiers:
+      - up_to: 10000
+        percent: 1
+      - up_to: 100000
+        flat: 20
+        percent: 0.25
+      - percent: 0.1
+`
+
+func TestCalculate(t *testing.T) {
+	schedule, err := ParseSchedule([]byte(testSchedule))
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name     string
+		currency string
+		amount   int64
+		expected Breakdown
+	}{
+		{
+			name:     "Flat and percentage",
+			currency: "USD",
+			amount:   10000,
+			expected: Breakdown{Currency: "USD", Flat: 25, Percentage: 50, Total: 75},
+		},
+		{
+			name:     "Minimum",
+			currency: "USD",
+			amount:   100,
+			expected: Breakdown{Currency: "USD", Flat: 25, Percentage: 1, Adjustment: 24, Total: 50},
+		},
+		{
+			name:     "Maximum",
+			currency: "USD",
+			amount:   1000000,
+			expected: Breakdown{Currency: "USD", Flat: 25, Percentage: 5000, Adjustment: -4025, Total: 1000},
+		},
+		{
+			name:     "First tier",
+			currency: "EUR",
+			amount:   10000,
+			expected: Breakdown{Currency: "EUR", Percentage: 100, Total: 100},
+		},
+		{
+			name:     "Second tier",
+			currency: "EUR",
+			amount:   10001,
+			expected: Breakdown{Currency: "EUR", Flat: 20, Percentage: 25, Total: 45},
+		},
+		{
+			name:     "Last tier",
+			currency: "EUR",
+			amount:   1000000,
+			expected: Breakdown{Currency: "EUR", Percentage: 1000, Total: 1000},
+		},
+		{
+			name:     "Default",
+			currency: "CAD",
+			amount:   1000,
+			expected: Breakdown{Currency: "CAD", Flat: 10, Total: 10},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, schedule.Calculate(tc.currency, tc.amount))
+		})
+	}
+}
+
+func TestCalculateWithoutSchedule(t *testing.T) {
+	var schedule *Schedule
+	require.Equal(t, Breakdown{Currency: "USD"}, schedule.Calculate("USD", 1000))
+
+	schedule, err := ParseSchedule([]byte("currencies:\n  USD:\n    flat: 5\n"))
+	require.NoError(t, err)
+	require.Equal(t, int64(0), schedule.Calculate("EUR", 1000).Total)
+}
+
+func TestPercentageOf(t *testing.T) {
+	require.Equal(t, int64(1), percentageOf(199, 50))
+	require.Equal(t, int64(0), percentageOf(99, 50))
+	require.Equal(t, int64(29), percentageOf(10000, 29))
+	require.Equal(t, int64(math.MaxInt64/10000*100+58), percentageOf(math.MaxInt64, 100))
+}
+
+func TestParseScheduleErrors(t *testing.T) {
+	testCases := []struct {
+		name     string
+		schedule string
+	}{
+		{name: "Not YAML", schedule: "currencies: ["},
+		{name: "Unsupported currency", schedule: "currencies:\n  XXX:\n    flat: 1\n"},
+		{name: "Negative flat", schedule: "default:\n  flat: -1\n"},
+		{name: "Percent above 100", schedule: "default:\n  percent: 101\n"},
+		{name: "Three decimals", schedule: "default:\n  percent: 0.125\n"},
+		{name: "Min above max", schedule: "default:\n  min: 10\n  max: 5\n"},
+		{name: "Tiers with a flat", schedule: "default:\n  flat: 1\n  tiers:\n    - percent: 1\n"},
+		{name: "Tier without up_to", schedule: "default:\n  tiers:\n    - percent: 1\n    - percent: 2\n"},
+		{name: "Tiers out of order", schedule: "default:\n  tiers:\n    - up_to: 10\n    - up_to: 5\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseSchedule([]byte(tc.schedule))
+			require.ErrorIs(t, err, ErrInvalidSchedule)
+		})
+	}
+}
+
+func TestLoadSchedule(t *testing.T) {
+	schedule, err := LoadSchedule("")
+	require.NoError(t, err)
+	require.Nil(t, schedule)
+
+	path := filepath.Join(t.TempDir(), "fees.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(testSchedule), 0o600))
+	schedule, err = LoadSchedule(path)
+	require.NoError(t, err)
+	require.Equal(t, int64(75), schedule.Calculate("USD", 10000).Total)
+
+	_, err = LoadSchedule(filepath.Join(t.TempDir(), "missing.yaml"))
+	require.Error(t, err)
+}
@@ -0,0 +1,47 @@
+package mail
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FileSender writes every email to a file in a local outbox directory
+// It stands in for a real SMTP server during development
+type FileSender struct {
+	fromAddress string
+	outboxDir   string
+}
+
+func NewFileSender(fromAddress string, outboxDir string) (EmailSender, error) {
+	if err := os.MkdirAll(outboxDir, 0o700); err != nil {
+		return nil, fmt.Errorf("cannot create outbox directory: %w", err)
+	}
+
+	return &FileSender{
+		fromAddress: fromAddress,
+		outboxDir:   outboxDir,
+	}, nil
+}
+
+func (sender *FileSender) SendEmail(subject string, content string, to []string) error {
+	if len(to) == 0 {
+		return fmt.Errorf("email has no recipients")
+	}
+
+	var message strings.Builder
+	fmt.Fprintf(&message, "From: %s\r\n", sender.fromAddress)
+	fmt.Fprintf(&message, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&message, "Subject: %s\r\n", subject)
+	fmt.Fprintf(&message, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	message.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	message.WriteString(content)
+
+	// the timestamp keeps the outbox sorted, the uuid avoids collisions
+	fileName := fmt.Sprintf("%s_%s.eml", time.Now().UTC().Format("20060102T150405.000000"), uuid.NewString())
+	return os.WriteFile(filepath.Join(sender.outboxDir, fileName), []byte(message.String()), 0o600)
+}
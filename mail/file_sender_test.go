@@ -0,0 +1,31 @@
+package mail
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileSender(t *testing.T) {
+	outboxDir := filepath.Join(t.TempDir(), "outbox")
+	sender, err := NewFileSender("bank@example.com", outboxDir)
+	require.NoError(t, err)
+
+	err = sender.SendEmail("Test subject", "Test content", []string{"user@example.com"})
+	require.NoError(t, err)
+
+	files, err := os.ReadDir(outboxDir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	data, err := os.ReadFile(filepath.Join(outboxDir, files[0].Name()))
+	require.NoError(t, err)
+	require.Contains(t, string(data), "To: user@example.com")
+	require.Contains(t, string(data), "Subject: Test subject")
+	require.Contains(t, string(data), "Test content")
+
+	err = sender.SendEmail("Test subject", "Test content", nil)
+	require.Error(t, err)
+}
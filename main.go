@@ -1,12 +1,17 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"log"
+	"os"
 
 	"github.com/go_backend_misc/api"
 	db "github.com/go_backend_misc/db/sqlc"
+	"github.com/go_backend_misc/fee"
+	"github.com/go_backend_misc/mail"
 	"github.com/go_backend_misc/util"
+	"github.com/go_backend_misc/worker"
 
 	// required to connect to DB
 	_ "github.com/lib/pq"
@@ -22,11 +27,56 @@ func main() {
 		log.Fatal("cannot connect with db:", err)
 	}
 
-	store := db.NewStore(conn)
-	server, err := api.NewServer(config, store)
+	fieldEncryptor, err := util.NewFieldEncryptor(config)
+	if err != nil {
+		log.Fatal("cannot create field encryptor:", err)
+	}
+
+	// the fee schedule and the validators check currencies against the registry
+	currencyLoader := worker.NewCurrencyLoader(db.New(conn), config)
+	if err := currencyLoader.Load(context.Background()); err != nil {
+		log.Fatal("cannot load currencies:", err)
+	}
+
+	feeSchedule, err := fee.LoadSchedule(config.FeeScheduleFile)
+	if err != nil {
+		log.Fatal("cannot load fee schedule:", err)
+	}
+	store := db.NewStore(conn, fieldEncryptor, feeSchedule)
+
+	// admin commands, e.g. `go run . unlock <username>`
+	if len(os.Args) > 1 {
+		if err := runCommand(config, store, os.Args[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	emailSender, err := mail.NewFileSender(config.EmailSenderAddress, config.EmailOutboxDir)
+	if err != nil {
+		log.Fatal("cannot create email sender:", err)
+	}
+
+	server, err := api.NewServer(config, store, emailSender)
 	if err != nil {
 		log.Fatal("cannot create server:", err)
 	}
+
+	if config.CurrencyRefreshInterval > 0 {
+		go currencyLoader.Run(context.Background())
+	}
+	if config.ScheduledTransferPollInterval > 0 {
+		go worker.NewScheduledTransferExecutor(store, config, server.RiskEngine()).Run(context.Background())
+	}
+	if config.StandingOrderPollInterval > 0 {
+		go worker.NewStandingOrderExecutor(store, config, server.RiskEngine()).Run(context.Background())
+	}
+	if config.HoldExpiryPollInterval > 0 {
+		go worker.NewHoldExpirer(store, config).Run(context.Background())
+	}
+	if config.BalanceSnapshotPollInterval > 0 {
+		go worker.NewBalanceSnapshotter(store, config).Run(context.Background())
+	}
 	if err := server.Start(config.ServerAddress); err != nil {
 		log.Fatal("cannot start server:", err)
 	}
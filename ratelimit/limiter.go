@@ -0,0 +1,89 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit is a token bucket that holds up to Requests tokens and refills all of them over Period
+// A zero Limit disables rate limiting
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// ParseLimit reads limits written as "<requests>/<period>", e.g. "10/1m"
+// An empty string or "0" disables the limit
+func ParseLimit(value string) (Limit, error) {
+	value = strings.TrimSpace(value)
+	if value == "" || value == "0" {
+		return Limit{}, nil
+	}
+
+	requestsValue, periodValue, found := strings.Cut(value, "/")
+	if !found {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: expected <requests>/<period>", value)
+	}
+	requests, err := strconv.Atoi(requestsValue)
+	if err != nil || requests <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: requests must be a positive integer", value)
+	}
+	period, err := time.ParseDuration(periodValue)
+	if err != nil || period <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: period must be a positive duration", value)
+	}
+
+	return Limit{Requests: requests, Period: period}, nil
+}
+
+func (limit Limit) Enabled() bool {
+	return limit.Requests > 0 && limit.Period > 0
+}
+
+// RefillRate is the number of tokens added per second
+func (limit Limit) RefillRate() float64 {
+	return float64(limit.Requests) / limit.Period.Seconds()
+}
+
+// refill adds the tokens earned since the last request, up to the bucket capacity
+func (limit Limit) refill(tokens float64, elapsed time.Duration) float64 {
+	return math.Min(float64(limit.Requests), tokens+elapsed.Seconds()*limit.RefillRate())
+}
+
+// Result describes the bucket right after a request took, or failed to take, a token
+type Result struct {
+	Allowed bool
+	// Tokens left in the bucket, it can be fractional while refilling
+	Tokens float64
+}
+
+func (result Result) Remaining() int {
+	return int(math.Floor(result.Tokens))
+}
+
+// ResetAfter is the time until the bucket is full again
+func (result Result) ResetAfter(limit Limit) time.Duration {
+	missing := float64(limit.Requests) - result.Tokens
+	return secondsToDuration(missing / limit.RefillRate())
+}
+
+// RetryAfter is the time until the next request is allowed
+func (result Result) RetryAfter(limit Limit) time.Duration {
+	missing := math.Max(0, 1-result.Tokens)
+	return secondsToDuration(missing / limit.RefillRate())
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(math.Ceil(seconds * float64(time.Second)))
+}
+
+// Store keeps the token buckets
+// Implementations must be safe for concurrent use
+type Store interface {
+	// Take removes a token from the bucket of the key if there's one left
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
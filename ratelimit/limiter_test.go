@@ -0,0 +1,40 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLimit(t *testing.T) {
+	limit, err := ParseLimit("10/1m")
+	require.NoError(t, err)
+	require.Equal(t, Limit{Requests: 10, Period: time.Minute}, limit)
+	require.True(t, limit.Enabled())
+	require.InDelta(t, 10.0/60, limit.RefillRate(), 1e-9)
+
+	for _, disabled := range []string{"", "0", " "} {
+		limit, err := ParseLimit(disabled)
+		require.NoError(t, err)
+		require.False(t, limit.Enabled())
+	}
+
+	for _, invalid := range []string{"10", "ten/1m", "-1/1m", "10/forever", "10/0s"} {
+		_, err := ParseLimit(invalid)
+		require.Error(t, err, invalid)
+	}
+}
+
+func TestResult(t *testing.T) {
+	limit := Limit{Requests: 10, Period: 10 * time.Second}
+
+	result := Result{Allowed: true, Tokens: 7.5}
+	require.Equal(t, 7, result.Remaining())
+	require.Equal(t, 2500*time.Millisecond, result.ResetAfter(limit))
+	require.Zero(t, result.RetryAfter(limit))
+
+	result = Result{Allowed: false, Tokens: 0.25}
+	require.Equal(t, 0, result.Remaining())
+	require.Equal(t, 750*time.Millisecond, result.RetryAfter(limit))
+}
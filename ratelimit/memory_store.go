@@ -0,0 +1,68 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// idle buckets are dropped once they would be full again, as a full bucket is the same as no bucket
+const memorySweepInterval = time.Minute
+
+type memoryBucket struct {
+	tokens    float64
+	updatedAt time.Time
+	limit     Limit
+}
+
+// MemoryStore keeps the buckets in the process memory
+// Limits are per instance, use PostgresStore when running more than one
+type MemoryStore struct {
+	mutex     sync.Mutex
+	buckets   map[string]*memoryBucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewMemoryStore() Store {
+	return &MemoryStore{
+		buckets: make(map[string]*memoryBucket),
+		now:     time.Now,
+	}
+}
+
+func (store *MemoryStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	now := store.now()
+	store.sweep(now)
+
+	bucket, ok := store.buckets[key]
+	if !ok {
+		bucket = &memoryBucket{tokens: float64(limit.Requests), updatedAt: now}
+		store.buckets[key] = bucket
+	}
+	bucket.tokens = limit.refill(bucket.tokens, now.Sub(bucket.updatedAt))
+	bucket.updatedAt = now
+	bucket.limit = limit
+
+	if bucket.tokens < 1 {
+		return Result{Allowed: false, Tokens: bucket.tokens}, nil
+	}
+	bucket.tokens--
+	return Result{Allowed: true, Tokens: bucket.tokens}, nil
+}
+
+func (store *MemoryStore) sweep(now time.Time) {
+	if now.Sub(store.lastSweep) < memorySweepInterval {
+		return
+	}
+	store.lastSweep = now
+
+	for key, bucket := range store.buckets {
+		if bucket.limit.refill(bucket.tokens, now.Sub(bucket.updatedAt)) >= float64(bucket.limit.Requests) {
+			delete(store.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestMemoryStore() (*MemoryStore, *time.Time) {
+	now := time.Now()
+	store := NewMemoryStore().(*MemoryStore)
+	store.now = func() time.Time { return now }
+	return store, &now
+}
+
+func TestMemoryStoreTake(t *testing.T) {
+	store, now := newTestMemoryStore()
+	limit := Limit{Requests: 3, Period: 3 * time.Second}
+
+	for i := 2; i >= 0; i-- {
+		result, err := store.Take(context.Background(), "key", limit)
+		require.NoError(t, err)
+		require.True(t, result.Allowed)
+		require.Equal(t, i, result.Remaining())
+	}
+
+	result, err := store.Take(context.Background(), "key", limit)
+	require.NoError(t, err)
+	require.False(t, result.Allowed)
+	require.Equal(t, time.Second, result.RetryAfter(limit))
+
+	// other keys have their own bucket
+	result, err = store.Take(context.Background(), "other", limit)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+
+	// a token is earned back every second
+	*now = now.Add(time.Second)
+	result, err = store.Take(context.Background(), "key", limit)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+	require.Equal(t, 0, result.Remaining())
+
+	// the bucket never holds more than its capacity
+	*now = now.Add(time.Hour)
+	result, err = store.Take(context.Background(), "key", limit)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+	require.Equal(t, 2, result.Remaining())
+}
+
+func TestMemoryStoreSweep(t *testing.T) {
+	store, now := newTestMemoryStore()
+	limit := Limit{Requests: 3, Period: time.Second}
+
+	_, err := store.Take(context.Background(), "idle", limit)
+	require.NoError(t, err)
+	require.Len(t, store.buckets, 1)
+
+	*now = now.Add(memorySweepInterval)
+	_, err = store.Take(context.Background(), "active", limit)
+	require.NoError(t, err)
+	require.Len(t, store.buckets, 1)
+	require.Contains(t, store.buckets, "active")
+}
+
+func TestMemoryStoreConcurrent(t *testing.T) {
+	store, _ := newTestMemoryStore()
+	limit := Limit{Requests: 50, Period: time.Hour}
+
+	var wg sync.WaitGroup
+	allowed := make(chan bool, 100)
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := store.Take(context.Background(), "key", limit)
+			require.NoError(t, err)
+			allowed <- result.Allowed
+		}()
+	}
+	wg.Wait()
+	close(allowed)
+
+	allowedCount := 0
+	for isAllowed := range allowed {
+		if isAllowed {
+			allowedCount++
+		}
+	}
+	require.Equal(t, 50, allowedCount)
+}
@@ -0,0 +1,59 @@
+package ratelimit
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	db "github.com/go_backend_misc/db/sqlc"
+)
+
+const (
+	postgresSweepInterval = 10 * time.Minute
+	// buckets idle for longer than this are full again for any sensible limit
+	postgresIdleBucketAge = 24 * time.Hour
+)
+
+// PostgresStore keeps the buckets in the database, so the limits hold across server instances
+type PostgresStore struct {
+	queries   db.Querier
+	mutex     sync.Mutex
+	lastSweep time.Time
+}
+
+func NewPostgresStore(queries db.Querier) Store {
+	return &PostgresStore{
+		queries:   queries,
+		lastSweep: time.Now(),
+	}
+}
+
+func (store *PostgresStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	store.sweep(ctx)
+
+	bucket, err := store.queries.TakeRateLimitToken(ctx, db.TakeRateLimitTokenParams{
+		Key:        key,
+		Capacity:   float64(limit.Requests),
+		RefillRate: limit.RefillRate(),
+	})
+	if err != nil {
+		return Result{}, err
+	}
+	return Result{Allowed: bucket.Allowed, Tokens: bucket.Tokens}, nil
+}
+
+// sweep deletes idle buckets now and then, so the table doesn't grow with every client IP ever seen
+func (store *PostgresStore) sweep(ctx context.Context) {
+	store.mutex.Lock()
+	if time.Since(store.lastSweep) < postgresSweepInterval {
+		store.mutex.Unlock()
+		return
+	}
+	store.lastSweep = time.Now()
+	store.mutex.Unlock()
+
+	if _, err := store.queries.DeleteIdleRateLimitBuckets(ctx, time.Now().Add(-postgresIdleBucketAge)); err != nil {
+		log.Printf("cannot delete idle rate limit buckets: %v", err)
+	}
+}
@@ -0,0 +1,238 @@
+package risk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	db "github.com/go_backend_misc/db/sqlc"
+	"github.com/go_backend_misc/util"
+	"gopkg.in/yaml.v3"
+)
+
+// Decision is what happens to a transfer, the strictest decision of the rules it triggers wins
+type Decision string
+
+const (
+	// Allow makes the transfer, rules that allow only flag it in the logs
+	Allow Decision = "allow"
+	// Hold keeps the transfer for an admin to approve or reject
+	Hold Decision = "hold"
+	Deny Decision = "deny"
+)
+
+var ErrInvalidRules = errors.New("invalid risk rules")
+
+func (decision Decision) valid() bool {
+	return decision == Allow || decision == Hold || decision == Deny
+}
+
+func (decision Decision) strictness() int {
+	switch decision {
+	case Deny:
+		return 2
+	case Hold:
+		return 1
+	}
+	return 0
+}
+
+// Transfer is a transfer about to be made, amounts are in minor units of the currency
+type Transfer struct {
+	Owner         string
+	FromAccountID int64
+	ToAccountID   int64
+	Amount        int64
+	Currency      string
+	// Earlier are the amounts of the transfers of the same batch before this one, they aren't made yet
+	// so the rules counting the transfers of the account count them too
+	Earlier []int64
+}
+
+// Rule looks at a transfer before it's made, Check returns why the transfer is suspicious,
+// or an empty reason when it isn't
+// The rules run before the transaction of the transfer, they only write what needs a review of its own,
+// like the screening matches of the sanctions rule
+type Rule interface {
+	Name() string
+	Check(ctx context.Context, store db.Querier, transfer *Transfer) (reason string, err error)
+}
+
+// Assessment is the decision on a transfer with the reasons of the rules it triggered
+type Assessment struct {
+	Decision Decision `json:"decision"`
+	Reasons  []string `json:"reasons"`
+}
+
+type engineRule struct {
+	rule   Rule
+	action Decision
+}
+
+// Engine runs every rule on a transfer, a nil Engine allows every transfer
+type Engine struct {
+	rules []engineRule
+}
+
+func NewEngine() *Engine {
+	return &Engine{}
+}
+
+// Add plugs a rule in, action is the decision when the rule is triggered
+func (engine *Engine) Add(rule Rule, action Decision) {
+	engine.rules = append(engine.rules, engineRule{rule: rule, action: action})
+}
+
+// Assess runs the rules on the transfer, a failing rule fails the assessment
+func (engine *Engine) Assess(ctx context.Context, store db.Querier, transfer *Transfer) (Assessment, error) {
+	assessment := Assessment{Decision: Allow}
+	if engine == nil {
+		return assessment, nil
+	}
+
+	for _, rule := range engine.rules {
+		reason, err := rule.rule.Check(ctx, store, transfer)
+		if err != nil {
+			return assessment, fmt.Errorf("rule %s: %w", rule.rule.Name(), err)
+		}
+		if reason == "" {
+			continue
+		}
+		assessment.Reasons = append(assessment.Reasons, fmt.Sprintf("%s: %s", rule.rule.Name(), reason))
+		if rule.action.strictness() > assessment.Decision.strictness() {
+			assessment.Decision = rule.action
+		}
+	}
+	return assessment, nil
+}
+
+// Review runs the rules on a transfer and records the held and denied ones as a risk review,
+// held transfers are pending review when they can wait for it and denied otherwise
+func (engine *Engine) Review(ctx context.Context, store db.Querier, transfer *Transfer, fee int64, canWait bool) (Decision, db.RiskReview, error) {
+	assessment, err := engine.Assess(ctx, store, transfer)
+	if err != nil {
+		return "", db.RiskReview{}, err
+	}
+	if assessment.Decision == Allow {
+		if len(assessment.Reasons) > 0 {
+			log.Printf("risk: transfer of %v from account %d flagged %v", transfer.Owner, transfer.FromAccountID, assessment.Reasons)
+		}
+		return Allow, db.RiskReview{}, nil
+	}
+
+	status := db.RiskReviewPending
+	if assessment.Decision == Deny || !canWait {
+		status = db.RiskReviewDenied
+	}
+	review, err := store.CreateRiskReview(ctx, db.CreateRiskReviewParams{
+		Owner:         transfer.Owner,
+		FromAccountID: transfer.FromAccountID,
+		ToAccountID:   transfer.ToAccountID,
+		Amount:        transfer.Amount,
+		Fee:           fee,
+		Currency:      transfer.Currency,
+		Decision:      string(assessment.Decision),
+		Reasons:       assessment.Reasons,
+		Status:        status,
+	})
+	if err != nil {
+		return "", db.RiskReview{}, err
+	}
+	log.Printf("security: risk review %d %v transfer of %v from account %d: %v",
+		review.ID, assessment.Decision, transfer.Owner, transfer.FromAccountID, assessment.Reasons)
+	return assessment.Decision, review, nil
+}
+
+// RuleConfig is a built-in rule in the YAML rules, amounts are in minor units of their currency
+type RuleConfig struct {
+	Type   string   `yaml:"type"`
+	Action Decision `yaml:"action"`
+	// Amounts are the amounts by currency the large_amount and new_payee rules apply from
+	Amounts map[string]int64 `yaml:"amounts"`
+	// RoundTo is the multiple by currency of the round amounts of the structuring rule
+	RoundTo map[string]int64 `yaml:"round_to"`
+	// Count and Window are how many transfers within how long trigger the velocity and structuring rules
+	Count  int64         `yaml:"count"`
+	Window time.Duration `yaml:"window"`
+}
+
+type config struct {
+	Rules []RuleConfig `yaml:"rules"`
+}
+
+// LoadRules reads YAML risk rules, an empty path means no rules
+func LoadRules(path string) (*Engine, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read risk rules: %w", err)
+	}
+	return ParseRules(data)
+}
+
+func ParseRules(data []byte) (*Engine, error) {
+	var config config
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRules, err)
+	}
+
+	engine := NewEngine()
+	for i, ruleConfig := range config.Rules {
+		rule, err := ruleConfig.build()
+		if err != nil {
+			return nil, fmt.Errorf("%w: rule %d: %v", ErrInvalidRules, i, err)
+		}
+		engine.Add(rule, ruleConfig.Action)
+	}
+	return engine, nil
+}
+
+func (ruleConfig *RuleConfig) build() (Rule, error) {
+	if !ruleConfig.Action.valid() {
+		return nil, fmt.Errorf("action must be allow, hold or deny")
+	}
+	if err := validateAmounts(ruleConfig.Amounts); err != nil {
+		return nil, fmt.Errorf("amounts: %v", err)
+	}
+	if err := validateAmounts(ruleConfig.RoundTo); err != nil {
+		return nil, fmt.Errorf("round_to: %v", err)
+	}
+
+	switch ruleConfig.Type {
+	case "large_amount":
+		if len(ruleConfig.Amounts) == 0 {
+			return nil, errors.New("large_amount needs amounts")
+		}
+		return &LargeAmount{Amounts: ruleConfig.Amounts}, nil
+	case "velocity":
+		if ruleConfig.Count <= 0 || ruleConfig.Window <= 0 {
+			return nil, errors.New("velocity needs a count and a window")
+		}
+		return &Velocity{Count: ruleConfig.Count, Window: ruleConfig.Window}, nil
+	case "new_payee":
+		return &NewPayee{Amounts: ruleConfig.Amounts}, nil
+	case "structuring":
+		if len(ruleConfig.RoundTo) == 0 || ruleConfig.Count <= 0 || ruleConfig.Window <= 0 {
+			return nil, errors.New("structuring needs round_to, a count and a window")
+		}
+		return &Structuring{RoundTo: ruleConfig.RoundTo, Count: ruleConfig.Count, Window: ruleConfig.Window}, nil
+	}
+	return nil, fmt.Errorf("unknown type %q", ruleConfig.Type)
+}
+
+func validateAmounts(amounts map[string]int64) error {
+	for currency, amount := range amounts {
+		if _, ok := util.LookupCurrency(currency); !ok {
+			return fmt.Errorf("unsupported currency %s", currency)
+		}
+		if amount <= 0 {
+			return fmt.Errorf("%s must be positive", currency)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,216 @@
+package risk
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	mockdb "github.com/go_backend_misc/db/mock"
+	db "github.com/go_backend_misc/db/sqlc"
+	"github.com/go_backend_misc/util"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestParseRules(t *testing.T) {
+	data, err := os.ReadFile("../risk.yaml")
+	require.NoError(t, err)
+	engine, err := ParseRules(data)
+	require.NoError(t, err)
+	require.Len(t, engine.rules, 5)
+	require.Equal(t, &Velocity{Count: 10, Window: 10 * time.Minute}, engine.rules[2].rule)
+
+	testCases := []struct {
+		name  string
+		rules string
+	}{
+		{name: "Unknown type", rules: "rules:\n  - type: unknown\n    action: hold\n"},
+		{name: "Unknown action", rules: "rules:\n  - type: new_payee\n    action: block\n"},
+		{name: "Large amount without amounts", rules: "rules:\n  - type: large_amount\n    action: hold\n"},
+		{name: "Unsupported currency", rules: "rules:\n  - type: large_amount\n    action: hold\n    amounts:\n      XYZ: 100\n"},
+		{name: "Negative amount", rules: "rules:\n  - type: large_amount\n    action: hold\n    amounts:\n      USD: -1\n"},
+		{name: "Velocity without window", rules: "rules:\n  - type: velocity\n    action: hold\n    count: 3\n"},
+		{name: "Structuring without round_to", rules: "rules:\n  - type: structuring\n    action: hold\n    count: 3\n    window: 1h\n"},
+		{name: "Invalid YAML", rules: "rules: ["},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseRules([]byte(tc.rules))
+			require.ErrorIs(t, err, ErrInvalidRules)
+		})
+	}
+}
+
+func TestAssess(t *testing.T) {
+	transfer := &Transfer{Owner: "owner", FromAccountID: 1, ToAccountID: 2, Amount: 300000, Currency: util.USD}
+
+	testCases := []struct {
+		name             string
+		rules            string
+		buildStubs       func(store *mockdb.MockStore)
+		expectedDecision Decision
+		expectedReasons  int
+	}{
+		{
+			name:             "No rule triggered",
+			rules:            "rules:\n  - type: large_amount\n    action: hold\n    amounts:\n      USD: 1000000\n",
+			buildStubs:       func(store *mockdb.MockStore) {},
+			expectedDecision: Allow,
+		},
+		{
+			name:             "Other currency",
+			rules:            "rules:\n  - type: large_amount\n    action: deny\n    amounts:\n      EUR: 100\n",
+			buildStubs:       func(store *mockdb.MockStore) {},
+			expectedDecision: Allow,
+		},
+		{
+			name:             "Allow only flags",
+			rules:            "rules:\n  - type: large_amount\n    action: allow\n    amounts:\n      USD: 100000\n",
+			buildStubs:       func(store *mockdb.MockStore) {},
+			expectedDecision: Allow,
+			expectedReasons:  1,
+		},
+		{
+			name:  "Velocity",
+			rules: "rules:\n  - type: velocity\n    action: hold\n    count: 3\n    window: 10m\n",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CountTransfersSince(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.CountTransfersSinceParams) (int64, error) {
+						require.Equal(t, int64(1), arg.AccountID.Int64)
+						require.WithinDuration(t, time.Now().Add(-10*time.Minute), arg.Since, time.Minute)
+						require.Zero(t, arg.RoundTo)
+						return 3, nil
+					})
+			},
+			expectedDecision: Hold,
+			expectedReasons:  1,
+		},
+		{
+			name:  "New payee",
+			rules: "rules:\n  - type: new_payee\n    action: hold\n",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					HasTransferredTo(gomock.Any(), db.HasTransferredToParams{
+						FromAccountID: db.Int64ToSqlInt64(1),
+						ToAccountID:   db.Int64ToSqlInt64(2),
+					}).
+					Times(1).
+					Return(false, nil)
+			},
+			expectedDecision: Hold,
+			expectedReasons:  1,
+		},
+		{
+			name:  "Known payee",
+			rules: "rules:\n  - type: new_payee\n    action: hold\n",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().HasTransferredTo(gomock.Any(), gomock.Any()).Times(1).Return(true, nil)
+			},
+			expectedDecision: Allow,
+		},
+		{
+			name:  "New payee below the amount",
+			rules: "rules:\n  - type: new_payee\n    action: hold\n    amounts:\n      USD: 500000\n",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().HasTransferredTo(gomock.Any(), gomock.Any()).Times(0)
+			},
+			expectedDecision: Allow,
+		},
+		{
+			name:  "Structuring",
+			rules: "rules:\n  - type: structuring\n    action: hold\n    round_to:\n      USD: 100000\n    count: 3\n    window: 24h\n",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CountTransfersSince(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.CountTransfersSinceParams) (int64, error) {
+						require.Equal(t, int64(100000), arg.RoundTo)
+						return 2, nil
+					})
+			},
+			expectedDecision: Hold,
+			expectedReasons:  1,
+		},
+		{
+			name:  "Not a round amount",
+			rules: "rules:\n  - type: structuring\n    action: hold\n    round_to:\n      USD: 200000\n    count: 3\n    window: 24h\n",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CountTransfersSince(gomock.Any(), gomock.Any()).Times(0)
+			},
+			expectedDecision: Allow,
+		},
+		{
+			name: "Strictest wins",
+			rules: "rules:\n  - type: large_amount\n    action: deny\n    amounts:\n      USD: 300000\n" +
+				"  - type: new_payee\n    action: hold\n",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().HasTransferredTo(gomock.Any(), gomock.Any()).Times(1).Return(false, nil)
+			},
+			expectedDecision: Deny,
+			expectedReasons:  2,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			engine, err := ParseRules([]byte(tc.rules))
+			require.NoError(t, err)
+			assessment, err := engine.Assess(context.Background(), store, transfer)
+			require.NoError(t, err)
+			require.Equal(t, tc.expectedDecision, assessment.Decision)
+			require.Len(t, assessment.Reasons, tc.expectedReasons)
+		})
+	}
+}
+
+func TestAssessEarlierBatchItems(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// no transfers are made yet, the earlier items of the batch trigger the rules on their own
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().CountTransfersSince(gomock.Any(), gomock.Any()).Times(2).Return(int64(0), nil)
+
+	engine, err := ParseRules([]byte("rules:\n  - type: velocity\n    action: hold\n    count: 3\n    window: 10m\n" +
+		"  - type: structuring\n    action: deny\n    round_to:\n      USD: 100000\n    count: 3\n    window: 24h\n"))
+	require.NoError(t, err)
+	assessment, err := engine.Assess(context.Background(), store, &Transfer{
+		FromAccountID: 1,
+		ToAccountID:   2,
+		Amount:        100000,
+		Currency:      util.USD,
+		Earlier:       []int64{100000, 100000, 123},
+	})
+	require.NoError(t, err)
+	require.Equal(t, Deny, assessment.Decision)
+	require.Len(t, assessment.Reasons, 2)
+}
+
+func TestAssessError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().HasTransferredTo(gomock.Any(), gomock.Any()).Times(1).Return(false, sql.ErrConnDone)
+
+	engine := NewEngine()
+	engine.Add(&NewPayee{}, Hold)
+	_, err := engine.Assess(context.Background(), store, &Transfer{FromAccountID: 1, ToAccountID: 2, Amount: 100, Currency: util.USD})
+	require.ErrorIs(t, err, sql.ErrConnDone)
+
+	// without rules every transfer is allowed
+	var noRules *Engine
+	assessment, err := noRules.Assess(context.Background(), store, &Transfer{})
+	require.NoError(t, err)
+	require.Equal(t, Allow, assessment.Decision)
+}
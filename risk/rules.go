@@ -0,0 +1,121 @@
+package risk
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	db "github.com/go_backend_misc/db/sqlc"
+	"github.com/go_backend_misc/util"
+)
+
+// LargeAmount flags transfers at or above the amount of their currency, other currencies aren't checked
+type LargeAmount struct {
+	Amounts map[string]int64
+}
+
+func (rule *LargeAmount) Name() string {
+	return "large_amount"
+}
+
+func (rule *LargeAmount) Check(ctx context.Context, store db.Querier, transfer *Transfer) (string, error) {
+	threshold := rule.Amounts[transfer.Currency]
+	if threshold == 0 || transfer.Amount < threshold {
+		return "", nil
+	}
+	return fmt.Sprintf("%s is at least %s", util.NewMoney(transfer.Amount, transfer.Currency),
+		util.NewMoney(threshold, transfer.Currency)), nil
+}
+
+// Velocity allows Count transfers of the account within Window and flags the ones after
+type Velocity struct {
+	Count  int64
+	Window time.Duration
+}
+
+func (rule *Velocity) Name() string {
+	return "velocity"
+}
+
+func (rule *Velocity) Check(ctx context.Context, store db.Querier, transfer *Transfer) (string, error) {
+	count, err := store.CountTransfersSince(ctx, db.CountTransfersSinceParams{
+		AccountID: db.Int64ToSqlInt64(transfer.FromAccountID),
+		Since:     time.Now().Add(-rule.Window),
+	})
+	if err != nil {
+		return "", err
+	}
+	count += int64(len(transfer.Earlier))
+	if count < rule.Count {
+		return "", nil
+	}
+	return fmt.Sprintf("%d transfers within %v", count, rule.Window), nil
+}
+
+// NewPayee flags the first transfer between two accounts, when Amounts is set only from the amount
+// of the currency and other currencies aren't checked
+type NewPayee struct {
+	Amounts map[string]int64
+}
+
+func (rule *NewPayee) Name() string {
+	return "new_payee"
+}
+
+func (rule *NewPayee) Check(ctx context.Context, store db.Querier, transfer *Transfer) (string, error) {
+	if len(rule.Amounts) > 0 {
+		threshold := rule.Amounts[transfer.Currency]
+		if threshold == 0 || transfer.Amount < threshold {
+			return "", nil
+		}
+	}
+
+	known, err := store.HasTransferredTo(ctx, db.HasTransferredToParams{
+		FromAccountID: db.Int64ToSqlInt64(transfer.FromAccountID),
+		ToAccountID:   db.Int64ToSqlInt64(transfer.ToAccountID),
+	})
+	if err != nil || known {
+		return "", err
+	}
+	return fmt.Sprintf("first transfer to account %d", transfer.ToAccountID), nil
+}
+
+// Structuring flags the Count-th round amount of the account within Window, and the ones after,
+// like splitting a large amount in round transfers to stay under the radar
+type Structuring struct {
+	// RoundTo is the multiple of the round amounts of each currency, other currencies aren't checked
+	RoundTo map[string]int64
+	Count   int64
+	Window  time.Duration
+}
+
+func (rule *Structuring) Name() string {
+	return "structuring"
+}
+
+func (rule *Structuring) Check(ctx context.Context, store db.Querier, transfer *Transfer) (string, error) {
+	roundTo := rule.RoundTo[transfer.Currency]
+	if roundTo == 0 || transfer.Amount%roundTo != 0 {
+		return "", nil
+	}
+
+	count, err := store.CountTransfersSince(ctx, db.CountTransfersSinceParams{
+		AccountID: db.Int64ToSqlInt64(transfer.FromAccountID),
+		Since:     time.Now().Add(-rule.Window),
+		RoundTo:   roundTo,
+	})
+	if err != nil {
+		return "", err
+	}
+	for _, amount := range transfer.Earlier {
+		if amount%roundTo == 0 {
+			count++
+		}
+	}
+	// this transfer is one more
+	if count+1 < rule.Count {
+		return "", nil
+	}
+	return fmt.Sprintf("%d transfers of multiples of %s within %v", count+1,
+		util.NewMoney(roundTo, transfer.Currency), rule.Window), nil
+}
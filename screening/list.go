@@ -0,0 +1,178 @@
+package screening
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var ErrInvalidList = errors.New("invalid sanctions list")
+
+// csvNull is how the OFAC CSV files write empty fields
+const csvNull = "-0-"
+
+// Entry is a party of the sanctions list, names are written like the list does, e.g. "LAST, First"
+type Entry struct {
+	ID       string
+	Name     string
+	Type     string
+	Programs []string
+	Aliases  []string
+
+	// names are the name and the aliases normalized for matching
+	names []name
+}
+
+// List is a sanctions list, vessels and aircraft are left out as they can't be users
+type List struct {
+	Entries []Entry
+}
+
+// LoadList reads a list in the OFAC SDN formats, sdn.xml when the file has the .xml extension
+// and sdn.csv otherwise
+func LoadList(path string) (*List, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read sanctions list: %w", err)
+	}
+	defer file.Close()
+
+	if strings.EqualFold(filepath.Ext(path), ".xml") {
+		return ParseXML(file)
+	}
+	return ParseCSV(file)
+}
+
+// ParseCSV reads the rows of sdn.csv: the entry number, the name, the type and the programs,
+// the other columns are ignored and a header row is skipped
+// Aliases are only in the XML list, sdn.csv doesn't have them
+func ParseCSV(r io.Reader) (*List, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.TrimLeadingSpace = true
+
+	list := &List{}
+	for line := 1; ; line++ {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidList, err)
+		}
+		if line == 1 && strings.EqualFold(row[0], "ent_num") {
+			continue
+		}
+		// sdn.csv ends with an end of file character on its own line
+		if len(row) < 2 || csvField(row, 1) == "" {
+			continue
+		}
+		if csvField(row, 0) == "" {
+			return nil, fmt.Errorf("%w: line %d: missing entry number", ErrInvalidList, line)
+		}
+
+		list.add(Entry{
+			ID:       csvField(row, 0),
+			Name:     csvField(row, 1),
+			Type:     csvField(row, 2),
+			Programs: parsePrograms(csvField(row, 3)),
+		})
+	}
+	return list, nil
+}
+
+func csvField(row []string, i int) string {
+	if i >= len(row) {
+		return ""
+	}
+	field := strings.TrimSpace(row[i])
+	if field == csvNull {
+		return ""
+	}
+	return field
+}
+
+// parsePrograms splits the programs column, written like "SDGT] [IRGC"
+func parsePrograms(programs string) []string {
+	return strings.FieldsFunc(programs, func(r rune) bool {
+		return r == '[' || r == ']' || r == ' ' || r == ';'
+	})
+}
+
+type xmlName struct {
+	FirstName string `xml:"firstName"`
+	LastName  string `xml:"lastName"`
+}
+
+func (n xmlName) String() string {
+	first, last := strings.TrimSpace(n.FirstName), strings.TrimSpace(n.LastName)
+	if first == "" {
+		return last
+	}
+	if last == "" {
+		return first
+	}
+	return last + ", " + first
+}
+
+type xmlEntry struct {
+	UID string `xml:"uid"`
+	xmlName
+	Type     string    `xml:"sdnType"`
+	Programs []string  `xml:"programList>program"`
+	Akas     []xmlName `xml:"akaList>aka"`
+}
+
+type xmlList struct {
+	Entries []xmlEntry `xml:"sdnEntry"`
+}
+
+// ParseXML reads sdn.xml, with the aliases of the entries
+func ParseXML(r io.Reader) (*List, error) {
+	var document xmlList
+	if err := xml.NewDecoder(r).Decode(&document); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidList, err)
+	}
+
+	list := &List{}
+	for i, xmlEntry := range document.Entries {
+		entry := Entry{
+			ID:       strings.TrimSpace(xmlEntry.UID),
+			Name:     xmlEntry.xmlName.String(),
+			Type:     strings.TrimSpace(xmlEntry.Type),
+			Programs: xmlEntry.Programs,
+		}
+		if entry.ID == "" || entry.Name == "" {
+			return nil, fmt.Errorf("%w: entry %d: missing uid or name", ErrInvalidList, i)
+		}
+		for _, aka := range xmlEntry.Akas {
+			if alias := aka.String(); alias != "" {
+				entry.Aliases = append(entry.Aliases, alias)
+			}
+		}
+		list.add(entry)
+	}
+	return list, nil
+}
+
+func (list *List) add(entry Entry) {
+	switch strings.ToLower(entry.Type) {
+	case "vessel", "aircraft":
+		return
+	}
+
+	for _, s := range append([]string{entry.Name}, entry.Aliases...) {
+		if n := newName(s); len(n.tokens) > 0 {
+			entry.names = append(entry.names, n)
+		}
+	}
+	if len(entry.names) > 0 {
+		list.Entries = append(list.Entries, entry)
+	}
+}
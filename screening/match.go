@@ -0,0 +1,116 @@
+package screening
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// name is a name normalized for matching: lowercase tokens without accents or punctuation, sorted so that
+// "LAST, First" and "First Last" are the same name
+type name struct {
+	// text is the name as written
+	text   string
+	tokens []string
+	joined string
+}
+
+func newName(s string) name {
+	var builder strings.Builder
+	for _, r := range norm.NFKD.String(s) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			// the accents NFKD split from their letters
+		case r == '\'' || r == '’':
+			// O'Brien and OBrien are the same name
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			builder.WriteRune(unicode.ToLower(r))
+		default:
+			builder.WriteRune(' ')
+		}
+	}
+
+	tokens := strings.Fields(builder.String())
+	sort.Strings(tokens)
+	return name{text: s, tokens: tokens, joined: strings.Join(tokens, " ")}
+}
+
+// similarity scores two names from 0 to 1, the best of the whole names compared and their tokens compared
+// The token score matches a name with a middle name left out or misspelled, it only counts for names
+// of two tokens or more, a single token would match every name sharing it
+func similarity(a, b name) float64 {
+	score := jaroWinkler(a.joined, b.joined)
+	if len(a.tokens) < 2 || len(b.tokens) < 2 {
+		return score
+	}
+
+	shorter, longer := a.tokens, b.tokens
+	if len(shorter) > len(longer) {
+		shorter, longer = longer, shorter
+	}
+	var total float64
+	for _, token := range shorter {
+		var best float64
+		for _, other := range longer {
+			best = max(best, jaroWinkler(token, other))
+		}
+		total += best
+	}
+	return max(score, total/float64(len(shorter)))
+}
+
+// jaroWinkler is the Jaro similarity of two strings boosted by the length of their common prefix,
+// it is forgiving of the typos and transliterations of names
+func jaroWinkler(a, b string) float64 {
+	s1, s2 := []rune(a), []rune(b)
+	if len(s1) == 0 || len(s2) == 0 {
+		if len(s1) == len(s2) {
+			return 1
+		}
+		return 0
+	}
+
+	window := max(len(s1), len(s2))/2 - 1
+	window = max(window, 0)
+	matched1 := make([]bool, len(s1))
+	matched2 := make([]bool, len(s2))
+	var matches int
+	for i := range s1 {
+		for j := max(0, i-window); j < min(len(s2), i+window+1); j++ {
+			if !matched2[j] && s1[i] == s2[j] {
+				matched1[i], matched2[j] = true, true
+				matches++
+				break
+			}
+		}
+	}
+	if matches == 0 {
+		return 0
+	}
+
+	var transpositions int
+	j := 0
+	for i := range s1 {
+		if !matched1[i] {
+			continue
+		}
+		for !matched2[j] {
+			j++
+		}
+		if s1[i] != s2[j] {
+			transpositions++
+		}
+		j++
+	}
+
+	m := float64(matches)
+	jaro := (m/float64(len(s1)) + m/float64(len(s2)) + (m-float64(transpositions)/2)/m) / 3
+
+	var prefix int
+	for prefix < min(4, len(s1), len(s2)) && s1[prefix] == s2[prefix] {
+		prefix++
+	}
+	return jaro + float64(prefix)*0.1*(1-jaro)
+}
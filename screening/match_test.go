@@ -0,0 +1,47 @@
+package screening
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewName(t *testing.T) {
+	testCases := []struct {
+		name   string
+		tokens []string
+	}{
+		{name: "AL-ZAWAHIRI, Ayman", tokens: []string{"al", "ayman", "zawahiri"}},
+		{name: "Ayman al Zawahiri", tokens: []string{"al", "ayman", "zawahiri"}},
+		{name: "José  Müller-Ñúñez", tokens: []string{"jose", "muller", "nunez"}},
+		{name: "O'Brien, Seán", tokens: []string{"obrien", "sean"}},
+		{name: " ,.- ", tokens: []string{}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.tokens, newName(tc.name).tokens)
+		})
+	}
+}
+
+func TestJaroWinkler(t *testing.T) {
+	require.Equal(t, 1.0, jaroWinkler("smith", "smith"))
+	require.Equal(t, 0.0, jaroWinkler("abc", "xyz"))
+	require.Equal(t, 0.0, jaroWinkler("", "xyz"))
+	require.InDelta(t, 0.961, jaroWinkler("martha", "marhta"), 0.001)
+	require.InDelta(t, 0.840, jaroWinkler("dwayne", "duane"), 0.001)
+	require.Equal(t, jaroWinkler("dixon", "dicksonx"), jaroWinkler("dicksonx", "dixon"))
+}
+
+func TestSimilarity(t *testing.T) {
+	entry := newName("DOE SANCTIONED, Johnathan")
+
+	require.Equal(t, 1.0, similarity(newName("Johnathan Doe Sanctioned"), entry))
+	// misspelled and with punctuation
+	require.Greater(t, similarity(newName("Jonathan Doe-Sanctioned"), entry), 0.9)
+	// a name left out, the tokens of the shorter name are compared
+	require.Greater(t, similarity(newName("Johnathan Sanctioned"), entry), 0.9)
+	require.Less(t, similarity(newName("Jane Roe"), entry), 0.9)
+	// a single token only compares as a whole name
+	require.Less(t, similarity(newName("Johnathan"), entry), 0.9)
+}
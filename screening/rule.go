@@ -0,0 +1,123 @@
+package screening
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	db "github.com/go_backend_misc/db/sqlc"
+	"github.com/go_backend_misc/risk"
+)
+
+// Rule is the risk rule screening the owners of the sending and the receiving account of transfers,
+// the entries cleared as false positives for an owner don't count
+// Entries the owner has no match for yet, when the list changed or the owner signed up before it,
+// are recorded as a pending match so compliance can clear or confirm them
+// The store must decrypt the names of users, like db.SQLStore does
+type Rule struct {
+	screener *Screener
+}
+
+func NewRule(screener *Screener) *Rule {
+	return &Rule{screener: screener}
+}
+
+func (rule *Rule) Name() string {
+	return "sanctions"
+}
+
+func (rule *Rule) Check(ctx context.Context, store db.Querier, transfer *risk.Transfer) (string, error) {
+	toAccount, err := store.GetAccount(ctx, transfer.ToAccountID)
+	if err != nil {
+		return "", err
+	}
+
+	var reasons []string
+	reason, err := rule.screenUser(ctx, store, "counterparty", toAccount.Owner)
+	if err != nil {
+		return "", err
+	}
+	if reason != "" {
+		reasons = append(reasons, reason)
+	}
+	if transfer.Owner != toAccount.Owner {
+		reason, err = rule.screenUser(ctx, store, "sender", transfer.Owner)
+		if err != nil {
+			return "", err
+		}
+		if reason != "" {
+			reasons = append(reasons, reason)
+		}
+	}
+	return strings.Join(reasons, "; "), nil
+}
+
+// screenUser screens the name of a party to the transfer, party is how the reason calls the user
+func (rule *Rule) screenUser(ctx context.Context, store db.Querier, party string, username string) (string, error) {
+	user, err := store.GetUserByUsername(ctx, username)
+	if err != nil {
+		return "", err
+	}
+	matches, err := RecordMatches(ctx, store, &user, rule.screener.Screen(user.FullName))
+	if err != nil || len(matches) == 0 {
+		return "", err
+	}
+
+	descriptions := make([]string, len(matches))
+	for i, match := range matches {
+		descriptions[i] = match.String()
+	}
+	return fmt.Sprintf("%s %s matches %s", party, user.Username, strings.Join(descriptions, "; ")), nil
+}
+
+// RecordMatches records the matches of a user for review, leaving out the entries the user already
+// has a match for under the same name, and returns the matches that aren't cleared as false positives
+// An entry is cleared when its latest match for the name is, a clearance doesn't carry over to another name
+func RecordMatches(ctx context.Context, store db.Querier, user *db.User, matches []Match) ([]Match, error) {
+	if len(matches) == 0 {
+		return nil, nil
+	}
+
+	entries, err := store.ListScreeningEntries(ctx, db.ListScreeningEntriesParams{
+		Username: user.Username,
+		FullName: user.FullName,
+	})
+	if err != nil {
+		return nil, err
+	}
+	isCleared := make(map[string]bool, len(entries))
+	isKnown := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		isKnown[entry.EntryID] = true
+		if entry.Status == db.ScreeningMatchCleared {
+			isCleared[entry.EntryID] = true
+		}
+	}
+
+	var uncleared []Match
+	arg := db.CreateScreeningMatchParams{Username: user.Username, FullName: user.FullName}
+	for _, match := range matches {
+		if !isCleared[match.Entry.ID] {
+			uncleared = append(uncleared, match)
+		}
+		if !isKnown[match.Entry.ID] {
+			// the matches are sorted, the first one has the best score
+			if len(arg.EntryIds) == 0 {
+				arg.Score = match.Score
+			}
+			arg.EntryIds = append(arg.EntryIds, match.Entry.ID)
+			arg.Matches = append(arg.Matches, match.String())
+		}
+	}
+	if len(arg.EntryIds) == 0 {
+		return uncleared, nil
+	}
+
+	screeningMatch, err := store.CreateScreeningMatch(ctx, arg)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("security: screening match %d of user %v: %v", screeningMatch.ID, user.Username, arg.Matches)
+	return uncleared, nil
+}
@@ -0,0 +1,155 @@
+package screening
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultThreshold is the match threshold when none is configured
+const DefaultThreshold = 0.9
+
+// Match is a list entry a name matched, Name is the name or alias of the entry it matched
+type Match struct {
+	Entry Entry
+	Name  string
+	Score float64
+}
+
+// String describes the match for the review queue, e.g. "36 AL-ZAWAHIRI, Ayman [SDGT] 0.95"
+func (match Match) String() string {
+	description := fmt.Sprintf("%s %s", match.Entry.ID, match.Name)
+	if len(match.Entry.Programs) > 0 {
+		description += fmt.Sprintf(" [%s]", strings.Join(match.Entry.Programs, ", "))
+	}
+	return fmt.Sprintf("%s %.2f", description, match.Score)
+}
+
+// Screener matches names against a sanctions list file, the list is replaced when the file changes
+// and is safe to use while it's reloaded
+// A nil Screener matches no name
+type Screener struct {
+	path      string
+	threshold float64
+
+	mutex   sync.RWMutex
+	list    *List
+	modTime time.Time
+}
+
+// NewScreener loads the list at path, an empty path means no screening
+// Names match entries from the threshold, between 0 and 1, 0 is DefaultThreshold
+func NewScreener(path string, threshold float64) (*Screener, error) {
+	if path == "" {
+		return nil, nil
+	}
+	if threshold == 0 {
+		threshold = DefaultThreshold
+	}
+	if threshold < 0 || threshold > 1 {
+		return nil, fmt.Errorf("match threshold must be between 0 and 1")
+	}
+
+	screener := &Screener{path: path, threshold: threshold}
+	if _, err := screener.Reload(); err != nil {
+		return nil, err
+	}
+	return screener, nil
+}
+
+// Reload loads the list again when the file was modified since it was loaded, it tells whether it did
+// The screener keeps the list it has when the file can't be read or parsed
+func (screener *Screener) Reload() (bool, error) {
+	info, err := os.Stat(screener.path)
+	if err != nil {
+		return false, fmt.Errorf("cannot read sanctions list: %w", err)
+	}
+
+	screener.mutex.RLock()
+	unchanged := screener.list != nil && info.ModTime().Equal(screener.modTime)
+	screener.mutex.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	list, err := LoadList(screener.path)
+	if err != nil {
+		return false, err
+	}
+
+	screener.mutex.Lock()
+	screener.list = list
+	screener.modTime = info.ModTime()
+	screener.mutex.Unlock()
+	return true, nil
+}
+
+// Run checks the list file for changes every interval until the context is canceled
+func (screener *Screener) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		reloaded, err := screener.Reload()
+		if err != nil {
+			log.Printf("cannot reload sanctions list: %v", err)
+			continue
+		}
+		if reloaded {
+			log.Printf("sanctions list reloaded: %d entries", screener.Len())
+		}
+	}
+}
+
+// Len is the number of entries of the list
+func (screener *Screener) Len() int {
+	if screener == nil {
+		return 0
+	}
+	screener.mutex.RLock()
+	defer screener.mutex.RUnlock()
+	return len(screener.list.Entries)
+}
+
+// Screen returns the entries the name matches, best match first
+func (screener *Screener) Screen(fullName string) []Match {
+	if screener == nil {
+		return nil
+	}
+	screened := newName(fullName)
+	if len(screened.tokens) == 0 {
+		return nil
+	}
+
+	screener.mutex.RLock()
+	list := screener.list
+	screener.mutex.RUnlock()
+
+	var matches []Match
+	for _, entry := range list.Entries {
+		best := Match{Entry: entry}
+		for _, entryName := range entry.names {
+			if score := similarity(screened, entryName); score > best.Score {
+				best.Score = score
+				best.Name = entryName.text
+			}
+		}
+		if best.Score >= screener.threshold {
+			matches = append(matches, best)
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Score > matches[j].Score })
+	return matches
+}
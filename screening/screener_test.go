@@ -0,0 +1,205 @@
+package screening
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	mockdb "github.com/go_backend_misc/db/mock"
+	db "github.com/go_backend_misc/db/sqlc"
+	"github.com/go_backend_misc/risk"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+const testXMLList = `<?xml version="1.0" standalone="yes"?>
+<sdnList xmlns="http://tempuri.org/sdnList.xsd">
+  <sdnEntry>
+    <uid>900010</uid>
+    <firstName>Maria</firstName>
+    <lastName>EXAMPLE LISTED</lastName>
+    <sdnType>Individual</sdnType>
+    <programList><program>SDGT</program><program>IRGC</program></programList>
+    <akaList>
+      <aka><uid>1</uid><type>a.k.a.</type><firstName>Mariam</firstName><lastName>LISTADA</lastName></aka>
+    </akaList>
+  </sdnEntry>
+  <sdnEntry>
+    <uid>900011</uid>
+    <lastName>EXAMPLE SHIP</lastName>
+    <sdnType>Vessel</sdnType>
+  </sdnEntry>
+</sdnList>
+`
+
+func writeList(t *testing.T, path string, data string) {
+	require.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+}
+
+func TestLoadList(t *testing.T) {
+	list, err := LoadList("../sanctions.csv")
+	require.NoError(t, err)
+	// the vessel is left out
+	require.Len(t, list.Entries, 2)
+	require.Equal(t, "900001", list.Entries[0].ID)
+	require.Equal(t, "DOE SANCTIONED, Johnathan", list.Entries[0].Name)
+	require.Equal(t, []string{"IRAN", "IFSR"}, list.Entries[1].Programs)
+	require.Empty(t, list.Entries[1].Type)
+
+	path := filepath.Join(t.TempDir(), "sdn.xml")
+	writeList(t, path, testXMLList)
+	list, err = LoadList(path)
+	require.NoError(t, err)
+	require.Len(t, list.Entries, 1)
+	require.Equal(t, "EXAMPLE LISTED, Maria", list.Entries[0].Name)
+	require.Equal(t, []string{"LISTADA, Mariam"}, list.Entries[0].Aliases)
+	require.Equal(t, []string{"SDGT", "IRGC"}, list.Entries[0].Programs)
+
+	_, err = ParseCSV(strings.NewReader(`,"NO NUMBER",individual`))
+	require.ErrorIs(t, err, ErrInvalidList)
+	_, err = ParseXML(strings.NewReader("<sdnList><sdnEntry><uid>1</uid></sdnEntry></sdnList>"))
+	require.ErrorIs(t, err, ErrInvalidList)
+	_, err = ParseXML(strings.NewReader("<sdnList>"))
+	require.ErrorIs(t, err, ErrInvalidList)
+	_, err = LoadList(filepath.Join(t.TempDir(), "missing.csv"))
+	require.Error(t, err)
+}
+
+func TestScreen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sdn.xml")
+	writeList(t, path, testXMLList)
+	screener, err := NewScreener(path, 0)
+	require.NoError(t, err)
+	require.Equal(t, DefaultThreshold, screener.threshold)
+
+	matches := screener.Screen("Mariam Listada")
+	require.Len(t, matches, 1)
+	require.Equal(t, "LISTADA, Mariam", matches[0].Name)
+	require.Equal(t, 1.0, matches[0].Score)
+	require.Equal(t, "900010 LISTADA, Mariam [SDGT, IRGC] 1.00", matches[0].String())
+
+	require.Empty(t, screener.Screen("Jane Roe"))
+	require.Empty(t, screener.Screen(""))
+
+	var nilScreener *Screener
+	require.Empty(t, nilScreener.Screen("Mariam Listada"))
+
+	screener, err = NewScreener("", 0.9)
+	require.NoError(t, err)
+	require.Nil(t, screener)
+	_, err = NewScreener(path, 1.5)
+	require.Error(t, err)
+}
+
+func TestReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sdn.csv")
+	writeList(t, path, `900001,"DOE SANCTIONED, Johnathan",individual,SDGT`+"\n")
+	screener, err := NewScreener(path, 0.9)
+	require.NoError(t, err)
+	require.Equal(t, 1, screener.Len())
+
+	reloaded, err := screener.Reload()
+	require.NoError(t, err)
+	require.False(t, reloaded)
+
+	writeList(t, path, `900001,"DOE SANCTIONED, Johnathan",individual,SDGT`+"\n"+`900002,"ROE, Jane",individual,SDGT`+"\n")
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(path, later, later))
+	reloaded, err = screener.Reload()
+	require.NoError(t, err)
+	require.True(t, reloaded)
+	require.Len(t, screener.Screen("Jane Roe"), 1)
+
+	// an invalid list keeps the one loaded
+	writeList(t, path, `,"NO NUMBER",individual`+"\n")
+	evenLater := later.Add(time.Minute)
+	require.NoError(t, os.Chtimes(path, evenLater, evenLater))
+	_, err = screener.Reload()
+	require.ErrorIs(t, err, ErrInvalidList)
+	require.Equal(t, 2, screener.Len())
+}
+
+func TestRule(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sdn.xml")
+	writeList(t, path, testXMLList)
+	screener, err := NewScreener(path, 0.9)
+	require.NoError(t, err)
+	rule := NewRule(screener)
+	transfer := &risk.Transfer{Owner: "payer", FromAccountID: 1, ToAccountID: 2, Amount: 100, Currency: "USD"}
+
+	listed := "900010 EXAMPLE LISTED, Maria [SDGT, IRGC] 1.00"
+	reason := "counterparty receiver matches " + listed
+	testCases := []struct {
+		name         string
+		receiverName string
+		senderName   string
+		entries      []db.ListScreeningEntriesRow
+		// recordedFor is the user a new match is recorded for
+		recordedFor string
+		reason      string
+	}{
+		{name: "Match recorded for review", receiverName: "Maria Example-Listed", recordedFor: "receiver", reason: reason},
+		{
+			name:         "Match pending review",
+			receiverName: "Maria Example-Listed",
+			entries:      []db.ListScreeningEntriesRow{{EntryID: "900010", Status: db.ScreeningMatchPending}},
+			reason:       reason,
+		},
+		{
+			name:         "Match confirmed",
+			receiverName: "Maria Example-Listed",
+			entries:      []db.ListScreeningEntriesRow{{EntryID: "900010", Status: db.ScreeningMatchConfirmed}},
+			reason:       reason,
+		},
+		{
+			name:         "Cleared",
+			receiverName: "Maria Example Listed",
+			entries:      []db.ListScreeningEntriesRow{{EntryID: "900010", Status: db.ScreeningMatchCleared}},
+		},
+		{
+			name:         "Sender matches",
+			receiverName: "Jane Roe",
+			senderName:   "Maria Example Listed",
+			recordedFor:  "payer",
+			reason:       "sender payer matches " + listed,
+		},
+		{name: "No match", receiverName: "Jane Roe"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().GetAccount(gomock.Any(), int64(2)).Times(1).Return(db.Account{ID: 2, Owner: "receiver"}, nil)
+			store.EXPECT().
+				GetUserByUsername(gomock.Any(), "receiver").
+				Times(1).
+				Return(db.User{Username: "receiver", FullName: tc.receiverName}, nil)
+			store.EXPECT().
+				GetUserByUsername(gomock.Any(), "payer").
+				Times(1).
+				Return(db.User{Username: "payer", FullName: tc.senderName}, nil)
+			store.EXPECT().ListScreeningEntries(gomock.Any(), gomock.Any()).AnyTimes().Return(tc.entries, nil)
+			if tc.recordedFor != "" {
+				store.EXPECT().
+					CreateScreeningMatch(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.CreateScreeningMatchParams) (db.ScreeningMatch, error) {
+						require.Equal(t, tc.recordedFor, arg.Username)
+						require.Equal(t, []string{"900010"}, arg.EntryIds)
+						return db.ScreeningMatch{ID: 1, Username: arg.Username, Status: db.ScreeningMatchPending}, nil
+					})
+			} else {
+				store.EXPECT().CreateScreeningMatch(gomock.Any(), gomock.Any()).Times(0)
+			}
+
+			reason, err := rule.Check(context.Background(), store, transfer)
+			require.NoError(t, err)
+			require.Equal(t, tc.reason, reason)
+		})
+	}
+}
@@ -15,6 +15,8 @@ type Payload struct {
 	Username  string    `json:"username"`
 	IssuedAt  time.Time `json:"issued_at"`
 	ExpiredAt time.Time `json:"expired_at"`
+	// Scopes restricts what the payload grants; it's empty for regular access tokens
+	Scopes []string `json:"scopes,omitempty"`
 }
 
 func NewPayload(username string, duration time.Duration) (*Payload, error) {
@@ -40,3 +42,17 @@ func (payload Payload) Valid() error {
 
 	return nil
 }
+
+// HasScope reports whether the payload grants the given scope
+// Payloads without scopes (regular access tokens) are not restricted
+func (payload Payload) HasScope(scope string) bool {
+	if len(payload.Scopes) == 0 {
+		return true
+	}
+	for _, grantedScope := range payload.Scopes {
+		if grantedScope == scope {
+			return true
+		}
+	}
+	return false
+}
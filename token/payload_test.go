@@ -0,0 +1,19 @@
+package token
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go_backend_misc/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPayloadHasScope(t *testing.T) {
+	payload, err := NewPayload(util.RandomOwner(), time.Minute)
+	require.NoError(t, err)
+	require.True(t, payload.HasScope("accounts:read"))
+
+	payload.Scopes = []string{"accounts:read"}
+	require.True(t, payload.HasScope("accounts:read"))
+	require.False(t, payload.HasScope("transfers:write"))
+}
@@ -12,6 +12,80 @@ type Config struct {
 	ServerAddress       string        `mapstructure:"SERVER_ADDRESS"`
 	TokenSymmetricKey   string        `mapstructure:"TOKEN_SYMMETRIC_KEY"`
 	AccessTokenDuration time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
+	// MFAEncryptionKey encrypts TOTP secrets at rest, it must be 32 characters long
+	MFAEncryptionKey     string        `mapstructure:"MFA_ENCRYPTION_KEY"`
+	MFAChallengeDuration time.Duration `mapstructure:"MFA_CHALLENGE_DURATION"`
+	// MFAStepUpAmount is the transfer amount above which a TOTP code is required, 0 disables it
+	MFAStepUpAmount int64 `mapstructure:"MFA_STEP_UP_AMOUNT"`
+	// failed logins are delayed exponentially from LoginBackoffBase, and locked out
+	// for LoginLockoutDuration after LoginMaxFailedAttempts failures within LoginAttemptWindow
+	LoginMaxFailedAttempts int32         `mapstructure:"LOGIN_MAX_FAILED_ATTEMPTS"`
+	LoginBackoffBase       time.Duration `mapstructure:"LOGIN_BACKOFF_BASE"`
+	LoginLockoutDuration   time.Duration `mapstructure:"LOGIN_LOCKOUT_DURATION"`
+	LoginAttemptWindow     time.Duration `mapstructure:"LOGIN_ATTEMPT_WINDOW"`
+	EmailSenderAddress     string        `mapstructure:"EMAIL_SENDER_ADDRESS"`
+	// EmailOutboxDir is where the local file sender writes emails
+	EmailOutboxDir             string        `mapstructure:"EMAIL_OUTBOX_DIR"`
+	PasswordResetTokenDuration time.Duration `mapstructure:"PASSWORD_RESET_TOKEN_DURATION"`
+	VerifyEmailTokenDuration   time.Duration `mapstructure:"VERIFY_EMAIL_TOKEN_DURATION"`
+	// users with an unverified email can't open accounts or send transfers when these are set
+	RequireVerifiedEmailForAccounts  bool `mapstructure:"REQUIRE_VERIFIED_EMAIL_FOR_ACCOUNTS"`
+	RequireVerifiedEmailForTransfers bool `mapstructure:"REQUIRE_VERIFIED_EMAIL_FOR_TRANSFERS"`
+	// PasswordHashAlgorithm is "argon2id" (default) or "bcrypt", zero costs use the defaults
+	PasswordHashAlgorithm string `mapstructure:"PASSWORD_HASH_ALGORITHM"`
+	Argon2Memory          uint32 `mapstructure:"ARGON2_MEMORY_KB"`
+	Argon2Iterations      uint32 `mapstructure:"ARGON2_ITERATIONS"`
+	Argon2Parallelism     uint8  `mapstructure:"ARGON2_PARALLELISM"`
+	BcryptCost            int    `mapstructure:"BCRYPT_COST"`
+	PasswordMinLength     int    `mapstructure:"PASSWORD_MIN_LENGTH"`
+	PasswordMaxLength     int    `mapstructure:"PASSWORD_MAX_LENGTH"`
+	PasswordRequireUpper  bool   `mapstructure:"PASSWORD_REQUIRE_UPPER"`
+	PasswordRequireLower  bool   `mapstructure:"PASSWORD_REQUIRE_LOWER"`
+	PasswordRequireDigit  bool   `mapstructure:"PASSWORD_REQUIRE_DIGIT"`
+	PasswordRequireSymbol bool   `mapstructure:"PASSWORD_REQUIRE_SYMBOL"`
+	// BreachedPasswordsFile lists SHA-1 hashes of breached passwords, empty disables the check
+	BreachedPasswordsFile string `mapstructure:"BREACHED_PASSWORDS_FILE"`
+	// PIIMasterKey wraps the keys that encrypt user emails and names, PIIMasterKeyFile takes precedence
+	// Both keys must be 32 characters long
+	PIIMasterKey     string `mapstructure:"PII_MASTER_KEY"`
+	PIIMasterKeyFile string `mapstructure:"PII_MASTER_KEY_FILE"`
+	PIIBlindIndexKey string `mapstructure:"PII_BLIND_INDEX_KEY"`
+	// RateLimitStore is "memory" for a single instance or "postgres" to share limits between instances
+	// Limits are written as "<requests>/<period>", e.g. "10/1m", and "0" disables them
+	RateLimitStore         string `mapstructure:"RATE_LIMIT_STORE"`
+	RateLimitPublic        string `mapstructure:"RATE_LIMIT_PUBLIC"`
+	RateLimitLogin         string `mapstructure:"RATE_LIMIT_LOGIN"`
+	RateLimitAuthenticated string `mapstructure:"RATE_LIMIT_AUTHENTICATED"`
+	RateLimitTransfers     string `mapstructure:"RATE_LIMIT_TRANSFERS"`
+	// ScheduledTransferPollInterval is how often the executor looks for due transfers, 0 disables it
+	ScheduledTransferPollInterval time.Duration `mapstructure:"SCHEDULED_TRANSFER_POLL_INTERVAL"`
+	ScheduledTransferMaxAttempts  int32         `mapstructure:"SCHEDULED_TRANSFER_MAX_ATTEMPTS"`
+	// ScheduledTransferRetryBackoff doubles after every failed attempt
+	ScheduledTransferRetryBackoff time.Duration `mapstructure:"SCHEDULED_TRANSFER_RETRY_BACKOFF"`
+	// StandingOrderPollInterval is how often the executor looks for due standing orders, 0 disables it
+	StandingOrderPollInterval time.Duration `mapstructure:"STANDING_ORDER_POLL_INTERVAL"`
+	// HoldDefaultExpiry applies to holds created without an expires_at, none can last longer than HoldMaxExpiry
+	HoldDefaultExpiry time.Duration `mapstructure:"HOLD_DEFAULT_EXPIRY"`
+	HoldMaxExpiry     time.Duration `mapstructure:"HOLD_MAX_EXPIRY"`
+	// HoldExpiryPollInterval is how often expired holds are released, 0 disables it
+	HoldExpiryPollInterval time.Duration `mapstructure:"HOLD_EXPIRY_POLL_INTERVAL"`
+	// BalanceSnapshotPollInterval is how often the snapshot job looks for days to snapshot, 0 disables it
+	BalanceSnapshotPollInterval time.Duration `mapstructure:"BALANCE_SNAPSHOT_POLL_INTERVAL"`
+	// BatchTransferMaxItems caps the items of a batch transfer, whether sent as JSON or CSV
+	BatchTransferMaxItems int `mapstructure:"BATCH_TRANSFER_MAX_ITEMS"`
+	// CurrencyRefreshInterval is how often the currency registry is reloaded from the currencies table, 0 loads it once
+	CurrencyRefreshInterval time.Duration `mapstructure:"CURRENCY_REFRESH_INTERVAL"`
+	// FeeScheduleFile is the YAML fee schedule of transfers, empty makes transfers free
+	FeeScheduleFile string `mapstructure:"FEE_SCHEDULE_FILE"`
+	// RiskRulesFile is the YAML risk rules transfers are screened with, empty allows every transfer
+	RiskRulesFile string `mapstructure:"RISK_RULES_FILE"`
+	// SanctionsListFile is the OFAC-style list, sdn.csv or sdn.xml, names are screened with at signup
+	// and when they receive transfers, empty disables screening
+	SanctionsListFile string `mapstructure:"SANCTIONS_LIST_FILE"`
+	// SanctionsMatchThreshold is the similarity between 0 and 1 from which a name matches a list entry, 0 is 0.9
+	SanctionsMatchThreshold float64 `mapstructure:"SANCTIONS_MATCH_THRESHOLD"`
+	// SanctionsReloadInterval is how often the list file is checked for changes, 0 loads it once
+	SanctionsReloadInterval time.Duration `mapstructure:"SANCTIONS_RELOAD_INTERVAL"`
 }
 
 func LoadConfig(path string) (config Config, err error) {
@@ -1,15 +1,103 @@
 package util
 
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// The currencies of the initial registry, the database can add more, see SetCurrencies
 const (
 	USD = "USD"
 	EUR = "EUR"
 	CAD = "CAD"
+	JPY = "JPY"
+	KWD = "KWD"
+)
+
+var (
+	ErrAmountBelowMinimum = errors.New("amount is below the minimum transfer of the currency")
+	ErrAmountAboveMaximum = errors.New("amount is above the maximum transfer of the currency")
 )
 
-func IsSupportedCurrency(currency string) bool {
-	switch currency {
-	case USD, EUR, CAD:
-		return true
+// Currency is an ISO 4217 currency, amounts are stored in its minor units
+type Currency struct {
+	Code string `json:"code"`
+	// Exponent is the number of decimals of the minor unit: 2 for cents, 0 for JPY, 3 for KWD
+	Exponent int32  `json:"exponent"`
+	Symbol   string `json:"symbol"`
+	// Enabled currencies can be used for new accounts and transfers
+	Enabled bool `json:"enabled"`
+	// MinTransferAmount and MaxTransferAmount are in minor units, 0 is no limit
+	MinTransferAmount int64 `json:"min_transfer_amount"`
+	MaxTransferAmount int64 `json:"max_transfer_amount"`
+}
+
+// CheckTransferAmount tells whether a transfer of amount minor units is within the limits of the currency
+func (currency Currency) CheckTransferAmount(amount int64) error {
+	if amount < currency.MinTransferAmount {
+		return fmt.Errorf("%w: %s", ErrAmountBelowMinimum, NewMoney(currency.MinTransferAmount, currency.Code))
+	}
+	if currency.MaxTransferAmount > 0 && amount > currency.MaxTransferAmount {
+		return fmt.Errorf("%w: %s", ErrAmountAboveMaximum, NewMoney(currency.MaxTransferAmount, currency.Code))
+	}
+	return nil
+}
+
+// registry holds the currencies, it starts with the built-in ones and is replaced with the ones
+// of the database while the server runs
+var registry = struct {
+	sync.RWMutex
+	currencies map[string]Currency
+}{
+	currencies: map[string]Currency{
+		USD: {Code: USD, Exponent: 2, Symbol: "$", Enabled: true},
+		EUR: {Code: EUR, Exponent: 2, Symbol: "€", Enabled: true},
+		CAD: {Code: CAD, Exponent: 2, Symbol: "CA$", Enabled: true},
+		JPY: {Code: JPY, Exponent: 0, Symbol: "¥", Enabled: true},
+		KWD: {Code: KWD, Exponent: 3, Symbol: "KD", Enabled: true},
+	},
+}
+
+// SetCurrencies replaces the registry, the validators and the money parsing use it right away
+func SetCurrencies(currencies []Currency) {
+	byCode := make(map[string]Currency, len(currencies))
+	for _, currency := range currencies {
+		byCode[currency.Code] = currency
+	}
+
+	registry.Lock()
+	defer registry.Unlock()
+	registry.currencies = byCode
+}
+
+// Currencies lists the enabled currencies by code
+func Currencies() []Currency {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	currencies := make([]Currency, 0, len(registry.currencies))
+	for _, currency := range registry.currencies {
+		if currency.Enabled {
+			currencies = append(currencies, currency)
+		}
 	}
-	return false
+	sort.Slice(currencies, func(i, j int) bool { return currencies[i].Code < currencies[j].Code })
+	return currencies
+}
+
+// LookupCurrency finds a currency by its code, disabled ones too so their amounts can still be shown
+func LookupCurrency(code string) (Currency, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	currency, ok := registry.currencies[code]
+	return currency, ok
+}
+
+// IsSupportedCurrency tells whether new accounts and transfers can use the currency
+func IsSupportedCurrency(code string) bool {
+	currency, ok := LookupCurrency(code)
+	return ok && currency.Enabled
 }
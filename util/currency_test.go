@@ -0,0 +1,48 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCurrencyRegistry(t *testing.T) {
+	previous := Currencies()
+	t.Cleanup(func() { SetCurrencies(previous) })
+
+	SetCurrencies([]Currency{
+		{Code: USD, Exponent: 2, Symbol: "$", Enabled: true},
+		{Code: EUR, Exponent: 2, Symbol: "€", Enabled: false},
+		{Code: "GBP", Exponent: 2, Symbol: "£", Enabled: true},
+	})
+
+	currencies := Currencies()
+	require.Len(t, currencies, 2)
+	require.Equal(t, "GBP", currencies[0].Code)
+	require.Equal(t, USD, currencies[1].Code)
+
+	require.True(t, IsSupportedCurrency("GBP"))
+	require.False(t, IsSupportedCurrency(EUR))
+	require.False(t, IsSupportedCurrency(CAD))
+
+	// amounts in a disabled currency can still be shown
+	_, ok := LookupCurrency(EUR)
+	require.True(t, ok)
+	require.Equal(t, "€1.50", NewMoney(150, EUR).Format())
+
+	money, err := ParseMoney("2.50", "GBP")
+	require.NoError(t, err)
+	require.Equal(t, int64(250), money.Amount)
+}
+
+func TestCheckTransferAmount(t *testing.T) {
+	currency := Currency{Code: USD, Exponent: 2, Enabled: true, MinTransferAmount: 100, MaxTransferAmount: 1000}
+
+	require.NoError(t, currency.CheckTransferAmount(100))
+	require.NoError(t, currency.CheckTransferAmount(1000))
+	require.ErrorIs(t, currency.CheckTransferAmount(99), ErrAmountBelowMinimum)
+	require.ErrorIs(t, currency.CheckTransferAmount(1001), ErrAmountAboveMaximum)
+
+	unlimited := Currency{Code: USD, Exponent: 2, Enabled: true}
+	require.NoError(t, unlimited.CheckTransferAmount(1_000_000_000))
+}
@@ -0,0 +1,30 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	key := []byte(RandomString(32))
+	plaintext := []byte("my_secret_value")
+
+	ciphertext, err := Encrypt(key, plaintext)
+	require.NoError(t, err)
+	require.NotContains(t, ciphertext, string(plaintext))
+
+	otherCiphertext, err := Encrypt(key, plaintext)
+	require.NoError(t, err)
+	require.NotEqual(t, ciphertext, otherCiphertext)
+
+	decrypted, err := Decrypt(key, ciphertext)
+	require.NoError(t, err)
+	require.Equal(t, plaintext, decrypted)
+
+	_, err = Decrypt([]byte(RandomString(32)), ciphertext)
+	require.ErrorIs(t, err, ErrInvalidCiphertext)
+
+	_, err = Encrypt([]byte("short"), plaintext)
+	require.Error(t, err)
+}
@@ -0,0 +1,106 @@
+package util
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	fieldEncryptionKeySize = 32
+	// encryptedFieldPrefix tells encrypted values apart from rows written before encryption was enabled
+	encryptedFieldPrefix = "enc:v1:"
+)
+
+var ErrInvalidEncryptedField = errors.New("invalid encrypted field")
+
+// FieldEncryptor encrypts single column values with envelope encryption:
+// every value gets its own random AES-GCM data key, which is stored next to it wrapped by the master key,
+// so rotating the master key only means rewrapping the data keys
+type FieldEncryptor struct {
+	masterKey     []byte
+	blindIndexKey []byte
+}
+
+// NewFieldEncryptor reads the master key from PII_MASTER_KEY_FILE when set, or from PII_MASTER_KEY otherwise
+func NewFieldEncryptor(config Config) (*FieldEncryptor, error) {
+	masterKey := []byte(config.PIIMasterKey)
+	if config.PIIMasterKeyFile != "" {
+		data, err := os.ReadFile(config.PIIMasterKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read master key file: %w", err)
+		}
+		masterKey = []byte(strings.TrimSpace(string(data)))
+	}
+
+	if len(masterKey) != fieldEncryptionKeySize {
+		return nil, fmt.Errorf("invalid pii master key size: must be %d characters", fieldEncryptionKeySize)
+	}
+	if len(config.PIIBlindIndexKey) != fieldEncryptionKeySize {
+		return nil, fmt.Errorf("invalid pii blind index key size: must be %d characters", fieldEncryptionKeySize)
+	}
+
+	return &FieldEncryptor{
+		masterKey:     masterKey,
+		blindIndexKey: []byte(config.PIIBlindIndexKey),
+	}, nil
+}
+
+// EncryptField returns "enc:v1:<wrapped data key>:<ciphertext>"
+func (encryptor *FieldEncryptor) EncryptField(plaintext string) (string, error) {
+	dataKey := make([]byte, fieldEncryptionKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", fmt.Errorf("Failed to generate data key: %w", err)
+	}
+
+	wrappedKey, err := Encrypt(encryptor.masterKey, dataKey)
+	if err != nil {
+		return "", err
+	}
+	ciphertext, err := Encrypt(dataKey, []byte(plaintext))
+	if err != nil {
+		return "", err
+	}
+
+	return encryptedFieldPrefix + wrappedKey + ":" + ciphertext, nil
+}
+
+// DecryptField opens a value produced by EncryptField
+// Values without the prefix were written before encryption was enabled and are returned as they are
+func (encryptor *FieldEncryptor) DecryptField(value string) (string, error) {
+	if !IsEncryptedField(value) {
+		return value, nil
+	}
+
+	wrappedKey, ciphertext, found := strings.Cut(strings.TrimPrefix(value, encryptedFieldPrefix), ":")
+	if !found {
+		return "", ErrInvalidEncryptedField
+	}
+
+	dataKey, err := Decrypt(encryptor.masterKey, wrappedKey)
+	if err != nil {
+		return "", ErrInvalidEncryptedField
+	}
+	plaintext, err := Decrypt(dataKey, ciphertext)
+	if err != nil {
+		return "", ErrInvalidEncryptedField
+	}
+	return string(plaintext), nil
+}
+
+// BlindIndex is a keyed hash of the value, so equal values can be looked up and kept unique
+// without storing them in plain text
+func (encryptor *FieldEncryptor) BlindIndex(value string) string {
+	mac := hmac.New(sha256.New, encryptor.blindIndexKey)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func IsEncryptedField(value string) bool {
+	return strings.HasPrefix(value, encryptedFieldPrefix)
+}
@@ -0,0 +1,82 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func randomFieldEncryptor(t *testing.T) *FieldEncryptor {
+	encryptor, err := NewFieldEncryptor(Config{
+		PIIMasterKey:     RandomString(32),
+		PIIBlindIndexKey: RandomString(32),
+	})
+	require.NoError(t, err)
+	return encryptor
+}
+
+func TestFieldEncryption(t *testing.T) {
+	encryptor := randomFieldEncryptor(t)
+	email := RandomEmail(RandomOwner())
+
+	encrypted, err := encryptor.EncryptField(email)
+	require.NoError(t, err)
+	require.True(t, IsEncryptedField(encrypted))
+	require.NotContains(t, encrypted, email)
+
+	otherEncrypted, err := encryptor.EncryptField(email)
+	require.NoError(t, err)
+	require.NotEqual(t, encrypted, otherEncrypted)
+
+	decrypted, err := encryptor.DecryptField(encrypted)
+	require.NoError(t, err)
+	require.Equal(t, email, decrypted)
+
+	// values written before encryption pass through
+	decrypted, err = encryptor.DecryptField(email)
+	require.NoError(t, err)
+	require.Equal(t, email, decrypted)
+
+	_, err = randomFieldEncryptor(t).DecryptField(encrypted)
+	require.ErrorIs(t, err, ErrInvalidEncryptedField)
+	_, err = encryptor.DecryptField(encryptedFieldPrefix + "garbage")
+	require.ErrorIs(t, err, ErrInvalidEncryptedField)
+}
+
+func TestBlindIndex(t *testing.T) {
+	encryptor := randomFieldEncryptor(t)
+	email := RandomEmail(RandomOwner())
+
+	index := encryptor.BlindIndex(email)
+	require.Len(t, index, 64)
+	require.Equal(t, index, encryptor.BlindIndex(email))
+	require.NotEqual(t, index, encryptor.BlindIndex(email+"x"))
+	require.NotEqual(t, index, randomFieldEncryptor(t).BlindIndex(email))
+}
+
+func TestNewFieldEncryptor(t *testing.T) {
+	_, err := NewFieldEncryptor(Config{PIIMasterKey: "short", PIIBlindIndexKey: RandomString(32)})
+	require.Error(t, err)
+	_, err = NewFieldEncryptor(Config{PIIMasterKey: RandomString(32), PIIBlindIndexKey: "short"})
+	require.Error(t, err)
+
+	masterKey := RandomString(32)
+	keyFile := filepath.Join(t.TempDir(), "master.key")
+	require.NoError(t, os.WriteFile(keyFile, []byte(masterKey+"\n"), 0600))
+
+	fileEncryptor, err := NewFieldEncryptor(Config{PIIMasterKeyFile: keyFile, PIIBlindIndexKey: RandomString(32)})
+	require.NoError(t, err)
+	encrypted, err := fileEncryptor.EncryptField("value")
+	require.NoError(t, err)
+
+	configEncryptor, err := NewFieldEncryptor(Config{PIIMasterKey: masterKey, PIIBlindIndexKey: RandomString(32)})
+	require.NoError(t, err)
+	decrypted, err := configEncryptor.DecryptField(encrypted)
+	require.NoError(t, err)
+	require.Equal(t, "value", decrypted)
+
+	_, err = NewFieldEncryptor(Config{PIIMasterKeyFile: keyFile + ".missing", PIIBlindIndexKey: RandomString(32)})
+	require.Error(t, err)
+}
@@ -0,0 +1,96 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidAmount       = errors.New("invalid amount")
+	ErrTooManyDecimals     = errors.New("amount has too many decimals for its currency")
+	ErrUnsupportedCurrency = errors.New("unsupported currency")
+)
+
+var decimalPattern = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+
+// Money is an amount in the minor units of its currency, like cents for USD
+// It's stored as the int64 of minor units and sent as a decimal string, "12.34" for 1234 USD
+type Money struct {
+	Amount   int64
+	Currency string
+}
+
+func NewMoney(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+// ParseMoney reads a decimal string like "12.34" in a supported currency,
+// it refuses more decimals than the currency has, even zeros, and amounts that don't fit in int64
+func ParseMoney(amount string, currencyCode string) (Money, error) {
+	currency, ok := LookupCurrency(currencyCode)
+	if !ok {
+		return Money{}, fmt.Errorf("%w: %s", ErrUnsupportedCurrency, currencyCode)
+	}
+	if !decimalPattern.MatchString(amount) {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, amount)
+	}
+
+	whole, fraction, _ := strings.Cut(amount, ".")
+	if len(fraction) > int(currency.Exponent) {
+		return Money{}, fmt.Errorf("%w: %s has %d", ErrTooManyDecimals, currency.Code, currency.Exponent)
+	}
+	fraction += strings.Repeat("0", int(currency.Exponent)-len(fraction))
+
+	minorUnits, err := strconv.ParseInt(whole+fraction, 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("%w: %q is out of range", ErrInvalidAmount, amount)
+	}
+	return Money{Amount: minorUnits, Currency: currency.Code}, nil
+}
+
+// IsPositiveDecimal tells whether s is a decimal string above zero, whatever its currency
+func IsPositiveDecimal(s string) bool {
+	return decimalPattern.MatchString(s) && !strings.HasPrefix(s, "-") && strings.Trim(s, "0.") != ""
+}
+
+// Decimal is the amount with the decimals of its currency, "12.34", "-0.05", "1200" for JPY
+// Amounts of unknown currencies are shown in minor units
+func (m Money) Decimal() string {
+	currency, _ := LookupCurrency(m.Currency)
+	digits := strconv.FormatInt(m.Amount, 10)
+	sign := ""
+	if m.Amount < 0 {
+		sign, digits = "-", digits[1:]
+	}
+	if currency.Exponent == 0 {
+		return sign + digits
+	}
+
+	exponent := int(currency.Exponent)
+	if len(digits) <= exponent {
+		digits = strings.Repeat("0", exponent-len(digits)+1) + digits
+	}
+	point := len(digits) - exponent
+	return sign + digits[:point] + "." + digits[point:]
+}
+
+// String is the decimal amount with its currency code, "12.34 USD"
+func (m Money) String() string {
+	return m.Decimal() + " " + m.Currency
+}
+
+// Format is the decimal amount with the symbol of its currency, "$12.34", "-¥1200"
+func (m Money) Format() string {
+	currency, ok := LookupCurrency(m.Currency)
+	if !ok || currency.Symbol == "" {
+		return m.String()
+	}
+	decimal := m.Decimal()
+	if m.Amount < 0 {
+		return "-" + currency.Symbol + decimal[1:]
+	}
+	return currency.Symbol + decimal
+}
@@ -0,0 +1,86 @@
+package util
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMoney(t *testing.T) {
+	testCases := []struct {
+		name     string
+		amount   string
+		currency string
+		expected int64
+		err      error
+	}{
+		{name: "Cents", amount: "12.34", currency: USD, expected: 1234},
+		{name: "Whole", amount: "12", currency: EUR, expected: 1200},
+		{name: "One decimal", amount: "0.5", currency: CAD, expected: 50},
+		{name: "No minor unit", amount: "1200", currency: JPY, expected: 1200},
+		{name: "Three decimals", amount: "1.005", currency: KWD, expected: 1005},
+		{name: "Negative", amount: "-0.05", currency: USD, expected: -5},
+		{name: "Largest", amount: "92233720368547758.07", currency: USD, expected: math.MaxInt64},
+		{name: "Too many decimals", amount: "12.345", currency: USD, err: ErrTooManyDecimals},
+		{name: "Trailing zero", amount: "12.340", currency: USD, err: ErrTooManyDecimals},
+		{name: "Decimals without minor unit", amount: "1.5", currency: JPY, err: ErrTooManyDecimals},
+		{name: "Out of range", amount: "92233720368547758.08", currency: USD, err: ErrInvalidAmount},
+		{name: "Not a number", amount: "12,34", currency: USD, err: ErrInvalidAmount},
+		{name: "Exponent", amount: "1e3", currency: USD, err: ErrInvalidAmount},
+		{name: "Missing decimals", amount: "12.", currency: USD, err: ErrInvalidAmount},
+		{name: "Unsupported currency", amount: "12", currency: "XXX", err: ErrUnsupportedCurrency},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			money, err := ParseMoney(tc.amount, tc.currency)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, NewMoney(tc.expected, tc.currency), money)
+		})
+	}
+}
+
+func TestMoneyDecimal(t *testing.T) {
+	testCases := []struct {
+		money     Money
+		decimal   string
+		formatted string
+	}{
+		{money: NewMoney(1234, USD), decimal: "12.34", formatted: "$12.34"},
+		{money: NewMoney(5, USD), decimal: "0.05", formatted: "$0.05"},
+		{money: NewMoney(-5, EUR), decimal: "-0.05", formatted: "-€0.05"},
+		{money: NewMoney(0, CAD), decimal: "0.00", formatted: "CA$0.00"},
+		{money: NewMoney(1200, JPY), decimal: "1200", formatted: "¥1200"},
+		{money: NewMoney(1005, KWD), decimal: "1.005", formatted: "KD1.005"},
+		{money: NewMoney(math.MinInt64, USD), decimal: "-92233720368547758.08", formatted: "-$92233720368547758.08"},
+		{money: NewMoney(1234, "XXX"), decimal: "1234", formatted: "1234 XXX"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.money.String(), func(t *testing.T) {
+			require.Equal(t, tc.decimal, tc.money.Decimal())
+			require.Equal(t, tc.formatted, tc.money.Format())
+
+			// the decimal string reads back to the same money
+			if tc.money.Currency != "XXX" && tc.money.Amount != math.MinInt64 {
+				money, err := ParseMoney(tc.decimal, tc.money.Currency)
+				require.NoError(t, err)
+				require.Equal(t, tc.money, money)
+			}
+		})
+	}
+}
+
+func TestIsPositiveDecimal(t *testing.T) {
+	for _, s := range []string{"1", "0.01", "12.340", "1200"} {
+		require.True(t, IsPositiveDecimal(s), s)
+	}
+	for _, s := range []string{"", "0", "0.00", "-1", "1.", ".5", "1e3", "abc"} {
+		require.False(t, IsPositiveDecimal(s), s)
+	}
+}
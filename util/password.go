@@ -1,19 +1,201 @@
 package util
 
 import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"strings"
 
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/bcrypt"
 )
 
+const (
+	PasswordHashArgon2id = "argon2id"
+	PasswordHashBcrypt   = "bcrypt"
+)
+
+// Argon2id defaults follow the OWASP password storage recommendations
+const (
+	DefaultArgon2Memory      uint32 = 19 * 1024
+	DefaultArgon2Iterations  uint32 = 2
+	DefaultArgon2Parallelism uint8  = 1
+	argon2SaltLength                = 16
+	argon2KeyLength                 = 32
+)
+
+var (
+	ErrMismatchedPassword   = errors.New("password does not match")
+	ErrUnknownPasswordHash  = errors.New("unknown password hash format")
+	errInvalidArgon2idHash  = errors.New("invalid argon2id hash")
+	errUnsupportedAlgorithm = errors.New("unsupported password hash algorithm")
+)
+
+var (
+	defaultPasswordHasher = &Argon2idHasher{
+		Memory:      DefaultArgon2Memory,
+		Iterations:  DefaultArgon2Iterations,
+		Parallelism: DefaultArgon2Parallelism,
+	}
+	argon2idHashPrefix = "$" + PasswordHashArgon2id + "$"
+	bcryptHashPrefixes = []string{"$2a$", "$2b$", "$2y$"}
+)
+
+// PasswordHasher hashes new passwords with one algorithm and cost
+// Verification doesn't depend on the hasher: CheckPassword accepts every supported format
+type PasswordHasher interface {
+	HashPassword(password string) (string, error)
+	// NeedsRehash reports whether the hash uses another algorithm or cost than the hasher
+	NeedsRehash(hashedPassword string) bool
+}
+
+// NewPasswordHasher builds the hasher selected in the config, zero values fall back to the defaults
+func NewPasswordHasher(config Config) (PasswordHasher, error) {
+	switch config.PasswordHashAlgorithm {
+	case "", PasswordHashArgon2id:
+		hasher := *defaultPasswordHasher
+		if config.Argon2Memory > 0 {
+			hasher.Memory = config.Argon2Memory
+		}
+		if config.Argon2Iterations > 0 {
+			hasher.Iterations = config.Argon2Iterations
+		}
+		if config.Argon2Parallelism > 0 {
+			hasher.Parallelism = config.Argon2Parallelism
+		}
+		return &hasher, nil
+	case PasswordHashBcrypt:
+		cost := config.BcryptCost
+		if cost == 0 {
+			cost = bcrypt.DefaultCost
+		}
+		if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+			return nil, fmt.Errorf("invalid bcrypt cost %d", cost)
+		}
+		return &BcryptHasher{Cost: cost}, nil
+	}
+	return nil, fmt.Errorf("%w: %v", errUnsupportedAlgorithm, config.PasswordHashAlgorithm)
+}
+
+// HashPassword hashes with the default argon2id parameters
 func HashPassword(password string) (string, error) {
-	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return defaultPasswordHasher.HashPassword(password)
+}
+
+// CheckPassword verifies argon2id and bcrypt hashes
+func CheckPassword(password string, hashedPassword string) error {
+	if strings.HasPrefix(hashedPassword, argon2idHashPrefix) {
+		return checkArgon2id(password, hashedPassword)
+	}
+	if isBcryptHash(hashedPassword) {
+		err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return ErrMismatchedPassword
+		}
+		return err
+	}
+	return ErrUnknownPasswordHash
+}
+
+type Argon2idHasher struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+}
+
+// HashPassword returns the hash in the PHC string format:
+// $argon2id$v=19$m=<memory>,t=<iterations>,p=<parallelism>$<salt>$<key>
+func (hasher *Argon2idHasher) HashPassword(password string) (string, error) {
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("Failed to hash password: %w", err)
+	}
+
+	key := argon2.IDKey([]byte(password), salt, hasher.Iterations, hasher.Memory, hasher.Parallelism, argon2KeyLength)
+	return fmt.Sprintf(
+		"$%s$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		PasswordHashArgon2id, argon2.Version, hasher.Memory, hasher.Iterations, hasher.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func (hasher *Argon2idHasher) NeedsRehash(hashedPassword string) bool {
+	params, _, _, err := decodeArgon2idHash(hashedPassword)
+	if err != nil {
+		return true
+	}
+	return *params != *hasher
+}
+
+type BcryptHasher struct {
+	Cost int
+}
+
+func (hasher *BcryptHasher) HashPassword(password string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), hasher.Cost)
 	if err != nil {
 		return "", fmt.Errorf("Failed to hash password: %w", err)
 	}
 	return string(hashed), nil
 }
 
-func CheckPassword(password string, hashedPassword string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+func (hasher *BcryptHasher) NeedsRehash(hashedPassword string) bool {
+	if !isBcryptHash(hashedPassword) {
+		return true
+	}
+	cost, err := bcrypt.Cost([]byte(hashedPassword))
+	return err != nil || cost != hasher.Cost
+}
+
+func isBcryptHash(hashedPassword string) bool {
+	for _, prefix := range bcryptHashPrefixes {
+		if strings.HasPrefix(hashedPassword, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func checkArgon2id(password string, hashedPassword string) error {
+	params, salt, key, err := decodeArgon2idHash(hashedPassword)
+	if err != nil {
+		return err
+	}
+
+	otherKey := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, uint32(len(key)))
+	if subtle.ConstantTimeCompare(key, otherKey) != 1 {
+		return ErrMismatchedPassword
+	}
+	return nil
+}
+
+func decodeArgon2idHash(hashedPassword string) (params *Argon2idHasher, salt []byte, key []byte, err error) {
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", salt, key
+	parts := strings.Split(hashedPassword, "$")
+	if len(parts) != 6 || parts[1] != PasswordHashArgon2id {
+		return nil, nil, nil, errInvalidArgon2idHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, nil, nil, errInvalidArgon2idHash
+	}
+
+	params = &Argon2idHasher{}
+	_, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism)
+	if err != nil {
+		return nil, nil, nil, errInvalidArgon2idHash
+	}
+
+	salt, err = base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return nil, nil, nil, errInvalidArgon2idHash
+	}
+	key, err = base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return nil, nil, nil, errInvalidArgon2idHash
+	}
+	return params, salt, key, nil
 }
@@ -0,0 +1,181 @@
+package util
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	DefaultPasswordMinLength = 8
+	DefaultPasswordMaxLength = 128
+	// personal info shorter than this is too common to be rejected as a substring
+	minPersonalInfoLength = 3
+	// the breached list is indexed by the first characters of the SHA-1 hash, like the
+	// k-anonymity range API of Have I Been Pwned
+	breachedHashPrefixLength = 5
+)
+
+// PasswordPolicyViolation codes
+const (
+	PasswordTooShort         = "too_short"
+	PasswordTooLong          = "too_long"
+	PasswordMissingUpper     = "missing_uppercase"
+	PasswordMissingLower     = "missing_lowercase"
+	PasswordMissingDigit     = "missing_digit"
+	PasswordMissingSymbol    = "missing_symbol"
+	PasswordContainsPersonal = "contains_personal_info"
+	PasswordBreached         = "breached"
+)
+
+type PasswordPolicyViolation struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type PasswordPolicy struct {
+	MinLength     int
+	MaxLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	// breachedHashes maps SHA-1 prefixes to the suffixes of known breached passwords
+	breachedHashes map[string]map[string]struct{}
+}
+
+// NewPasswordPolicy builds the policy from the config, loading the breached password file if set
+func NewPasswordPolicy(config Config) (*PasswordPolicy, error) {
+	policy := &PasswordPolicy{
+		MinLength:     config.PasswordMinLength,
+		MaxLength:     config.PasswordMaxLength,
+		RequireUpper:  config.PasswordRequireUpper,
+		RequireLower:  config.PasswordRequireLower,
+		RequireDigit:  config.PasswordRequireDigit,
+		RequireSymbol: config.PasswordRequireSymbol,
+	}
+	if policy.MinLength == 0 {
+		policy.MinLength = DefaultPasswordMinLength
+	}
+	if policy.MaxLength == 0 {
+		policy.MaxLength = DefaultPasswordMaxLength
+	}
+	if policy.MinLength > policy.MaxLength {
+		return nil, fmt.Errorf("password min length %d is above max length %d", policy.MinLength, policy.MaxLength)
+	}
+
+	if config.BreachedPasswordsFile != "" {
+		if err := policy.LoadBreachedPasswords(config.BreachedPasswordsFile); err != nil {
+			return nil, err
+		}
+	}
+	return policy, nil
+}
+
+// LoadBreachedPasswords reads a file of uppercase hex SHA-1 hashes, one per line,
+// optionally followed by ":<count>" like the Have I Been Pwned downloads
+func (policy *PasswordPolicy) LoadBreachedPasswords(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("cannot open breached passwords file: %w", err)
+	}
+	defer file.Close()
+
+	breachedHashes := make(map[string]map[string]struct{})
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		hash, _, _ := strings.Cut(line, ":")
+		hash = strings.ToUpper(hash)
+		if _, err := hex.DecodeString(hash); err != nil || len(hash) != sha1.Size*2 {
+			return fmt.Errorf("invalid SHA-1 hash on line %d of breached passwords file", lineNumber)
+		}
+
+		prefix, suffix := hash[:breachedHashPrefixLength], hash[breachedHashPrefixLength:]
+		if breachedHashes[prefix] == nil {
+			breachedHashes[prefix] = make(map[string]struct{})
+		}
+		breachedHashes[prefix][suffix] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("cannot read breached passwords file: %w", err)
+	}
+
+	policy.breachedHashes = breachedHashes
+	return nil
+}
+
+// IsBreached reports whether the password is in the breached password list
+func (policy *PasswordPolicy) IsBreached(password string) bool {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	_, found := policy.breachedHashes[hash[:breachedHashPrefixLength]][hash[breachedHashPrefixLength:]]
+	return found
+}
+
+// Validate returns every rule the password breaks, or nothing when it's acceptable
+// username and email are used to reject passwords built from personal info
+func (policy *PasswordPolicy) Validate(password string, username string, email string) []PasswordPolicyViolation {
+	var violations []PasswordPolicyViolation
+	addViolation := func(code string, message string) {
+		violations = append(violations, PasswordPolicyViolation{Code: code, Message: message})
+	}
+
+	length := utf8.RuneCountInString(password)
+	if length < policy.MinLength {
+		addViolation(PasswordTooShort, fmt.Sprintf("must be at least %d characters long", policy.MinLength))
+	}
+	if length > policy.MaxLength {
+		addViolation(PasswordTooLong, fmt.Sprintf("must be at most %d characters long", policy.MaxLength))
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, character := range password {
+		switch {
+		case unicode.IsUpper(character):
+			hasUpper = true
+		case unicode.IsLower(character):
+			hasLower = true
+		case unicode.IsDigit(character):
+			hasDigit = true
+		case unicode.IsPunct(character) || unicode.IsSymbol(character) || unicode.IsSpace(character):
+			hasSymbol = true
+		}
+	}
+	if policy.RequireUpper && !hasUpper {
+		addViolation(PasswordMissingUpper, "must contain an uppercase letter")
+	}
+	if policy.RequireLower && !hasLower {
+		addViolation(PasswordMissingLower, "must contain a lowercase letter")
+	}
+	if policy.RequireDigit && !hasDigit {
+		addViolation(PasswordMissingDigit, "must contain a digit")
+	}
+	if policy.RequireSymbol && !hasSymbol {
+		addViolation(PasswordMissingSymbol, "must contain a symbol")
+	}
+
+	emailName, _, _ := strings.Cut(email, "@")
+	lowerPassword := strings.ToLower(password)
+	for _, personalInfo := range []string{username, emailName} {
+		if len(personalInfo) >= minPersonalInfoLength && strings.Contains(lowerPassword, strings.ToLower(personalInfo)) {
+			addViolation(PasswordContainsPersonal, "must not contain the username or email")
+			break
+		}
+	}
+
+	if policy.IsBreached(password) {
+		addViolation(PasswordBreached, "has appeared in a data breach, choose another one")
+	}
+
+	return violations
+}
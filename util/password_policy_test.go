@@ -0,0 +1,98 @@
+package util
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func violationCodes(violations []PasswordPolicyViolation) []string {
+	codes := make([]string, 0, len(violations))
+	for _, violation := range violations {
+		codes = append(codes, violation.Code)
+	}
+	return codes
+}
+
+func writeBreachedPasswords(t *testing.T, passwords ...string) string {
+	lines := []string{"# sample breached list"}
+	for _, password := range passwords {
+		sum := sha1.Sum([]byte(password))
+		lines = append(lines, strings.ToUpper(hex.EncodeToString(sum[:]))+":42")
+	}
+
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	require.NoError(t, os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0600))
+	return path
+}
+
+func TestPasswordPolicyDefaults(t *testing.T) {
+	policy, err := NewPasswordPolicy(Config{})
+	require.NoError(t, err)
+	require.Equal(t, DefaultPasswordMinLength, policy.MinLength)
+	require.Equal(t, DefaultPasswordMaxLength, policy.MaxLength)
+
+	require.Empty(t, policy.Validate(RandomString(10), "alice", "alice@example.com"))
+	require.Equal(t, []string{PasswordTooShort}, violationCodes(policy.Validate("abc", "alice", "alice@example.com")))
+	require.Equal(t, []string{PasswordTooLong}, violationCodes(policy.Validate(strings.Repeat("x", 129), "alice", "alice@example.com")))
+
+	_, err = NewPasswordPolicy(Config{PasswordMinLength: 20, PasswordMaxLength: 10})
+	require.Error(t, err)
+}
+
+func TestPasswordPolicyCharacterClasses(t *testing.T) {
+	policy, err := NewPasswordPolicy(Config{
+		PasswordRequireUpper:  true,
+		PasswordRequireLower:  true,
+		PasswordRequireDigit:  true,
+		PasswordRequireSymbol: true,
+	})
+	require.NoError(t, err)
+
+	require.Empty(t, policy.Validate("Correct-h0rse", "alice", "alice@example.com"))
+	require.ElementsMatch(t,
+		[]string{PasswordMissingUpper, PasswordMissingDigit, PasswordMissingSymbol},
+		violationCodes(policy.Validate("correcthorse", "alice", "alice@example.com")),
+	)
+	require.ElementsMatch(t,
+		[]string{PasswordMissingLower},
+		violationCodes(policy.Validate("CORRECT-H0RSE", "alice", "alice@example.com")),
+	)
+}
+
+func TestPasswordPolicyPersonalInfo(t *testing.T) {
+	policy, err := NewPasswordPolicy(Config{})
+	require.NoError(t, err)
+
+	require.Equal(t, []string{PasswordContainsPersonal}, violationCodes(policy.Validate("xxALICExx", "alice", "bob@example.com")))
+	require.Equal(t, []string{PasswordContainsPersonal}, violationCodes(policy.Validate("bobbyTables1", "alice", "bobby@example.com")))
+	// very short names are too common to reject
+	require.Empty(t, policy.Validate("jo-jo-jo-jo", "jo", "jo@example.com"))
+}
+
+func TestPasswordPolicyBreachedList(t *testing.T) {
+	path := writeBreachedPasswords(t, "password123", "qwertyuiop")
+
+	policy, err := NewPasswordPolicy(Config{BreachedPasswordsFile: path})
+	require.NoError(t, err)
+
+	require.True(t, policy.IsBreached("password123"))
+	require.True(t, policy.IsBreached("qwertyuiop"))
+	require.False(t, policy.IsBreached("password124"))
+	require.Equal(t, []string{PasswordBreached}, violationCodes(policy.Validate("password123", "alice", "alice@example.com")))
+}
+
+func TestPasswordPolicyInvalidBreachedList(t *testing.T) {
+	_, err := NewPasswordPolicy(Config{BreachedPasswordsFile: filepath.Join(t.TempDir(), "missing.txt")})
+	require.Error(t, err)
+
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	require.NoError(t, os.WriteFile(path, []byte("not-a-hash:1\n"), 0600))
+	_, err = NewPasswordPolicy(Config{BreachedPasswordsFile: path})
+	require.Error(t, err)
+}
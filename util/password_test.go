@@ -1,6 +1,7 @@
 package util
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -12,6 +13,7 @@ func TestHashedPassword(t *testing.T) {
 	hashedPassword, err := HashPassword(password)
 	require.NoError(t, err)
 	require.NotEmpty(t, hashedPassword)
+	require.True(t, strings.HasPrefix(hashedPassword, "$argon2id$v=19$"))
 
 	err = CheckPassword(password, hashedPassword)
 	require.NoError(t, err)
@@ -19,10 +21,56 @@ func TestHashedPassword(t *testing.T) {
 	differentPassword := "my_different_password"
 	err = CheckPassword(differentPassword, hashedPassword)
 	require.Error(t, err)
-	require.EqualError(t, err, bcrypt.ErrMismatchedHashAndPassword.Error())
+	require.EqualError(t, err, ErrMismatchedPassword.Error())
 
 	var freshHash string
 	freshHash, err = HashPassword(password)
 	require.NoError(t, err)
 	require.NotEqual(t, freshHash, hashedPassword)
 }
+
+func TestCheckBcryptPassword(t *testing.T) {
+	password := "my_secret_password"
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	require.NoError(t, CheckPassword(password, string(hashedPassword)))
+	require.ErrorIs(t, CheckPassword("my_different_password", string(hashedPassword)), ErrMismatchedPassword)
+	require.ErrorIs(t, CheckPassword(password, "plain_text"), ErrUnknownPasswordHash)
+}
+
+func TestNewPasswordHasher(t *testing.T) {
+	hasher, err := NewPasswordHasher(Config{})
+	require.NoError(t, err)
+	require.Equal(t, defaultPasswordHasher, hasher)
+
+	hasher, err = NewPasswordHasher(Config{PasswordHashAlgorithm: PasswordHashBcrypt})
+	require.NoError(t, err)
+	require.Equal(t, &BcryptHasher{Cost: bcrypt.DefaultCost}, hasher)
+
+	_, err = NewPasswordHasher(Config{PasswordHashAlgorithm: PasswordHashBcrypt, BcryptCost: 100})
+	require.Error(t, err)
+
+	_, err = NewPasswordHasher(Config{PasswordHashAlgorithm: "md5"})
+	require.ErrorIs(t, err, errUnsupportedAlgorithm)
+}
+
+func TestPasswordNeedsRehash(t *testing.T) {
+	password := "my_secret_password"
+	argon2idHasher := &Argon2idHasher{Memory: 8 * 1024, Iterations: 1, Parallelism: 1}
+	strongerHasher := &Argon2idHasher{Memory: 8 * 1024, Iterations: 2, Parallelism: 1}
+	bcryptHasher := &BcryptHasher{Cost: bcrypt.MinCost}
+
+	argon2idHash, err := argon2idHasher.HashPassword(password)
+	require.NoError(t, err)
+	require.NoError(t, CheckPassword(password, argon2idHash))
+	bcryptHash, err := bcryptHasher.HashPassword(password)
+	require.NoError(t, err)
+
+	require.False(t, argon2idHasher.NeedsRehash(argon2idHash))
+	require.True(t, strongerHasher.NeedsRehash(argon2idHash))
+	require.True(t, argon2idHasher.NeedsRehash(bcryptHash))
+	require.False(t, bcryptHasher.NeedsRehash(bcryptHash))
+	require.True(t, (&BcryptHasher{Cost: bcrypt.MinCost + 1}).NeedsRehash(bcryptHash))
+	require.True(t, bcryptHasher.NeedsRehash(argon2idHash))
+}
@@ -0,0 +1,274 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	FrequencyDaily   = "DAILY"
+	FrequencyWeekly  = "WEEKLY"
+	FrequencyMonthly = "MONTHLY"
+)
+
+// LastDayOfMonth as BYMONTHDAY runs a monthly recurrence on the last day of every month
+const LastDayOfMonth = -1
+
+const maxRecurrenceInterval = 366
+
+var ErrInvalidRecurrence = errors.New("invalid recurrence rule")
+
+var weekdayCodes = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+// Recurrence is the subset of an iCalendar RRULE that standing orders support:
+// FREQ=DAILY|WEEKLY|MONTHLY, INTERVAL, BYDAY (weekly), BYMONTHDAY (monthly), COUNT and UNTIL
+// Occurrences are at the time of day of Start, in UTC
+type Recurrence struct {
+	Frequency  string
+	Interval   int
+	ByDay      []time.Weekday
+	ByMonthDay int
+	// Count is the number of occurrences, 0 for no limit
+	Count int
+	// Until is the last moment an occurrence can happen, zero for no limit
+	Until time.Time
+	Start time.Time
+}
+
+// ParseRecurrence parses a rule like "FREQ=MONTHLY;BYMONTHDAY=1;COUNT=12" starting at start
+// BYDAY defaults to the weekday of start and BYMONTHDAY to its day of the month
+func ParseRecurrence(rule string, start time.Time) (Recurrence, error) {
+	recurrence := Recurrence{Interval: 1, Start: start.UTC().Truncate(time.Second)}
+
+	rule = strings.TrimPrefix(strings.TrimSpace(rule), "RRULE:")
+	if rule == "" {
+		return recurrence, fmt.Errorf("%w: empty rule", ErrInvalidRecurrence)
+	}
+
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(rule, ";") {
+		name, value, found := strings.Cut(part, "=")
+		name = strings.ToUpper(strings.TrimSpace(name))
+		value = strings.ToUpper(strings.TrimSpace(value))
+		if !found || value == "" {
+			return recurrence, fmt.Errorf("%w: %q is not NAME=VALUE", ErrInvalidRecurrence, part)
+		}
+		if seen[name] {
+			return recurrence, fmt.Errorf("%w: %v is repeated", ErrInvalidRecurrence, name)
+		}
+		seen[name] = true
+
+		var err error
+		switch name {
+		case "FREQ":
+			switch value {
+			case FrequencyDaily, FrequencyWeekly, FrequencyMonthly:
+				recurrence.Frequency = value
+			default:
+				err = fmt.Errorf("unsupported frequency %v", value)
+			}
+		case "INTERVAL":
+			recurrence.Interval, err = parseRecurrenceInt(value, 1, maxRecurrenceInterval)
+		case "COUNT":
+			recurrence.Count, err = parseRecurrenceInt(value, 1, 1<<20)
+		case "UNTIL":
+			recurrence.Until, err = parseRecurrenceUntil(value)
+		case "BYDAY":
+			for _, code := range strings.Split(value, ",") {
+				weekday, ok := weekdayCodes[code]
+				if !ok {
+					err = fmt.Errorf("unknown weekday %v", code)
+					break
+				}
+				recurrence.ByDay = append(recurrence.ByDay, weekday)
+			}
+		case "BYMONTHDAY":
+			recurrence.ByMonthDay, err = strconv.Atoi(value)
+			if err == nil && recurrence.ByMonthDay != LastDayOfMonth && (recurrence.ByMonthDay < 1 || recurrence.ByMonthDay > 31) {
+				err = fmt.Errorf("BYMONTHDAY must be 1 to 31 or -1")
+			}
+		default:
+			err = fmt.Errorf("unsupported part %v", name)
+		}
+		if err != nil {
+			return recurrence, fmt.Errorf("%w: %v", ErrInvalidRecurrence, err)
+		}
+	}
+
+	if recurrence.Frequency == "" {
+		return recurrence, fmt.Errorf("%w: FREQ is required", ErrInvalidRecurrence)
+	}
+	if seen["COUNT"] && seen["UNTIL"] {
+		return recurrence, fmt.Errorf("%w: COUNT and UNTIL can't be combined", ErrInvalidRecurrence)
+	}
+	if seen["BYDAY"] && recurrence.Frequency != FrequencyWeekly {
+		return recurrence, fmt.Errorf("%w: BYDAY is only supported with FREQ=WEEKLY", ErrInvalidRecurrence)
+	}
+	if seen["BYMONTHDAY"] && recurrence.Frequency != FrequencyMonthly {
+		return recurrence, fmt.Errorf("%w: BYMONTHDAY is only supported with FREQ=MONTHLY", ErrInvalidRecurrence)
+	}
+
+	if len(recurrence.ByDay) == 0 {
+		recurrence.ByDay = []time.Weekday{recurrence.Start.Weekday()}
+	}
+	if recurrence.ByMonthDay == 0 {
+		recurrence.ByMonthDay = recurrence.Start.Day()
+	}
+	return recurrence, nil
+}
+
+func parseRecurrenceInt(value string, min int, max int) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < min || n > max {
+		return 0, fmt.Errorf("%v must be a number from %d to %d", value, min, max)
+	}
+	return n, nil
+}
+
+// parseRecurrenceUntil accepts a UTC date-time like 20261231T235959Z or a date like 20261231,
+// a date includes the whole day
+func parseRecurrenceUntil(value string) (time.Time, error) {
+	if until, err := time.Parse("20060102T150405Z", value); err == nil {
+		return until, nil
+	}
+	until, err := time.Parse("20060102", value)
+	if err != nil {
+		return until, fmt.Errorf("UNTIL must look like 20261231 or 20261231T235959Z")
+	}
+	return until.Add(24*time.Hour - time.Second), nil
+}
+
+// String formats the recurrence back into a rule, with the defaults made explicit
+func (recurrence Recurrence) String() string {
+	parts := []string{"FREQ=" + recurrence.Frequency}
+	if recurrence.Interval > 1 {
+		parts = append(parts, fmt.Sprintf("INTERVAL=%d", recurrence.Interval))
+	}
+	switch recurrence.Frequency {
+	case FrequencyWeekly:
+		codes := make([]string, 0, len(recurrence.ByDay))
+		for _, weekday := range recurrence.ByDay {
+			codes = append(codes, strings.ToUpper(weekday.String()[:2]))
+		}
+		parts = append(parts, "BYDAY="+strings.Join(codes, ","))
+	case FrequencyMonthly:
+		parts = append(parts, fmt.Sprintf("BYMONTHDAY=%d", recurrence.ByMonthDay))
+	}
+	if recurrence.Count > 0 {
+		parts = append(parts, fmt.Sprintf("COUNT=%d", recurrence.Count))
+	}
+	if !recurrence.Until.IsZero() {
+		parts = append(parts, "UNTIL="+recurrence.Until.UTC().Format("20060102T150405Z"))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Next returns the first occurrence strictly after the given time, or the zero time once past Until
+// Count is left to the caller, which knows how many occurrences have been used
+func (recurrence Recurrence) Next(after time.Time) time.Time {
+	after = after.UTC()
+	var next time.Time
+	switch recurrence.Frequency {
+	case FrequencyDaily:
+		next = recurrence.nextDaily(after)
+	case FrequencyWeekly:
+		next = recurrence.nextWeekly(after)
+	case FrequencyMonthly:
+		next = recurrence.nextMonthly(after)
+	}
+
+	if !recurrence.Until.IsZero() && next.After(recurrence.Until) {
+		return time.Time{}
+	}
+	return next
+}
+
+func (recurrence Recurrence) nextDaily(after time.Time) time.Time {
+	start := recurrence.Start
+	if after.Before(start) {
+		return start
+	}
+	period := time.Duration(recurrence.Interval) * 24 * time.Hour
+	return start.Add((after.Sub(start)/period + 1) * period)
+}
+
+func (recurrence Recurrence) nextWeekly(after time.Time) time.Time {
+	start := recurrence.Start
+	// weeks start on Monday, INTERVAL counts weeks from the week of start
+	firstMonday := startOfDay(start).AddDate(0, 0, -((int(start.Weekday()) + 6) % 7))
+
+	candidate := recurrence.atStartTimeOfDay(after)
+	if after.Before(start) {
+		candidate = start
+	}
+	for i := 0; i <= 7*recurrence.Interval+7; i++ {
+		if candidate.After(after) && !candidate.Before(start) {
+			week := int(startOfDay(candidate).Sub(firstMonday).Hours()/24) / 7
+			if week%recurrence.Interval == 0 && containsWeekday(recurrence.ByDay, candidate.Weekday()) {
+				return candidate
+			}
+		}
+		candidate = candidate.AddDate(0, 0, 1)
+	}
+	return time.Time{}
+}
+
+func (recurrence Recurrence) nextMonthly(after time.Time) time.Time {
+	start := recurrence.Start
+	months := 0
+	if after.After(start) {
+		months = (after.Year()-start.Year())*12 + int(after.Month()-start.Month())
+		months -= months % recurrence.Interval
+	}
+	// the day may be clamped to a short month, so a couple of iterations can be needed
+	for ; ; months += recurrence.Interval {
+		candidate := recurrence.monthlyOccurrence(months)
+		if candidate.After(after) && !candidate.Before(start) {
+			return candidate
+		}
+	}
+}
+
+// monthlyOccurrence is the occurrence in the month that is the given number of months after start,
+// days past the end of a month fall on its last day
+func (recurrence Recurrence) monthlyOccurrence(months int) time.Time {
+	start := recurrence.Start
+	firstOfMonth := time.Date(start.Year(), start.Month()+time.Month(months), 1,
+		start.Hour(), start.Minute(), start.Second(), 0, time.UTC)
+	lastDay := firstOfMonth.AddDate(0, 1, -1).Day()
+
+	day := recurrence.ByMonthDay
+	if day == LastDayOfMonth || day > lastDay {
+		day = lastDay
+	}
+	return firstOfMonth.AddDate(0, 0, day-1)
+}
+
+func (recurrence Recurrence) atStartTimeOfDay(t time.Time) time.Time {
+	start := recurrence.Start
+	return time.Date(t.Year(), t.Month(), t.Day(), start.Hour(), start.Minute(), start.Second(), 0, time.UTC)
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func containsWeekday(weekdays []time.Weekday, weekday time.Weekday) bool {
+	for _, w := range weekdays {
+		if w == weekday {
+			return true
+		}
+	}
+	return false
+}
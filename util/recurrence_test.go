@@ -0,0 +1,169 @@
+package util
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func date(year int, month time.Month, day int, hour int) time.Time {
+	return time.Date(year, month, day, hour, 0, 0, 0, time.UTC)
+}
+
+func occurrences(recurrence Recurrence, after time.Time, n int) []time.Time {
+	var result []time.Time
+	for len(result) < n {
+		after = recurrence.Next(after)
+		if after.IsZero() {
+			break
+		}
+		result = append(result, after)
+	}
+	return result
+}
+
+func TestParseRecurrence(t *testing.T) {
+	// 2026-01-07 is a Wednesday
+	start := date(2026, time.January, 7, 9)
+
+	testCases := []struct {
+		name     string
+		rule     string
+		expected string
+		wantErr  bool
+	}{
+		{name: "Daily", rule: "FREQ=DAILY", expected: "FREQ=DAILY"},
+		{name: "Prefix and lowercase", rule: "RRULE:freq=daily;interval=2", expected: "FREQ=DAILY;INTERVAL=2"},
+		{name: "Weekly defaults to the start weekday", rule: "FREQ=WEEKLY", expected: "FREQ=WEEKLY;BYDAY=WE"},
+		{name: "Weekly by day", rule: "FREQ=WEEKLY;BYDAY=MO,FR;COUNT=4", expected: "FREQ=WEEKLY;BYDAY=MO,FR;COUNT=4"},
+		{name: "Monthly defaults to the start day", rule: "FREQ=MONTHLY", expected: "FREQ=MONTHLY;BYMONTHDAY=7"},
+		{name: "Monthly until a date", rule: "FREQ=MONTHLY;BYMONTHDAY=-1;UNTIL=20261231", expected: "FREQ=MONTHLY;BYMONTHDAY=-1;UNTIL=20261231T235959Z"},
+		{name: "Empty", rule: "", wantErr: true},
+		{name: "No frequency", rule: "INTERVAL=2", wantErr: true},
+		{name: "Unsupported frequency", rule: "FREQ=HOURLY", wantErr: true},
+		{name: "Unsupported part", rule: "FREQ=DAILY;BYHOUR=9", wantErr: true},
+		{name: "Repeated part", rule: "FREQ=DAILY;FREQ=WEEKLY", wantErr: true},
+		{name: "Bad interval", rule: "FREQ=DAILY;INTERVAL=0", wantErr: true},
+		{name: "Bad weekday", rule: "FREQ=WEEKLY;BYDAY=XX", wantErr: true},
+		{name: "Bad month day", rule: "FREQ=MONTHLY;BYMONTHDAY=32", wantErr: true},
+		{name: "BYDAY without weekly", rule: "FREQ=MONTHLY;BYDAY=MO", wantErr: true},
+		{name: "COUNT and UNTIL", rule: "FREQ=DAILY;COUNT=2;UNTIL=20261231", wantErr: true},
+		{name: "Bad until", rule: "FREQ=DAILY;UNTIL=tomorrow", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			recurrence, err := ParseRecurrence(tc.rule, start)
+			if tc.wantErr {
+				require.ErrorIs(t, err, ErrInvalidRecurrence)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, recurrence.String())
+		})
+	}
+}
+
+func TestRecurrenceNext(t *testing.T) {
+	// 2026-01-07 is a Wednesday
+	start := date(2026, time.January, 7, 9)
+
+	testCases := []struct {
+		name     string
+		rule     string
+		start    time.Time
+		after    time.Time
+		expected []time.Time
+	}{
+		{
+			name:     "Daily from before the start",
+			rule:     "FREQ=DAILY;INTERVAL=2",
+			start:    start,
+			after:    start.Add(-48 * time.Hour),
+			expected: []time.Time{start, date(2026, time.January, 9, 9), date(2026, time.January, 11, 9)},
+		},
+		{
+			name:     "Daily from the middle of a period",
+			rule:     "FREQ=DAILY;INTERVAL=2",
+			start:    start,
+			after:    date(2026, time.January, 8, 12),
+			expected: []time.Time{date(2026, time.January, 9, 9), date(2026, time.January, 11, 9)},
+		},
+		{
+			name:  "Weekly on several days",
+			rule:  "FREQ=WEEKLY;BYDAY=MO,FR",
+			start: start,
+			after: start,
+			expected: []time.Time{
+				date(2026, time.January, 9, 9),
+				date(2026, time.January, 12, 9),
+				date(2026, time.January, 16, 9),
+			},
+		},
+		{
+			name:  "Every other week",
+			rule:  "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE",
+			start: start,
+			after: start.Add(-time.Second),
+			expected: []time.Time{
+				start,
+				date(2026, time.January, 19, 9),
+				date(2026, time.January, 21, 9),
+				date(2026, time.February, 2, 9),
+			},
+		},
+		{
+			name:  "Monthly clamps to short months",
+			rule:  "FREQ=MONTHLY;BYMONTHDAY=31",
+			start: date(2026, time.January, 1, 9),
+			after: date(2026, time.January, 1, 9),
+			expected: []time.Time{
+				date(2026, time.January, 31, 9),
+				date(2026, time.February, 28, 9),
+				date(2026, time.March, 31, 9),
+				date(2026, time.April, 30, 9),
+			},
+		},
+		{
+			name:  "Last day of every third month",
+			rule:  "FREQ=MONTHLY;INTERVAL=3;BYMONTHDAY=-1",
+			start: start,
+			after: start,
+			expected: []time.Time{
+				date(2026, time.January, 31, 9),
+				date(2026, time.April, 30, 9),
+				date(2026, time.July, 31, 9),
+			},
+		},
+		{
+			name:     "Monthly skips a day before the start",
+			rule:     "FREQ=MONTHLY;BYMONTHDAY=1",
+			start:    start,
+			after:    start.Add(-time.Hour),
+			expected: []time.Time{date(2026, time.February, 1, 9), date(2026, time.March, 1, 9)},
+		},
+		{
+			name:     "Stops at until",
+			rule:     "FREQ=WEEKLY;UNTIL=20260121",
+			start:    start,
+			after:    start.Add(-time.Second),
+			expected: []time.Time{start, date(2026, time.January, 14, 9), date(2026, time.January, 21, 9)},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			recurrence, err := ParseRecurrence(tc.rule, tc.start)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, occurrences(recurrence, tc.after, len(tc.expected)+1)[:len(tc.expected)])
+		})
+	}
+}
+
+func TestRecurrenceNextAfterUntil(t *testing.T) {
+	recurrence, err := ParseRecurrence("FREQ=DAILY;UNTIL=20260110", date(2026, time.January, 7, 9))
+	require.NoError(t, err)
+	require.Len(t, occurrences(recurrence, time.Time{}, 10), 4)
+	require.True(t, recurrence.Next(date(2026, time.January, 10, 9)).IsZero())
+}
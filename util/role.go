@@ -0,0 +1,14 @@
+package util
+
+const (
+	RoleCustomer = "customer"
+	RoleAdmin    = "admin"
+)
+
+func IsSupportedRole(role string) bool {
+	switch role {
+	case RoleCustomer, RoleAdmin:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,30 @@
+package util
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+)
+
+// RandomSecret returns n cryptographically secure random bytes, hex encoded.
+// Unlike RandomString, it is safe to use for credentials.
+func RandomSecret(n int) (string, error) {
+	buffer := make([]byte, n)
+	if _, err := rand.Read(buffer); err != nil {
+		return "", fmt.Errorf("Failed to generate secret: %w", err)
+	}
+	return hex.EncodeToString(buffer), nil
+}
+
+// HashSecret hashes a high-entropy secret (API keys, one-time tokens).
+// A fast hash is enough here: these secrets can't be brute-forced like passwords.
+func HashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func CheckSecret(secret string, hashedSecret string) bool {
+	return subtle.ConstantTimeCompare([]byte(HashSecret(secret)), []byte(hashedSecret)) == 1
+}
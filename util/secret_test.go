@@ -0,0 +1,27 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRandomSecret(t *testing.T) {
+	secret, err := RandomSecret(16)
+	require.NoError(t, err)
+	require.Len(t, secret, 32)
+
+	otherSecret, err := RandomSecret(16)
+	require.NoError(t, err)
+	require.NotEqual(t, secret, otherSecret)
+}
+
+func TestHashSecret(t *testing.T) {
+	secret, err := RandomSecret(16)
+	require.NoError(t, err)
+
+	hashedSecret := HashSecret(secret)
+	require.NotEqual(t, secret, hashedSecret)
+	require.True(t, CheckSecret(secret, hashedSecret))
+	require.False(t, CheckSecret(secret+"x", hashedSecret))
+}
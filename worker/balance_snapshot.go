@@ -0,0 +1,76 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	db "github.com/go_backend_misc/db/sqlc"
+	"github.com/go_backend_misc/util"
+)
+
+// balanceSnapshotSettle is how long after midnight the day is snapshotted, so that transfers
+// started before midnight and committed after it are in the snapshot
+const balanceSnapshotSettle = 10 * time.Minute
+
+// BalanceSnapshotter takes a snapshot of every balance at midnight UTC, balances at a point in time
+// only add up the entries after the latest snapshot
+type BalanceSnapshotter struct {
+	store        db.Store
+	pollInterval time.Duration
+}
+
+func NewBalanceSnapshotter(store db.Store, config util.Config) *BalanceSnapshotter {
+	return &BalanceSnapshotter{
+		store:        store,
+		pollInterval: config.BalanceSnapshotPollInterval,
+	}
+}
+
+// Run polls for days to snapshot until the context is canceled
+func (snapshotter *BalanceSnapshotter) Run(ctx context.Context) {
+	ticker := time.NewTicker(snapshotter.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := snapshotter.SnapshotDue(ctx, time.Now()); err != nil {
+			log.Printf("cannot snapshot balances: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SnapshotDue snapshots every midnight since the latest snapshot and returns how many days it snapshotted,
+// the first run only snapshots the latest midnight
+func (snapshotter *BalanceSnapshotter) SnapshotDue(ctx context.Context, now time.Time) (days int, err error) {
+	latestMidnight := now.Add(-balanceSnapshotSettle).UTC().Truncate(24 * time.Hour)
+
+	next := latestMidnight
+	latest, err := snapshotter.store.GetLatestBalanceSnapshotTime(ctx)
+	switch {
+	case err == nil:
+		next = latest.UTC().Add(24 * time.Hour)
+	case !errors.Is(err, sql.ErrNoRows):
+		return 0, err
+	}
+
+	for ; !next.After(latestMidnight); next = next.Add(24 * time.Hour) {
+		if ctx.Err() != nil {
+			return days, ctx.Err()
+		}
+		accounts, err := snapshotter.store.CreateBalanceSnapshots(ctx, next)
+		if err != nil {
+			return days, err
+		}
+		log.Printf("snapshotted the balances of %d accounts until %v", accounts, next)
+		days++
+	}
+	return days, nil
+}
@@ -0,0 +1,94 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	mockdb "github.com/go_backend_misc/db/mock"
+	"github.com/go_backend_misc/util"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestSnapshotDue(t *testing.T) {
+	now := time.Date(2026, 4, 3, 8, 0, 0, 0, time.UTC)
+	midnight := time.Date(2026, 4, 3, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name         string
+		now          time.Time
+		buildStubs   func(store *mockdb.MockStore)
+		expectedDays int
+	}{
+		{
+			name: "First run",
+			now:  now,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetLatestBalanceSnapshotTime(gomock.Any()).Times(1).Return(time.Time{}, sql.ErrNoRows)
+				store.EXPECT().CreateBalanceSnapshots(gomock.Any(), midnight).Times(1).Return(int64(3), nil)
+			},
+			expectedDays: 1,
+		},
+		{
+			name: "Missed days",
+			now:  now,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetLatestBalanceSnapshotTime(gomock.Any()).Times(1).Return(midnight.AddDate(0, 0, -3), nil)
+				gomock.InOrder(
+					store.EXPECT().CreateBalanceSnapshots(gomock.Any(), midnight.AddDate(0, 0, -2)).Times(1),
+					store.EXPECT().CreateBalanceSnapshots(gomock.Any(), midnight.AddDate(0, 0, -1)).Times(1),
+					store.EXPECT().CreateBalanceSnapshots(gomock.Any(), midnight).Times(1),
+				)
+			},
+			expectedDays: 3,
+		},
+		{
+			name: "Up to date",
+			now:  now,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetLatestBalanceSnapshotTime(gomock.Any()).Times(1).Return(midnight, nil)
+				store.EXPECT().CreateBalanceSnapshots(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name: "Right after midnight",
+			now:  midnight.Add(time.Minute),
+			buildStubs: func(store *mockdb.MockStore) {
+				// the transfers of the day before may still be committing
+				store.EXPECT().GetLatestBalanceSnapshotTime(gomock.Any()).Times(1).Return(midnight.AddDate(0, 0, -1), nil)
+				store.EXPECT().CreateBalanceSnapshots(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			snapshotter := NewBalanceSnapshotter(store, util.Config{BalanceSnapshotPollInterval: time.Hour})
+			days, err := snapshotter.SnapshotDue(context.Background(), tc.now)
+			require.NoError(t, err)
+			require.Equal(t, tc.expectedDays, days)
+		})
+	}
+}
+
+func TestSnapshotDueError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetLatestBalanceSnapshotTime(gomock.Any()).Times(1).Return(time.Time{}, sql.ErrNoRows)
+	store.EXPECT().CreateBalanceSnapshots(gomock.Any(), gomock.Any()).Times(1).Return(int64(0), sql.ErrConnDone)
+
+	snapshotter := NewBalanceSnapshotter(store, util.Config{BalanceSnapshotPollInterval: time.Hour})
+	days, err := snapshotter.SnapshotDue(context.Background(), time.Now())
+	require.ErrorIs(t, err, sql.ErrConnDone)
+	require.Zero(t, days)
+}
@@ -0,0 +1,65 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	db "github.com/go_backend_misc/db/sqlc"
+	"github.com/go_backend_misc/util"
+)
+
+// CurrencyLoader keeps the currency registry in sync with the currencies table,
+// so currencies are added, disabled or limited without a deploy
+type CurrencyLoader struct {
+	store        db.Querier
+	pollInterval time.Duration
+}
+
+// NewCurrencyLoader only needs queries, the registry is loaded before the store, which depends on it
+func NewCurrencyLoader(store db.Querier, config util.Config) *CurrencyLoader {
+	return &CurrencyLoader{
+		store:        store,
+		pollInterval: config.CurrencyRefreshInterval,
+	}
+}
+
+// Run reloads the currencies until the context is canceled, the registry keeps the last ones loaded on errors
+func (loader *CurrencyLoader) Run(ctx context.Context) {
+	ticker := time.NewTicker(loader.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := loader.Load(ctx); err != nil {
+			log.Printf("cannot reload currencies: %v", err)
+		}
+	}
+}
+
+// Load replaces the registry with the currencies of the database
+func (loader *CurrencyLoader) Load(ctx context.Context) error {
+	rows, err := loader.store.ListCurrencies(ctx)
+	if err != nil {
+		return err
+	}
+
+	currencies := make([]util.Currency, len(rows))
+	for i, row := range rows {
+		currencies[i] = util.Currency{
+			Code:              row.Code,
+			Exponent:          row.Exponent,
+			Symbol:            row.Symbol,
+			Enabled:           row.Enabled,
+			MinTransferAmount: row.MinTransferAmount,
+			MaxTransferAmount: row.MaxTransferAmount,
+		}
+	}
+	util.SetCurrencies(currencies)
+	return nil
+}
@@ -0,0 +1,44 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	mockdb "github.com/go_backend_misc/db/mock"
+	db "github.com/go_backend_misc/db/sqlc"
+	"github.com/go_backend_misc/util"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestLoadCurrencies(t *testing.T) {
+	previous := util.Currencies()
+	t.Cleanup(func() { util.SetCurrencies(previous) })
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	gomock.InOrder(
+		store.EXPECT().ListCurrencies(gomock.Any()).Times(1).Return([]db.Currency{
+			{Code: "GBP", Exponent: 2, Symbol: "£", Enabled: true, MaxTransferAmount: 100000},
+			{Code: util.USD, Exponent: 2, Symbol: "$", Enabled: false},
+		}, nil),
+		store.EXPECT().ListCurrencies(gomock.Any()).Times(1).Return(nil, errors.New("db is down")),
+	)
+
+	loader := NewCurrencyLoader(store, util.Config{})
+	require.NoError(t, loader.Load(context.Background()))
+
+	gbp, ok := util.LookupCurrency("GBP")
+	require.True(t, ok)
+	require.Equal(t, int64(100000), gbp.MaxTransferAmount)
+	require.True(t, util.IsSupportedCurrency("GBP"))
+	require.False(t, util.IsSupportedCurrency(util.USD))
+	require.False(t, util.IsSupportedCurrency(util.EUR))
+
+	// the registry keeps the last currencies loaded
+	require.Error(t, loader.Load(context.Background()))
+	require.True(t, util.IsSupportedCurrency("GBP"))
+}
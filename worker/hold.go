@@ -0,0 +1,60 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	db "github.com/go_backend_misc/db/sqlc"
+	"github.com/go_backend_misc/util"
+)
+
+// HoldExpirer releases the holds past their expiry, giving the amount back to the available balance
+// Captures and voids already refuse expired holds, the expirer only frees the money
+type HoldExpirer struct {
+	store        db.Store
+	pollInterval time.Duration
+}
+
+func NewHoldExpirer(store db.Store, config util.Config) *HoldExpirer {
+	return &HoldExpirer{
+		store:        store,
+		pollInterval: config.HoldExpiryPollInterval,
+	}
+}
+
+// Run polls for expired holds until the context is canceled
+func (expirer *HoldExpirer) Run(ctx context.Context) {
+	ticker := time.NewTicker(expirer.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := expirer.ExpireDue(ctx); err != nil {
+			log.Printf("cannot expire holds: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ExpireDue releases every expired hold and returns how many were released
+func (expirer *HoldExpirer) ExpireDue(ctx context.Context) (expired int, err error) {
+	for ctx.Err() == nil {
+		hold, err := expirer.store.ExpireHoldTx(ctx)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return expired, nil
+			}
+			return expired, err
+		}
+		log.Printf("hold %d of %d on account %d expired", hold.ID, hold.Amount, hold.AccountID)
+		expired++
+	}
+	return expired, ctx.Err()
+}
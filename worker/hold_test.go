@@ -0,0 +1,44 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	mockdb "github.com/go_backend_misc/db/mock"
+	db "github.com/go_backend_misc/db/sqlc"
+	"github.com/go_backend_misc/util"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestExpireDueHolds(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	hold := db.Hold{ID: 1, AccountID: 2, Amount: 10, Status: db.HoldExpired}
+	gomock.InOrder(
+		store.EXPECT().ExpireHoldTx(gomock.Any()).Times(2).Return(hold, nil),
+		store.EXPECT().ExpireHoldTx(gomock.Any()).Times(1).Return(db.Hold{}, sql.ErrNoRows),
+	)
+
+	expirer := NewHoldExpirer(store, util.Config{HoldExpiryPollInterval: time.Minute})
+	expired, err := expirer.ExpireDue(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 2, expired)
+}
+
+func TestExpireDueHoldsError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().ExpireHoldTx(gomock.Any()).Times(1).Return(db.Hold{}, sql.ErrConnDone)
+
+	expirer := NewHoldExpirer(store, util.Config{HoldExpiryPollInterval: time.Minute})
+	expired, err := expirer.ExpireDue(context.Background())
+	require.ErrorIs(t, err, sql.ErrConnDone)
+	require.Zero(t, expired)
+}
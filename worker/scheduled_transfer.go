@@ -0,0 +1,156 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	db "github.com/go_backend_misc/db/sqlc"
+	"github.com/go_backend_misc/risk"
+	"github.com/go_backend_misc/util"
+	"github.com/lib/pq"
+)
+
+const (
+	defaultScheduledTransferMaxAttempts  = 5
+	defaultScheduledTransferRetryBackoff = time.Minute
+)
+
+// ScheduledTransferExecutor makes the scheduled transfers once they are due
+// Several executors can run at once, each due transfer is claimed by a single one
+// The risk rules run again when a transfer is due, a held or denied one is recorded as denied and fails
+type ScheduledTransferExecutor struct {
+	store        db.Store
+	riskEngine   *risk.Engine
+	pollInterval time.Duration
+	maxAttempts  int32
+	retryBackoff time.Duration
+	now          func() time.Time
+}
+
+func NewScheduledTransferExecutor(store db.Store, config util.Config, riskEngine *risk.Engine) *ScheduledTransferExecutor {
+	executor := &ScheduledTransferExecutor{
+		store:        store,
+		riskEngine:   riskEngine,
+		pollInterval: config.ScheduledTransferPollInterval,
+		maxAttempts:  config.ScheduledTransferMaxAttempts,
+		retryBackoff: config.ScheduledTransferRetryBackoff,
+		now:          time.Now,
+	}
+	if executor.maxAttempts <= 0 {
+		executor.maxAttempts = defaultScheduledTransferMaxAttempts
+	}
+	if executor.retryBackoff <= 0 {
+		executor.retryBackoff = defaultScheduledTransferRetryBackoff
+	}
+	return executor
+}
+
+// Run polls for due transfers until the context is canceled
+func (executor *ScheduledTransferExecutor) Run(ctx context.Context) {
+	ticker := time.NewTicker(executor.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := executor.ExecuteDue(ctx); err != nil {
+			log.Printf("cannot execute scheduled transfers: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ExecuteDue works through every due transfer and returns how many were attempted
+func (executor *ScheduledTransferExecutor) ExecuteDue(ctx context.Context) (attempted int, err error) {
+	for ctx.Err() == nil {
+		found, err := executor.executeNext(ctx)
+		if err != nil || !found {
+			return attempted, err
+		}
+		attempted++
+	}
+	return attempted, ctx.Err()
+}
+
+func (executor *ScheduledTransferExecutor) executeNext(ctx context.Context) (found bool, err error) {
+	scheduled, result, err := executor.store.ExecuteScheduledTransferTx(ctx, screenTransfer(executor.store, executor.riskEngine))
+	if err == nil {
+		log.Printf("scheduled transfer %d executed as transfer %d", scheduled.ID, result.Transfer.ID)
+		return true, nil
+	}
+	if scheduled.ID == 0 {
+		// nothing was claimed, either nothing is due or the database is unavailable
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	attempts := scheduled.Attempts + 1
+	arg := db.RecordScheduledTransferFailureParams{
+		ID:            scheduled.ID,
+		Status:        db.ScheduledTransferFailed,
+		LastError:     sql.NullString{String: err.Error(), Valid: true},
+		NextAttemptAt: scheduled.NextAttemptAt,
+	}
+	if isTransientError(err) && attempts < executor.maxAttempts {
+		arg.Status = db.ScheduledTransferPending
+		arg.NextAttemptAt = executor.now().Add(executor.retryBackoff << (attempts - 1))
+	}
+
+	if _, recordErr := executor.store.RecordScheduledTransferFailure(ctx, arg); recordErr != nil && recordErr != sql.ErrNoRows {
+		return false, recordErr
+	}
+	log.Printf("scheduled transfer %d failed attempt=%d status=%v: %v", scheduled.ID, attempts, arg.Status, err)
+	return true, nil
+}
+
+// screenTransfer runs the risk rules on a due transfer, it can't wait for a review:
+// held and denied transfers are recorded as denied and refused with db.ErrTransferDenied
+func screenTransfer(store db.Store, riskEngine *risk.Engine) db.ScreenTransfer {
+	if riskEngine == nil {
+		return nil
+	}
+	return func(ctx context.Context, owner string, currency string, arg db.CreateTransferParams) error {
+		decision, review, err := riskEngine.Review(ctx, store, &risk.Transfer{
+			Owner:         owner,
+			FromAccountID: arg.FromAccountID.Int64,
+			ToAccountID:   arg.ToAccountID.Int64,
+			Amount:        arg.Amount,
+			Currency:      currency,
+		}, arg.Fee, false)
+		if err != nil {
+			return err
+		}
+		if decision != risk.Allow {
+			return fmt.Errorf("%w: risk review %d", db.ErrTransferDenied, review.ID)
+		}
+		return nil
+	}
+}
+
+// isTransientError tells apart failures that may go away on their own, like deadlocks or lost connections,
+// from transfers that can't be made anymore
+func isTransientError(err error) bool {
+	if errors.Is(err, db.ErrScheduledTransferInvalid) || errors.Is(err, db.ErrInvalidDestination) ||
+		errors.Is(err, db.ErrTransferDenied) {
+		return false
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code.Class() {
+		// data exception and integrity constraint violation
+		case "22", "23":
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,300 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	mockdb "github.com/go_backend_misc/db/mock"
+	db "github.com/go_backend_misc/db/sqlc"
+	"github.com/go_backend_misc/risk"
+	"github.com/go_backend_misc/util"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func newTestExecutor(store db.Store, now time.Time) *ScheduledTransferExecutor {
+	executor := NewScheduledTransferExecutor(store, util.Config{
+		ScheduledTransferPollInterval: time.Minute,
+		ScheduledTransferMaxAttempts:  3,
+		ScheduledTransferRetryBackoff: time.Minute,
+	}, nil)
+	executor.now = func() time.Time { return now }
+	return executor
+}
+
+func TestExecuteDue(t *testing.T) {
+	now := time.Now()
+	scheduled := db.ScheduledTransfer{
+		ID:            1,
+		Owner:         "test_owner",
+		FromAccountID: 1,
+		ToAccountID:   2,
+		Amount:        10,
+		Currency:      "USD",
+		Status:        db.ScheduledTransferPending,
+		NextAttemptAt: now.Add(-time.Minute),
+	}
+
+	testCases := []struct {
+		name          string
+		buildStubs    func(store *mockdb.MockStore)
+		wantAttempted int
+		wantErr       bool
+	}{
+		{
+			name: "Nothing due",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ExecuteScheduledTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ScheduledTransfer{}, db.TransferTxResult{}, sql.ErrNoRows)
+			},
+			wantAttempted: 0,
+		},
+		{
+			name: "Executes every due transfer",
+			buildStubs: func(store *mockdb.MockStore) {
+				completed := scheduled
+				completed.Status = db.ScheduledTransferCompleted
+				gomock.InOrder(
+					store.EXPECT().
+						ExecuteScheduledTransferTx(gomock.Any(), gomock.Any()).
+						Times(2).
+						Return(completed, db.TransferTxResult{Transfer: db.Transfer{ID: 7}}, nil),
+					store.EXPECT().
+						ExecuteScheduledTransferTx(gomock.Any(), gomock.Any()).
+						Times(1).
+						Return(db.ScheduledTransfer{}, db.TransferTxResult{}, sql.ErrNoRows),
+				)
+				store.EXPECT().RecordScheduledTransferFailure(gomock.Any(), gomock.Any()).Times(0)
+			},
+			wantAttempted: 2,
+		},
+		{
+			name: "Transient error is retried later",
+			buildStubs: func(store *mockdb.MockStore) {
+				deadlock := &pq.Error{Code: "40P01"}
+				gomock.InOrder(
+					store.EXPECT().
+						ExecuteScheduledTransferTx(gomock.Any(), gomock.Any()).
+						Times(1).
+						Return(scheduled, db.TransferTxResult{}, deadlock),
+					store.EXPECT().
+						ExecuteScheduledTransferTx(gomock.Any(), gomock.Any()).
+						Times(1).
+						Return(db.ScheduledTransfer{}, db.TransferTxResult{}, sql.ErrNoRows),
+				)
+				arg := db.RecordScheduledTransferFailureParams{
+					ID:            scheduled.ID,
+					Status:        db.ScheduledTransferPending,
+					LastError:     sql.NullString{String: deadlock.Error(), Valid: true},
+					NextAttemptAt: now.Add(time.Minute),
+				}
+				store.EXPECT().
+					RecordScheduledTransferFailure(gomock.Any(), gomock.Eq(arg)).
+					Times(1)
+			},
+			wantAttempted: 1,
+		},
+		{
+			name: "Backoff doubles with every attempt",
+			buildStubs: func(store *mockdb.MockStore) {
+				retried := scheduled
+				retried.Attempts = 1
+				gomock.InOrder(
+					store.EXPECT().
+						ExecuteScheduledTransferTx(gomock.Any(), gomock.Any()).
+						Times(1).
+						Return(retried, db.TransferTxResult{}, sql.ErrConnDone),
+					store.EXPECT().
+						ExecuteScheduledTransferTx(gomock.Any(), gomock.Any()).
+						Times(1).
+						Return(db.ScheduledTransfer{}, db.TransferTxResult{}, sql.ErrNoRows),
+				)
+				store.EXPECT().
+					RecordScheduledTransferFailure(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.RecordScheduledTransferFailureParams) (db.ScheduledTransfer, error) {
+						require.Equal(t, db.ScheduledTransferPending, arg.Status)
+						require.Equal(t, now.Add(2*time.Minute), arg.NextAttemptAt)
+						return db.ScheduledTransfer{}, nil
+					})
+			},
+			wantAttempted: 1,
+		},
+		{
+			name: "Gives up after the last attempt",
+			buildStubs: func(store *mockdb.MockStore) {
+				lastAttempt := scheduled
+				lastAttempt.Attempts = 2
+				gomock.InOrder(
+					store.EXPECT().
+						ExecuteScheduledTransferTx(gomock.Any(), gomock.Any()).
+						Times(1).
+						Return(lastAttempt, db.TransferTxResult{}, sql.ErrConnDone),
+					store.EXPECT().
+						ExecuteScheduledTransferTx(gomock.Any(), gomock.Any()).
+						Times(1).
+						Return(db.ScheduledTransfer{}, db.TransferTxResult{}, sql.ErrNoRows),
+				)
+				store.EXPECT().
+					RecordScheduledTransferFailure(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.RecordScheduledTransferFailureParams) (db.ScheduledTransfer, error) {
+						require.Equal(t, db.ScheduledTransferFailed, arg.Status)
+						return db.ScheduledTransfer{}, nil
+					})
+			},
+			wantAttempted: 1,
+		},
+		{
+			name: "Invalid transfer fails right away",
+			buildStubs: func(store *mockdb.MockStore) {
+				invalid := fmt.Errorf("%w: account 2 currency mismatch", db.ErrScheduledTransferInvalid)
+				gomock.InOrder(
+					store.EXPECT().
+						ExecuteScheduledTransferTx(gomock.Any(), gomock.Any()).
+						Times(1).
+						Return(scheduled, db.TransferTxResult{}, invalid),
+					store.EXPECT().
+						ExecuteScheduledTransferTx(gomock.Any(), gomock.Any()).
+						Times(1).
+						Return(db.ScheduledTransfer{}, db.TransferTxResult{}, sql.ErrNoRows),
+				)
+				store.EXPECT().
+					RecordScheduledTransferFailure(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.RecordScheduledTransferFailureParams) (db.ScheduledTransfer, error) {
+						require.Equal(t, db.ScheduledTransferFailed, arg.Status)
+						require.Equal(t, invalid.Error(), arg.LastError.String)
+						return db.ScheduledTransfer{}, nil
+					})
+			},
+			wantAttempted: 1,
+		},
+		{
+			name: "Database unavailable",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ExecuteScheduledTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ScheduledTransfer{}, db.TransferTxResult{}, sql.ErrConnDone)
+				store.EXPECT().RecordScheduledTransferFailure(gomock.Any(), gomock.Any()).Times(0)
+			},
+			wantAttempted: 0,
+			wantErr:       true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			attempted, err := newTestExecutor(store, now).ExecuteDue(context.Background())
+			if tc.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tc.wantAttempted, attempted)
+		})
+	}
+}
+
+func TestIsTransientError(t *testing.T) {
+	require.True(t, isTransientError(sql.ErrConnDone))
+	require.True(t, isTransientError(&pq.Error{Code: "40001"}))
+	require.False(t, isTransientError(fmt.Errorf("%w: gone", db.ErrScheduledTransferInvalid)))
+	require.False(t, isTransientError(fmt.Errorf("%w: risk review 1", db.ErrTransferDenied)))
+	require.False(t, isTransientError(fmt.Errorf("%w: account 1 is a system account", db.ErrInvalidDestination)))
+	require.False(t, isTransientError(&pq.Error{Code: "23503"}))
+}
+
+func TestScreenTransfer(t *testing.T) {
+	require.Nil(t, screenTransfer(nil, nil))
+
+	arg := db.CreateTransferParams{
+		FromAccountID: db.Int64ToSqlInt64(1),
+		ToAccountID:   db.Int64ToSqlInt64(2),
+		Amount:        500,
+		Fee:           3,
+	}
+	testCases := []struct {
+		name       string
+		action     risk.Decision
+		amount     int64
+		buildStubs func(store *mockdb.MockStore)
+		wantErr    error
+	}{
+		{
+			name:   "Allowed",
+			action: risk.Deny,
+			amount: 100,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateRiskReview(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:   "Held is denied",
+			action: risk.Hold,
+			amount: arg.Amount,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateRiskReview(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, review db.CreateRiskReviewParams) (db.RiskReview, error) {
+						require.Equal(t, db.RiskReviewDenied, review.Status)
+						require.Equal(t, string(risk.Hold), review.Decision)
+						require.Equal(t, "test_owner", review.Owner)
+						require.Equal(t, arg.Amount, review.Amount)
+						require.Equal(t, arg.Fee, review.Fee)
+						return db.RiskReview{ID: 7}, nil
+					})
+			},
+			wantErr: db.ErrTransferDenied,
+		},
+		{
+			name:   "Denied",
+			action: risk.Deny,
+			amount: arg.Amount,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateRiskReview(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.RiskReview{ID: 8}, nil)
+			},
+			wantErr: db.ErrTransferDenied,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			riskEngine := risk.NewEngine()
+			riskEngine.Add(&risk.LargeAmount{Amounts: map[string]int64{util.USD: 200}}, tc.action)
+			transferArg := arg
+			transferArg.Amount = tc.amount
+
+			err := screenTransfer(store, riskEngine)(context.Background(), "test_owner", util.USD, transferArg)
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+				require.False(t, isTransientError(err))
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
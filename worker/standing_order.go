@@ -0,0 +1,97 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	db "github.com/go_backend_misc/db/sqlc"
+	"github.com/go_backend_misc/risk"
+	"github.com/go_backend_misc/util"
+)
+
+// StandingOrderExecutor makes the runs of the standing orders once they are due
+// Runs missed while no executor was running are caught up according to the catch up of each order
+// The risk rules run again on every run, a held or denied run is recorded as denied and fails
+type StandingOrderExecutor struct {
+	store        db.Store
+	riskEngine   *risk.Engine
+	pollInterval time.Duration
+	now          func() time.Time
+}
+
+func NewStandingOrderExecutor(store db.Store, config util.Config, riskEngine *risk.Engine) *StandingOrderExecutor {
+	return &StandingOrderExecutor{
+		store:        store,
+		riskEngine:   riskEngine,
+		pollInterval: config.StandingOrderPollInterval,
+		now:          time.Now,
+	}
+}
+
+// Run polls for due standing orders until the context is canceled
+func (executor *StandingOrderExecutor) Run(ctx context.Context) {
+	ticker := time.NewTicker(executor.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := executor.ExecuteDue(ctx); err != nil {
+			log.Printf("cannot execute standing orders: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ExecuteDue works through every due run and returns how many were attempted
+func (executor *StandingOrderExecutor) ExecuteDue(ctx context.Context) (attempted int, err error) {
+	now := executor.now()
+	for ctx.Err() == nil {
+		found, err := executor.executeNext(ctx, now)
+		if err != nil || !found {
+			return attempted, err
+		}
+		attempted++
+	}
+	return attempted, ctx.Err()
+}
+
+func (executor *StandingOrderExecutor) executeNext(ctx context.Context, now time.Time) (found bool, err error) {
+	order, run, err := executor.store.ExecuteStandingOrderTx(ctx, now, screenTransfer(executor.store, executor.riskEngine))
+	if err == nil {
+		log.Printf("standing order %d run for %v executed as transfer %d", order.ID, run.ScheduledFor, run.TransferID.Int64)
+		return true, nil
+	}
+	if order.ID == 0 {
+		// nothing was claimed, either nothing is due or the database is unavailable
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	// a run without the funds, or above the limits of the account, fails like a bounced payment,
+	// waiting for them would hold up the other orders
+	if isTransientError(err) && !errors.Is(err, db.ErrInsufficientFunds) && !errors.Is(err, db.ErrTransferLimitExceeded) {
+		// the order is still due, the next poll tries it again
+		return false, fmt.Errorf("standing order %d: %w", order.ID, err)
+	}
+
+	_, _, recordErr := executor.store.RecordStandingOrderFailureTx(ctx, db.RecordStandingOrderFailureTxParams{
+		ID:        order.ID,
+		NextRunAt: order.NextRunAt,
+		Error:     err.Error(),
+		Now:       now,
+	})
+	if recordErr != nil && !errors.Is(recordErr, sql.ErrNoRows) {
+		return false, recordErr
+	}
+	log.Printf("standing order %d run for %v failed: %v", order.ID, order.NextRunAt, err)
+	return true, nil
+}
@@ -0,0 +1,188 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	mockdb "github.com/go_backend_misc/db/mock"
+	db "github.com/go_backend_misc/db/sqlc"
+	"github.com/go_backend_misc/util"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestExecuteDueStandingOrders(t *testing.T) {
+	now := time.Now()
+	order := db.StandingOrder{
+		ID:        1,
+		Owner:     "test_owner",
+		Amount:    10,
+		Currency:  "USD",
+		Rrule:     "FREQ=DAILY",
+		Status:    db.StandingOrderActive,
+		NextRunAt: now.Add(-time.Hour),
+	}
+
+	testCases := []struct {
+		name          string
+		buildStubs    func(store *mockdb.MockStore)
+		wantAttempted int
+		wantErr       bool
+	}{
+		{
+			name: "Catches up every due run",
+			buildStubs: func(store *mockdb.MockStore) {
+				run := db.StandingOrderRun{StandingOrderID: order.ID, Status: db.StandingOrderRunSucceeded}
+				gomock.InOrder(
+					store.EXPECT().
+						ExecuteStandingOrderTx(gomock.Any(), gomock.Eq(now), gomock.Any()).
+						Times(3).
+						Return(order, run, nil),
+					store.EXPECT().
+						ExecuteStandingOrderTx(gomock.Any(), gomock.Eq(now), gomock.Any()).
+						Times(1).
+						Return(db.StandingOrder{}, db.StandingOrderRun{}, sql.ErrNoRows),
+				)
+				store.EXPECT().RecordStandingOrderFailureTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			wantAttempted: 3,
+		},
+		{
+			name: "Invalid run is recorded as failed",
+			buildStubs: func(store *mockdb.MockStore) {
+				invalid := fmt.Errorf("%w: account 2 not found", db.ErrScheduledTransferInvalid)
+				gomock.InOrder(
+					store.EXPECT().
+						ExecuteStandingOrderTx(gomock.Any(), gomock.Any(), gomock.Any()).
+						Times(1).
+						Return(order, db.StandingOrderRun{}, invalid),
+					store.EXPECT().
+						ExecuteStandingOrderTx(gomock.Any(), gomock.Any(), gomock.Any()).
+						Times(1).
+						Return(db.StandingOrder{}, db.StandingOrderRun{}, sql.ErrNoRows),
+				)
+				arg := db.RecordStandingOrderFailureTxParams{
+					ID:        order.ID,
+					NextRunAt: order.NextRunAt,
+					Error:     invalid.Error(),
+					Now:       now,
+				}
+				store.EXPECT().
+					RecordStandingOrderFailureTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(order, db.StandingOrderRun{Status: db.StandingOrderRunFailed}, nil)
+			},
+			wantAttempted: 1,
+		},
+		{
+			name: "Order changed before the failure was recorded",
+			buildStubs: func(store *mockdb.MockStore) {
+				gomock.InOrder(
+					store.EXPECT().
+						ExecuteStandingOrderTx(gomock.Any(), gomock.Any(), gomock.Any()).
+						Times(1).
+						Return(order, db.StandingOrderRun{}, &pq.Error{Code: "23503"}),
+					store.EXPECT().
+						ExecuteStandingOrderTx(gomock.Any(), gomock.Any(), gomock.Any()).
+						Times(1).
+						Return(db.StandingOrder{}, db.StandingOrderRun{}, sql.ErrNoRows),
+				)
+				store.EXPECT().
+					RecordStandingOrderFailureTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.StandingOrder{}, db.StandingOrderRun{}, sql.ErrNoRows)
+			},
+			wantAttempted: 1,
+		},
+		{
+			name: "Insufficient funds fails the run",
+			buildStubs: func(store *mockdb.MockStore) {
+				insufficient := fmt.Errorf("%w: account 1", db.ErrInsufficientFunds)
+				gomock.InOrder(
+					store.EXPECT().
+						ExecuteStandingOrderTx(gomock.Any(), gomock.Any(), gomock.Any()).
+						Times(1).
+						Return(order, db.StandingOrderRun{}, insufficient),
+					store.EXPECT().
+						ExecuteStandingOrderTx(gomock.Any(), gomock.Any(), gomock.Any()).
+						Times(1).
+						Return(db.StandingOrder{}, db.StandingOrderRun{}, sql.ErrNoRows),
+				)
+				store.EXPECT().
+					RecordStandingOrderFailureTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(order, db.StandingOrderRun{Status: db.StandingOrderRunFailed}, nil)
+			},
+			wantAttempted: 1,
+		},
+		{
+			name: "Transfer limit fails the run",
+			buildStubs: func(store *mockdb.MockStore) {
+				exceeded := fmt.Errorf("%w: daily limit of 5.00 USD, 0.00 USD left", db.ErrTransferLimitExceeded)
+				gomock.InOrder(
+					store.EXPECT().
+						ExecuteStandingOrderTx(gomock.Any(), gomock.Any(), gomock.Any()).
+						Times(1).
+						Return(order, db.StandingOrderRun{}, exceeded),
+					store.EXPECT().
+						ExecuteStandingOrderTx(gomock.Any(), gomock.Any(), gomock.Any()).
+						Times(1).
+						Return(db.StandingOrder{}, db.StandingOrderRun{}, sql.ErrNoRows),
+				)
+				store.EXPECT().
+					RecordStandingOrderFailureTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(order, db.StandingOrderRun{Status: db.StandingOrderRunFailed}, nil)
+			},
+			wantAttempted: 1,
+		},
+		{
+			name: "Transient error waits for the next poll",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ExecuteStandingOrderTx(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(order, db.StandingOrderRun{}, &pq.Error{Code: "40P01"})
+				store.EXPECT().RecordStandingOrderFailureTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			wantAttempted: 0,
+			wantErr:       true,
+		},
+		{
+			name: "Database unavailable",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ExecuteStandingOrderTx(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.StandingOrder{}, db.StandingOrderRun{}, sql.ErrConnDone)
+			},
+			wantAttempted: 0,
+			wantErr:       true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			executor := NewStandingOrderExecutor(store, util.Config{StandingOrderPollInterval: time.Minute}, nil)
+			executor.now = func() time.Time { return now }
+
+			attempted, err := executor.ExecuteDue(context.Background())
+			if tc.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tc.wantAttempted, attempted)
+		})
+	}
+}